@@ -0,0 +1,507 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"mywallet/internal/config"
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	"mywallet/internal/service"
+	"mywallet/pkg/logger"
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const adminUsage = `用法: mywallet admin <命令> [参数]
+
+  wallet create [--private-key KEY] [--key-id ID] [--client ID]
+                                                        创建托管钱包，不传私钥时生成新钱包；外部签名服务用 --key-id 登记已有密钥；
+                                                        --client 为归属的 API 客户端，不传时为平台钱包
+  wallet list                                           列出托管钱包
+  balance [--chain=false] ADDRESS                       对比 Redis、账本与链上余额
+  credit --reason TEXT [--operator NAME] ADDRESS AMOUNT 手动入账
+  debit --reason TEXT [--operator NAME] ADDRESS AMOUNT  手动出账
+  reconcile [--chain] [--fix] [ADDRESS...]              对账 Redis 与账本，--fix 以账本为准修正 Redis
+  outbox replay [--since T] [--until T] [--type TYPE] [--client ID] [EVENT_ID...]
+                                                        重新投递 webhook 事件，时间为 RFC3339
+  client create NAME                                    创建 API 客户端
+  client list                                           列出 API 客户端
+  client rotate-key CLIENT_ID                           轮换 API Key，旧 Key 立即失效
+  audit list [--address A] [--actor ID] [--operation OP] [--since T] [--until T] [--limit N]
+                                                        查询审计日志，时间为 RFC3339
+  audit verify                                          校验审计日志哈希链，输出链头哈希
+  treasury status                                       查询热钱包余额、阈值与补充请求
+  treasury sweep                                        立即执行一轮归集
+  keystore status                                       主密钥轮换进度：各版本加密的钱包数量与可下线的版本
+  keystore rotate                                       立即将旧版本主密钥加密的私钥重新加密为当前版本
+  hd mnemonic                                           生成新的 24 词助记词，用于配置 HD_MNEMONIC
+  hd recover [--start N] [--gap N] [--restore]          从助记词重新派生地址并扫描链上记录，--restore 恢复缺失的托管钱包
+  migrate up | down [n] | status                        数据库迁移
+`
+
+// adminCLI 运维命令使用的服务
+type adminCLI struct {
+	wallet  *service.WalletService
+	clients *service.ClientService
+	out     *tabwriter.Writer
+}
+
+// runAdmin 执行 admin 子命令
+func runAdmin(cfg *config.Config, l *logger.Logger, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Print(adminUsage)
+		return nil
+	}
+	if args[0] == "migrate" {
+		return runMigrate(cfg, l, args[1:])
+	}
+	// 生成助记词不需要连接数据库
+	if args[0] == "hd" && len(args) > 1 && args[1] == "mnemonic" {
+		mnemonic, err := solanaclient.NewMnemonic()
+		if err != nil {
+			return err
+		}
+		fmt.Println(mnemonic)
+		fmt.Fprintln(os.Stderr, "\n助记词只显示这一次，请离线备份；丢失后无法恢复派生的托管钱包")
+		return nil
+	}
+
+	postgres, err := repository.NewPostgresRepository(cfg.PostgresURL, l)
+	if err != nil {
+		return err
+	}
+	defer postgres.Close()
+	redis, err := repository.NewRedisRepository(cfg.RedisURL, l)
+	if err != nil {
+		return err
+	}
+	wallet, err := service.NewWalletService(l, cfg, postgres, redis)
+	if err != nil {
+		return err
+	}
+
+	a := &adminCLI{
+		wallet:  wallet,
+		clients: service.NewClientService(l, postgres),
+		out:     tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0),
+	}
+	defer a.out.Flush()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// 审计主体为执行命令的系统用户，同一次命令的操作使用相同的关联 ID
+	ctx = service.WithActor(ctx, models.Actor{Type: models.ActorAdmin, ID: "cli:" + os.Getenv("USER")})
+	ctx = service.WithRequestMeta(ctx, service.RequestMeta{CorrelationID: uuid.NewString()})
+
+	cmd, rest := args[0], args[1:]
+	switch cmd {
+	case "wallet":
+		return a.walletCmd(ctx, rest)
+	case "balance":
+		return a.balance(ctx, rest)
+	case "credit", "debit":
+		return a.adjust(ctx, cmd, rest)
+	case "reconcile":
+		return a.reconcile(ctx, rest)
+	case "outbox":
+		return a.outbox(ctx, rest)
+	case "client":
+		return a.clientCmd(ctx, rest)
+	case "audit":
+		return a.auditCmd(ctx, rest)
+	case "treasury":
+		return a.treasuryCmd(ctx, rest)
+	case "keystore":
+		return a.keystoreCmd(ctx, rest)
+	case "hd":
+		return a.hdCmd(ctx, rest)
+	default:
+		return fmt.Errorf("unknown admin command: %s\n\n%s", cmd, adminUsage)
+	}
+}
+
+func (a *adminCLI) walletCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: wallet create | wallet list")
+	}
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("wallet create", flag.ContinueOnError)
+		privateKey := fs.String("private-key", "", "导入的 base58 私钥")
+		keyID := fs.String("key-id", "", "外部签名服务上已有的密钥 ID")
+		clientID := fs.String("client", "", "归属的 API 客户端 ID")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		w, err := a.wallet.CreateManagedWallet(ctx, *privateKey, *keyID, *clientID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "address\t%s\nclient_id\t%s\nsigner\t%s\nkey_id\t%s\ncreated_at\t%s\n", w.Address, w.ClientID, w.Signer, w.KeyID, w.CreatedAt.Format(time.RFC3339))
+		return nil
+	case "list":
+		wallets, err := a.wallet.ListManagedWallets(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(a.out, "ADDRESS\tCLIENT_ID\tSIGNER\tKEY_ID\tINDEX\tCREATED_AT")
+		for _, w := range wallets {
+			index := "-"
+			if w.DerivationIndex != nil {
+				index = strconv.FormatInt(*w.DerivationIndex, 10)
+			}
+			clientID := w.ClientID
+			if clientID == "" {
+				clientID = "-"
+			}
+			fmt.Fprintf(a.out, "%s\t%s\t%s\t%s\t%s\t%s\n", w.Address, clientID, w.Signer, w.KeyID, index, w.CreatedAt.Format(time.RFC3339))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown wallet command: %s", args[0])
+	}
+}
+
+func (a *adminCLI) balance(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("balance", flag.ContinueOnError)
+	chain := fs.Bool("chain", true, "同时查询链上余额")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: balance [--chain=false] ADDRESS")
+	}
+
+	in, err := a.wallet.InspectBalance(ctx, fs.Arg(0), *chain)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "address\t%s\n", in.Address)
+	fmt.Fprintf(a.out, "redis\t%s\treserved %s\n", in.Redis, in.RedisReserved)
+	fmt.Fprintf(a.out, "ledger\t%s\treserved %s\tpending %s\n", in.Ledger, in.LedgerReserved, in.Pending)
+	switch {
+	case in.OnChain != nil:
+		fmt.Fprintf(a.out, "chain\t%s\n", in.OnChain)
+	case in.OnChainError != "":
+		fmt.Fprintf(a.out, "chain\terror: %s\n", in.OnChainError)
+	}
+	fmt.Fprintf(a.out, "consistent\t%t\n", in.Consistent)
+	return nil
+}
+
+func (a *adminCLI) adjust(ctx context.Context, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	reason := fs.String("reason", "", "调账原因（必填）")
+	operator := fs.String("operator", os.Getenv("USER"), "操作人")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: %s --reason TEXT [--operator NAME] ADDRESS AMOUNT", cmd)
+	}
+	amount, err := decimal.NewFromString(fs.Arg(1))
+	if err != nil || !amount.IsPositive() {
+		return fmt.Errorf("invalid amount: %s", fs.Arg(1))
+	}
+	if cmd == "debit" {
+		amount = amount.Neg()
+	}
+
+	adj, err := a.wallet.AdjustBalance(ctx, service.AdjustmentRequest{
+		Address:  fs.Arg(0),
+		Amount:   amount,
+		Reason:   *reason,
+		Operator: *operator,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "adjustment\t%s\ntransaction\t%s\naddress\t%s\namount\t%s\n",
+		adj.ID, adj.TransactionID, adj.Address, adj.Amount)
+	return nil
+}
+
+func (a *adminCLI) reconcile(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	chain := fs.Bool("chain", false, "同时对比链上余额（只报告，不修正）")
+	fix := fs.Bool("fix", false, "以账本为准修正 Redis")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	checked, mismatched, err := a.wallet.Reconcile(ctx, fs.Args(), *chain, *fix)
+	if len(mismatched) > 0 {
+		fmt.Fprintln(a.out, "ADDRESS\tREDIS\tLEDGER\tREDIS_RESERVED\tLEDGER_RESERVED\tCHAIN")
+		for _, m := range mismatched {
+			onChain := "-"
+			if m.OnChain != nil {
+				onChain = m.OnChain.String()
+			}
+			fmt.Fprintf(a.out, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				m.Address, m.Redis, m.Ledger, m.RedisReserved, m.LedgerReserved, onChain)
+		}
+	}
+	fmt.Fprintf(a.out, "checked %d, mismatched %d", checked, len(mismatched))
+	if *fix {
+		fmt.Fprint(a.out, ", redis reset to ledger")
+	}
+	fmt.Fprintln(a.out)
+	return err
+}
+
+func (a *adminCLI) outbox(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "replay" {
+		return fmt.Errorf("usage: outbox replay [--since T] [--until T] [--type TYPE] [--client ID] [EVENT_ID...]")
+	}
+	fs := flag.NewFlagSet("outbox replay", flag.ContinueOnError)
+	since := fs.String("since", "", "起始时间（RFC3339）")
+	until := fs.String("until", "", "截止时间（RFC3339，不含）")
+	eventType := fs.String("type", "", "事件类型")
+	clientID := fs.String("client", "", "API 客户端 ID")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	filter := repository.EventFilter{IDs: fs.Args(), Type: *eventType, ClientID: *clientID}
+	var err error
+	if filter.Since, err = parseTimeFlag("since", *since); err != nil {
+		return err
+	}
+	if filter.Until, err = parseTimeFlag("until", *until); err != nil {
+		return err
+	}
+
+	n, err := a.wallet.Webhooks().ReplayEvents(ctx, filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "created %d deliveries\n", n)
+	return nil
+}
+
+func (a *adminCLI) clientCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: client create NAME | client list | client rotate-key CLIENT_ID")
+	}
+	switch args[0] {
+	case "create":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: client create NAME")
+		}
+		client, key, err := a.clients.CreateClient(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		printClientKey(a.out, client, key)
+		return nil
+	case "list":
+		clients, err := a.clients.ListClients(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(a.out, "ID\tNAME\tCREATED_AT\tUPDATED_AT")
+		for _, c := range clients {
+			fmt.Fprintf(a.out, "%s\t%s\t%s\t%s\n", c.ID, c.Name,
+				c.CreatedAt.Format(time.RFC3339), c.UpdatedAt.Format(time.RFC3339))
+		}
+		return nil
+	case "rotate-key":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: client rotate-key CLIENT_ID")
+		}
+		client, key, err := a.clients.RotateAPIKey(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		printClientKey(a.out, client, key)
+		return nil
+	default:
+		return fmt.Errorf("unknown client command: %s", args[0])
+	}
+}
+
+func (a *adminCLI) auditCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: audit list [flags] | audit verify")
+	}
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("audit list", flag.ContinueOnError)
+		address := fs.String("address", "", "钱包地址")
+		actor := fs.String("actor", "", "操作主体 ID")
+		operation := fs.String("operation", "", "操作类型")
+		since := fs.String("since", "", "起始时间（RFC3339）")
+		until := fs.String("until", "", "截止时间（RFC3339，不含）")
+		limit := fs.Int("limit", 50, "返回数量")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		filter := repository.AuditFilter{Address: *address, ActorID: *actor, Operation: *operation, Limit: *limit}
+		var err error
+		if filter.Since, err = parseTimeFlag("since", *since); err != nil {
+			return err
+		}
+		if filter.Until, err = parseTimeFlag("until", *until); err != nil {
+			return err
+		}
+
+		entries, err := a.wallet.ListAudit(ctx, filter)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(a.out, "SEQ\tCREATED_AT\tACTOR\tOPERATION\tAMOUNT\tADDRESSES\tOUTCOME\tREFERENCE")
+		for _, e := range entries {
+			fmt.Fprintf(a.out, "%d\t%s\t%s:%s\t%s\t%s\t%s\t%s\t%s\n", e.Seq, e.CreatedAt.Format(time.RFC3339),
+				e.Actor.Type, e.Actor.ID, e.Operation, e.Amount, strings.Join(e.Addresses, ","), e.Outcome, e.Reference)
+		}
+		return nil
+	case "verify":
+		result, err := a.wallet.VerifyAudit(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "checked\t%d\nhead_seq\t%d\nhead_hash\t%s\n", result.Checked, result.HeadSeq, result.HeadHash)
+		if !result.Valid {
+			return fmt.Errorf("audit log broken at seq %d: %s", result.BrokenAt, result.Error)
+		}
+		fmt.Fprintln(a.out, "valid\ttrue")
+		return nil
+	default:
+		return fmt.Errorf("unknown audit command: %s", args[0])
+	}
+}
+
+func (a *adminCLI) treasuryCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: treasury status | treasury sweep")
+	}
+	switch args[0] {
+	case "status":
+		status, err := a.wallet.TreasuryStatus(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "hot_wallet\t%s\nhot_balance\t%s\nhot_min\t%s\nhot_max\t%s\ncold_wallet\t%s\n",
+			status.HotWallet, status.HotBalance, status.HotMin, status.HotMax, status.ColdWallet)
+		if status.TopUp != nil {
+			fmt.Fprintf(a.out, "topup\t%s %s (%s)\n", status.TopUp.Amount, status.TopUp.ID, status.TopUp.CreatedAt.Format(time.RFC3339))
+		}
+		return nil
+	case "sweep":
+		report, err := a.wallet.Sweep(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(a.out, "KIND\tFROM\tTO\tAMOUNT\tSIGNATURE")
+		movements := report.Swept
+		if report.Cold != nil {
+			movements = append(movements, *report.Cold)
+		}
+		if report.TopUp != nil {
+			movements = append(movements, *report.TopUp)
+		}
+		for _, m := range movements {
+			fmt.Fprintf(a.out, "%s\t%s\t%s\t%s\t%s\n", m.Kind, m.FromAddress, m.ToAddress, m.Amount, m.Signature)
+		}
+		fmt.Fprintf(a.out, "settled %d, swept %d\n", report.Settled, len(report.Swept))
+		for _, e := range report.Errors {
+			fmt.Fprintf(a.out, "error\t%s\n", e)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown treasury command: %s", args[0])
+	}
+}
+
+func (a *adminCLI) keystoreCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: keystore status | keystore rotate")
+	}
+	switch args[0] {
+	case "status":
+	case "rotate":
+		report, err := a.wallet.RotateKeys(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "rewrapped %d, failed %d\n", report.Rewrapped, report.Failed)
+		for _, e := range report.Errors {
+			fmt.Fprintf(a.out, "error\t%s\n", e)
+		}
+	default:
+		return fmt.Errorf("unknown keystore command: %s", args[0])
+	}
+
+	status, err := a.wallet.KeystoreStatus(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.out, "VERSION\tWALLETS\tSTATE")
+	for _, v := range status.Versions {
+		state := "pending"
+		switch {
+		case v.Active:
+			state = "active"
+		case !v.Configured:
+			state = "missing"
+		case v.Retirable:
+			state = "retirable"
+		}
+		fmt.Fprintf(a.out, "%d\t%d\t%s\n", v.Version, v.Wallets, state)
+	}
+	fmt.Fprintf(a.out, "pending %d\n", status.Pending)
+	return nil
+}
+
+func (a *adminCLI) hdCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "recover" {
+		return fmt.Errorf("usage: hd mnemonic | hd recover [--start N] [--gap N] [--restore]")
+	}
+	fs := flag.NewFlagSet("hd recover", flag.ContinueOnError)
+	start := fs.Int64("start", 0, "起始派生序号")
+	gap := fs.Int("gap", 20, "连续未使用的地址数达到该值后停止")
+	restore := fs.Bool("restore", false, "恢复链上有记录但数据库中缺失的托管钱包")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	report, err := a.wallet.RecoverHDWallets(ctx, service.HDRecoverOptions{Start: *start, Gap: *gap, Restore: *restore})
+	if report != nil {
+		fmt.Fprintln(a.out, "INDEX\tADDRESS\tSTATUS\tUSED\tBALANCE")
+		for _, w := range report.Wallets {
+			fmt.Fprintf(a.out, "%d\t%s\t%s\t%t\t%s\n", w.Index, w.Address, w.Status, w.Used, w.Balance)
+		}
+		fmt.Fprintf(a.out, "scanned %d, restored %d, missing %d, mismatched %d, next index %d\n",
+			report.Scanned, report.Restored, report.Missing, report.Mismatched, report.NextIndex)
+	}
+	return err
+}
+
+func printClientKey(out *tabwriter.Writer, client *models.APIClient, key string) {
+	fmt.Fprintf(out, "id\t%s\nname\t%s\napi_key\t%s\n", client.ID, client.Name, key)
+	fmt.Fprintln(out, "\nAPI Key 只显示这一次，请妥善保存")
+}
+
+func parseTimeFlag(name, value string) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", name, err)
+	}
+	// 事件时间以服务本地时间写入不带时区的 TIMESTAMP，按本地时间比较
+	t = t.Local()
+	return &t, nil
+}
@@ -1,14 +1,35 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	"mywallet/internal/config"
+	"mywallet/internal/migrate"
+	"mywallet/internal/repository"
 	"mywallet/internal/routes"
 	"mywallet/pkg/logger"
 )
 
 func main() {
+	// 离线签名不加载配置，可在隔离网络的机器上运行
+	if len(os.Args) > 1 && os.Args[1] == "sign" {
+		if err := runSign(os.Args[2:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+	// 本地签名服务同样不加载配置，与钱包服务分进程运行
+	if len(os.Args) > 1 && os.Args[1] == "signer" {
+		if err := runSigner(os.Args[2:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
 	// 初始化配置
 	cfg, err := config.Load()
 	if err != nil {
@@ -19,9 +40,81 @@ func main() {
 	l := logger.NewLogger()
 	defer l.Sync()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(cfg, l, os.Args[2:]); err != nil {
+				log.Fatalf("数据库迁移失败: %v", err)
+			}
+			return
+		case "admin":
+			if err := runAdmin(cfg, l, os.Args[2:]); err != nil {
+				log.Fatalf("%v", err)
+			}
+			return
+		case "serve":
+		default:
+			log.Fatalf("未知命令: %s (可用命令: serve, migrate, admin, sign, signer)", os.Args[1])
+		}
+	}
+
+	if cfg.MigrateOnStart {
+		if err := runMigrate(cfg, l, []string{"up"}); err != nil {
+			l.Fatal("数据库迁移失败", err)
+		}
+	}
+
 	// 初始化API服务器
 	r := routes.InitRouter()
 	if err := r.Run(cfg.ServerPort); err != nil {
 		l.Fatal("服务器启动失败", err)
 	}
 }
+
+// runMigrate 执行 migrate 子命令: migrate up | migrate down [n] | migrate status
+func runMigrate(cfg *config.Config, l *logger.Logger, args []string) error {
+	postgres, err := repository.NewPostgresRepository(cfg.PostgresURL, l)
+	if err != nil {
+		return err
+	}
+	defer postgres.Close()
+
+	migrator, err := migrate.New(postgres.GetDB(), l)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid step count: %s", args[1])
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command: %s", cmd)
+	}
+}
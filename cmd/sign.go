@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const signUsage = `用法: mywallet sign [advance-nonce] --keypair PATH [--in FILE] [--out FILE] [--qr] [--yes]
+
+在离线机器上签名 POST /withdrawals/offline 导出的交易，不读取配置，不访问网络。
+指定 advance-nonce 时不签名出账交易，而是为其生成并签名推进同一 nonce 的交易，导入
+POST /withdrawals/offline/:id/advance-nonce 后作废已取消出账的交易。
+  --keypair PATH  签名私钥，solana-keygen 生成的 JSON 文件或 base58 私钥文件
+  --in FILE       未签名交易（base64 或 base32），默认从标准输入读取
+  --out FILE      签名后的交易，默认输出到标准输出
+  --qr            以 base32 输出，便于生成二维码
+  --yes           不确认直接签名，从标准输入读取交易时必须指定
+`
+
+// runSign 执行 sign 子命令
+func runSign(args []string) error {
+	advance := len(args) > 0 && args[0] == "advance-nonce"
+	if advance {
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, signUsage) }
+	keypair := fs.String("keypair", "", "签名私钥文件")
+	in := fs.String("in", "-", "未签名交易文件")
+	out := fs.String("out", "-", "签名后交易文件")
+	qr := fs.Bool("qr", false, "以 base32 输出")
+	yes := fs.Bool("yes", false, "不确认直接签名")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keypair == "" {
+		return fmt.Errorf("--keypair is required\n\n%s", signUsage)
+	}
+	if *in == "-" && !*yes {
+		return fmt.Errorf("--yes is required when reading the transaction from stdin")
+	}
+
+	key, err := loadKeypair(*keypair)
+	if err != nil {
+		return err
+	}
+	payload, err := readInput(*in)
+	if err != nil {
+		return err
+	}
+	tx, err := solanaclient.DecodeTransaction(payload)
+	if err != nil {
+		return err
+	}
+	transfer, err := solanaclient.ParseOfflineTransfer(tx)
+	if err != nil {
+		return err
+	}
+	if !transfer.From.Equals(key.PublicKey()) {
+		return fmt.Errorf("transaction must be signed by %s, keypair is %s", transfer.From, key.PublicKey())
+	}
+
+	fmt.Fprintf(os.Stderr, "from\t%s\nto\t%s\namount\t%s SOL\n", transfer.From, transfer.To, transfer.Amount)
+	if transfer.Memo != "" {
+		fmt.Fprintf(os.Stderr, "memo\t%s\n", transfer.Memo)
+	}
+	fmt.Fprintf(os.Stderr, "nonce\t%s (%s)\n", transfer.Nonce, transfer.NonceAccount)
+	prompt := "签名该交易? [y/N] "
+	if advance {
+		// 推进 nonce 的交易上链后，上面的出账交易不可能再上链
+		if tx, err = solanaclient.BuildNonceAdvance(solanaclient.NonceAdvance{
+			NonceAccount: transfer.NonceAccount,
+			Authority:    transfer.From,
+			Nonce:        transfer.Nonce,
+		}); err != nil {
+			return err
+		}
+		prompt = "签名推进 nonce 的交易以作废该出账? [y/N] "
+	}
+	if !*yes && !confirm(prompt) {
+		return fmt.Errorf("aborted")
+	}
+
+	if _, err := tx.PartialSign(func(pub solana.PublicKey) *solana.PrivateKey {
+		if pub.Equals(key.PublicKey()) {
+			return &key
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	encode := solanaclient.EncodeTransaction
+	if *qr {
+		encode = solanaclient.EncodeTransactionQR
+	}
+	signed, err := encode(tx)
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		_, err = fmt.Println(signed)
+		return err
+	}
+	return os.WriteFile(*out, []byte(signed+"\n"), 0o600)
+}
+
+// loadKeypair 读取 solana-keygen JSON 私钥文件，失败时按 base58 私钥读取
+func loadKeypair(path string) (solana.PrivateKey, error) {
+	if key, err := solana.PrivateKeyFromSolanaKeygenFile(path); err == nil {
+		return key, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keypair: %w", err)
+	}
+	key, err := solana.PrivateKeyFromBase58(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("keypair is neither a solana-keygen file nor a base58 private key")
+	}
+	return key, nil
+}
+
+func readInput(path string) (string, error) {
+	var (
+		raw []byte
+		err error
+	)
+	if path == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read transaction: %w", err)
+	}
+	return string(raw), nil
+}
+
+func confirm(prompt string) bool {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
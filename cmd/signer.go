@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const signerUsage = `用法: mywallet signer serve --keys DIR --token TOKEN [--listen ADDR] [--pkcs11-slot N] [--pkcs11-pin PIN]
+
+本地签名服务，供开发和测试时代替 KMS/HSM，不读取配置。
+  --keys DIR         私钥目录，每个 solana-keygen JSON 文件一个密钥，密钥 ID 为去掉 .json 的文件名，
+                     生成的新密钥也写入该目录
+  --listen ADDR      监听地址，默认 127.0.0.1:8090
+  --token TOKEN      /v1/keys/ 远程签名协议的 Bearer token（SIGNER_BACKEND=remote）
+  --pkcs11-slot N    /pkcs11 模拟令牌的 slot（SIGNER_BACKEND=pkcs11）
+  --pkcs11-pin PIN   /pkcs11 模拟令牌的 PIN，为空时不提供 /pkcs11
+`
+
+// runSigner 执行 signer 子命令
+func runSigner(args []string) error {
+	if len(args) == 0 || args[0] != "serve" {
+		return fmt.Errorf("%s", signerUsage)
+	}
+	fs := flag.NewFlagSet("signer serve", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, signerUsage) }
+	dir := fs.String("keys", "", "私钥目录")
+	listen := fs.String("listen", "127.0.0.1:8090", "监听地址")
+	token := fs.String("token", "", "远程签名协议 token")
+	slot := fs.Uint64("pkcs11-slot", 0, "模拟令牌 slot")
+	pin := fs.String("pkcs11-pin", "", "模拟令牌 PIN")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return fmt.Errorf("--keys is required\n\n%s", signerUsage)
+	}
+	if *token == "" && *pin == "" {
+		return fmt.Errorf("--token or --pkcs11-pin is required\n\n%s", signerUsage)
+	}
+
+	signer, err := newDirSigner(*dir)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	if *token != "" {
+		mux.Handle("/v1/keys/", solanaclient.NewRemoteSignerHandler(signer, *token))
+	}
+	if *pin != "" {
+		mux.Handle("/pkcs11", solanaclient.NewPKCS11Handler(signer, *slot, *pin))
+	}
+	log.Printf("signer listening on %s with %d keys", *listen, len(signer.KeyIDs()))
+	return http.ListenAndServe(*listen, mux)
+}
+
+// dirSigner 从目录加载私钥，生成的新密钥写回目录
+type dirSigner struct {
+	*solanaclient.LocalSigner
+	dir string
+	mu  sync.Mutex
+}
+
+func newDirSigner(dir string) (*dirSigner, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	s := &dirSigner{LocalSigner: solanaclient.NewLocalSigner(), dir: dir}
+	for _, file := range files {
+		key, err := solana.PrivateKeyFromSolanaKeygenFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", file, err)
+		}
+		s.Add(strings.TrimSuffix(filepath.Base(file), ".json"), key)
+	}
+	return s, nil
+}
+
+func (s *dirSigner) GenerateKey(ctx context.Context, keyID string) (solana.PublicKey, error) {
+	if keyID != filepath.Base(keyID) || strings.HasPrefix(keyID, ".") {
+		return solana.PublicKey{}, fmt.Errorf("invalid key id %q", keyID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.PublicKey(ctx, keyID); err == nil {
+		return solana.PublicKey{}, fmt.Errorf("%w: %s", solanaclient.ErrKeyExists, keyID)
+	}
+
+	key := solana.NewWallet().PrivateKey
+	raw := make([]int, len(key))
+	for i, b := range key {
+		raw[i] = int(b)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	// O_EXCL 防止覆盖已有私钥文件
+	f, err := os.OpenFile(filepath.Join(s.dir, keyID+".json"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to write key: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return solana.PublicKey{}, fmt.Errorf("failed to write key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to write key: %w", err)
+	}
+	s.Add(keyID, key)
+	return key.PublicKey(), nil
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 地址簿处理方法，API 客户端与管理员共用，由服务按调用方限定范围
+
+func (s *Server) ListAddressBook(c *gin.Context) {
+	entries, err := s.wallet.ListAddressBook(c.Request.Context(), c.Query("scope"), c.Query("subject"))
+	if err != nil {
+		respondAddressBookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func (s *Server) AddAddress(c *gin.Context) {
+	var req struct {
+		Scope   string   `json:"scope"`
+		Subject string   `json:"subject"`
+		Address string   `json:"address" binding:"required"`
+		Labels  []string `json:"labels"`
+		Memo    string   `json:"memo"`
+		// CooldownSeconds 仅管理员可用，不传时使用 WHITELIST_COOLDOWN
+		CooldownSeconds *int64 `json:"cooldown_seconds"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var cooldown *time.Duration
+	if req.CooldownSeconds != nil {
+		d := time.Duration(*req.CooldownSeconds) * time.Second
+		cooldown = &d
+	}
+	entry, err := s.wallet.AddAddress(c.Request.Context(), service.AddressBookRequest{
+		Scope:    req.Scope,
+		Subject:  req.Subject,
+		Address:  req.Address,
+		Labels:   req.Labels,
+		Memo:     req.Memo,
+		Cooldown: cooldown,
+	})
+	if err != nil {
+		respondAddressBookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entry": entry})
+}
+
+func (s *Server) DeleteAddress(c *gin.Context) {
+	if err := s.wallet.DeleteAddress(c.Request.Context(), c.Param("id")); err != nil {
+		respondAddressBookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "address book entry deleted"})
+}
+
+func respondAddressBookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrClientRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAddressBookEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAddressBookEntryExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// 管理接口处理方法
+
+func (s *Server) CreateClient(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, key, err := s.clients.CreateClient(c.Request.Context(), req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"client": client, "api_key": key})
+}
+
+func (s *Server) ListLimits(c *gin.Context) {
+	limits, err := s.wallet.Limits().ListLimits(c.Request.Context(), c.Query("scope"), c.Query("subject"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"limits": limits})
+}
+
+func (s *Server) SetLimit(c *gin.Context) {
+	var req struct {
+		Scope         string           `json:"scope" binding:"required"`
+		Subject       string           `json:"subject" binding:"required"`
+		Asset         string           `json:"asset"`
+		PerTxMax      *decimal.Decimal `json:"per_tx_max"`
+		DailyAmount   *decimal.Decimal `json:"daily_amount"`
+		DailyCount    *int64           `json:"daily_count"`
+		MonthlyAmount *decimal.Decimal `json:"monthly_amount"`
+		MonthlyCount  *int64           `json:"monthly_count"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := s.wallet.Limits().SetLimit(c.Request.Context(), &models.Limit{
+		Scope:         req.Scope,
+		Subject:       req.Subject,
+		Asset:         req.Asset,
+		PerTxMax:      req.PerTxMax,
+		DailyAmount:   req.DailyAmount,
+		DailyCount:    req.DailyCount,
+		MonthlyAmount: req.MonthlyAmount,
+		MonthlyCount:  req.MonthlyCount,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"limit": limit})
+}
+
+func (s *Server) DeleteLimit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit id"})
+		return
+	}
+
+	if err := s.wallet.Limits().DeleteLimit(c.Request.Context(), id); err != nil {
+		if errors.Is(err, service.ErrLimitNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "limit deleted"})
+}
+
+func (s *Server) GetLimitStatus(c *gin.Context) {
+	scope, subject := c.Query("scope"), c.Query("subject")
+	if scope == "" || subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope and subject are required"})
+		return
+	}
+
+	status, err := s.wallet.Limits().Status(c.Request.Context(), scope, subject, c.Query("asset"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (s *Server) CreateManagedWallet(c *gin.Context) {
+	var req struct {
+		// 为空时生成新钱包，否则导入该私钥
+		PrivateKey string `json:"private_key"`
+		// 使用外部签名服务时登记签名服务上已有的密钥
+		KeyID string `json:"key_id"`
+		// 钱包归属的 API 客户端，为空时为平台钱包
+		ClientID string `json:"client_id"`
+	}
+
+	// 允许空请求体
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wallet, err := s.wallet.CreateManagedWallet(c.Request.Context(), req.PrivateKey, req.KeyID, req.ClientID)
+	if err != nil {
+		if errors.Is(err, service.ErrKeystoreDisabled) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"wallet": wallet})
+}
+
+func (s *Server) ListManagedWallets(c *gin.Context) {
+	wallets, err := s.wallet.ListManagedWallets(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"wallets": wallets})
+}
+
+func (s *Server) ListAudit(c *gin.Context) {
+	filter := repository.AuditFilter{
+		Address:   c.Query("address"),
+		ActorType: c.Query("actor_type"),
+		ActorID:   c.Query("actor_id"),
+		Operation: c.Query("operation"),
+	}
+	for name, dst := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+				return
+			}
+			*dst = &t
+		}
+	}
+	// 分页：传入上一页最后一条记录的 seq
+	if value := c.Query("before_seq"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before_seq"})
+			return
+		}
+		filter.BeforeSeq = n
+	}
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := s.wallet.ListAudit(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func (s *Server) VerifyAudit(c *gin.Context) {
+	result, err := s.wallet.VerifyAudit(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
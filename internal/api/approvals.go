@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"mywallet/internal/models"
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// 大额出账审批处理方法
+
+func (s *Server) ListApprovalPolicies(c *gin.Context) {
+	policies, err := s.wallet.ListApprovalPolicies(c.Request.Context(), c.Query("address"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+func (s *Server) SetApprovalPolicy(c *gin.Context) {
+	var req struct {
+		Address           string          `json:"address"`
+		MinAmount         decimal.Decimal `json:"min_amount"`
+		RequiredApprovals int             `json:"required_approvals" binding:"required"`
+		Approvers         []string        `json:"approvers" binding:"required"`
+		TTLSeconds        int64           `json:"ttl_seconds"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := s.wallet.SetApprovalPolicy(c.Request.Context(), &models.ApprovalPolicy{
+		Address:           req.Address,
+		MinAmount:         req.MinAmount,
+		RequiredApprovals: req.RequiredApprovals,
+		Approvers:         req.Approvers,
+		TTLSeconds:        req.TTLSeconds,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policy": policy})
+}
+
+func (s *Server) DeleteApprovalPolicy(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid policy id"})
+		return
+	}
+
+	if err := s.wallet.DeleteApprovalPolicy(c.Request.Context(), id); err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "approval policy deleted"})
+}
+
+func (s *Server) ListApprovals(c *gin.Context) {
+	approvals, err := s.wallet.ListApprovals(c.Request.Context(), c.Query("client_id"), c.Query("status"))
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"approvals": approvals})
+}
+
+func (s *Server) GetApproval(c *gin.Context) {
+	approval, err := s.wallet.GetApproval(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"approval": approval})
+}
+
+func (s *Server) ApproveRequest(c *gin.Context) {
+	s.decideApproval(c, s.wallet.ApproveRequest)
+}
+
+func (s *Server) RejectRequest(c *gin.Context) {
+	s.decideApproval(c, s.wallet.RejectRequest)
+}
+
+func (s *Server) decideApproval(c *gin.Context, decide func(ctx context.Context, id, comment string) (*models.ApprovalRequest, error)) {
+	var req struct {
+		Comment string `json:"comment"`
+	}
+
+	// 允许空请求体
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	approval, err := decide(c.Request.Context(), c.Param("id"), req.Comment)
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"approval": approval})
+}
+
+func respondApprovalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrClientRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotApprover):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrApprovalNotFound), errors.Is(err, service.ErrApprovalPolicyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrApprovalNotPending), errors.Is(err, service.ErrAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 代付手续费处理方法
+
+func (s *Server) GetSponsoredFees(c *gin.Context) {
+	summary, err := s.wallet.SponsoredFees(c.Request.Context())
+	if err != nil {
+		respondTreasuryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"fees": summary})
+}
+
+func (s *Server) SummarizeSponsoredFees(c *gin.Context) {
+	var since, until *time.Time
+	for name, dst := range map[string]**time.Time{"since": &since, "until": &until} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	summaries, err := s.wallet.SummarizeSponsoredFees(c.Request.Context(), since, until)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"fees": summaries})
+}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"mywallet/internal/config"
 	"mywallet/internal/repository"
@@ -13,9 +16,11 @@ import (
 )
 
 type Server struct {
-	cfg    *config.Config
-	logger *logger.Logger
-	wallet *service.WalletService
+	cfg     *config.Config
+	logger  *logger.Logger
+	redis   *repository.RedisRepository
+	wallet  *service.WalletService
+	clients *service.ClientService
 }
 
 func NewServer(cfg *config.Config, logger *logger.Logger) *Server {
@@ -26,30 +31,83 @@ func NewServer(cfg *config.Config, logger *logger.Logger) *Server {
 	}
 	redis, err := repository.NewRedisRepository(cfg.RedisURL, logger)
 	if err != nil {
-		wallet, s_err := service.NewWalletService(logger, cfg.SolanaRPC, postgres, redis)
-		if s_err != nil {
-			s := &Server{
-				cfg:    cfg,
-				logger: logger,
-				wallet: wallet,
-			}
-			return s
-		} else {
-			logger.Fatal("failed to create wallet service", err)
-			return nil
-		}
-
-	} else {
 		logger.Fatal("failed to create redis repository", err)
 		return nil
 	}
+	wallet, err := service.NewWalletService(logger, cfg, postgres, redis)
+	if err != nil {
+		logger.Fatal("failed to create wallet service", err)
+		return nil
+	}
+	return &Server{
+		cfg:     cfg,
+		logger:  logger,
+		redis:   redis,
+		wallet:  wallet,
+		clients: service.NewClientService(logger, postgres),
+	}
+}
+
+// StartWorkers 启动后台任务，ctx 取消时退出
+func (s *Server) StartWorkers(ctx context.Context) {
+	go s.wallet.RunHoldExpirer(ctx, time.Minute)
+	go s.wallet.RunApprovalExpirer(ctx, time.Minute)
+	go s.wallet.RunScheduler(ctx, 15*time.Second)
+	go s.wallet.Webhooks().RunDispatcher(ctx, 5*time.Second)
+	go s.wallet.Streams().Run(ctx)
+	go s.wallet.RunRPCProbe(ctx, s.cfg.RPCProbeInterval)
+	go s.wallet.RunSubscriptions(ctx)
+	if s.cfg.HotWalletAddress != "" {
+		go s.wallet.RunSweeper(ctx, s.cfg.SweepInterval)
+	}
+	if s.cfg.OfflineSignerAddress != "" && len(s.cfg.NonceAccounts) > 0 {
+		go s.wallet.RunOfflineTracker(ctx, s.cfg.OfflineTrackInterval)
+	}
+	// 配置了多个版本的主密钥时，将旧版本加密的私钥重新加密为当前版本
+	if len(s.cfg.KeystoreMasterKeys) > 1 {
+		go s.wallet.RunKeyRotation(ctx, s.cfg.KeyRotationInterval)
+	}
+}
+
+// respondError 返回错误响应，超出限额时附带剩余额度，需要审批时返回 202 及审批请求，
+// RPC 不可用时返回 503，交易发送结果未知时返回 504
+func respondError(c *gin.Context, err error) {
+	var limitErr *service.LimitExceededError
+	if errors.As(err, &limitErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "limit": limitErr})
+		return
+	}
+	var pending *service.PendingApprovalError
+	if errors.As(err, &pending) {
+		c.JSON(http.StatusAccepted, gin.H{"message": err.Error(), "approval": pending.Approval})
+		return
+	}
+	if errors.Is(err, service.ErrAddressNotWhitelisted) || errors.Is(err, service.ErrAddressCoolingDown) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrDuplicateReference) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrSendUnconfirmed) {
+		// 交易可能已上链，客户端不应重试
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrUpstreamUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 }
 
 // API 处理方法
 func (s *Server) Deposit(c *gin.Context) {
 	var req struct {
-		Address string `json:"address" binding:"required"`
-		Amount  string `json:"amount" binding:"required"`
+		Address         string `json:"address" binding:"required"`
+		Amount          string `json:"amount" binding:"required"`
+		ClientReference string `json:"client_reference"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -63,17 +121,20 @@ func (s *Server) Deposit(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
 		return
 	}
-	if err := s.wallet.Deposit(c.Request.Context(), req.Address, amount); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := service.WithClientReference(c.Request.Context(), req.ClientReference)
+	tx, err := s.wallet.Deposit(ctx, req.Address, amount)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "deposit successful"})
+	c.JSON(http.StatusOK, gin.H{"message": "deposit successful", "transaction": tx})
 }
 
 func (s *Server) Withdraw(c *gin.Context) {
 	var req struct {
-		Address string `json:"address" binding:"required"`
-		Amount  string `json:"amount" binding:"required"`
+		Address         string `json:"address" binding:"required"`
+		Amount          string `json:"amount" binding:"required"`
+		ClientReference string `json:"client_reference"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -87,18 +148,21 @@ func (s *Server) Withdraw(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
 		return
 	}
-	if err := s.wallet.Withdraw(c.Request.Context(), req.Address, amount); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := service.WithClientReference(c.Request.Context(), req.ClientReference)
+	tx, err := s.wallet.Withdraw(ctx, req.Address, amount)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "withdrawal successful"})
+	c.JSON(http.StatusOK, gin.H{"message": "withdrawal successful", "transaction": tx})
 }
 
 func (s *Server) Transfer(c *gin.Context) {
 	var req struct {
-		FromAddress string `json:"from_address" binding:"required"`
-		ToAddress   string `json:"to_address" binding:"required"`
-		Amount      string `json:"amount" binding:"required"`
+		FromAddress     string `json:"from_address" binding:"required"`
+		ToAddress       string `json:"to_address" binding:"required"`
+		Amount          string `json:"amount" binding:"required"`
+		ClientReference string `json:"client_reference"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -112,23 +176,93 @@ func (s *Server) Transfer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
 		return
 	}
-	if err := s.wallet.Transfer(c.Request.Context(), req.FromAddress, req.ToAddress, amount); err != nil {
+	ctx := service.WithClientReference(c.Request.Context(), req.ClientReference)
+	tx, err := s.wallet.Transfer(ctx, req.FromAddress, req.ToAddress, amount)
+	if err != nil && tx != nil {
+		// 链上转账已成功但账本更新失败，资金已经转出，客户端不能重试
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":           "recorded on chain, ledger update failed: " + err.Error(),
+			"transaction":     tx,
+			"chain_signature": tx.ChainSignature,
+			"retryable":       false,
+		})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transfer successful", "transaction": tx})
+}
+
+// SimulateTransfer 模拟转账，返回手续费、计算单元、程序日志、余额变化与失败原因
+func (s *Server) SimulateTransfer(c *gin.Context) {
+	var req struct {
+		FromAddress string `json:"from_address" binding:"required"`
+		ToAddress   string `json:"to_address" binding:"required"`
+		Amount      string `json:"amount" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transfer successful"})
+
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+		return
+	}
+	result, err := s.wallet.SimulateTransfer(c.Request.Context(), req.FromAddress, req.ToAddress, amount)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	changes := make([]gin.H, 0, len(result.BalanceChanges))
+	for _, change := range result.BalanceChanges {
+		changes = append(changes, gin.H{
+			"address": change.Address,
+			"before":  change.Before,
+			"after":   change.After,
+			"change":  change.Change(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":         result.Err == nil,
+		"fee":             result.Fee,
+		"compute_units":   result.UnitsConsumed,
+		"logs":            result.Logs,
+		"balance_changes": changes,
+		"error":           result.Err,
+		"slot":            result.Slot,
+	})
 }
 
 func (s *Server) GetBalance(c *gin.Context) {
 	address := c.Param("address")
 
-	// TODO: 实现余额查询逻辑
-	balance, err := s.wallet.GetBalance(c.Request.Context(), address)
+	opts := service.BalanceOptions{
+		Fresh:      c.Query("fresh") == "true",
+		Commitment: c.Query("commitment"),
+	}
+	view, err := s.wallet.GetBalanceView(c.Request.Context(), address, opts)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"address": address, "balance": balance})
+	c.JSON(http.StatusOK, gin.H{
+		"address":        address,
+		"balance":        view.Available,
+		"available":      view.Available,
+		"reserved":       view.Reserved,
+		"pending":        view.Pending,
+		"holds":          view.Holds,
+		"on_chain":       view.OnChain,
+		"on_chain_error": view.OnChainError,
+		"cached":         view.Cached,
+		"as_of":          view.AsOf,
+	})
 }
 
 func (s *Server) GetTransactions(c *gin.Context) {
@@ -145,3 +279,21 @@ func (s *Server) GetTransactions(c *gin.Context) {
 		"transactions": transactions,
 	})
 }
+
+// LookupTransactions 按 id、chain_signature 或 client_reference 查询交易
+func (s *Server) LookupTransactions(c *gin.Context) {
+	transactions, err := s.wallet.LookupTransactions(c.Request.Context(), service.TransactionLookup{
+		ID:              c.Query("id"),
+		ChainSignature:  c.Query("chain_signature"),
+		ClientReference: c.Query("client_reference"),
+	})
+	if errors.Is(err, service.ErrTransactionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"transactions": transactions})
+}
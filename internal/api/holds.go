@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// 资金冻结处理方法
+
+func (s *Server) CreateHold(c *gin.Context) {
+	var req struct {
+		Address    string `json:"address" binding:"required"`
+		Amount     string `json:"amount" binding:"required"`
+		TTLSeconds int64  `json:"ttl_seconds"`
+		Reference  string `json:"reference"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+		return
+	}
+	hold, err := s.wallet.CreateHold(c.Request.Context(), req.Address, amount,
+		time.Duration(req.TTLSeconds)*time.Second, req.Reference)
+	if err != nil {
+		respondHoldError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"hold": hold})
+}
+
+func (s *Server) ListHolds(c *gin.Context) {
+	address := c.Query("address")
+	if address == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "address is required"})
+		return
+	}
+
+	holds, err := s.wallet.ListHolds(c.Request.Context(), address, c.Query("status"))
+	if err != nil {
+		respondHoldError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": address, "holds": holds})
+}
+
+func (s *Server) GetHold(c *gin.Context) {
+	hold, err := s.wallet.GetHold(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondHoldError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"hold": hold})
+}
+
+func (s *Server) CaptureHold(c *gin.Context) {
+	var req struct {
+		// 为空时扣除全部冻结金额
+		Amount string `json:"amount"`
+	}
+
+	// 允许空请求体
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var amount *decimal.Decimal
+	if req.Amount != "" {
+		d, err := decimal.NewFromString(req.Amount)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+			return
+		}
+		amount = &d
+	}
+
+	hold, err := s.wallet.CaptureHold(c.Request.Context(), c.Param("id"), amount)
+	if err != nil {
+		respondHoldError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"hold": hold})
+}
+
+func (s *Server) ReleaseHold(c *gin.Context) {
+	hold, err := s.wallet.ReleaseHold(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondHoldError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"hold": hold})
+}
+
+func respondHoldError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrClientRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrHoldNotFound), errors.Is(err, service.ErrManagedWalletNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrHoldNotActive), errors.Is(err, service.ErrSystemHold):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
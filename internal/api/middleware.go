@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"mywallet/internal/models"
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	apiKeyHeader     = "X-API-Key"
+	adminTokenHeader = "X-Admin-Token"
+	requestIDHeader  = "X-Request-ID"
+	clientIDKey      = "client_id"
+	// maxAuditBody 计算请求体哈希时读取的最大长度
+	maxAuditBody = 1 << 20
+	// maxRequestIDLen 超过长度的请求 ID 重新生成
+	maxRequestIDLen = 128
+)
+
+// RequestMeta 记录请求来源 IP、请求 ID 与请求体哈希，供审计日志使用。
+// 请求 ID 优先使用 X-Request-ID，没有时生成，并在响应头中返回
+func (s *Server) RequestMeta() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLen {
+			requestID = uuid.NewString()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		meta := service.RequestMeta{IP: c.ClientIP(), CorrelationID: requestID}
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody+1))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+				return
+			}
+			if len(body) > maxAuditBody {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+				return
+			}
+			if len(body) > 0 {
+				sum := sha256.Sum256(body)
+				meta.PayloadHash = hex.EncodeToString(sum[:])
+			}
+			// 放回请求体供后续绑定
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		c.Request = c.Request.WithContext(service.WithRequestMeta(c.Request.Context(), meta))
+		c.Next()
+	}
+}
+
+// ClientAuth 根据 X-API-Key 识别 API 客户端，并写入请求 context
+func (s *Server) ClientAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(apiKeyHeader)
+		if key == "" {
+			// 关闭 REQUIRE_API_KEY 时也只放行匿名的只读请求，变更资金或状态的接口必须携带 API Key
+			if s.cfg.RequireAPIKey || !readOnlyMethod(c.Request.Method) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing api key"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		client, err := s.clients.Authenticate(c.Request.Context(), key)
+		if err != nil {
+			s.logger.Logger.Error("failed to authenticate api client", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to authenticate"})
+			return
+		}
+		if client == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+			return
+		}
+
+		c.Set(clientIDKey, client.ID)
+		c.Request = c.Request.WithContext(service.WithClientID(c.Request.Context(), client.ID))
+		c.Next()
+	}
+}
+
+func readOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// AdminAuth 校验管理接口令牌，操作人由令牌确定：操作人令牌对应配置的操作人，
+// 共用的 ADMIN_TOKEN 记为 service.SharedAdminOperator
+func (s *Server) AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.cfg.AdminToken == "" && len(s.cfg.AdminOperators) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin api is disabled"})
+			return
+		}
+		operator := s.adminOperator(c.GetHeader(adminTokenHeader))
+		if operator == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(service.WithActor(c.Request.Context(),
+			models.Actor{Type: models.ActorAdmin, ID: operator}))
+		c.Next()
+	}
+}
+
+// adminOperator 返回令牌对应的操作人，令牌无效时返回空。逐个比较全部令牌，耗时与匹配位置无关
+func (s *Server) adminOperator(token string) string {
+	if token == "" {
+		return ""
+	}
+	operator := ""
+	if s.cfg.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminToken)) == 1 {
+		operator = service.SharedAdminOperator
+	}
+	for name, operatorToken := range s.cfg.AdminOperators {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(operatorToken)) == 1 {
+			operator = name
+		}
+	}
+	return operator
+}
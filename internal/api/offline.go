@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"mywallet/internal/repository"
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// 离线签名出账处理方法
+
+func (s *Server) CreateOfflineWithdrawal(c *gin.Context) {
+	var req struct {
+		Address   string `json:"address" binding:"required"`
+		ToAddress string `json:"to_address" binding:"required"`
+		Amount    string `json:"amount" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+		return
+	}
+	withdrawal, err := s.wallet.PrepareOfflineWithdrawal(c.Request.Context(), req.Address, req.ToAddress, amount)
+	if err != nil {
+		respondOfflineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawal": withdrawal})
+}
+
+func (s *Server) ListOfflineWithdrawals(c *gin.Context) {
+	withdrawals, err := s.wallet.ListOfflineWithdrawals(c.Request.Context(), c.Query("client_id"), c.Query("status"))
+	if err != nil {
+		respondOfflineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals})
+}
+
+func (s *Server) GetOfflineWithdrawal(c *gin.Context) {
+	withdrawal, err := s.wallet.GetOfflineWithdrawal(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondOfflineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawal": withdrawal})
+}
+
+func (s *Server) SubmitOfflineWithdrawal(c *gin.Context) {
+	var req struct {
+		// 离线签名后的交易，base64 或二维码使用的 base32
+		Transaction string `json:"transaction" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	withdrawal, err := s.wallet.SubmitOfflineWithdrawal(c.Request.Context(), c.Param("id"), req.Transaction)
+	if err != nil {
+		respondOfflineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawal": withdrawal})
+}
+
+func (s *Server) CancelOfflineWithdrawal(c *gin.Context) {
+	withdrawal, err := s.wallet.CancelOfflineWithdrawal(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondOfflineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawal": withdrawal})
+}
+
+func (s *Server) AdvanceOfflineNonce(c *gin.Context) {
+	var req struct {
+		// 离线签名的 nonce 推进交易，由 mywallet sign advance-nonce 生成
+		Transaction string `json:"transaction" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	withdrawal, err := s.wallet.AdvanceOfflineNonce(c.Request.Context(), c.Param("id"), req.Transaction)
+	if err != nil {
+		respondOfflineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawal": withdrawal})
+}
+
+func respondOfflineError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrClientRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOfflineWithdrawalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOfflineWithdrawalState), errors.Is(err, service.ErrNoNonceAvailable),
+		errors.Is(err, repository.ErrNonceInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOfflineSigningDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		respondError(c, err)
+	}
+}
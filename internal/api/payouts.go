@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 批量付款处理方法
+
+func (s *Server) CreatePayout(c *gin.Context) {
+	var req struct {
+		// 与 /transfer 一致，传入发送方私钥
+		FromAddress string                  `json:"from_address" binding:"required"`
+		Items       []service.PayoutRequest `json:"items" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch, err := s.wallet.CreatePayout(c.Request.Context(), req.FromAddress, req.Items)
+	if err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"payout": batch})
+}
+
+func (s *Server) GetPayout(c *gin.Context) {
+	batch, err := s.wallet.GetPayout(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"payout": batch})
+}
+
+func (s *Server) RetryPayout(c *gin.Context) {
+	var req struct {
+		FromAddress string `json:"from_address" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch, err := s.wallet.RetryPayout(c.Request.Context(), c.Param("id"), req.FromAddress)
+	if err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"payout": batch})
+}
+
+func respondPayoutError(c *gin.Context, err error) {
+	var invalid *service.PayoutValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "items": invalid.Items})
+	case errors.Is(err, service.ErrPayoutNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPayoutInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		respondError(c, err)
+	}
+}
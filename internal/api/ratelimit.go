@@ -0,0 +1,155 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mywallet/internal/config"
+	"mywallet/internal/repository"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxRateLimitBody 为提取钱包地址读取的最大请求体大小
+const maxRateLimitBody = 1 << 20
+
+// RateLimit 按 API 客户端、钱包地址和路由进行令牌桶限流。
+// Redis 不可用时放行请求，避免限流组件成为单点故障。
+func (s *Server) RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+
+		var (
+			buckets  []repository.TokenBucket
+			policies []config.RateLimitPolicy
+		)
+		for _, p := range s.cfg.RateLimitPolicies {
+			if p.Route != "" && p.Route != route {
+				continue
+			}
+			subject := s.rateLimitSubject(c, p.Key)
+			if subject == "" {
+				continue
+			}
+			key := p.Name + ":" + subject
+			if p.Route != "" {
+				key = p.Name + ":" + route + ":" + subject
+			}
+			buckets = append(buckets, repository.TokenBucket{
+				Key:      key,
+				Capacity: p.Burst,
+				Refill:   p.Limit,
+				Period:   p.Period,
+			})
+			policies = append(policies, p)
+		}
+		if len(buckets) == 0 {
+			c.Next()
+			return
+		}
+
+		results, err := s.redis.TakeTokens(c.Request.Context(), buckets, 1, time.Now())
+		if err != nil {
+			s.logger.Logger.Warn("rate limiter unavailable, allowing request", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		// 响应头报告剩余额度最少的策略；被拒绝时报告需要等待最久的策略
+		reported := 0
+		allowed := true
+		for i, r := range results {
+			if !r.Allowed {
+				allowed = false
+				if results[reported].Allowed || r.RetryAfter > results[reported].RetryAfter {
+					reported = i
+				}
+			} else if allowed && r.Remaining < results[reported].Remaining {
+				reported = i
+			}
+		}
+
+		p, r := policies[reported], results[reported]
+		c.Header("RateLimit-Limit", strconv.FormatInt(p.Burst, 10))
+		c.Header("RateLimit-Remaining", strconv.FormatInt(r.Remaining, 10))
+		c.Header("RateLimit-Reset", strconv.FormatInt(ceilSeconds(r.Reset), 10))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d;name=%q", p.Limit, int64(p.Period.Seconds()), p.Name))
+
+		if !allowed {
+			c.Header("Retry-After", strconv.FormatInt(ceilSeconds(r.RetryAfter), 10))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":  "rate limit exceeded",
+				"policy": p.Name,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitSubject 根据策略维度确定限流对象，无法确定时返回空字符串
+func (s *Server) rateLimitSubject(c *gin.Context, key string) string {
+	switch key {
+	case "client":
+		if id := c.GetString(clientIDKey); id != "" {
+			return "client:" + id
+		}
+		return "ip:" + c.ClientIP()
+	case "ip":
+		return "ip:" + c.ClientIP()
+	case "wallet":
+		if addr := walletFromRequest(c); addr != "" {
+			return "wallet:" + addr
+		}
+	}
+	return ""
+}
+
+// walletFromRequest 从路径参数或 JSON 请求体中提取钱包地址。
+// 请求体读取后会被还原，不影响后续处理方法解析。
+func walletFromRequest(c *gin.Context) string {
+	if addr := c.Param("address"); addr != "" {
+		return addr
+	}
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRateLimitBody))
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+	var req struct {
+		Address     string `json:"address"`
+		FromAddress string `json:"from_address"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	if req.Address != "" {
+		return req.Address
+	}
+	// 转账接口的 from_address 实际传入的是私钥，换算为公钥，避免私钥出现在 Redis 键中
+	if req.FromAddress != "" {
+		if key, err := solana.PrivateKeyFromBase58(req.FromAddress); err == nil {
+			return key.PublicKey().String()
+		}
+		return req.FromAddress
+	}
+	return ""
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
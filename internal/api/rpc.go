@@ -0,0 +1,17 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RPC 节点处理方法
+
+func (s *Server) ListRPCEndpoints(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"endpoints":     s.wallet.RPCEndpoints(),
+		"breaker":       s.wallet.RPCBreaker(),
+		"subscriptions": s.wallet.SubscriptionStatus(),
+	})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// 定时转账处理方法
+
+func (s *Server) CreateSchedule(c *gin.Context) {
+	var req struct {
+		// 转出的托管钱包地址
+		FromAddress       string     `json:"from_address" binding:"required"`
+		ToAddress         string     `json:"to_address" binding:"required"`
+		Amount            string     `json:"amount" binding:"required"`
+		RunAt             *time.Time `json:"run_at"`
+		Cron              string     `json:"cron"`
+		MaxRetries        *int       `json:"max_retries"`
+		RetryDelaySeconds int64      `json:"retry_delay_seconds"`
+		Reference         string     `json:"reference"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+		return
+	}
+	schedule, err := s.wallet.CreateSchedule(c.Request.Context(), service.ScheduleRequest{
+		FromAddress: req.FromAddress,
+		ToAddress:   req.ToAddress,
+		Amount:      amount,
+		RunAt:       req.RunAt,
+		Cron:        req.Cron,
+		MaxRetries:  req.MaxRetries,
+		RetryDelay:  time.Duration(req.RetryDelaySeconds) * time.Second,
+		Reference:   req.Reference,
+	})
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+func (s *Server) ListSchedules(c *gin.Context) {
+	address := c.Query("address")
+	if address == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "address is required"})
+		return
+	}
+
+	schedules, err := s.wallet.ListSchedules(c.Request.Context(), address)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": address, "schedules": schedules})
+}
+
+func (s *Server) GetSchedule(c *gin.Context) {
+	schedule, err := s.wallet.GetSchedule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+func (s *Server) ListScheduleExecutions(c *gin.Context) {
+	executions, err := s.wallet.ListScheduleExecutions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"executions": executions})
+}
+
+func (s *Server) PauseSchedule(c *gin.Context) {
+	s.changeSchedule(c, s.wallet.PauseSchedule)
+}
+
+func (s *Server) ResumeSchedule(c *gin.Context) {
+	s.changeSchedule(c, s.wallet.ResumeSchedule)
+}
+
+func (s *Server) CancelSchedule(c *gin.Context) {
+	s.changeSchedule(c, s.wallet.CancelSchedule)
+}
+
+func (s *Server) changeSchedule(c *gin.Context, change func(ctx context.Context, id string) (*models.TransferSchedule, error)) {
+	schedule, err := change(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+func respondScheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrClientRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrScheduleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrScheduleStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrManagedWalletNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from_address is not a managed wallet"})
+	case errors.Is(err, service.ErrKeystoreDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAddressNotWhitelisted), errors.Is(err, service.ErrAddressCoolingDown):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// streamHeartbeat 定期发送注释行，避免代理断开空闲连接
+	streamHeartbeat = 15 * time.Second
+	// streamRetryMs 建议客户端断线后的重连间隔
+	streamRetryMs = 3000
+)
+
+// Stream 以 Server-Sent Events 推送地址的余额变动与交易状态。
+// 地址通过 address 参数传入（可重复或逗号分隔），断线重连时通过 Last-Event-ID 续传
+func (s *Server) Stream(c *gin.Context) {
+	var addresses []string
+	for _, value := range c.QueryArray("address") {
+		for _, address := range strings.Split(value, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	ctx := c.Request.Context()
+	events, err := s.wallet.Streams().Subscribe(ctx, addresses, lastEventID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetryMs)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event})
+			return true
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+			return true
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 充值地址与资金归集处理方法
+
+func (s *Server) CreateDepositAddress(c *gin.Context) {
+	var req struct {
+		UserRef string `json:"user_ref" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := s.wallet.CreateDepositAddress(c.Request.Context(), req.UserRef)
+	if err != nil {
+		respondTreasuryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deposit_address": address})
+}
+
+func (s *Server) CreateDepositAddresses(c *gin.Context) {
+	var req struct {
+		UserRefs []string `json:"user_refs" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addresses, err := s.wallet.CreateDepositAddresses(c.Request.Context(), req.UserRefs)
+	if err != nil {
+		respondTreasuryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deposit_addresses": addresses})
+}
+
+func (s *Server) ListDepositAddresses(c *gin.Context) {
+	addresses, err := s.wallet.ListDepositAddresses(c.Request.Context(), c.Query("client_id"))
+	if err != nil {
+		respondTreasuryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deposit_addresses": addresses})
+}
+
+func (s *Server) GetTreasury(c *gin.Context) {
+	status, err := s.wallet.TreasuryStatus(c.Request.Context())
+	if err != nil {
+		respondTreasuryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"treasury": status})
+}
+
+func (s *Server) ListTreasuryMovements(c *gin.Context) {
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	movements, err := s.wallet.ListTreasuryMovements(c.Request.Context(), c.Query("kind"), c.Query("status"), limit)
+	if err != nil {
+		respondTreasuryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"movements": movements})
+}
+
+func (s *Server) Sweep(c *gin.Context) {
+	report, err := s.wallet.Sweep(c.Request.Context())
+	if err != nil {
+		respondTreasuryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+func respondTreasuryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrClientRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTreasuryDisabled), errors.Is(err, service.ErrKeystoreDisabled),
+		errors.Is(err, service.ErrUpstreamUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSweepRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
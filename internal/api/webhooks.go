@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"mywallet/internal/models"
+	"mywallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhook 处理方法
+
+func (s *Server) CreateWebhook(c *gin.Context) {
+	var req struct {
+		URL string `json:"url" binding:"required"`
+		// 为空时订阅全部事件
+		EventTypes []string `json:"event_types"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := s.wallet.Webhooks().CreateEndpoint(c.Request.Context(), req.URL, req.EventTypes)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook": endpoint})
+}
+
+func (s *Server) ListWebhooks(c *gin.Context) {
+	endpoints, err := s.wallet.Webhooks().ListEndpoints(c.Request.Context())
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": endpoints, "event_types": models.EventTypes})
+}
+
+func (s *Server) DeleteWebhook(c *gin.Context) {
+	if err := s.wallet.Webhooks().DeleteEndpoint(c.Request.Context(), c.Param("id")); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+}
+
+func (s *Server) TestWebhook(c *gin.Context) {
+	delivery, err := s.wallet.Webhooks().TestEndpoint(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"delivery": delivery})
+}
+
+func (s *Server) ListWebhookDeliveries(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.DeliveryStatusPending, models.DeliveryStatusSucceeded, models.DeliveryStatusDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+
+	deliveries, err := s.wallet.Webhooks().ListDeliveries(c.Request.Context(), c.Param("id"), status)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+func (s *Server) GetWebhookDelivery(c *gin.Context) {
+	delivery, err := s.wallet.Webhooks().GetDelivery(c.Request.Context(), c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"delivery": delivery})
+}
+
+func (s *Server) RedeliverWebhook(c *gin.Context) {
+	delivery, err := s.wallet.Webhooks().Redeliver(c.Request.Context(), c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrClientRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"mywallet/internal/models"
+)
+
+// GenesisHash 第一条记录的 prev_hash
+var GenesisHash = strings.Repeat("0", 64)
+
+// ChainError 哈希链校验失败的记录
+type ChainError struct {
+	Seq    int64
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit log broken at seq %d: %s", e.Seq, e.Reason)
+}
+
+// canonicalEntry 参与哈希计算的字段，字段顺序固定，金额保留 8 位小数，时间精确到微秒（与数据库一致）
+type canonicalEntry struct {
+	Prev           string               `json:"prev"`
+	ID             string               `json:"id"`
+	ActorType      string               `json:"actor_type"`
+	ActorID        string               `json:"actor_id"`
+	Operation      string               `json:"operation"`
+	Addresses      []string             `json:"addresses"`
+	Amount         string               `json:"amount"`
+	Reference      string               `json:"reference"`
+	PayloadHash    string               `json:"payload_hash"`
+	BalancesBefore map[string][2]string `json:"balances_before"`
+	BalancesAfter  map[string][2]string `json:"balances_after"`
+	IP             string               `json:"ip"`
+	CorrelationID  string               `json:"correlation_id"`
+	Outcome        string               `json:"outcome"`
+	Error          string               `json:"error"`
+	CreatedAt      string               `json:"created_at"`
+}
+
+// Hash 计算记录的哈希：sha256(prev_hash 与记录内容的规范 JSON)，不包含 seq 与 hash 本身
+func Hash(prev string, e *models.AuditEntry) string {
+	addresses := e.Addresses
+	if addresses == nil {
+		addresses = []string{}
+	}
+	c := canonicalEntry{
+		Prev:           prev,
+		ID:             e.ID,
+		ActorType:      e.Actor.Type,
+		ActorID:        e.Actor.ID,
+		Operation:      e.Operation,
+		Addresses:      addresses,
+		Amount:         e.Amount.StringFixed(8),
+		Reference:      e.Reference,
+		PayloadHash:    e.PayloadHash,
+		BalancesBefore: canonicalBalances(e.BalancesBefore),
+		BalancesAfter:  canonicalBalances(e.BalancesAfter),
+		IP:             e.IP,
+		CorrelationID:  e.CorrelationID,
+		Outcome:        e.Outcome,
+		Error:          e.Error,
+		CreatedAt:      e.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z"),
+	}
+	// 字段均为字符串，不会失败；map 按键排序输出
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func canonicalBalances(balances map[string]models.AuditBalance) map[string][2]string {
+	out := make(map[string][2]string, len(balances))
+	for address, b := range balances {
+		out[address] = [2]string{b.Balance.StringFixed(8), b.Reserved.StringFixed(8)}
+	}
+	return out
+}
+
+// Normalize 将记录调整为写入数据库后的精度，保证写入前后的哈希一致
+func Normalize(e *models.AuditEntry) {
+	e.CreatedAt = e.CreatedAt.UTC().Truncate(time.Microsecond)
+	e.Amount = e.Amount.Round(8)
+}
+
+// Verify 按 seq 顺序校验一段连续的记录，prev 为这段记录之前的哈希（从头校验时为 GenesisHash）。
+// 返回最后一条记录的哈希，用于校验下一段；发现篡改时返回 *ChainError
+func Verify(prev string, entries []models.AuditEntry) (string, error) {
+	for i := range entries {
+		e := &entries[i]
+		if e.PrevHash != prev {
+			return prev, &ChainError{Seq: e.Seq, Reason: "prev_hash does not match previous entry"}
+		}
+		if Hash(prev, e) != e.Hash {
+			return prev, &ChainError{Seq: e.Seq, Reason: "hash does not match entry content"}
+		}
+		prev = e.Hash
+	}
+	return prev, nil
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	now := time.Date(2024, 5, 1, 8, 0, 0, 123456789, time.UTC)
+	entries := make([]models.AuditEntry, 3)
+	prev := GenesisHash
+	for i := range entries {
+		e := &entries[i]
+		*e = models.AuditEntry{
+			Seq:       int64(i + 1),
+			ID:        string(rune('a' + i)),
+			Actor:     models.Actor{Type: models.ActorAPIClient, ID: "client-1"},
+			Operation: models.AuditDeposit,
+			Addresses: []string{"addr"},
+			Amount:    decimal.NewFromInt(int64(i + 1)),
+			BalancesBefore: map[string]models.AuditBalance{
+				"addr": {Balance: decimal.NewFromInt(int64(i))},
+			},
+			Outcome:   models.AuditSucceeded,
+			CreatedAt: now.Add(time.Duration(i) * time.Second),
+		}
+		Normalize(e)
+		e.PrevHash = prev
+		e.Hash = Hash(prev, e)
+		prev = e.Hash
+	}
+
+	head, err := Verify(GenesisHash, entries)
+	require.NoError(t, err)
+	assert.Equal(t, entries[2].Hash, head)
+
+	// 分段校验结果一致
+	mid, err := Verify(GenesisHash, entries[:1])
+	require.NoError(t, err)
+	head, err = Verify(mid, entries[1:])
+	require.NoError(t, err)
+	assert.Equal(t, entries[2].Hash, head)
+
+	// 数值相同但表示不同的金额不影响哈希
+	entries[0].Amount = decimal.RequireFromString("1.00000000")
+	_, err = Verify(GenesisHash, entries)
+	require.NoError(t, err)
+
+	// 修改内容
+	entries[1].Amount = decimal.NewFromInt(100)
+	_, err = Verify(GenesisHash, entries)
+	var chainErr *ChainError
+	require.True(t, errors.As(err, &chainErr))
+	assert.Equal(t, int64(2), chainErr.Seq)
+	entries[1].Amount = decimal.NewFromInt(2)
+
+	// 删除中间记录
+	_, err = Verify(GenesisHash, []models.AuditEntry{entries[0], entries[2]})
+	require.True(t, errors.As(err, &chainErr))
+	assert.Equal(t, int64(3), chainErr.Seq)
+}
@@ -0,0 +1,119 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// KeySize 主密钥长度（AES-256）
+const KeySize = 32
+
+// LegacyVersion 只配置 KEYSTORE_MASTER_KEY 时主密钥的版本
+const LegacyVersion = 1
+
+var (
+	ErrDecrypt = errors.New("failed to decrypt key")
+	// ErrUnknownVersion 密文使用的主密钥版本未配置，可能已被下线
+	ErrUnknownVersion = errors.New("master key version is not configured")
+)
+
+// Keystore 使用主密钥（KEK）以 AES-256-GCM 加密托管钱包的私钥。
+// 密文格式为 nonce || ciphertext，钱包地址作为附加数据，密文不能挪用到其他地址。
+// 主密钥带版本号，新密文使用当前版本加密，旧版本只用于解密和重新加密
+type Keystore struct {
+	aeads  map[int]cipher.AEAD
+	active int
+}
+
+// New 使用单个主密钥创建 Keystore，版本为 LegacyVersion
+func New(masterKey []byte) (*Keystore, error) {
+	return NewVersioned(map[int][]byte{LegacyVersion: masterKey}, LegacyVersion)
+}
+
+// NewVersioned 使用多个版本的主密钥创建 Keystore，active 为加密使用的版本
+func NewVersioned(masterKeys map[int][]byte, active int) (*Keystore, error) {
+	if _, ok := masterKeys[active]; !ok {
+		return nil, fmt.Errorf("active master key version %d is not configured", active)
+	}
+	k := &Keystore{aeads: make(map[int]cipher.AEAD, len(masterKeys)), active: active}
+	for version, masterKey := range masterKeys {
+		if version <= 0 {
+			return nil, fmt.Errorf("invalid master key version %d", version)
+		}
+		if len(masterKey) != KeySize {
+			return nil, fmt.Errorf("master key version %d must be %d bytes, got %d", version, KeySize, len(masterKey))
+		}
+		block, err := aes.NewCipher(masterKey)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[version] = aead
+	}
+	return k, nil
+}
+
+// Version 返回加密使用的主密钥版本
+func (k *Keystore) Version() int {
+	return k.active
+}
+
+// Versions 返回已配置的主密钥版本，从小到大
+func (k *Keystore) Versions() []int {
+	versions := make([]int, 0, len(k.aeads))
+	for version := range k.aeads {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Encrypt 使用当前版本的主密钥加密私钥，address 作为附加数据
+func (k *Keystore) Encrypt(address string, key []byte) ([]byte, error) {
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, key, []byte(address)), nil
+}
+
+// Decrypt 使用当前版本的主密钥解密私钥
+func (k *Keystore) Decrypt(address string, data []byte) ([]byte, error) {
+	return k.DecryptVersion(k.active, address, data)
+}
+
+// DecryptVersion 使用指定版本的主密钥解密私钥
+func (k *Keystore) DecryptVersion(version int, address string, data []byte) ([]byte, error) {
+	aead, ok := k.aeads[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	size := aead.NonceSize()
+	if len(data) < size {
+		return nil, ErrDecrypt
+	}
+	key, err := aead.Open(nil, data[:size], data[size:], []byte(address))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return key, nil
+}
+
+// Rewrap 将 version 版本加密的私钥改用当前版本重新加密，明文用完立即清零
+func (k *Keystore) Rewrap(version int, address string, data []byte) ([]byte, error) {
+	key, err := k.DecryptVersion(version, address, data)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	return k.Encrypt(address, key)
+}
@@ -0,0 +1,99 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	ks, err := New(bytes.Repeat([]byte{1}, KeySize))
+	require.NoError(t, err)
+
+	secret := []byte("private key bytes")
+	data, err := ks.Encrypt("addr1", secret)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), string(secret))
+
+	got, err := ks.Decrypt("addr1", data)
+	require.NoError(t, err)
+	assert.Equal(t, secret, got)
+
+	// 密文与地址绑定
+	_, err = ks.Decrypt("addr2", data)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	other, err := New(bytes.Repeat([]byte{2}, KeySize))
+	require.NoError(t, err)
+	_, err = other.Decrypt("addr1", data)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = New([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestRewrap(t *testing.T) {
+	v1 := bytes.Repeat([]byte{1}, KeySize)
+	v2 := bytes.Repeat([]byte{2}, KeySize)
+	old, err := New(v1)
+	require.NoError(t, err)
+	data, err := old.Encrypt("addr1", []byte("secret"))
+	require.NoError(t, err)
+
+	ks, err := NewVersioned(map[int][]byte{1: v1, 2: v2}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, ks.Version())
+	assert.Equal(t, []int{1, 2}, ks.Versions())
+
+	rewrapped, err := ks.Rewrap(1, "addr1", data)
+	require.NoError(t, err)
+	got, err := ks.Decrypt("addr1", rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), got)
+	_, err = ks.DecryptVersion(1, "addr1", rewrapped)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// 旧版本下线后无法解密未重新加密的密文
+	retired, err := NewVersioned(map[int][]byte{2: v2}, 2)
+	require.NoError(t, err)
+	_, err = retired.Rewrap(1, "addr1", data)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+
+	_, err = NewVersioned(map[int][]byte{1: v1}, 2)
+	assert.Error(t, err)
+	_, err = NewVersioned(map[int][]byte{0: v1}, 0)
+	assert.Error(t, err)
+}
+
+func TestSigner(t *testing.T) {
+	ks, err := New(bytes.Repeat([]byte{1}, KeySize))
+	require.NoError(t, err)
+
+	key := solana.NewWallet().PrivateKey
+	address := key.PublicKey().String()
+	data, err := ks.Encrypt(address, key)
+	require.NoError(t, err)
+	signer := NewSigner(ks, func(_ context.Context, keyID string) ([]byte, int, error) {
+		if keyID != address {
+			return nil, 0, solanaclient.ErrKeyNotFound
+		}
+		return data, ks.Version(), nil
+	})
+
+	ctx := context.Background()
+	pub, err := signer.PublicKey(ctx, address)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey(), pub)
+	sig, err := signer.Sign(ctx, address, []byte("message"))
+	require.NoError(t, err)
+	assert.True(t, sig.Verify(pub, []byte("message")))
+
+	_, err = signer.Sign(ctx, "other", []byte("message"))
+	assert.ErrorIs(t, err, solanaclient.ErrKeyNotFound)
+}
@@ -0,0 +1,61 @@
+package keystore
+
+import (
+	"context"
+	"fmt"
+
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// KeyLookup 按密钥 ID 查询加密私钥及其主密钥版本
+type KeyLookup func(ctx context.Context, keyID string) (encrypted []byte, version int, err error)
+
+// Signer 使用 Keystore 加密保存的私钥签名，密钥 ID 为钱包地址。
+// 私钥只在签名时解密，用完立即清零
+type Signer struct {
+	keys   *Keystore
+	lookup KeyLookup
+}
+
+var _ solanaclient.Signer = (*Signer)(nil)
+
+func NewSigner(keys *Keystore, lookup KeyLookup) *Signer {
+	return &Signer{keys: keys, lookup: lookup}
+}
+
+func (s *Signer) PublicKey(ctx context.Context, keyID string) (solana.PublicKey, error) {
+	var pub solana.PublicKey
+	err := s.withKey(ctx, keyID, func(key solana.PrivateKey) error {
+		pub = key.PublicKey()
+		return nil
+	})
+	return pub, err
+}
+
+func (s *Signer) Sign(ctx context.Context, keyID string, message []byte) (solana.Signature, error) {
+	var sig solana.Signature
+	err := s.withKey(ctx, keyID, func(key solana.PrivateKey) error {
+		var err error
+		sig, err = key.Sign(message)
+		return err
+	})
+	return sig, err
+}
+
+func (s *Signer) withKey(ctx context.Context, keyID string, fn func(solana.PrivateKey) error) error {
+	encrypted, version, err := s.lookup(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	key, err := s.keys.DecryptVersion(version, keyID, encrypted)
+	if err != nil {
+		return err
+	}
+	defer clear(key)
+	if len(key) != 64 {
+		return fmt.Errorf("invalid key length %d", len(key))
+	}
+	return fn(solana.PrivateKey(key))
+}
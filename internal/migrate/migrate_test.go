@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(migrationFiles)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.Up, "migration %d has no up script", m.Version)
+		assert.NotEmpty(t, m.Down, "migration %d has no down script", m.Version)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"migrations/init.up.sql": {Data: []byte("SELECT 1")},
+	})
+	assert.Error(t, err)
+
+	_, err = Load(fstest.MapFS{
+		"migrations/0002_only_down.down.sql": {Data: []byte("SELECT 1")},
+	})
+	assert.Error(t, err)
+}
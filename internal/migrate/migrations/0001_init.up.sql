@@ -0,0 +1,35 @@
+-- 初始表结构。兼容已通过 scripts/init.sql 初始化过的数据库：
+-- 表不存在时创建，已存在时修正与代码不一致的地方。
+
+CREATE TABLE IF NOT EXISTS wallets (
+    id VARCHAR(64) PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    address VARCHAR(64) UNIQUE NOT NULL,
+    balance DECIMAL(20,8) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- AddBalance 只插入 address/balance，id 与时间戳需要默认值
+ALTER TABLE wallets ALTER COLUMN id SET DEFAULT gen_random_uuid()::text;
+ALTER TABLE wallets ALTER COLUMN created_at SET DEFAULT NOW();
+ALTER TABLE wallets ALTER COLUMN updated_at SET DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id VARCHAR(64) PRIMARY KEY,
+    from_wallet VARCHAR(64) NOT NULL,
+    to_wallet VARCHAR(64) NOT NULL,
+    amount DECIMAL(20,8) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+-- 充值/提现使用 "deposit"/"withdraw" 作为对手方，链上转账的接收方也
+-- 不一定是本地钱包，因此不能对 wallets(address) 建外键
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_from_wallet_fkey;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_to_wallet_fkey;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_from_wallet ON transactions(from_wallet);
+CREATE INDEX IF NOT EXISTS idx_transactions_to_wallet ON transactions(to_wallet);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS limit_usage;
+DROP TABLE IF EXISTS limits;
+DROP TABLE IF EXISTS api_clients;
@@ -0,0 +1,38 @@
+-- API 客户端，API Key 只保存 SHA-256 摘要
+CREATE TABLE api_clients (
+    id VARCHAR(64) PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    name VARCHAR(255) NOT NULL,
+    api_key_hash CHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 交易限额配置。scope 为 wallet 或 client，subject 为钱包地址或客户端 ID，
+-- subject = '*' 表示该 scope 下的默认限额。NULL 表示不限制。
+CREATE TABLE limits (
+    id BIGSERIAL PRIMARY KEY,
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('wallet', 'client')),
+    subject VARCHAR(64) NOT NULL,
+    asset VARCHAR(16) NOT NULL DEFAULT 'SOL',
+    per_tx_max DECIMAL(20,8),
+    daily_amount DECIMAL(20,8),
+    daily_count INTEGER,
+    monthly_amount DECIMAL(20,8),
+    monthly_count INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (scope, subject, asset)
+);
+
+-- 限额使用记录，Redis 计数器丢失时据此重建
+CREATE TABLE limit_usage (
+    id VARCHAR(64) NOT NULL,
+    scope VARCHAR(16) NOT NULL,
+    subject VARCHAR(64) NOT NULL,
+    asset VARCHAR(16) NOT NULL,
+    amount DECIMAL(20,8) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, scope)
+);
+
+CREATE INDEX idx_limit_usage_subject ON limit_usage(scope, subject, asset, created_at);
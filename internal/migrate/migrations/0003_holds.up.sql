@@ -0,0 +1,17 @@
+-- 冻结金额，可用余额 = balance - reserved_balance
+ALTER TABLE wallets ADD COLUMN reserved_balance DECIMAL(20,8) NOT NULL DEFAULT 0;
+
+CREATE TABLE holds (
+    id VARCHAR(64) PRIMARY KEY,
+    address VARCHAR(64) NOT NULL,
+    amount DECIMAL(20,8) NOT NULL CHECK (amount > 0),
+    captured_amount DECIMAL(20,8) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL,
+    reference VARCHAR(255),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_holds_address ON holds(address, status);
+CREATE INDEX idx_holds_active_expiry ON holds(expires_at) WHERE status = 'active';
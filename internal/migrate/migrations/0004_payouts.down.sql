@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS payout_items;
+DROP TABLE IF EXISTS payout_batches;
+ALTER TABLE transactions DROP COLUMN IF EXISTS asset;
@@ -0,0 +1,33 @@
+-- 交易记录的资产，已有记录均为 SOL
+ALTER TABLE transactions ADD COLUMN asset VARCHAR(16) NOT NULL DEFAULT 'SOL';
+
+CREATE TABLE payout_batches (
+    id VARCHAR(64) PRIMARY KEY,
+    client_id VARCHAR(64),
+    from_address VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE payout_items (
+    id VARCHAR(64) PRIMARY KEY,
+    batch_id VARCHAR(64) NOT NULL REFERENCES payout_batches(id) ON DELETE CASCADE,
+    seq INT NOT NULL,
+    recipient VARCHAR(64) NOT NULL,
+    amount DECIMAL(20,8) NOT NULL CHECK (amount > 0),
+    asset VARCHAR(16) NOT NULL,
+    reference VARCHAR(255),
+    status VARCHAR(20) NOT NULL,
+    -- 最近一次提交的交易签名，同一笔交易可包含多个付款项
+    signature VARCHAR(128),
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    -- 占用的限额，失败时释放；重试时仍未释放的占用直接复用，不重复计入限额
+    limit_reservation VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (batch_id, seq)
+);
+
+CREATE INDEX idx_payout_items_signature ON payout_items(signature);
@@ -0,0 +1,44 @@
+-- 托管钱包，私钥以主密钥加密保存
+CREATE TABLE managed_wallets (
+    address VARCHAR(64) PRIMARY KEY,
+    encrypted_key BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE transfer_schedules (
+    id VARCHAR(64) PRIMARY KEY,
+    client_id VARCHAR(64),
+    from_address VARCHAR(64) NOT NULL REFERENCES managed_wallets(address),
+    to_address VARCHAR(64) NOT NULL,
+    amount DECIMAL(20,8) NOT NULL CHECK (amount > 0),
+    -- 为空时只执行一次
+    cron VARCHAR(100),
+    status VARCHAR(20) NOT NULL,
+    next_run_at TIMESTAMP,
+    max_retries INT NOT NULL,
+    retry_delay_seconds INT NOT NULL,
+    -- 当前这次执行已失败的次数
+    attempt INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    reference VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transfer_schedules_due ON transfer_schedules(next_run_at) WHERE status = 'active';
+CREATE INDEX idx_transfer_schedules_from ON transfer_schedules(from_address);
+
+CREATE TABLE schedule_executions (
+    id VARCHAR(64) PRIMARY KEY,
+    schedule_id VARCHAR(64) NOT NULL REFERENCES transfer_schedules(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMP NOT NULL,
+    attempt INT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    signature VARCHAR(128),
+    error TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_schedule_executions_schedule ON schedule_executions(schedule_id, started_at);
+CREATE INDEX idx_schedule_executions_running ON schedule_executions(started_at) WHERE status = 'running';
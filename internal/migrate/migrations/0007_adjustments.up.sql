@@ -0,0 +1,12 @@
+-- 运维手动调账记录，amount 为正数表示入账，负数表示出账
+CREATE TABLE balance_adjustments (
+    id VARCHAR(64) PRIMARY KEY,
+    address VARCHAR(64) NOT NULL,
+    amount DECIMAL(20,8) NOT NULL,
+    reason TEXT NOT NULL,
+    operator VARCHAR(255) NOT NULL,
+    transaction_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_balance_adjustments_address ON balance_adjustments(address, created_at);
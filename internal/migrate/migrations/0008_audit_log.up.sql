@@ -0,0 +1,42 @@
+-- 审计日志，只允许追加。每条记录的 hash 覆盖上一条记录的 hash，篡改或删除中间记录都会使后续校验失败
+CREATE TABLE audit_log (
+    seq BIGSERIAL PRIMARY KEY,
+    id VARCHAR(64) NOT NULL UNIQUE,
+    -- api_client, admin, system, anonymous
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(255) NOT NULL DEFAULT '',
+    operation VARCHAR(64) NOT NULL,
+    addresses TEXT[] NOT NULL DEFAULT '{}',
+    amount DECIMAL(20,8) NOT NULL DEFAULT 0,
+    -- 关联的业务记录，如交易签名、冻结 ID、批次 ID
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    payload_hash VARCHAR(64) NOT NULL DEFAULT '',
+    balances_before JSONB NOT NULL DEFAULT '{}',
+    balances_after JSONB NOT NULL DEFAULT '{}',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    correlation_id VARCHAR(128) NOT NULL DEFAULT '',
+    -- succeeded, failed, rollback_failed
+    outcome VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_addresses ON audit_log USING GIN (addresses);
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_type, actor_id, seq);
+
+CREATE FUNCTION audit_log_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_immutable();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_immutable();
@@ -0,0 +1,49 @@
+-- 大额出账审批策略。address 为空表示默认策略，设置了钱包级策略的地址只使用钱包级策略；
+-- 金额达到 min_amount 时需要 approvers 中 required_approvals 人批准
+CREATE TABLE approval_policies (
+    id BIGSERIAL PRIMARY KEY,
+    address VARCHAR(64) NOT NULL DEFAULT '',
+    min_amount DECIMAL(20,8) NOT NULL,
+    required_approvals INTEGER NOT NULL CHECK (required_approvals > 0),
+    approvers TEXT[] NOT NULL,
+    ttl_seconds INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (address, min_amount)
+);
+
+-- 待审批的提现与转账，资金通过 hold_id 对应的冻结占用
+CREATE TABLE approval_requests (
+    id VARCHAR(64) PRIMARY KEY,
+    -- withdraw, transfer
+    operation VARCHAR(20) NOT NULL,
+    client_id VARCHAR(64) NOT NULL DEFAULT '',
+    from_address VARCHAR(64) NOT NULL,
+    to_address VARCHAR(64) NOT NULL DEFAULT '',
+    amount DECIMAL(20,8) NOT NULL,
+    policy_id BIGINT NOT NULL,
+    required_approvals INTEGER NOT NULL,
+    approvers TEXT[] NOT NULL,
+    hold_id VARCHAR(64) NOT NULL,
+    -- awaiting_approval, approved, rejected, expired, executed, failed
+    status VARCHAR(20) NOT NULL,
+    -- 执行后的交易 ID 或签名
+    reference VARCHAR(128) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_approval_requests_client ON approval_requests(client_id, created_at);
+CREATE INDEX idx_approval_requests_pending ON approval_requests(expires_at) WHERE status = 'awaiting_approval';
+
+CREATE TABLE approval_decisions (
+    request_id VARCHAR(64) NOT NULL REFERENCES approval_requests(id) ON DELETE CASCADE,
+    approver VARCHAR(255) NOT NULL,
+    -- approve, reject
+    decision VARCHAR(10) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (request_id, approver)
+);
@@ -0,0 +1,17 @@
+-- 地址簿。scope 为 wallet 或 client，subject 为发送方钱包地址或 API 客户端 ID；
+-- 新增地址在 active_at 之前处于冷却期，不能作为转账目标
+CREATE TABLE address_book (
+    id VARCHAR(64) PRIMARY KEY,
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('wallet', 'client')),
+    subject VARCHAR(64) NOT NULL,
+    address VARCHAR(64) NOT NULL,
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    -- 非空时转账必须附带该 memo，如交易所充值标识
+    memo VARCHAR(255) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    active_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (scope, subject, address)
+);
+
+CREATE INDEX idx_address_book_address ON address_book(address);
@@ -0,0 +1,29 @@
+-- 充值地址：分配给 API 客户端下某个用户的托管钱包，余额定期归集到热钱包
+CREATE TABLE deposit_addresses (
+    address VARCHAR(64) PRIMARY KEY REFERENCES managed_wallets(address),
+    client_id VARCHAR(64) NOT NULL,
+    -- API 客户端的用户标识，同一客户端内唯一
+    user_ref VARCHAR(255) NOT NULL,
+    -- 归集金额按 lamport 精确记录
+    swept_total DECIMAL(20,9) NOT NULL DEFAULT 0,
+    last_swept_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (client_id, user_ref)
+);
+
+-- 资金归集记录。kind 为 sweep（充值地址 -> 热钱包）、cold（热钱包 -> 冷钱包）、
+-- topup（热钱包余额不足，请求从冷钱包补充，由人工执行）
+CREATE TABLE treasury_movements (
+    id VARCHAR(64) PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    from_address VARCHAR(64) NOT NULL,
+    to_address VARCHAR(64) NOT NULL,
+    amount DECIMAL(20,9) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL,
+    signature VARCHAR(128),
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_treasury_movements_status ON treasury_movements(kind, status);
@@ -0,0 +1,13 @@
+-- 代付手续费记录，按 API 客户端汇总计费。client_id 为空表示未认证的请求
+CREATE TABLE sponsored_fees (
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL DEFAULT '',
+    -- 被代付的托管钱包
+    address VARCHAR(64) NOT NULL,
+    fee_payer VARCHAR(64) NOT NULL,
+    signature VARCHAR(128) NOT NULL,
+    amount DECIMAL(20,9) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_sponsored_fees_client ON sponsored_fees(client_id, created_at);
@@ -0,0 +1,29 @@
+-- 离线签名出账：服务生成使用 durable nonce 的未签名交易，冷钱包离线签名后导入广播
+CREATE TABLE offline_withdrawals (
+    id VARCHAR(64) PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL DEFAULT '',
+    -- 扣减余额的账本钱包
+    address VARCHAR(64) NOT NULL,
+    -- 链上付款并签名的冷钱包
+    signer VARCHAR(64) NOT NULL,
+    to_address VARCHAR(64) NOT NULL,
+    amount DECIMAL(20,9) NOT NULL CHECK (amount > 0),
+    memo TEXT NOT NULL DEFAULT '',
+    nonce_account VARCHAR(64) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    -- base64 编码的未签名交易
+    transaction TEXT NOT NULL,
+    -- 占用的限额，失败或取消时释放
+    limit_reservation VARCHAR(64) NOT NULL,
+    signature VARCHAR(128),
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    submitted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- 同一 nonce 只能用于一笔交易，nonce 推进前账户不能再次使用
+    UNIQUE (nonce_account, nonce)
+);
+
+CREATE INDEX idx_offline_withdrawals_status ON offline_withdrawals(status);
+CREATE INDEX idx_offline_withdrawals_client ON offline_withdrawals(client_id, created_at);
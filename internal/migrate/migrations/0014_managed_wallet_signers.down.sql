@@ -0,0 +1,5 @@
+-- 存在外部签名服务持有的托管钱包时无法回滚
+ALTER TABLE managed_wallets DROP CONSTRAINT IF EXISTS managed_wallets_key;
+ALTER TABLE managed_wallets ALTER COLUMN encrypted_key SET NOT NULL;
+ALTER TABLE managed_wallets DROP COLUMN IF EXISTS key_id;
+ALTER TABLE managed_wallets DROP COLUMN IF EXISTS signer;
@@ -0,0 +1,8 @@
+-- 托管钱包可由外部签名服务（HTTP 签名服务、PKCS#11 HSM）持有私钥，此时只记录密钥 ID
+ALTER TABLE managed_wallets ADD COLUMN signer VARCHAR(16) NOT NULL DEFAULT 'keystore';
+-- 签名服务中的密钥 ID，keystore 为钱包地址
+ALTER TABLE managed_wallets ADD COLUMN key_id VARCHAR(255);
+UPDATE managed_wallets SET key_id = address;
+ALTER TABLE managed_wallets ALTER COLUMN key_id SET NOT NULL;
+ALTER TABLE managed_wallets ALTER COLUMN encrypted_key DROP NOT NULL;
+ALTER TABLE managed_wallets ADD CONSTRAINT managed_wallets_key CHECK (signer <> 'keystore' OR encrypted_key IS NOT NULL);
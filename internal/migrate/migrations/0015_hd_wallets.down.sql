@@ -0,0 +1,4 @@
+DROP SEQUENCE IF EXISTS managed_wallet_derivation_seq;
+DROP INDEX IF EXISTS idx_managed_wallets_derivation_index;
+ALTER TABLE managed_wallets DROP CONSTRAINT IF EXISTS managed_wallets_derivation_index;
+ALTER TABLE managed_wallets DROP COLUMN IF EXISTS derivation_index;
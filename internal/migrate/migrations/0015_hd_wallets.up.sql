@@ -0,0 +1,8 @@
+-- HD 派生的托管钱包记录派生路径 m/44'/501'/n'/0' 中的 n，导入或随机生成的钱包为空
+ALTER TABLE managed_wallets ADD COLUMN derivation_index BIGINT;
+ALTER TABLE managed_wallets ADD CONSTRAINT managed_wallets_derivation_index
+    CHECK (derivation_index IS NULL OR derivation_index BETWEEN 0 AND 2147483647);
+CREATE UNIQUE INDEX idx_managed_wallets_derivation_index ON managed_wallets (derivation_index)
+    WHERE derivation_index IS NOT NULL;
+-- 分配派生序号，并发创建时不会重复；恢复后由恢复工具推进
+CREATE SEQUENCE managed_wallet_derivation_seq MINVALUE 0 START 0 MAXVALUE 2147483647;
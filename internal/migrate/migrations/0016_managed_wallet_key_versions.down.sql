@@ -0,0 +1,4 @@
+-- 回滚前须将全部私钥重新加密为版本 1，否则无法解密
+DROP INDEX IF EXISTS idx_managed_wallets_key_version;
+ALTER TABLE managed_wallets DROP CONSTRAINT IF EXISTS managed_wallets_key_version;
+ALTER TABLE managed_wallets DROP COLUMN IF EXISTS key_version;
@@ -0,0 +1,6 @@
+-- keystore 私钥加密使用的主密钥版本，轮换主密钥时逐个重新加密；此前只有一个主密钥，即版本 1
+ALTER TABLE managed_wallets ADD COLUMN key_version INT;
+UPDATE managed_wallets SET key_version = 1 WHERE encrypted_key IS NOT NULL;
+ALTER TABLE managed_wallets ADD CONSTRAINT managed_wallets_key_version
+    CHECK ((encrypted_key IS NULL) = (key_version IS NULL));
+CREATE INDEX idx_managed_wallets_key_version ON managed_wallets (key_version) WHERE key_version IS NOT NULL;
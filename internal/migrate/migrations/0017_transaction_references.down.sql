@@ -0,0 +1,6 @@
+ALTER TABLE approval_requests DROP COLUMN IF EXISTS client_reference;
+DROP INDEX IF EXISTS idx_transactions_chain_signature;
+DROP INDEX IF EXISTS idx_transactions_client_reference;
+ALTER TABLE transactions DROP COLUMN IF EXISTS client_reference;
+ALTER TABLE transactions DROP COLUMN IF EXISTS client_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS chain_signature;
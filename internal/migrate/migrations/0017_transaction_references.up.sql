@@ -0,0 +1,20 @@
+-- 交易 ID 改为按时间排序的 UUIDv7，链上签名与客户端引用单独保存。
+-- 此前转账与离线出账以签名（88 字符）作为 ID，超出 id 列长度，这些记录未能写入
+ALTER TABLE transactions ADD COLUMN chain_signature VARCHAR(128);
+ALTER TABLE transactions ADD COLUMN client_id VARCHAR(64);
+-- API 客户端提交的引用，同一客户端内唯一
+ALTER TABLE transactions ADD COLUMN client_reference VARCHAR(255);
+
+-- 付款交易的 ID 为付款项 ID，签名保存在付款项上
+UPDATE transactions t SET chain_signature = i.signature
+FROM payout_items i
+WHERE t.type = 'payout' AND t.id = i.id AND i.signature IS NOT NULL AND i.signature <> '';
+
+-- 匿名请求的 client_id 为空，同样按引用去重
+CREATE UNIQUE INDEX idx_transactions_client_reference ON transactions (COALESCE(client_id, ''), client_reference)
+    WHERE client_reference IS NOT NULL;
+-- 批量付款的多笔交易共用一个签名，不唯一
+CREATE INDEX idx_transactions_chain_signature ON transactions (chain_signature) WHERE chain_signature IS NOT NULL;
+
+-- 需要审批的提现与转账在执行时使用发起请求的客户端引用
+ALTER TABLE approval_requests ADD COLUMN client_reference VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE managed_wallets DROP COLUMN IF EXISTS client_id;
@@ -0,0 +1,3 @@
+-- 托管钱包归属的 API 客户端，只有归属客户端可以从钱包发起定时转账。
+-- 为空表示平台钱包（热钱包、代付钱包、充值地址等），只供管理员与后台任务使用
+ALTER TABLE managed_wallets ADD COLUMN client_id VARCHAR(64) REFERENCES api_clients(id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_holds_client;
+ALTER TABLE holds DROP COLUMN IF EXISTS client_id;
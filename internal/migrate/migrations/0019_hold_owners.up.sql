@@ -0,0 +1,4 @@
+-- 创建冻结的 API 客户端，只有该客户端可以查询、扣款和释放
+ALTER TABLE holds ADD COLUMN client_id VARCHAR(64);
+
+CREATE INDEX idx_holds_client ON holds(client_id, address, status);
@@ -0,0 +1,2 @@
+-- 系统冻结（如审批期间的冻结）只能由服务内部流程释放，不能通过冻结接口扣款或释放
+ALTER TABLE holds ADD COLUMN system BOOLEAN NOT NULL DEFAULT FALSE;
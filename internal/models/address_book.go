@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// 地址簿作用范围
+const (
+	AddressScopeWallet = "wallet"
+	AddressScopeClient = "client"
+)
+
+// AddressBookEntry 地址簿中允许转入的目标地址
+type AddressBookEntry struct {
+	ID string `json:"id"`
+	// Scope 为 wallet 时 Subject 为发送方钱包地址，为 client 时为 API 客户端 ID
+	Scope   string   `json:"scope"`
+	Subject string   `json:"subject"`
+	Address string   `json:"address"`
+	Labels  []string `json:"labels"`
+	// Memo 非空时转往该地址必须附带的 memo
+	Memo      string `json:"memo,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+	// ActiveAt 冷却期结束时间，之前不能作为转账目标
+	ActiveAt  time.Time `json:"active_at"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
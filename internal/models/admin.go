@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BalanceAdjustment 运维手动调账记录
+type BalanceAdjustment struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+	// Amount 正数为入账，负数为出账
+	Amount        decimal.Decimal `json:"amount"`
+	Reason        string          `json:"reason"`
+	Operator      string          `json:"operator"`
+	TransactionID string          `json:"transaction_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// BalanceInspection 同一地址在 Redis、Postgres 账本与链上的余额
+type BalanceInspection struct {
+	Address        string          `json:"address"`
+	Redis          decimal.Decimal `json:"redis"`
+	RedisReserved  decimal.Decimal `json:"redis_reserved"`
+	Ledger         decimal.Decimal `json:"ledger"`
+	LedgerReserved decimal.Decimal `json:"ledger_reserved"`
+	Pending        decimal.Decimal `json:"pending"`
+	// OnChain 未查询或查询失败时为 nil
+	OnChain      *decimal.Decimal `json:"on_chain,omitempty"`
+	OnChainError string           `json:"on_chain_error,omitempty"`
+	// Consistent Redis 与账本的余额及冻结金额一致
+	Consistent bool `json:"consistent"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 需要审批的操作
+const (
+	ApprovalWithdraw = "withdraw"
+	ApprovalTransfer = "transfer"
+)
+
+// 审批状态
+const (
+	ApprovalAwaiting = "awaiting_approval"
+	// ApprovalApproved 已达到批准人数，正在执行
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+	ApprovalExecuted = "executed"
+	// ApprovalFailed 批准后执行失败，冻结资金已释放
+	ApprovalFailed = "failed"
+)
+
+// 审批意见
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// ApprovalPolicy 大额出账审批策略，金额达到 MinAmount 时需要 Approvers 中 RequiredApprovals 人批准
+type ApprovalPolicy struct {
+	ID int64 `json:"id"`
+	// Address 为空表示默认策略
+	Address           string          `json:"address"`
+	MinAmount         decimal.Decimal `json:"min_amount"`
+	RequiredApprovals int             `json:"required_approvals"`
+	Approvers         []string        `json:"approvers"`
+	// TTLSeconds 审批有效期，过期后释放冻结资金
+	TTLSeconds int64     `json:"ttl_seconds"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ApprovalRequest 待审批的提现或转账
+type ApprovalRequest struct {
+	ID                string          `json:"id"`
+	Operation         string          `json:"operation"`
+	ClientID          string          `json:"client_id,omitempty"`
+	FromAddress       string          `json:"from_address"`
+	ToAddress         string          `json:"to_address,omitempty"`
+	Amount            decimal.Decimal `json:"amount"`
+	PolicyID          int64           `json:"policy_id"`
+	RequiredApprovals int             `json:"required_approvals"`
+	Approvers         []string        `json:"approvers"`
+	HoldID            string          `json:"hold_id"`
+	Status            string          `json:"status"`
+	Reference         string          `json:"reference,omitempty"`
+	// ClientReference 发起请求时提交的客户端引用，执行后记录在交易上
+	ClientReference string `json:"client_reference,omitempty"`
+	Error           string `json:"error,omitempty"`
+	// Decisions 列表接口不返回
+	Decisions []ApprovalDecision `json:"decisions,omitempty"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// ApprovalDecision 审批人的意见
+type ApprovalDecision struct {
+	Approver  string    `json:"approver"`
+	Decision  string    `json:"decision"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
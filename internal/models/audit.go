@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 审计操作主体类型
+const (
+	ActorAPIClient = "api_client"
+	ActorAdmin     = "admin"
+	ActorSystem    = "system"
+	ActorAnonymous = "anonymous"
+)
+
+// 审计操作类型
+const (
+	AuditDeposit     = "deposit"
+	AuditWithdraw    = "withdraw"
+	AuditTransfer    = "transfer"
+	AuditHoldCreate  = "hold.create"
+	AuditHoldCapture = "hold.capture"
+	AuditHoldRelease = "hold.release"
+	AuditHoldExpire  = "hold.expire"
+	AuditPayout      = "payout"
+	AuditAdjustment  = "adjustment"
+	AuditReconcile   = "reconcile"
+	// 地址簿变更不影响余额，但决定资金可以转往哪里
+	AuditAddressBookCreate = "address_book.create"
+	AuditAddressBookDelete = "address_book.delete"
+	// 链上资金在充值地址、热钱包与冷钱包之间移动，不改变账本余额
+	AuditTreasurySweep = "treasury.sweep"
+	AuditTreasuryCold  = "treasury.cold"
+	// 离线签名出账：准备时扣减余额，取消后 nonce 推进或链上失败时退回
+	AuditOfflinePrepare      = "withdraw.offline.prepare"
+	AuditOfflineSubmit       = "withdraw.offline.submit"
+	AuditOfflineCancel       = "withdraw.offline.cancel"
+	AuditOfflineAdvanceNonce = "withdraw.offline.advance_nonce"
+	AuditOfflineRefund       = "withdraw.offline.refund"
+)
+
+// 审计结果
+const (
+	AuditSucceeded = "succeeded"
+	AuditFailed    = "failed"
+	// AuditRollbackFailed 操作失败且回滚也失败，Redis 与账本可能不一致
+	AuditRollbackFailed = "rollback_failed"
+)
+
+// Actor 发起操作的主体
+type Actor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// AuditBalance 操作前后的账本余额与冻结金额
+type AuditBalance struct {
+	Balance  decimal.Decimal `json:"balance"`
+	Reserved decimal.Decimal `json:"reserved"`
+}
+
+// AuditEntry 审计日志记录
+type AuditEntry struct {
+	Seq         int64           `json:"seq"`
+	ID          string          `json:"id"`
+	Actor       Actor           `json:"actor"`
+	Operation   string          `json:"operation"`
+	Addresses   []string        `json:"addresses"`
+	Amount      decimal.Decimal `json:"amount"`
+	Reference   string          `json:"reference,omitempty"`
+	PayloadHash string          `json:"payload_hash"`
+	// 以地址为键
+	BalancesBefore map[string]AuditBalance `json:"balances_before"`
+	BalancesAfter  map[string]AuditBalance `json:"balances_after"`
+	IP             string                  `json:"ip,omitempty"`
+	CorrelationID  string                  `json:"correlation_id,omitempty"`
+	Outcome        string                  `json:"outcome"`
+	Error          string                  `json:"error,omitempty"`
+	CreatedAt      time.Time               `json:"created_at"`
+	PrevHash       string                  `json:"prev_hash"`
+	Hash           string                  `json:"hash"`
+}
+
+// AuditVerification 哈希链校验结果
+type AuditVerification struct {
+	Checked int64 `json:"checked"`
+	// HeadSeq、HeadHash 最后一条通过校验的记录，可记录到外部用于发现整体替换
+	HeadSeq  int64  `json:"head_seq"`
+	HeadHash string `json:"head_hash"`
+	Valid    bool   `json:"valid"`
+	// BrokenAt 第一条校验失败的记录
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
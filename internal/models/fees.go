@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SponsoredFee 代付钱包为托管钱包交易支付的手续费
+type SponsoredFee struct {
+	ID        int64           `json:"id"`
+	ClientID  string          `json:"client_id,omitempty"`
+	Address   string          `json:"address"`
+	FeePayer  string          `json:"fee_payer"`
+	Signature string          `json:"signature"`
+	Amount    decimal.Decimal `json:"amount"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// SponsoredFeeSummary API 客户端在一段时间内的代付手续费合计
+type SponsoredFeeSummary struct {
+	ClientID string          `json:"client_id"`
+	Count    int64           `json:"count"`
+	Total    decimal.Decimal `json:"total"`
+	// Cap 每月上限，为 0 表示不限制
+	Cap         decimal.Decimal `json:"cap"`
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// HD 钱包恢复时地址的状态
+const (
+	// HDWalletKnown 数据库中已有该地址
+	HDWalletKnown = "known"
+	// HDWalletMissing 链上有记录但数据库中没有，未恢复
+	HDWalletMissing = "missing"
+	// HDWalletRestored 已按派生序号恢复为托管钱包
+	HDWalletRestored = "restored"
+	// HDWalletMismatch 派生序号已被其他地址占用，助记词与数据库不一致
+	HDWalletMismatch = "mismatch"
+	// HDWalletUnused 数据库与链上都没有记录，不计入恢复结果
+	HDWalletUnused = "unused"
+)
+
+// HDWallet 恢复时按派生序号扫描到的地址
+type HDWallet struct {
+	Index   int64           `json:"index"`
+	Path    string          `json:"path"`
+	Address string          `json:"address"`
+	Status  string          `json:"status"`
+	Balance decimal.Decimal `json:"balance"`
+	// Used 链上有交易记录或余额
+	Used bool `json:"used"`
+}
+
+// HDRecoveryReport HD 钱包恢复结果
+type HDRecoveryReport struct {
+	Scanned    int        `json:"scanned"`
+	Restored   int        `json:"restored"`
+	Missing    int        `json:"missing"`
+	Mismatched int        `json:"mismatched"`
+	NextIndex  int64      `json:"next_index"`
+	Wallets    []HDWallet `json:"wallets"`
+}
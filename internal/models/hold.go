@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 冻结状态
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+// Hold 资金冻结：先从可用余额转入冻结余额，之后扣款、释放或到期自动释放
+type Hold struct {
+	ID       string `json:"id"`
+	ClientID string `json:"client_id,omitempty"`
+	// System 为 true 时由服务内部流程（如审批）管理，不能通过冻结接口扣款或释放
+	System         bool            `json:"system,omitempty"`
+	Address        string          `json:"address"`
+	Amount         decimal.Decimal `json:"amount"`
+	CapturedAmount decimal.Decimal `json:"captured_amount"`
+	Status         string          `json:"status"`
+	Reference      string          `json:"reference,omitempty"`
+	ExpiresAt      time.Time       `json:"expires_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// KeyVersionStatus 主密钥版本的使用情况
+type KeyVersionStatus struct {
+	Version int `json:"version"`
+	// Wallets 使用该版本加密的托管钱包数量
+	Wallets int64 `json:"wallets"`
+	// Configured 本实例配置了该版本，未配置时这些钱包无法签名
+	Configured bool `json:"configured"`
+	Active     bool `json:"active"`
+	// Retirable 已配置且没有钱包引用，可以从配置中移除
+	Retirable bool `json:"retirable"`
+}
+
+// KeystoreStatus 主密钥轮换进度
+type KeystoreStatus struct {
+	ActiveVersion int `json:"active_version"`
+	// Pending 仍使用旧版本加密、等待重新加密的托管钱包数量
+	Pending  int64              `json:"pending"`
+	Versions []KeyVersionStatus `json:"versions"`
+}
+
+// KeyRotationReport 一轮重新加密的结果
+type KeyRotationReport struct {
+	Rewrapped int      `json:"rewrapped"`
+	Failed    int      `json:"failed"`
+	Errors    []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const AssetSOL = "SOL"
+
+// 限额作用范围
+const (
+	LimitScopeWallet = "wallet"
+	LimitScopeClient = "client"
+	// LimitSubjectDefault 表示该 scope 下未单独配置时使用的默认限额
+	LimitSubjectDefault = "*"
+)
+
+// Limit 交易限额配置，nil 字段表示不限制
+type Limit struct {
+	ID            int64            `json:"id"`
+	Scope         string           `json:"scope"`
+	Subject       string           `json:"subject"`
+	Asset         string           `json:"asset"`
+	PerTxMax      *decimal.Decimal `json:"per_tx_max"`
+	DailyAmount   *decimal.Decimal `json:"daily_amount"`
+	DailyCount    *int64           `json:"daily_count"`
+	MonthlyAmount *decimal.Decimal `json:"monthly_amount"`
+	MonthlyCount  *int64           `json:"monthly_count"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// LimitUsage 一次计入限额的出账记录
+type LimitUsage struct {
+	ID        string          `json:"id"`
+	Scope     string          `json:"scope"`
+	Subject   string          `json:"subject"`
+	Asset     string          `json:"asset"`
+	Amount    decimal.Decimal `json:"amount"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// APIClient 调用方信息
+type APIClient struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 离线签名出账状态
+const (
+	// OfflinePrepared 未签名交易已生成，等待离线签名后导入
+	OfflinePrepared = "prepared"
+	// OfflineSubmitted 已签名交易已广播，等待确认
+	OfflineSubmitted = "submitted"
+	OfflineCompleted = "completed"
+	// OfflineFailed 交易执行失败或 nonce 已被其他交易推进，余额已退回
+	OfflineFailed = "failed"
+	// OfflineCancelling 已取消，已签名的交易在 nonce 推进前仍可能上链，余额暂不退回
+	OfflineCancelling = "cancelling"
+	// OfflineCancelled nonce 已被其他交易推进，余额已退回
+	OfflineCancelled = "cancelled"
+)
+
+// OfflineWithdrawal 冷钱包出账，交易由离线签名后导入
+type OfflineWithdrawal struct {
+	ID       string `json:"id"`
+	ClientID string `json:"client_id,omitempty"`
+	// Address 扣减余额的账本钱包
+	Address string `json:"address"`
+	// Signer 链上付款并签名的冷钱包
+	Signer       string          `json:"signer"`
+	ToAddress    string          `json:"to_address"`
+	Amount       decimal.Decimal `json:"amount"`
+	Memo         string          `json:"memo,omitempty"`
+	NonceAccount string          `json:"nonce_account"`
+	Nonce        string          `json:"nonce"`
+	// Transaction base64 编码的未签名交易
+	Transaction string `json:"transaction"`
+	// QRPayload 同一交易的 base32 编码，适合生成二维码
+	QRPayload        string     `json:"qr_payload,omitempty"`
+	LimitReservation string     `json:"-"`
+	Signature        string     `json:"signature,omitempty"`
+	Status           string     `json:"status"`
+	Error            string     `json:"error,omitempty"`
+	SubmittedAt      *time.Time `json:"submitted_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
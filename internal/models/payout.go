@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 批量付款状态
+const (
+	PayoutStatusProcessing = "processing"
+	PayoutStatusCompleted  = "completed"
+	// PayoutStatusPartial 部分付款项失败，可重试失败项
+	PayoutStatusPartial = "partially_failed"
+	PayoutStatusFailed  = "failed"
+	// PayoutStatusUnconfirmed 处理结束时仍有交易未确认，重试时重新查询链上结果
+	PayoutStatusUnconfirmed = "unconfirmed"
+)
+
+// 付款项状态
+const (
+	PayoutItemPending = "pending"
+	// PayoutItemSubmitted 交易已提交，等待确认
+	PayoutItemSubmitted = "submitted"
+	PayoutItemCompleted = "completed"
+	PayoutItemFailed    = "failed"
+)
+
+// PayoutBatch 批量付款，多个付款项打包进尽量少的链上交易
+type PayoutBatch struct {
+	ID          string        `json:"id"`
+	ClientID    string        `json:"client_id,omitempty"`
+	FromAddress string        `json:"from_address"`
+	Status      string        `json:"status"`
+	Summary     PayoutSummary `json:"summary"`
+	Items       []PayoutItem  `json:"items,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// PayoutSummary 各状态的付款项数量
+type PayoutSummary struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Submitted int `json:"submitted"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// PayoutItem 单个付款项
+type PayoutItem struct {
+	ID        string          `json:"id"`
+	BatchID   string          `json:"batch_id"`
+	Seq       int             `json:"seq"`
+	Recipient string          `json:"recipient"`
+	Amount    decimal.Decimal `json:"amount"`
+	Asset     string          `json:"asset"`
+	Reference string          `json:"reference,omitempty"`
+	Status    string          `json:"status"`
+	Signature string          `json:"signature,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	// LimitReservation 尚未释放的限额占用，失败时释放
+	LimitReservation string `json:"-"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 定时转账状态
+const (
+	ScheduleStatusActive    = "active"
+	ScheduleStatusPaused    = "paused"
+	ScheduleStatusCancelled = "cancelled"
+	// ScheduleStatusCompleted 单次转账执行成功
+	ScheduleStatusCompleted = "completed"
+	// ScheduleStatusFailed 单次转账重试次数用尽
+	ScheduleStatusFailed = "failed"
+)
+
+// 执行状态
+const (
+	ExecutionStatusRunning   = "running"
+	ExecutionStatusSucceeded = "succeeded"
+	ExecutionStatusFailed    = "failed"
+	// ExecutionStatusInterrupted 执行过程中进程退出，链上结果未知
+	ExecutionStatusInterrupted = "interrupted"
+)
+
+// ManagedWallet 托管钱包，私钥加密保存在服务端或由外部签名服务持有
+type ManagedWallet struct {
+	Address string `json:"address"`
+	// ClientID 归属的 API 客户端，为空表示平台钱包
+	ClientID string `json:"client_id,omitempty"`
+	// Signer 持有私钥的签名方式: keystore、remote、pkcs11
+	Signer string `json:"signer"`
+	// KeyID 签名服务中的密钥 ID，keystore 为钱包地址
+	KeyID string `json:"key_id"`
+	// DerivationIndex HD 派生路径 m/44'/501'/n'/0' 中的 n，非派生钱包为空
+	DerivationIndex *int64 `json:"derivation_index,omitempty"`
+	// KeyVersion keystore 加密私钥使用的主密钥版本，外部签名服务为空
+	KeyVersion *int      `json:"key_version,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TransferSchedule 定时或周期转账，从托管钱包转出
+type TransferSchedule struct {
+	ID          string          `json:"id"`
+	ClientID    string          `json:"client_id,omitempty"`
+	FromAddress string          `json:"from_address"`
+	ToAddress   string          `json:"to_address"`
+	Amount      decimal.Decimal `json:"amount"`
+	// Cron 标准五段 cron 表达式（UTC），为空时只在 NextRunAt 执行一次
+	Cron              string     `json:"cron,omitempty"`
+	Status            string     `json:"status"`
+	NextRunAt         *time.Time `json:"next_run_at"`
+	MaxRetries        int        `json:"max_retries"`
+	RetryDelaySeconds int        `json:"retry_delay_seconds"`
+	Attempt           int        `json:"attempt"`
+	LastError         string     `json:"last_error,omitempty"`
+	Reference         string     `json:"reference,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// ScheduleExecution 定时转账的一次执行记录
+type ScheduleExecution struct {
+	ID           string     `json:"id"`
+	ScheduleID   string     `json:"schedule_id"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	Attempt      int        `json:"attempt"`
+	Status       string     `json:"status"`
+	Signature    string     `json:"signature,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 实时推送事件类型
+const (
+	// StreamEventBalance 账本余额变动，data 为 BalanceUpdate
+	StreamEventBalance = "balance"
+	// StreamEventTransaction 交易状态变化，data 为 Transaction
+	StreamEventTransaction = "transaction"
+	// StreamEventReset 断点已超出保留范围，客户端需重新查询余额与交易
+	StreamEventReset = "reset"
+)
+
+// StreamEvent 推送给订阅方的地址事件
+type StreamEvent struct {
+	// ID 事件 ID，断线重连时通过 Last-Event-ID 续传；快照事件没有 ID
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	// Addresses 事件涉及的地址，订阅其中任一地址即可收到
+	Addresses []string        `json:"addresses"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// BalanceUpdate 地址的最新账本余额
+type BalanceUpdate struct {
+	Address   string          `json:"address"`
+	Available decimal.Decimal `json:"available"`
+	Reserved  decimal.Decimal `json:"reserved"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 资金归集类型
+const (
+	// MovementSweep 充值地址归集到热钱包
+	MovementSweep = "sweep"
+	// MovementCold 热钱包超出上限的部分转入冷钱包
+	MovementCold = "cold"
+	// MovementTopUp 热钱包余额不足，请求从冷钱包补充
+	MovementTopUp = "topup"
+)
+
+// 资金归集状态
+const (
+	MovementSubmitted = "submitted"
+	MovementConfirmed = "confirmed"
+	MovementFailed    = "failed"
+	// MovementRequested 补充请求等待人工从冷钱包转入
+	MovementRequested = "requested"
+	// MovementFulfilled 热钱包余额已恢复到下限以上
+	MovementFulfilled = "fulfilled"
+)
+
+// DepositAddress 分配给 API 客户端用户的充值地址（托管钱包）
+type DepositAddress struct {
+	Address     string          `json:"address"`
+	ClientID    string          `json:"client_id"`
+	UserRef     string          `json:"user_ref"`
+	SweptTotal  decimal.Decimal `json:"swept_total"`
+	LastSweptAt *time.Time      `json:"last_swept_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// TreasuryMovement 一次资金归集、冷钱包转出或补充请求
+type TreasuryMovement struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	FromAddress string          `json:"from_address"`
+	ToAddress   string          `json:"to_address"`
+	Amount      decimal.Decimal `json:"amount"`
+	Status      string          `json:"status"`
+	Signature   string          `json:"signature,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// TreasuryStatus 热钱包余额与阈值
+type TreasuryStatus struct {
+	HotWallet  string          `json:"hot_wallet"`
+	ColdWallet string          `json:"cold_wallet,omitempty"`
+	HotBalance decimal.Decimal `json:"hot_balance"`
+	HotMin     decimal.Decimal `json:"hot_min"`
+	HotMax     decimal.Decimal `json:"hot_max"`
+	// TopUp 未完成的补充请求
+	TopUp *TreasuryMovement `json:"topup,omitempty"`
+}
+
+// SweepReport 一轮归集的结果
+type SweepReport struct {
+	// Settled 本轮确认或判定失败的已提交交易数
+	Settled int                `json:"settled"`
+	Swept   []TreasuryMovement `json:"swept"`
+	Cold    *TreasuryMovement  `json:"cold,omitempty"`
+	TopUp   *TreasuryMovement  `json:"topup,omitempty"`
+	// Errors 单个地址归集失败不影响其他地址
+	Errors []string `json:"errors,omitempty"`
+}
@@ -16,12 +16,44 @@ type Wallet struct {
 }
 
 type Transaction struct {
-	ID          string          `json:"id"`
-	FromWallet  string          `json:"from_wallet"`
-	ToWallet    string          `json:"to_wallet"`
-	Amount      decimal.Decimal `json:"amount"`
-	Type        string          `json:"type"` // deposit, withdraw, transfer
-	Status      string          `json:"status"`
-	CreatedAt   time.Time       `json:"created_at"`
-	CompletedAt time.Time       `json:"completed_at"`
+	// ID 按时间排序的 UUIDv7
+	ID         string          `json:"id"`
+	FromWallet string          `json:"from_wallet"`
+	ToWallet   string          `json:"to_wallet"`
+	Amount     decimal.Decimal `json:"amount"`
+	Asset      string          `json:"asset"`
+	Type       string          `json:"type"` // deposit, withdraw, transfer, capture, payout
+	Status     string          `json:"status"`
+	// ChainSignature 链上交易签名，只记账不上链的交易为空
+	ChainSignature string `json:"chain_signature,omitempty"`
+	ClientID       string `json:"client_id,omitempty"`
+	// ClientReference API 客户端提交的引用，同一客户端内唯一
+	ClientReference string    `json:"client_reference,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	CompletedAt     time.Time `json:"completed_at"`
+}
+
+// BalanceView 钱包余额视图，区分内部账本余额与链上余额
+type BalanceView struct {
+	Address string `json:"address"`
+	// Available 可用余额（账本余额扣除冻结金额）
+	Available decimal.Decimal `json:"available"`
+	// Reserved 已冻结、等待扣款的金额
+	Reserved decimal.Decimal `json:"reserved"`
+	// Pending 尚未完成的出账交易金额
+	Pending decimal.Decimal `json:"pending"`
+	// Holds 当前生效的冻结
+	Holds   []Hold          `json:"holds"`
+	OnChain *OnChainBalance `json:"on_chain"`
+	// OnChainError 链上余额查询失败时的错误信息
+	OnChainError string    `json:"on_chain_error,omitempty"`
+	Cached       bool      `json:"cached"`
+	AsOf         time.Time `json:"as_of"`
+}
+
+// OnChainBalance 链上余额及其查询时的确认级别与 slot
+type OnChainBalance struct {
+	Balance    decimal.Decimal `json:"balance"`
+	Commitment string          `json:"commitment"`
+	Slot       uint64          `json:"slot"`
 }
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 事件类型
+const (
+	EventDepositCompleted    = "deposit.completed"
+	EventWithdrawalCompleted = "withdrawal.completed"
+	EventTransferCompleted   = "transfer.completed"
+	// EventPayoutFinished 批量付款处理结束，data 中包含批次状态与各状态数量
+	EventPayoutFinished = "payout.finished"
+	// EventAddressBookCreated、EventAddressBookDeleted 地址簿变更，data 为地址簿记录
+	EventAddressBookCreated = "address_book.created"
+	EventAddressBookDeleted = "address_book.deleted"
+	// EventWebhookTest 测试投递
+	EventWebhookTest = "webhook.test"
+)
+
+// EventTypes 可订阅的事件类型
+var EventTypes = []string{
+	EventDepositCompleted,
+	EventWithdrawalCompleted,
+	EventTransferCompleted,
+	EventPayoutFinished,
+	EventAddressBookCreated,
+	EventAddressBookDeleted,
+	EventWebhookTest,
+}
+
+// 投递状态
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	// DeliveryStatusDead 重试次数用尽，可手动重新投递
+	DeliveryStatusDead = "dead"
+)
+
+// Event 业务事件，写入发件箱后投递给订阅方
+type Event struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	ClientID string          `json:"-"`
+	Data     json.RawMessage `json:"data"`
+	// CreatedAt 事件发生时间
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookEndpoint API 客户端注册的 webhook 地址
+type WebhookEndpoint struct {
+	ID       string `json:"id"`
+	ClientID string `json:"client_id"`
+	URL      string `json:"url"`
+	// Secret 签名密钥，只在创建时返回
+	Secret string `json:"secret,omitempty"`
+	// EventTypes 订阅的事件类型，为空时订阅全部
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery 一个事件对一个 webhook 地址的投递
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	EndpointID     string     `json:"endpoint_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	// AttemptLog 各次投递尝试，只在查询单个投递时返回
+	AttemptLog []WebhookAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookAttempt 一次投递尝试
+type WebhookAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
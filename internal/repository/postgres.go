@@ -34,6 +34,16 @@ func NewPostgresRepository(connStr string, logger *logger.Logger) (*PostgresRepo
 	}, nil
 }
 
+// GetDB 返回底层数据库连接池
+func (r *PostgresRepository) GetDB() *sql.DB {
+	return r.db
+}
+
+// Close 关闭数据库连接池
+func (r *PostgresRepository) Close() error {
+	return r.db.Close()
+}
+
 func (r *PostgresRepository) CreateWallet(ctx context.Context, wallet *models.Wallet) error {
 	query := `
         INSERT INTO wallets (id, address, balance, created_at, updated_at)
@@ -72,59 +82,27 @@ func (r *PostgresRepository) GetBalance(ctx context.Context, address string) (de
 	return balance, err
 }
 
-func (r *PostgresRepository) CreateTransaction(ctx context.Context, tx *models.Transaction) error {
-	query := `
-        INSERT INTO transactions (id, from_wallet, to_wallet, amount, type, status, created_at, completed_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-    `
+// GetPendingAmount 统计钱包尚未完成的出账交易金额
+func (r *PostgresRepository) GetPendingAmount(ctx context.Context, address string) (decimal.Decimal, error) {
+	query := `SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE from_wallet = $1 AND status = 'pending'`
 
-	_, err := r.db.ExecContext(ctx, query,
-		tx.ID,
-		tx.FromWallet,
-		tx.ToWallet,
-		tx.Amount,
-		tx.Type,
-		tx.Status,
-		tx.CreatedAt,
-		tx.CompletedAt,
-	)
-	return err
+	var amount decimal.Decimal
+	err := r.db.QueryRowContext(ctx, query, address).Scan(&amount)
+	return amount, err
+}
+
+// CreateTransaction 写入交易记录，客户端引用重复时返回 ErrDuplicateReference
+func (r *PostgresRepository) CreateTransaction(ctx context.Context, tx *models.Transaction) error {
+	return insertTransaction(ctx, r.db, tx)
 }
 
 func (r *PostgresRepository) GetTransactions(ctx context.Context, address string) ([]models.Transaction, error) {
-	query := `
-        SELECT id, from_wallet, to_wallet, amount, type, status, created_at, completed_at
+	return r.queryTransactions(ctx, `
+        SELECT `+transactionColumns+`
         FROM transactions
         WHERE from_wallet = $1 OR to_wallet = $1
         ORDER BY created_at DESC
-    `
-
-	rows, err := r.db.QueryContext(ctx, query, address)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var transactions []models.Transaction
-	for rows.Next() {
-		var tx models.Transaction
-		err := rows.Scan(
-			&tx.ID,
-			&tx.FromWallet,
-			&tx.ToWallet,
-			&tx.Amount,
-			&tx.Type,
-			&tx.Status,
-			&tx.CreatedAt,
-			&tx.CompletedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, tx)
-	}
-
-	return transactions, rows.Err()
+    `, address)
 }
 
 func (r *PostgresRepository) AddBalance(ctx context.Context, address string, amount decimal.Decimal) error {
@@ -167,10 +145,10 @@ func (r *PostgresRepository) SubBalance(ctx context.Context, address string, amo
 	defer tx.Rollback()
 
 	// 使用 FOR UPDATE 子句锁定行并检查余额
-	var currentBalance decimal.Decimal
+	var currentBalance, reserved decimal.Decimal
 	err = tx.QueryRowContext(ctx, 
-		"SELECT balance FROM wallets WHERE address = $1 FOR UPDATE", 
-		address).Scan(&currentBalance)
+		"SELECT balance, reserved_balance FROM wallets WHERE address = $1 FOR UPDATE", 
+		address).Scan(&currentBalance, &reserved)
 	
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("wallet not found")
@@ -178,8 +156,8 @@ func (r *PostgresRepository) SubBalance(ctx context.Context, address string, amo
 		return fmt.Errorf("query balance failed: %w", err)
 	}
 
-	// 检查余额是否足够
-	if currentBalance.LessThan(amount) {
+	// 检查可用余额（扣除冻结金额）是否足够
+	if currentBalance.Sub(reserved).LessThan(amount) {
 		return fmt.Errorf("insufficient balance")
 	}
 
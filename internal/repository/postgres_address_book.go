@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"mywallet/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrAddressBookEntryNotFound = errors.New("address book entry not found")
+	ErrAddressBookEntryExists   = errors.New("address is already in the address book")
+)
+
+const addressBookColumns = `id, scope, subject, address, labels, memo, created_by, active_at, created_at`
+
+func scanAddressBookEntry(row rowScanner) (*models.AddressBookEntry, error) {
+	var e models.AddressBookEntry
+	err := row.Scan(&e.ID, &e.Scope, &e.Subject, &e.Address, pq.Array(&e.Labels), &e.Memo, &e.CreatedBy,
+		&e.ActiveAt, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrAddressBookEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CreateAddressBookEntry 新增地址簿记录，同一 scope/subject 下地址已存在时返回 ErrAddressBookEntryExists
+func (r *PostgresRepository) CreateAddressBookEntry(ctx context.Context, e *models.AddressBookEntry) error {
+	labels := e.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO address_book (id, scope, subject, address, labels, memo, created_by, active_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (scope, subject, address) DO NOTHING
+        RETURNING id
+    `, e.ID, e.Scope, e.Subject, e.Address, pq.Array(labels), e.Memo, e.CreatedBy, e.ActiveAt, e.CreatedAt).Scan(&e.ID)
+	if err == sql.ErrNoRows {
+		return ErrAddressBookEntryExists
+	}
+	return err
+}
+
+// GetAddressBookEntry 查询地址簿记录
+func (r *PostgresRepository) GetAddressBookEntry(ctx context.Context, id string) (*models.AddressBookEntry, error) {
+	return scanAddressBookEntry(r.db.QueryRowContext(ctx,
+		`SELECT `+addressBookColumns+` FROM address_book WHERE id = $1`, id))
+}
+
+// ListAddressBook 查询 scope/subject 下的地址簿，subject 为空时返回该 scope 的全部记录
+func (r *PostgresRepository) ListAddressBook(ctx context.Context, scope, subject string) ([]models.AddressBookEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+addressBookColumns+`
+        FROM address_book
+        WHERE scope = $1 AND ($2 = '' OR subject = $2)
+        ORDER BY created_at
+    `, scope, subject)
+	if err != nil {
+		return nil, err
+	}
+	return scanAddressBookEntries(rows)
+}
+
+// FindAddressBookEntries 查询发送方钱包或 API 客户端地址簿中的目标地址，钱包级记录在前
+func (r *PostgresRepository) FindAddressBookEntries(ctx context.Context, wallet, clientID, address string) ([]models.AddressBookEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+addressBookColumns+`
+        FROM address_book
+        WHERE address = $3
+          AND ((scope = 'wallet' AND subject = $1) OR (scope = 'client' AND subject = $2 AND $2 <> ''))
+        ORDER BY scope = 'wallet' DESC, active_at
+    `, wallet, clientID, address)
+	if err != nil {
+		return nil, err
+	}
+	return scanAddressBookEntries(rows)
+}
+
+// DeleteAddressBookEntry 删除地址簿记录
+func (r *PostgresRepository) DeleteAddressBookEntry(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM address_book WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAddressBookEntryNotFound
+	}
+	return nil
+}
+
+func scanAddressBookEntries(rows *sql.Rows) ([]models.AddressBookEntry, error) {
+	defer rows.Close()
+
+	entries := make([]models.AddressBookEntry, 0)
+	for rows.Next() {
+		e, err := scanAddressBookEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"mywallet/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// CreateAdjustment 在同一事务中调整账本余额并写入交易记录与调账记录，出账时检查可用余额
+func (r *PostgresRepository) CreateAdjustment(ctx context.Context, adj *models.BalanceAdjustment, record *models.Transaction) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if adj.Amount.IsPositive() {
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO wallets (address, balance) VALUES ($1, $2)
+            ON CONFLICT (address) DO UPDATE SET balance = wallets.balance + EXCLUDED.balance, updated_at = NOW()
+        `, adj.Address, adj.Amount)
+		if err != nil {
+			return fmt.Errorf("update balance failed: %w", err)
+		}
+	} else {
+		var balance, reserved decimal.Decimal
+		err = tx.QueryRowContext(ctx,
+			"SELECT balance, reserved_balance FROM wallets WHERE address = $1 FOR UPDATE",
+			adj.Address).Scan(&balance, &reserved)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("wallet not found")
+		} else if err != nil {
+			return fmt.Errorf("query balance failed: %w", err)
+		}
+		if balance.Sub(reserved).Add(adj.Amount).IsNegative() {
+			return fmt.Errorf("insufficient balance")
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE wallets SET balance = balance + $1, updated_at = NOW() WHERE address = $2",
+			adj.Amount, adj.Address)
+		if err != nil {
+			return fmt.Errorf("update balance failed: %w", err)
+		}
+	}
+
+	err = insertTransaction(ctx, tx, record)
+	if err != nil {
+		return fmt.Errorf("create transaction failed: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO balance_adjustments (id, address, amount, reason, operator, transaction_id, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `, adj.ID, adj.Address, adj.Amount, adj.Reason, adj.Operator, adj.TransactionID, adj.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create adjustment failed: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListWalletAddresses 查询账本中的全部钱包地址
+func (r *PostgresRepository) ListWalletAddresses(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT address FROM wallets ORDER BY address")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
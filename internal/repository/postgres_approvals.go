@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrApprovalPolicyNotFound = errors.New("approval policy not found")
+	ErrApprovalNotFound       = errors.New("approval request not found")
+	ErrApprovalNotPending     = errors.New("approval request is not awaiting approval")
+	ErrNotApprover            = errors.New("not an approver of this request")
+	ErrAlreadyDecided         = errors.New("approver has already decided on this request")
+)
+
+const approvalPolicyColumns = `id, address, min_amount, required_approvals, approvers, ttl_seconds, created_at, updated_at`
+
+const approvalColumns = `id, operation, client_id, from_address, to_address, amount, policy_id, required_approvals,
+        approvers, hold_id, status, reference, client_reference, error, expires_at, created_at, updated_at`
+
+func scanApprovalPolicy(row rowScanner) (*models.ApprovalPolicy, error) {
+	var p models.ApprovalPolicy
+	err := row.Scan(&p.ID, &p.Address, &p.MinAmount, &p.RequiredApprovals, pq.Array(&p.Approvers),
+		&p.TTLSeconds, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanApproval(row rowScanner) (*models.ApprovalRequest, error) {
+	var a models.ApprovalRequest
+	err := row.Scan(&a.ID, &a.Operation, &a.ClientID, &a.FromAddress, &a.ToAddress, &a.Amount, &a.PolicyID,
+		&a.RequiredApprovals, pq.Array(&a.Approvers), &a.HoldID, &a.Status, &a.Reference,
+		&a.ClientReference, &a.Error, &a.ExpiresAt, &a.CreatedAt, &a.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrApprovalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// UpsertApprovalPolicy 创建或更新审批策略，同一地址与金额档位只有一条
+func (r *PostgresRepository) UpsertApprovalPolicy(ctx context.Context, p *models.ApprovalPolicy) (*models.ApprovalPolicy, error) {
+	return scanApprovalPolicy(r.db.QueryRowContext(ctx, `
+        INSERT INTO approval_policies (address, min_amount, required_approvals, approvers, ttl_seconds)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (address, min_amount) DO UPDATE SET
+            required_approvals = EXCLUDED.required_approvals,
+            approvers = EXCLUDED.approvers,
+            ttl_seconds = EXCLUDED.ttl_seconds,
+            updated_at = NOW()
+        RETURNING `+approvalPolicyColumns,
+		p.Address, p.MinAmount, p.RequiredApprovals, pq.Array(p.Approvers), p.TTLSeconds))
+}
+
+// ListApprovalPolicies 查询审批策略，address 为空时返回全部
+func (r *PostgresRepository) ListApprovalPolicies(ctx context.Context, address string) ([]models.ApprovalPolicy, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+approvalPolicyColumns+`
+        FROM approval_policies
+        WHERE ($1 = '' OR address = $1)
+        ORDER BY address, min_amount
+    `, address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]models.ApprovalPolicy, 0)
+	for rows.Next() {
+		p, err := scanApprovalPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *p)
+	}
+	return policies, rows.Err()
+}
+
+// DeleteApprovalPolicy 删除审批策略，已创建的审批不受影响
+func (r *PostgresRepository) DeleteApprovalPolicy(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM approval_policies WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrApprovalPolicyNotFound
+	}
+	return nil
+}
+
+// MatchApprovalPolicy 查询金额适用的审批策略：地址有钱包级策略时只使用钱包级策略，否则使用默认策略，
+// 取 min_amount 不超过金额的最高档位。不需要审批时返回 nil
+func (r *PostgresRepository) MatchApprovalPolicy(ctx context.Context, address string, amount decimal.Decimal) (*models.ApprovalPolicy, error) {
+	p, err := scanApprovalPolicy(r.db.QueryRowContext(ctx, `
+        SELECT `+approvalPolicyColumns+`
+        FROM approval_policies
+        WHERE min_amount <= $2
+          AND address = CASE
+              WHEN EXISTS (SELECT 1 FROM approval_policies WHERE address = $1) THEN $1
+              ELSE ''
+          END
+        ORDER BY min_amount DESC
+        LIMIT 1
+    `, address, amount))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// CreateApprovalRequest 创建待审批请求
+func (r *PostgresRepository) CreateApprovalRequest(ctx context.Context, a *models.ApprovalRequest) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO approval_requests (id, operation, client_id, from_address, to_address, amount, policy_id,
+            required_approvals, approvers, hold_id, status, client_reference, expires_at, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)
+    `, a.ID, a.Operation, a.ClientID, a.FromAddress, a.ToAddress, a.Amount, a.PolicyID,
+		a.RequiredApprovals, pq.Array(a.Approvers), a.HoldID, a.Status, a.ClientReference, a.ExpiresAt, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create approval request failed: %w", err)
+	}
+	return nil
+}
+
+// GetApprovalRequest 查询审批请求及审批意见
+func (r *PostgresRepository) GetApprovalRequest(ctx context.Context, id string) (*models.ApprovalRequest, error) {
+	return getApprovalRequest(ctx, r.db, id, false)
+}
+
+// ListApprovalRequests 查询审批请求，clientID、status 为空时不过滤，按创建时间倒序
+func (r *PostgresRepository) ListApprovalRequests(ctx context.Context, clientID, status string, limit int) ([]models.ApprovalRequest, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+approvalColumns+`
+        FROM approval_requests
+        WHERE ($1 = '' OR client_id = $1) AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC
+        LIMIT $3
+    `, clientID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := make([]models.ApprovalRequest, 0)
+	for rows.Next() {
+		a, err := scanApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, *a)
+	}
+	return approvals, rows.Err()
+}
+
+// DecideApproval 记录审批意见：任一审批人拒绝即拒绝，批准人数达到要求时状态变为 approved。
+// 返回更新后的审批请求
+func (r *PostgresRepository) DecideApproval(ctx context.Context, id string, d models.ApprovalDecision, now time.Time) (*models.ApprovalRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	a, err := getApprovalRequest(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != models.ApprovalAwaiting || !a.ExpiresAt.After(now) {
+		return nil, ErrApprovalNotPending
+	}
+	allowed := false
+	for _, approver := range a.Approvers {
+		if approver == d.Approver {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, ErrNotApprover
+	}
+	for _, prev := range a.Decisions {
+		if prev.Approver == d.Approver {
+			return nil, ErrAlreadyDecided
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO approval_decisions (request_id, approver, decision, comment, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `, id, d.Approver, d.Decision, d.Comment, now)
+	if err != nil {
+		return nil, fmt.Errorf("create approval decision failed: %w", err)
+	}
+	d.CreatedAt = now
+	a.Decisions = append(a.Decisions, d)
+
+	approvals := 0
+	for _, prev := range a.Decisions {
+		if prev.Decision == models.DecisionApprove {
+			approvals++
+		}
+	}
+	switch {
+	case d.Decision == models.DecisionReject:
+		a.Status = models.ApprovalRejected
+	case approvals >= a.RequiredApprovals:
+		a.Status = models.ApprovalApproved
+	}
+	if a.Status != models.ApprovalAwaiting {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE approval_requests SET status = $1, updated_at = $2 WHERE id = $3",
+			a.Status, now, id)
+		if err != nil {
+			return nil, fmt.Errorf("update approval request failed: %w", err)
+		}
+		a.UpdatedAt = now
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// FinishApprovalRequest 记录批准后的执行结果
+func (r *PostgresRepository) FinishApprovalRequest(ctx context.Context, id, status, reference, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE approval_requests SET status = $1, reference = $2, error = $3, updated_at = NOW()
+        WHERE id = $4 AND status = $5
+    `, status, reference, errMsg, id, models.ApprovalApproved)
+	return err
+}
+
+// ExpireApprovalRequests 将已过期的待审批请求标记为 expired，返回这些请求
+func (r *PostgresRepository) ExpireApprovalRequests(ctx context.Context, now time.Time, limit int) ([]models.ApprovalRequest, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        UPDATE approval_requests SET status = $1, updated_at = $2
+        WHERE id IN (
+            SELECT id FROM approval_requests
+            WHERE status = $3 AND expires_at <= $2
+            ORDER BY expires_at
+            LIMIT $4
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING `+approvalColumns,
+		models.ApprovalExpired, now, models.ApprovalAwaiting, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := make([]models.ApprovalRequest, 0)
+	for rows.Next() {
+		a, err := scanApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, *a)
+	}
+	return approvals, rows.Err()
+}
+
+// queryer 同时适用于 *sql.DB 与 *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func getApprovalRequest(ctx context.Context, q queryer, id string, forUpdate bool) (*models.ApprovalRequest, error) {
+	query := `SELECT ` + approvalColumns + ` FROM approval_requests WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	a, err := scanApproval(q.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+        SELECT approver, decision, comment, created_at
+        FROM approval_decisions
+        WHERE request_id = $1
+        ORDER BY created_at
+    `, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.ApprovalDecision
+		if err := rows.Scan(&d.Approver, &d.Decision, &d.Comment, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Decisions = append(a.Decisions, d)
+	}
+	return a, rows.Err()
+}
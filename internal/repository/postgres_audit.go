@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"mywallet/internal/audit"
+	"mywallet/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// auditLockKey 追加审计日志时的事务级 advisory lock，保证哈希链按 seq 串行
+const auditLockKey = 7_240_385_003
+
+// AuditFilter 审计日志查询条件，零值字段不参与筛选
+type AuditFilter struct {
+	Address   string
+	ActorType string
+	ActorID   string
+	Operation string
+	Since     *time.Time
+	Until     *time.Time
+	// BeforeSeq 分页游标，只返回 seq 小于该值的记录
+	BeforeSeq int64
+	Limit     int
+}
+
+const auditColumns = `seq, id, actor_type, actor_id, operation, addresses, amount, reference, payload_hash,
+    balances_before, balances_after, ip, correlation_id, outcome, error, created_at, prev_hash, hash`
+
+// AppendAudit 追加审计记录：在锁内读取上一条记录的哈希并计算本条哈希，写入后回填 seq、prev_hash 与 hash
+func (r *PostgresRepository) AppendAudit(ctx context.Context, e *models.AuditEntry) error {
+	audit.Normalize(e)
+	if e.Addresses == nil {
+		e.Addresses = []string{}
+	}
+	before, err := json.Marshal(auditBalances(e.BalancesBefore))
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(auditBalances(e.BalancesAfter))
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", auditLockKey); err != nil {
+		return fmt.Errorf("acquire audit lock failed: %w", err)
+	}
+
+	prev := audit.GenesisHash
+	err = tx.QueryRowContext(ctx, "SELECT hash FROM audit_log ORDER BY seq DESC LIMIT 1").Scan(&prev)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("query last audit hash failed: %w", err)
+	}
+	e.PrevHash = prev
+	e.Hash = audit.Hash(prev, e)
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO audit_log (id, actor_type, actor_id, operation, addresses, amount, reference, payload_hash,
+            balances_before, balances_after, ip, correlation_id, outcome, error, created_at, prev_hash, hash)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+        RETURNING seq
+    `, e.ID, e.Actor.Type, e.Actor.ID, e.Operation, pq.Array(e.Addresses), e.Amount, e.Reference, e.PayloadHash,
+		before, after, e.IP, e.CorrelationID, e.Outcome, e.Error, e.CreatedAt, e.PrevHash, e.Hash).Scan(&e.Seq)
+	if err != nil {
+		return fmt.Errorf("insert audit entry failed: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListAudit 按条件查询审计日志，按 seq 倒序
+func (r *PostgresRepository) ListAudit(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error) {
+	var since, until *time.Time
+	if filter.Since != nil {
+		t := filter.Since.UTC()
+		since = &t
+	}
+	if filter.Until != nil {
+		t := filter.Until.UTC()
+		until = &t
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+auditColumns+`
+        FROM audit_log
+        WHERE ($1 = '' OR $1 = ANY(addresses))
+          AND ($2 = '' OR actor_type = $2)
+          AND ($3 = '' OR actor_id = $3)
+          AND ($4 = '' OR operation = $4)
+          AND ($5::timestamp IS NULL OR created_at >= $5)
+          AND ($6::timestamp IS NULL OR created_at < $6)
+          AND ($7::bigint = 0 OR seq < $7)
+        ORDER BY seq DESC
+        LIMIT $8
+    `, filter.Address, filter.ActorType, filter.ActorID, filter.Operation, since, until, filter.BeforeSeq, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEntries(rows)
+}
+
+// AuditEntriesAfter 按 seq 顺序读取 afterSeq 之后的审计记录，用于校验哈希链
+func (r *PostgresRepository) AuditEntriesAfter(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+auditColumns+`
+        FROM audit_log
+        WHERE seq > $1
+        ORDER BY seq
+        LIMIT $2
+    `, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEntries(rows)
+}
+
+func scanAuditEntries(rows *sql.Rows) ([]models.AuditEntry, error) {
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&e.Seq, &e.ID, &e.Actor.Type, &e.Actor.ID, &e.Operation, pq.Array(&e.Addresses),
+			&e.Amount, &e.Reference, &e.PayloadHash, &before, &after, &e.IP, &e.CorrelationID, &e.Outcome,
+			&e.Error, &e.CreatedAt, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(before, &e.BalancesBefore); err != nil {
+			return nil, fmt.Errorf("decode audit balances failed: %w", err)
+		}
+		if err := json.Unmarshal(after, &e.BalancesAfter); err != nil {
+			return nil, fmt.Errorf("decode audit balances failed: %w", err)
+		}
+		// 写入时为 UTC
+		e.CreatedAt = time.Date(e.CreatedAt.Year(), e.CreatedAt.Month(), e.CreatedAt.Day(), e.CreatedAt.Hour(),
+			e.CreatedAt.Minute(), e.CreatedAt.Second(), e.CreatedAt.Nanosecond(), time.UTC)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func auditBalances(balances map[string]models.AuditBalance) map[string]models.AuditBalance {
+	if balances == nil {
+		return map[string]models.AuditBalance{}
+	}
+	return balances
+}
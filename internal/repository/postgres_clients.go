@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"mywallet/internal/models"
+)
+
+var ErrClientNotFound = errors.New("api client not found")
+
+// CreateAPIClient 创建 API 客户端
+func (r *PostgresRepository) CreateAPIClient(ctx context.Context, name, apiKeyHash string) (*models.APIClient, error) {
+	query := `
+        INSERT INTO api_clients (name, api_key_hash)
+        VALUES ($1, $2)
+        RETURNING id, name, created_at, updated_at
+    `
+
+	var c models.APIClient
+	err := r.db.QueryRowContext(ctx, query, name, apiKeyHash).
+		Scan(&c.ID, &c.Name, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetAPIClientByKeyHash 根据 API Key 摘要查询客户端
+func (r *PostgresRepository) GetAPIClientByKeyHash(ctx context.Context, apiKeyHash string) (*models.APIClient, error) {
+	query := `SELECT id, name, created_at, updated_at FROM api_clients WHERE api_key_hash = $1`
+
+	var c models.APIClient
+	err := r.db.QueryRowContext(ctx, query, apiKeyHash).
+		Scan(&c.ID, &c.Name, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListAPIClients 查询全部 API 客户端
+func (r *PostgresRepository) ListAPIClients(ctx context.Context) ([]models.APIClient, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name, created_at, updated_at FROM api_clients ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := make([]models.APIClient, 0)
+	for rows.Next() {
+		var c models.APIClient
+		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// RotateAPIClientKey 替换客户端的 API Key 摘要，旧 Key 立即失效
+func (r *PostgresRepository) RotateAPIClientKey(ctx context.Context, id, apiKeyHash string) (*models.APIClient, error) {
+	query := `
+        UPDATE api_clients SET api_key_hash = $1, updated_at = NOW()
+        WHERE id = $2
+        RETURNING id, name, created_at, updated_at
+    `
+
+	var c models.APIClient
+	err := r.db.QueryRowContext(ctx, query, apiKeyHash, id).
+		Scan(&c.ID, &c.Name, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// sponsoredFeeLockKey 占用代付额度时的事务级 advisory lock，与 client_id 的哈希组合，同一客户端串行
+const sponsoredFeeLockKey = 7_240_385_004
+
+// ReserveSponsoredFee 在 API 客户端自 since 起的代付合计加上本笔不超过 limit 时写入代付记录（签名为空），
+// 超过时返回 false。limit 不为正数时不限制。交易发送前调用，检查与写入在同一把锁内完成
+func (r *PostgresRepository) ReserveSponsoredFee(ctx context.Context, f *models.SponsoredFee, since time.Time, limit decimal.Decimal) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", sponsoredFeeLockKey, f.ClientID); err != nil {
+		return false, fmt.Errorf("lock sponsored fees failed: %w", err)
+	}
+	if limit.IsPositive() {
+		var total decimal.Decimal
+		err := tx.QueryRowContext(ctx, `
+            SELECT COALESCE(SUM(amount), 0) FROM sponsored_fees
+            WHERE client_id = $1 AND created_at >= $2
+        `, f.ClientID, since).Scan(&total)
+		if err != nil {
+			return false, fmt.Errorf("query sponsored fees failed: %w", err)
+		}
+		if total.Add(f.Amount).GreaterThan(limit) {
+			return false, nil
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO sponsored_fees (client_id, address, fee_payer, signature, amount, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `, f.ClientID, f.Address, f.FeePayer, f.Signature, f.Amount, f.CreatedAt).Scan(&f.ID)
+	if err != nil {
+		return false, fmt.Errorf("insert sponsored fee failed: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// SetSponsoredFeeSignature 交易发送后记录代付交易的签名
+func (r *PostgresRepository) SetSponsoredFeeSignature(ctx context.Context, id int64, signature string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE sponsored_fees SET signature = $1 WHERE id = $2", signature, id)
+	return err
+}
+
+// DeleteSponsoredFee 删除交易未发出的代付记录，退回额度
+func (r *PostgresRepository) DeleteSponsoredFee(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM sponsored_fees WHERE id = $1", id)
+	return err
+}
+
+// SponsoredFeeTotal 查询 API 客户端在 [since, until) 内的代付手续费笔数与合计
+func (r *PostgresRepository) SponsoredFeeTotal(ctx context.Context, clientID string, since, until time.Time) (int64, decimal.Decimal, error) {
+	var (
+		count int64
+		total decimal.Decimal
+	)
+	err := r.db.QueryRowContext(ctx, `
+        SELECT COUNT(*), COALESCE(SUM(amount), 0)
+        FROM sponsored_fees
+        WHERE client_id = $1 AND created_at >= $2 AND created_at < $3
+    `, clientID, since, until).Scan(&count, &total)
+	return count, total, err
+}
+
+// SummarizeSponsoredFees 按 API 客户端汇总 [since, until) 内的代付手续费
+func (r *PostgresRepository) SummarizeSponsoredFees(ctx context.Context, since, until time.Time) ([]models.SponsoredFeeSummary, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT client_id, COUNT(*), SUM(amount)
+        FROM sponsored_fees
+        WHERE created_at >= $1 AND created_at < $2
+        GROUP BY client_id
+        ORDER BY SUM(amount) DESC
+    `, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]models.SponsoredFeeSummary, 0)
+	for rows.Next() {
+		sum := models.SponsoredFeeSummary{PeriodStart: since, PeriodEnd: until}
+		if err := rows.Scan(&sum.ClientID, &sum.Count, &sum.Total); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, sum)
+	}
+	return summaries, rows.Err()
+}
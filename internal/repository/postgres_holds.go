@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrHoldNotFound  = errors.New("hold not found")
+	ErrHoldNotActive = errors.New("hold is not active")
+	ErrSystemHold    = errors.New("hold is managed by the system")
+)
+
+const holdColumns = `id, COALESCE(client_id, ''), system, address, amount, captured_amount, status, COALESCE(reference, ''),
+        expires_at, created_at, updated_at`
+
+func scanHold(row rowScanner) (*models.Hold, error) {
+	var h models.Hold
+	err := row.Scan(&h.ID, &h.ClientID, &h.System, &h.Address, &h.Amount, &h.CapturedAmount, &h.Status, &h.Reference,
+		&h.ExpiresAt, &h.CreatedAt, &h.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// GetBalances 查询账本余额与冻结金额，钱包不存在时均为 0
+func (r *PostgresRepository) GetBalances(ctx context.Context, address string) (balance, reserved decimal.Decimal, err error) {
+	query := `SELECT balance, reserved_balance FROM wallets WHERE address = $1`
+
+	err = r.db.QueryRowContext(ctx, query, address).Scan(&balance, &reserved)
+	if err == sql.ErrNoRows {
+		return decimal.Zero, decimal.Zero, nil
+	}
+	return balance, reserved, err
+}
+
+// CreateHold 冻结资金：检查可用余额并增加冻结金额
+func (r *PostgresRepository) CreateHold(ctx context.Context, hold *models.Hold) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var balance, reserved decimal.Decimal
+	err = tx.QueryRowContext(ctx,
+		"SELECT balance, reserved_balance FROM wallets WHERE address = $1 FOR UPDATE",
+		hold.Address).Scan(&balance, &reserved)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("wallet not found")
+	} else if err != nil {
+		return fmt.Errorf("query balance failed: %w", err)
+	}
+
+	if balance.Sub(reserved).LessThan(hold.Amount) {
+		return fmt.Errorf("insufficient balance")
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE wallets SET reserved_balance = reserved_balance + $1, updated_at = NOW() WHERE address = $2",
+		hold.Amount, hold.Address)
+	if err != nil {
+		return fmt.Errorf("update reserved balance failed: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO holds (id, client_id, system, address, amount, captured_amount, status, reference, expires_at, created_at, updated_at)
+        VALUES ($1, NULLIF($2, ''), $3, $4, $5, 0, $6, NULLIF($7, ''), $8, $9, $9)
+    `, hold.ID, hold.ClientID, hold.System, hold.Address, hold.Amount, hold.Status, hold.Reference, hold.ExpiresAt, hold.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert hold failed: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetHold 查询冻结记录
+func (r *PostgresRepository) GetHold(ctx context.Context, id string) (*models.Hold, error) {
+	return scanHold(r.db.QueryRowContext(ctx, "SELECT "+holdColumns+" FROM holds WHERE id = $1", id))
+}
+
+// ListHolds 查询钱包的冻结记录，status 为空时返回全部，clientID 不为空时只返回该客户端创建的
+func (r *PostgresRepository) ListHolds(ctx context.Context, address, status, clientID string) ([]models.Hold, error) {
+	query := `
+        SELECT ` + holdColumns + `
+        FROM holds
+        WHERE address = $1 AND ($2 = '' OR status = $2) AND ($3 = '' OR client_id = $3)
+        ORDER BY created_at DESC
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, address, status, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := make([]models.Hold, 0)
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, *h)
+	}
+	return holds, rows.Err()
+}
+
+// CaptureHold 扣款：从账本余额扣除 amount，整笔冻结金额解除冻结，未扣部分回到可用余额。
+// record 不为 nil 时在同一事务中写入交易记录。系统冻结不能扣款
+func (r *PostgresRepository) CaptureHold(ctx context.Context, id string, amount decimal.Decimal, record *models.Transaction) (*models.Hold, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	hold, err := scanHold(tx.QueryRowContext(ctx, "SELECT "+holdColumns+" FROM holds WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		return nil, err
+	}
+	if hold.System {
+		return nil, ErrSystemHold
+	}
+	if hold.Status != models.HoldStatusActive || !hold.ExpiresAt.After(time.Now()) {
+		return nil, ErrHoldNotActive
+	}
+	if amount.GreaterThan(hold.Amount) {
+		return nil, fmt.Errorf("capture amount exceeds hold amount")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE wallets
+        SET balance = balance - $1, reserved_balance = reserved_balance - $2, updated_at = NOW()
+        WHERE address = $3
+    `, amount, hold.Amount, hold.Address)
+	if err != nil {
+		return nil, fmt.Errorf("update balance failed: %w", err)
+	}
+
+	row := tx.QueryRowContext(ctx, `
+        UPDATE holds SET status = $1, captured_amount = $2, updated_at = NOW()
+        WHERE id = $3
+        RETURNING `+holdColumns,
+		models.HoldStatusCaptured, amount, id)
+	if hold, err = scanHold(row); err != nil {
+		return nil, err
+	}
+
+	if record != nil {
+		if err = insertTransaction(ctx, tx, record); err != nil {
+			return nil, fmt.Errorf("create transaction failed: %w", err)
+		}
+	}
+
+	return hold, tx.Commit()
+}
+
+// ReleaseHold 解除冻结，status 为 released 或 expired
+func (r *PostgresRepository) ReleaseHold(ctx context.Context, id, status string) (*models.Hold, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	hold, err := scanHold(tx.QueryRowContext(ctx, "SELECT "+holdColumns+" FROM holds WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != models.HoldStatusActive {
+		return nil, ErrHoldNotActive
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE wallets SET reserved_balance = reserved_balance - $1, updated_at = NOW() WHERE address = $2",
+		hold.Amount, hold.Address)
+	if err != nil {
+		return nil, fmt.Errorf("update reserved balance failed: %w", err)
+	}
+
+	row := tx.QueryRowContext(ctx, `
+        UPDATE holds SET status = $1, updated_at = NOW()
+        WHERE id = $2
+        RETURNING `+holdColumns,
+		status, id)
+	if hold, err = scanHold(row); err != nil {
+		return nil, err
+	}
+
+	return hold, tx.Commit()
+}
+
+// ListExpiredHoldIDs 查询已过期但仍处于冻结状态的记录
+func (r *PostgresRepository) ListExpiredHoldIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query := `
+        SELECT id FROM holds
+        WHERE status = 'active' AND expires_at <= $1
+        ORDER BY expires_at
+        LIMIT $2
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
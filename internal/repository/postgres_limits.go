@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+const limitColumns = `id, scope, subject, asset, per_tx_max, daily_amount, daily_count,
+        monthly_amount, monthly_count, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLimit(row rowScanner) (*models.Limit, error) {
+	var (
+		l                        models.Limit
+		perTx, daily, monthly    decimal.NullDecimal
+		dailyCount, monthlyCount sql.NullInt64
+	)
+	err := row.Scan(&l.ID, &l.Scope, &l.Subject, &l.Asset, &perTx, &daily, &dailyCount,
+		&monthly, &monthlyCount, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	l.PerTxMax = nullDecimalPtr(perTx)
+	l.DailyAmount = nullDecimalPtr(daily)
+	l.MonthlyAmount = nullDecimalPtr(monthly)
+	l.DailyCount = nullInt64Ptr(dailyCount)
+	l.MonthlyCount = nullInt64Ptr(monthlyCount)
+	return &l, nil
+}
+
+func nullDecimalPtr(v decimal.NullDecimal) *decimal.Decimal {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Decimal
+}
+
+func nullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
+// UpsertLimit 创建或更新限额配置
+func (r *PostgresRepository) UpsertLimit(ctx context.Context, l *models.Limit) (*models.Limit, error) {
+	query := `
+        INSERT INTO limits (scope, subject, asset, per_tx_max, daily_amount, daily_count, monthly_amount, monthly_count)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (scope, subject, asset) DO UPDATE SET
+            per_tx_max = EXCLUDED.per_tx_max,
+            daily_amount = EXCLUDED.daily_amount,
+            daily_count = EXCLUDED.daily_count,
+            monthly_amount = EXCLUDED.monthly_amount,
+            monthly_count = EXCLUDED.monthly_count,
+            updated_at = NOW()
+        RETURNING ` + limitColumns
+
+	row := r.db.QueryRowContext(ctx, query,
+		l.Scope, l.Subject, l.Asset,
+		l.PerTxMax, l.DailyAmount, l.DailyCount, l.MonthlyAmount, l.MonthlyCount,
+	)
+	return scanLimit(row)
+}
+
+// ListLimits 查询限额配置，scope/subject 为空时不过滤
+func (r *PostgresRepository) ListLimits(ctx context.Context, scope, subject string) ([]models.Limit, error) {
+	query := `
+        SELECT ` + limitColumns + `
+        FROM limits
+        WHERE ($1 = '' OR scope = $1) AND ($2 = '' OR subject = $2)
+        ORDER BY scope, subject, asset
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, scope, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make([]models.Limit, 0)
+	for rows.Next() {
+		l, err := scanLimit(rows)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, *l)
+	}
+	return limits, rows.Err()
+}
+
+// GetEffectiveLimit 获取生效的限额：优先使用 subject 自己的配置，否则使用默认配置
+func (r *PostgresRepository) GetEffectiveLimit(ctx context.Context, scope, subject, asset string) (*models.Limit, error) {
+	query := `
+        SELECT ` + limitColumns + `
+        FROM limits
+        WHERE scope = $1 AND subject IN ($2, '*') AND asset = $3
+        ORDER BY (subject = '*')
+        LIMIT 1
+    `
+
+	l, err := scanLimit(r.db.QueryRowContext(ctx, query, scope, subject, asset))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+// DeleteLimit 删除限额配置
+func (r *PostgresRepository) DeleteLimit(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM limits WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RecordLimitUsage 记录计入限额的出账
+func (r *PostgresRepository) RecordLimitUsage(ctx context.Context, usages []models.LimitUsage) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, u := range usages {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO limit_usage (id, scope, subject, asset, amount, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6)
+        `, u.ID, u.Scope, u.Subject, u.Asset, u.Amount, u.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteLimitUsage 撤销一次限额占用
+func (r *PostgresRepository) DeleteLimitUsage(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM limit_usage WHERE id = $1", id)
+	return err
+}
+
+// GetLimitUsage 查询一次占用在各主体下的使用记录
+func (r *PostgresRepository) GetLimitUsage(ctx context.Context, id string) ([]models.LimitUsage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, scope, subject, asset, amount, created_at
+        FROM limit_usage
+        WHERE id = $1
+    `, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []models.LimitUsage
+	for rows.Next() {
+		var u models.LimitUsage
+		if err := rows.Scan(&u.ID, &u.Scope, &u.Subject, &u.Asset, &u.Amount, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		usages = append(usages, u)
+	}
+	return usages, rows.Err()
+}
+
+// ListLimitUsage 查询 since 之后的限额使用记录
+func (r *PostgresRepository) ListLimitUsage(ctx context.Context, scope, subject, asset string, since time.Time) ([]models.LimitUsage, error) {
+	query := `
+        SELECT id, scope, subject, asset, amount, created_at
+        FROM limit_usage
+        WHERE scope = $1 AND subject = $2 AND asset = $3 AND created_at >= $4
+        ORDER BY created_at
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, scope, subject, asset, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []models.LimitUsage
+	for rows.Next() {
+		var u models.LimitUsage
+		if err := rows.Scan(&u.ID, &u.Scope, &u.Subject, &u.Asset, &u.Amount, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		usages = append(usages, u)
+	}
+	return usages, rows.Err()
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"mywallet/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrOfflineWithdrawalNotFound = errors.New("offline withdrawal not found")
+	ErrOfflineWithdrawalState    = errors.New("offline withdrawal is not in the expected state")
+	// ErrNonceInUse nonce 账户的当前 nonce 已被另一笔离线出账使用
+	ErrNonceInUse = errors.New("nonce is already used by another offline withdrawal")
+)
+
+const offlineWithdrawalColumns = `id, client_id, address, signer, to_address, amount, memo, nonce_account, nonce,
+        transaction, limit_reservation, signature, status, error, submitted_at, created_at, updated_at`
+
+func scanOfflineWithdrawal(row rowScanner) (*models.OfflineWithdrawal, error) {
+	var (
+		w                 models.OfflineWithdrawal
+		signature, errMsg sql.NullString
+		submittedAt       sql.NullTime
+	)
+	err := row.Scan(&w.ID, &w.ClientID, &w.Address, &w.Signer, &w.ToAddress, &w.Amount, &w.Memo,
+		&w.NonceAccount, &w.Nonce, &w.Transaction, &w.LimitReservation, &signature, &w.Status, &errMsg,
+		&submittedAt, &w.CreatedAt, &w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOfflineWithdrawalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.Signature = signature.String
+	w.Error = errMsg.String
+	if submittedAt.Valid {
+		w.SubmittedAt = &submittedAt.Time
+	}
+	return &w, nil
+}
+
+// CreateOfflineWithdrawal 写入离线出账，nonce 已被使用时返回 ErrNonceInUse
+func (r *PostgresRepository) CreateOfflineWithdrawal(ctx context.Context, w *models.OfflineWithdrawal) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO offline_withdrawals (id, client_id, address, signer, to_address, amount, memo, nonce_account,
+            nonce, transaction, limit_reservation, status, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+    `, w.ID, w.ClientID, w.Address, w.Signer, w.ToAddress, w.Amount, w.Memo, w.NonceAccount,
+		w.Nonce, w.Transaction, w.LimitReservation, w.Status, w.CreatedAt, w.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrNonceInUse
+	}
+	return err
+}
+
+// GetOfflineWithdrawal 查询离线出账
+func (r *PostgresRepository) GetOfflineWithdrawal(ctx context.Context, id string) (*models.OfflineWithdrawal, error) {
+	return scanOfflineWithdrawal(r.db.QueryRowContext(ctx,
+		`SELECT `+offlineWithdrawalColumns+` FROM offline_withdrawals WHERE id = $1`, id))
+}
+
+// ListOfflineWithdrawals 查询离线出账，clientID 与 status 为空时不过滤
+func (r *PostgresRepository) ListOfflineWithdrawals(ctx context.Context, clientID, status string, limit int) ([]models.OfflineWithdrawal, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+offlineWithdrawalColumns+`
+        FROM offline_withdrawals
+        WHERE ($1 = '' OR client_id = $1) AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC
+        LIMIT $3
+    `, clientID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	withdrawals := make([]models.OfflineWithdrawal, 0)
+	for rows.Next() {
+		w, err := scanOfflineWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, *w)
+	}
+	return withdrawals, rows.Err()
+}
+
+// UpdateOfflineWithdrawal 将处于 from 状态的离线出账更新为 to 状态。signature 为空时保留原签名，
+// 进入 submitted 时记录提交时间
+func (r *PostgresRepository) UpdateOfflineWithdrawal(ctx context.Context, id, from, to, signature, errMsg string) (*models.OfflineWithdrawal, error) {
+	w, err := scanOfflineWithdrawal(r.db.QueryRowContext(ctx, `
+        UPDATE offline_withdrawals
+        SET status = $3,
+            signature = COALESCE(NULLIF($4, ''), signature),
+            error = NULLIF($5, ''),
+            submitted_at = CASE WHEN $3 = '`+models.OfflineSubmitted+`' THEN NOW() ELSE submitted_at END,
+            updated_at = NOW()
+        WHERE id = $1 AND status = $2
+        RETURNING `+offlineWithdrawalColumns,
+		id, from, to, signature, errMsg))
+	if !errors.Is(err, ErrOfflineWithdrawalNotFound) {
+		return w, err
+	}
+	if _, getErr := r.GetOfflineWithdrawal(ctx, id); getErr != nil {
+		return nil, getErr
+	}
+	return nil, ErrOfflineWithdrawalState
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrPayoutNotFound   = errors.New("payout batch not found")
+	ErrPayoutInProgress = errors.New("payout batch is being processed")
+)
+
+const payoutItemColumns = `id, batch_id, seq, recipient, amount, asset, COALESCE(reference, ''), status,
+        COALESCE(signature, ''), COALESCE(error, ''), attempts, created_at, updated_at, COALESCE(limit_reservation, '')`
+
+func scanPayoutItem(row rowScanner) (*models.PayoutItem, error) {
+	var it models.PayoutItem
+	err := row.Scan(&it.ID, &it.BatchID, &it.Seq, &it.Recipient, &it.Amount, &it.Asset, &it.Reference,
+		&it.Status, &it.Signature, &it.Error, &it.Attempts, &it.CreatedAt, &it.UpdatedAt, &it.LimitReservation)
+	if err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// CreatePayoutBatch 在同一事务中写入批次与全部付款项
+func (r *PostgresRepository) CreatePayoutBatch(ctx context.Context, batch *models.PayoutBatch) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO payout_batches (id, client_id, from_address, status, created_at, updated_at)
+        VALUES ($1, NULLIF($2, ''), $3, $4, $5, $5)
+    `, batch.ID, batch.ClientID, batch.FromAddress, batch.Status, batch.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert payout batch failed: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO payout_items (id, batch_id, seq, recipient, amount, asset, reference, status, limit_reservation,
+            created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NULLIF($9, ''), $10, $10)
+    `)
+	if err != nil {
+		return fmt.Errorf("prepare payout item failed: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, it := range batch.Items {
+		_, err := stmt.ExecContext(ctx, it.ID, batch.ID, it.Seq, it.Recipient, it.Amount, it.Asset,
+			it.Reference, it.Status, it.LimitReservation, it.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("insert payout item failed: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPayoutBatch 查询批次及其付款项
+func (r *PostgresRepository) GetPayoutBatch(ctx context.Context, id string) (*models.PayoutBatch, error) {
+	var b models.PayoutBatch
+	err := r.db.QueryRowContext(ctx, `
+        SELECT id, COALESCE(client_id, ''), from_address, status, created_at, updated_at
+        FROM payout_batches WHERE id = $1
+    `, id).Scan(&b.ID, &b.ClientID, &b.FromAddress, &b.Status, &b.CreatedAt, &b.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPayoutNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+payoutItemColumns+" FROM payout_items WHERE batch_id = $1 ORDER BY seq", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	b.Items = make([]models.PayoutItem, 0)
+	for rows.Next() {
+		it, err := scanPayoutItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		b.Items = append(b.Items, *it)
+	}
+	return &b, rows.Err()
+}
+
+// ClaimPayoutBatch 将批次标记为处理中。其他流程正在处理且未超过 staleAfter 时返回 ErrPayoutInProgress
+func (r *PostgresRepository) ClaimPayoutBatch(ctx context.Context, id string, staleAfter time.Duration) error {
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE payout_batches SET status = $1, updated_at = NOW()
+        WHERE id = $2 AND (status <> $1 OR updated_at < $3)
+    `, models.PayoutStatusProcessing, id, time.Now().Add(-staleAfter))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := r.GetPayoutBatch(ctx, id); err != nil {
+			return err
+		}
+		return ErrPayoutInProgress
+	}
+	return nil
+}
+
+// UpdatePayoutBatchStatus 更新批次状态
+func (r *PostgresRepository) UpdatePayoutBatchStatus(ctx context.Context, id, status string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE payout_batches SET status = $1, updated_at = NOW() WHERE id = $2", status, id)
+	return err
+}
+
+// MarkPayoutItemsSubmitted 记录付款项所在交易的签名，在交易发送前调用
+func (r *PostgresRepository) MarkPayoutItemsSubmitted(ctx context.Context, ids []string, signature string) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE payout_items
+        SET status = $1, signature = $2, error = NULL, attempts = attempts + 1, updated_at = NOW()
+        WHERE id = ANY($3)
+    `, models.PayoutItemSubmitted, signature, pq.Array(ids))
+	return err
+}
+
+// SetPayoutItemReservations 记录重试时新占用的限额，在交易发送前调用
+func (r *PostgresRepository) SetPayoutItemReservations(ctx context.Context, items []models.PayoutItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, it := range items {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE payout_items SET limit_reservation = NULLIF($1, ''), updated_at = NOW() WHERE id = $2",
+			it.LimitReservation, it.ID)
+		if err != nil {
+			return fmt.Errorf("update payout item failed: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// MarkPayoutItemsFailed 标记付款项失败并清除其限额占用，返回状态发生变化的付款项（含清除前的占用），
+// 由调用方释放。已完成的付款项不受影响
+func (r *PostgresRepository) MarkPayoutItemsFailed(ctx context.Context, ids []string, reason string) ([]models.PayoutItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        WITH old AS (
+            SELECT id, COALESCE(limit_reservation, '') AS limit_reservation
+            FROM payout_items
+            WHERE id = ANY($3) AND status <> $4
+            FOR UPDATE
+        )
+        UPDATE payout_items p SET status = $1, error = $2, limit_reservation = NULL, updated_at = NOW()
+        FROM old WHERE p.id = old.id
+        RETURNING p.id, p.amount, p.asset, old.limit_reservation
+    `, models.PayoutItemFailed, reason, pq.Array(ids), models.PayoutItemCompleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failed []models.PayoutItem
+	for rows.Next() {
+		var it models.PayoutItem
+		if err := rows.Scan(&it.ID, &it.Amount, &it.Asset, &it.LimitReservation); err != nil {
+			return nil, err
+		}
+		failed = append(failed, it)
+	}
+	return failed, rows.Err()
+}
+
+// CompletePayoutItems 标记付款项完成并在同一事务中写入交易记录。仍占用限额的付款项已在发送前扣减账本余额，
+// 失败后已退回的 SOL 付款项在此重新扣减。返回本次变为完成的付款项（含完成前的限额占用），已完成的付款项不重复扣减
+func (r *PostgresRepository) CompletePayoutItems(ctx context.Context, from string, records []models.Transaction) ([]models.PayoutItem, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	rows, err := tx.QueryContext(ctx, `
+        UPDATE payout_items SET status = $1, error = NULL, updated_at = NOW()
+        WHERE id = ANY($2) AND status <> $1
+        RETURNING id, amount, asset, COALESCE(limit_reservation, '')
+    `, models.PayoutItemCompleted, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("update payout items failed: %w", err)
+	}
+	var completed []models.PayoutItem
+	for rows.Next() {
+		var it models.PayoutItem
+		if err := rows.Scan(&it.ID, &it.Amount, &it.Asset, &it.LimitReservation); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		completed = append(completed, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	done := make(map[string]bool, len(completed))
+	debit := decimal.Zero
+	for _, it := range completed {
+		done[it.ID] = true
+		if it.LimitReservation == "" && it.Asset == models.AssetSOL {
+			debit = debit.Add(it.Amount)
+		}
+	}
+
+	// 资金已在链上转出，账本按实际付款扣减，不再检查可用余额
+	if debit.IsPositive() {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE wallets SET balance = balance - $1, updated_at = NOW() WHERE address = $2", debit, from)
+		if err != nil {
+			return nil, fmt.Errorf("update balance failed: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n != 1 {
+			return nil, fmt.Errorf("wallet not found")
+		}
+	}
+
+	for _, record := range records {
+		if !done[record.ID] {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, insertTransactionQuery+` ON CONFLICT (id) DO NOTHING`, transactionArgs(&record)...)
+		if err != nil {
+			return nil, fmt.Errorf("create transaction failed: %w", err)
+		}
+	}
+
+	return completed, tx.Commit()
+}
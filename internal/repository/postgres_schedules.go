@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrManagedWalletNotFound = errors.New("managed wallet not found")
+	ErrScheduleNotFound      = errors.New("schedule not found")
+	ErrScheduleStatus        = errors.New("schedule status does not allow this operation")
+)
+
+const scheduleColumns = `id, COALESCE(client_id, ''), from_address, to_address, amount, COALESCE(cron, ''), status,
+        next_run_at, max_retries, retry_delay_seconds, attempt, COALESCE(last_error, ''), COALESCE(reference, ''),
+        created_at, updated_at`
+
+func scanSchedule(row rowScanner) (*models.TransferSchedule, error) {
+	var s models.TransferSchedule
+	var next sql.NullTime
+	err := row.Scan(&s.ID, &s.ClientID, &s.FromAddress, &s.ToAddress, &s.Amount, &s.Cron, &s.Status,
+		&next, &s.MaxRetries, &s.RetryDelaySeconds, &s.Attempt, &s.LastError, &s.Reference,
+		&s.CreatedAt, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if next.Valid {
+		s.NextRunAt = &next.Time
+	}
+	return &s, nil
+}
+
+func scanSchedules(rows *sql.Rows) ([]models.TransferSchedule, error) {
+	defer rows.Close()
+
+	schedules := make([]models.TransferSchedule, 0)
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *s)
+	}
+	return schedules, rows.Err()
+}
+
+// CreateManagedWallet 保存托管钱包。encryptedKey 为 keystore 加密的私钥，外部签名服务持有私钥时为 nil
+func (r *PostgresRepository) CreateManagedWallet(ctx context.Context, w *models.ManagedWallet, encryptedKey []byte) error {
+	return r.db.QueryRowContext(ctx, `
+        INSERT INTO managed_wallets (address, client_id, signer, key_id, encrypted_key, key_version, derivation_index)
+        VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7)
+        RETURNING created_at
+    `, w.Address, w.ClientID, w.Signer, w.KeyID, encryptedKey, w.KeyVersion, w.DerivationIndex).Scan(&w.CreatedAt)
+}
+
+const managedWalletColumns = "address, COALESCE(client_id, ''), signer, key_id, derivation_index, key_version, created_at"
+
+func scanManagedWallet(row rowScanner) (*models.ManagedWallet, error) {
+	var w models.ManagedWallet
+	var index, version sql.NullInt64
+	err := row.Scan(&w.Address, &w.ClientID, &w.Signer, &w.KeyID, &index, &version, &w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrManagedWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if index.Valid {
+		w.DerivationIndex = &index.Int64
+	}
+	if version.Valid {
+		v := int(version.Int64)
+		w.KeyVersion = &v
+	}
+	return &w, nil
+}
+
+// GetManagedWallet 查询托管钱包
+func (r *PostgresRepository) GetManagedWallet(ctx context.Context, address string) (*models.ManagedWallet, error) {
+	return scanManagedWallet(r.db.QueryRowContext(ctx,
+		"SELECT "+managedWalletColumns+" FROM managed_wallets WHERE address = $1", address))
+}
+
+// GetManagedWalletByIndex 按 HD 派生序号查询托管钱包
+func (r *PostgresRepository) GetManagedWalletByIndex(ctx context.Context, index int64) (*models.ManagedWallet, error) {
+	return scanManagedWallet(r.db.QueryRowContext(ctx,
+		"SELECT "+managedWalletColumns+" FROM managed_wallets WHERE derivation_index = $1", index))
+}
+
+// NextDerivationIndex 分配下一个 HD 派生序号
+func (r *PostgresRepository) NextDerivationIndex(ctx context.Context) (int64, error) {
+	var index int64
+	err := r.db.QueryRowContext(ctx, "SELECT nextval('managed_wallet_derivation_seq')").Scan(&index)
+	return index, err
+}
+
+// AdvanceDerivationIndex 保证之后分配的派生序号大于 index，用于恢复钱包后
+func (r *PostgresRepository) AdvanceDerivationIndex(ctx context.Context, index int64) error {
+	_, err := r.db.ExecContext(ctx, `
+        SELECT setval('managed_wallet_derivation_seq', GREATEST($1, last_value), true)
+        FROM managed_wallet_derivation_seq
+    `, index)
+	return err
+}
+
+// GetManagedWalletKey 查询 keystore 托管钱包的加密私钥及其主密钥版本
+func (r *PostgresRepository) GetManagedWalletKey(ctx context.Context, address string) ([]byte, int, error) {
+	var key []byte
+	var version int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT encrypted_key, key_version FROM managed_wallets WHERE address = $1 AND encrypted_key IS NOT NULL", address).
+		Scan(&key, &version)
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrManagedWalletNotFound
+	}
+	return key, version, err
+}
+
+// ManagedWalletKey keystore 加密的托管钱包私钥
+type ManagedWalletKey struct {
+	Address      string
+	Version      int
+	EncryptedKey []byte
+}
+
+// ListStaleManagedWalletKeys 按地址顺序查询地址大于 after、由 versions 中旧版本主密钥加密的私钥，用于重新加密
+func (r *PostgresRepository) ListStaleManagedWalletKeys(ctx context.Context, versions []int, after string, limit int) ([]ManagedWalletKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT address, key_version, encrypted_key FROM managed_wallets
+        WHERE key_version = ANY($1) AND address > $2
+        ORDER BY address
+        LIMIT $3
+    `, pq.Array(versions), after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]ManagedWalletKey, 0)
+	for rows.Next() {
+		var k ManagedWalletKey
+		if err := rows.Scan(&k.Address, &k.Version, &k.EncryptedKey); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RewrapManagedWalletKey 替换重新加密的私钥。私钥已被其他实例重新加密时返回 false
+func (r *PostgresRepository) RewrapManagedWalletKey(ctx context.Context, address string, oldVersion int, encryptedKey []byte, version int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE managed_wallets SET encrypted_key = $3, key_version = $4
+        WHERE address = $1 AND key_version = $2
+    `, address, oldVersion, encryptedKey, version)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CountManagedWalletKeyVersions 按主密钥版本统计 keystore 托管钱包数量
+func (r *PostgresRepository) CountManagedWalletKeyVersions(ctx context.Context) (map[int]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT key_version, COUNT(*) FROM managed_wallets
+        WHERE key_version IS NOT NULL
+        GROUP BY key_version
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int64)
+	for rows.Next() {
+		var version int
+		var n int64
+		if err := rows.Scan(&version, &n); err != nil {
+			return nil, err
+		}
+		counts[version] = n
+	}
+	return counts, rows.Err()
+}
+
+// ListManagedWallets 查询全部托管钱包
+func (r *PostgresRepository) ListManagedWallets(ctx context.Context) ([]models.ManagedWallet, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+managedWalletColumns+" FROM managed_wallets ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := make([]models.ManagedWallet, 0)
+	for rows.Next() {
+		w, err := scanManagedWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, *w)
+	}
+	return wallets, rows.Err()
+}
+
+// CreateSchedule 创建定时转账
+func (r *PostgresRepository) CreateSchedule(ctx context.Context, s *models.TransferSchedule) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO transfer_schedules (id, client_id, from_address, to_address, amount, cron, status, next_run_at,
+            max_retries, retry_delay_seconds, reference, created_at, updated_at)
+        VALUES ($1, NULLIF($2, ''), $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, NULLIF($11, ''), $12, $12)
+    `, s.ID, s.ClientID, s.FromAddress, s.ToAddress, s.Amount, s.Cron, s.Status, s.NextRunAt,
+		s.MaxRetries, s.RetryDelaySeconds, s.Reference, s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert schedule failed: %w", err)
+	}
+	return nil
+}
+
+// GetSchedule 查询定时转账
+func (r *PostgresRepository) GetSchedule(ctx context.Context, id string) (*models.TransferSchedule, error) {
+	return scanSchedule(r.db.QueryRowContext(ctx, "SELECT "+scheduleColumns+" FROM transfer_schedules WHERE id = $1", id))
+}
+
+// ListSchedules 查询钱包转出的定时转账，clientID 不为空时只返回该客户端创建的
+func (r *PostgresRepository) ListSchedules(ctx context.Context, fromAddress, clientID string) ([]models.TransferSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+scheduleColumns+` FROM transfer_schedules
+        WHERE from_address = $1 AND ($2 = '' OR client_id = $2)
+        ORDER BY created_at DESC
+    `, fromAddress, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSchedules(rows)
+}
+
+// SetScheduleStatus 在当前状态属于 from 时修改状态；nextRunAt 不为 nil 时同时更新下次执行时间并清零失败次数
+func (r *PostgresRepository) SetScheduleStatus(ctx context.Context, id string, from []string, to string, nextRunAt *time.Time) (*models.TransferSchedule, error) {
+	row := r.db.QueryRowContext(ctx, `
+        UPDATE transfer_schedules
+        SET status = $1,
+            next_run_at = CASE WHEN $2::timestamp IS NULL THEN next_run_at ELSE $2 END,
+            attempt = CASE WHEN $2::timestamp IS NULL THEN attempt ELSE 0 END,
+            updated_at = NOW()
+        WHERE id = $3 AND status = ANY($4)
+        RETURNING `+scheduleColumns,
+		to, nextRunAt, id, pq.Array(from))
+	s, err := scanSchedule(row)
+	if errors.Is(err, ErrScheduleNotFound) {
+		if _, err := r.GetSchedule(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrScheduleStatus
+	}
+	return s, err
+}
+
+// ListDueSchedules 查询到期且没有执行中记录的定时转账
+func (r *PostgresRepository) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.TransferSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+scheduleColumns+`
+        FROM transfer_schedules s
+        WHERE status = 'active' AND next_run_at <= $1
+          AND NOT EXISTS (
+              SELECT 1 FROM schedule_executions e WHERE e.schedule_id = s.id AND e.status = 'running'
+          )
+        ORDER BY next_run_at
+        LIMIT $2
+    `, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSchedules(rows)
+}
+
+// UpdateScheduleAfterRun 记录执行结果与下次执行时间。执行期间被暂停或取消的定时转账保持原状态
+func (r *PostgresRepository) UpdateScheduleAfterRun(ctx context.Context, id, status string, nextRunAt *time.Time, attempt int, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE transfer_schedules
+        SET status = CASE WHEN status = 'active' THEN $1 ELSE status END,
+            next_run_at = $2, attempt = $3, last_error = NULLIF($4, ''), updated_at = NOW()
+        WHERE id = $5
+    `, status, nextRunAt, attempt, lastError, id)
+	return err
+}
+
+// StartExecution 写入执行记录
+func (r *PostgresRepository) StartExecution(ctx context.Context, e *models.ScheduleExecution) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO schedule_executions (id, schedule_id, scheduled_for, attempt, status, started_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, e.ID, e.ScheduleID, e.ScheduledFor, e.Attempt, e.Status, e.StartedAt)
+	return err
+}
+
+// FinishExecution 记录执行结果
+func (r *PostgresRepository) FinishExecution(ctx context.Context, id, status, signature, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE schedule_executions
+        SET status = $1, signature = NULLIF($2, ''), error = NULLIF($3, ''), finished_at = NOW()
+        WHERE id = $4
+    `, status, signature, errMsg, id)
+	return err
+}
+
+// ListExecutions 查询定时转账的执行历史
+func (r *PostgresRepository) ListExecutions(ctx context.Context, scheduleID string, limit int) ([]models.ScheduleExecution, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, schedule_id, scheduled_for, attempt, status, COALESCE(signature, ''), COALESCE(error, ''),
+            started_at, finished_at
+        FROM schedule_executions
+        WHERE schedule_id = $1
+        ORDER BY started_at DESC
+        LIMIT $2
+    `, scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	executions := make([]models.ScheduleExecution, 0)
+	for rows.Next() {
+		var e models.ScheduleExecution
+		var finished sql.NullTime
+		if err := rows.Scan(&e.ID, &e.ScheduleID, &e.ScheduledFor, &e.Attempt, &e.Status, &e.Signature,
+			&e.Error, &e.StartedAt, &finished); err != nil {
+			return nil, err
+		}
+		if finished.Valid {
+			e.FinishedAt = &finished.Time
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+// InterruptStaleExecutions 将早于 before 仍在执行的记录标记为中断，并暂停对应的定时转账，
+// 链上结果需人工确认后再恢复，避免重复转账。返回受影响的定时转账 ID
+func (r *PostgresRepository) InterruptStaleExecutions(ctx context.Context, before time.Time) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        UPDATE schedule_executions
+        SET status = $1, error = 'execution interrupted, verify the transfer on chain before resuming', finished_at = NOW()
+        WHERE status = $2 AND started_at < $3
+        RETURNING schedule_id
+    `, models.ExecutionStatusInterrupted, models.ExecutionStatusRunning, before)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE transfer_schedules
+        SET status = $1, last_error = 'execution interrupted', updated_at = NOW()
+        WHERE id = ANY($2) AND status = $3
+    `, models.ScheduleStatusPaused, pq.Array(ids), models.ScheduleStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+// WithAdvisoryLock 在独立连接上尝试获取会话级 advisory lock，获取成功后执行 fn 并释放锁。
+// 锁已被其他实例持有时返回 false
+func (r *PostgresRepository) WithAdvisoryLock(ctx context.Context, key int64, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		return false, err
+	}
+	if !locked {
+		return false, nil
+	}
+	defer func() {
+		// 使用新的 context，ctx 取消后仍能释放锁
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			r.logger.Logger.Error("failed to release advisory lock",
+				zap.Int64("key", key),
+				zap.Error(err))
+		}
+	}()
+
+	return true, fn(ctx)
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"mywallet/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrDuplicateReference 同一客户端已使用过该引用
+	ErrDuplicateReference = errors.New("client reference is already used")
+)
+
+const transactionColumns = `id, from_wallet, to_wallet, amount, asset, type, status,
+    COALESCE(chain_signature, ''), COALESCE(client_id, ''), COALESCE(client_reference, ''), created_at, completed_at`
+
+// insertTransactionQuery 写入交易记录，空的签名、客户端与引用保存为 NULL
+const insertTransactionQuery = `
+    INSERT INTO transactions (id, from_wallet, to_wallet, amount, asset, type, status,
+        chain_signature, client_id, client_reference, created_at, completed_at)
+    VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'SOL'), $6, $7,
+        NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11, $12)`
+
+func transactionArgs(tx *models.Transaction) []interface{} {
+	return []interface{}{tx.ID, tx.FromWallet, tx.ToWallet, tx.Amount, tx.Asset, tx.Type, tx.Status,
+		tx.ChainSignature, tx.ClientID, tx.ClientReference, tx.CreatedAt, tx.CompletedAt}
+}
+
+// execer 同时适用于 *sql.DB 与 *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertTransaction 写入交易记录，客户端引用重复时返回 ErrDuplicateReference
+func insertTransaction(ctx context.Context, e execer, tx *models.Transaction) error {
+	_, err := e.ExecContext(ctx, insertTransactionQuery, transactionArgs(tx)...)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_transactions_client_reference" {
+		return ErrDuplicateReference
+	}
+	return err
+}
+
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+	var tx models.Transaction
+	err := row.Scan(&tx.ID, &tx.FromWallet, &tx.ToWallet, &tx.Amount, &tx.Asset, &tx.Type, &tx.Status,
+		&tx.ChainSignature, &tx.ClientID, &tx.ClientReference, &tx.CreatedAt, &tx.CompletedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (r *PostgresRepository) queryTransactions(ctx context.Context, query string, args ...interface{}) ([]models.Transaction, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, *tx)
+	}
+	return transactions, rows.Err()
+}
+
+// GetTransaction 按 ID 查询交易
+func (r *PostgresRepository) GetTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	return scanTransaction(r.db.QueryRowContext(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE id = $1`, id))
+}
+
+// ListTransactionsBySignature 按链上签名查询交易，批量付款的多笔交易共用一个签名
+func (r *PostgresRepository) ListTransactionsBySignature(ctx context.Context, signature string) ([]models.Transaction, error) {
+	return r.queryTransactions(ctx, `
+        SELECT `+transactionColumns+`
+        FROM transactions
+        WHERE chain_signature = $1
+        ORDER BY id
+    `, signature)
+}
+
+// GetTransactionByReference 按客户端引用查询交易，clientID 为空时查询匿名请求的交易
+func (r *PostgresRepository) GetTransactionByReference(ctx context.Context, clientID, ref string) (*models.Transaction, error) {
+	return scanTransaction(r.db.QueryRowContext(ctx, `
+        SELECT `+transactionColumns+`
+        FROM transactions
+        WHERE COALESCE(client_id, '') = $1 AND client_reference = $2
+    `, clientID, ref))
+}
+
+// UpdatePendingTransaction 更新 pending 状态交易记录的状态、签名与完成时间，记录不存在或已结束时返回 ErrTransactionNotFound
+func (r *PostgresRepository) UpdatePendingTransaction(ctx context.Context, tx *models.Transaction) error {
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE transactions
+        SET status = $2, chain_signature = NULLIF($3, ''), completed_at = $4
+        WHERE id = $1 AND status = 'pending'
+    `, tx.ID, tx.Status, tx.ChainSignature, tx.CompletedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTransactionNotFound
+	}
+	return nil
+}
+
+// DeletePendingTransaction 删除 pending 状态的交易记录，释放其客户端引用
+func (r *PostgresRepository) DeletePendingTransaction(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM transactions WHERE id = $1 AND status = 'pending'`, id)
+	return err
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrDepositAddressNotFound   = errors.New("deposit address not found")
+	ErrTreasuryMovementNotFound = errors.New("treasury movement not found")
+)
+
+const depositAddressColumns = `address, client_id, user_ref, swept_total, last_swept_at, created_at`
+
+func scanDepositAddress(row rowScanner) (*models.DepositAddress, error) {
+	var (
+		d           models.DepositAddress
+		lastSweptAt sql.NullTime
+	)
+	err := row.Scan(&d.Address, &d.ClientID, &d.UserRef, &d.SweptTotal, &lastSweptAt, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrDepositAddressNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastSweptAt.Valid {
+		d.LastSweptAt = &lastSweptAt.Time
+	}
+	return &d, nil
+}
+
+// CreateDepositAddress 分配充值地址。同一客户端的 user_ref 已分配时返回已有地址，created 为 false
+func (r *PostgresRepository) CreateDepositAddress(ctx context.Context, d *models.DepositAddress) (created bool, err error) {
+	err = r.db.QueryRowContext(ctx, `
+        INSERT INTO deposit_addresses (address, client_id, user_ref, created_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (client_id, user_ref) DO NOTHING
+        RETURNING address
+    `, d.Address, d.ClientID, d.UserRef, d.CreatedAt).Scan(&d.Address)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetDepositAddressByRef 按客户端与用户标识查询充值地址
+func (r *PostgresRepository) GetDepositAddressByRef(ctx context.Context, clientID, userRef string) (*models.DepositAddress, error) {
+	return scanDepositAddress(r.db.QueryRowContext(ctx,
+		`SELECT `+depositAddressColumns+` FROM deposit_addresses WHERE client_id = $1 AND user_ref = $2`,
+		clientID, userRef))
+}
+
+// ListDepositAddresses 查询充值地址，clientID 为空时返回全部
+func (r *PostgresRepository) ListDepositAddresses(ctx context.Context, clientID string) ([]models.DepositAddress, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+depositAddressColumns+`
+        FROM deposit_addresses
+        WHERE $1 = '' OR client_id = $1
+        ORDER BY created_at
+    `, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make([]models.DepositAddress, 0)
+	for rows.Next() {
+		d, err := scanDepositAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, *d)
+	}
+	return addresses, rows.Err()
+}
+
+// IsDepositAddress 地址是否为充值地址
+func (r *PostgresRepository) IsDepositAddress(ctx context.Context, address string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM deposit_addresses WHERE address = $1)", address).Scan(&exists)
+	return exists, err
+}
+
+const treasuryMovementColumns = `id, kind, from_address, to_address, amount, status, signature, error, created_at, updated_at`
+
+func scanTreasuryMovement(row rowScanner) (*models.TreasuryMovement, error) {
+	var (
+		m                 models.TreasuryMovement
+		signature, errMsg sql.NullString
+	)
+	err := row.Scan(&m.ID, &m.Kind, &m.FromAddress, &m.ToAddress, &m.Amount, &m.Status,
+		&signature, &errMsg, &m.CreatedAt, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTreasuryMovementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.Signature = signature.String
+	m.Error = errMsg.String
+	return &m, nil
+}
+
+// CreateTreasuryMovement 写入归集记录
+func (r *PostgresRepository) CreateTreasuryMovement(ctx context.Context, m *models.TreasuryMovement) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO treasury_movements (id, kind, from_address, to_address, amount, status, signature, error, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10)
+    `, m.ID, m.Kind, m.FromAddress, m.ToAddress, m.Amount, m.Status, m.Signature, m.Error, m.CreatedAt, m.UpdatedAt)
+	return err
+}
+
+// ListTreasuryMovements 查询归集记录，kind、status 为空时不筛选，按创建时间倒序
+func (r *PostgresRepository) ListTreasuryMovements(ctx context.Context, kind, status string, limit int) ([]models.TreasuryMovement, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+treasuryMovementColumns+`
+        FROM treasury_movements
+        WHERE ($1 = '' OR kind = $1) AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC
+        LIMIT $3
+    `, kind, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := make([]models.TreasuryMovement, 0)
+	for rows.Next() {
+		m, err := scanTreasuryMovement(rows)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, *m)
+	}
+	return movements, rows.Err()
+}
+
+// FinishTreasuryMovement 更新归集记录状态，只更新处于 from 状态的记录。
+// 归集确认时同时累计充值地址的已归集金额
+func (r *PostgresRepository) FinishTreasuryMovement(ctx context.Context, id, from, to, errMsg string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		kind, address string
+		amount        decimal.Decimal
+		updatedAt     time.Time
+	)
+	err = tx.QueryRowContext(ctx, `
+        UPDATE treasury_movements
+        SET status = $3, error = NULLIF($4, ''), updated_at = NOW()
+        WHERE id = $1 AND status = $2
+        RETURNING kind, from_address, amount, updated_at
+    `, id, from, to, errMsg).Scan(&kind, &address, &amount, &updatedAt)
+	if err == sql.ErrNoRows {
+		return ErrTreasuryMovementNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if kind == models.MovementSweep && to == models.MovementConfirmed {
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE deposit_addresses
+            SET swept_total = swept_total + $2, last_swept_at = $3
+            WHERE address = $1
+        `, address, amount, updatedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,356 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// PendingDelivery 待投递的事件及目标地址
+type PendingDelivery struct {
+	ID         string
+	EndpointID string
+	URL        string
+	Secret     string
+	Attempts   int
+	Event      models.Event
+}
+
+const webhookColumns = `id, client_id, url, event_types, active, created_at, updated_at`
+
+func scanWebhook(row rowScanner) (*models.WebhookEndpoint, error) {
+	var e models.WebhookEndpoint
+	err := row.Scan(&e.ID, &e.ClientID, &e.URL, pq.Array(&e.EventTypes), &e.Active, &e.CreatedAt, &e.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+const deliveryColumns = `d.id, d.endpoint_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at,
+        COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), d.delivered_at, d.created_at, d.updated_at`
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var delivered sql.NullTime
+	err := row.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &delivered, &d.CreatedAt, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if delivered.Valid {
+		d.DeliveredAt = &delivered.Time
+	}
+	return &d, nil
+}
+
+// CreateWebhookEndpoint 注册 webhook 地址
+func (r *PostgresRepository) CreateWebhookEndpoint(ctx context.Context, e *models.WebhookEndpoint) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO webhook_endpoints (id, client_id, url, secret, event_types, active, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, TRUE, $6, $6)
+    `, e.ID, e.ClientID, e.URL, e.Secret, pq.Array(e.EventTypes), e.CreatedAt)
+	return err
+}
+
+// ListWebhookEndpoints 查询 API 客户端的 webhook 地址
+func (r *PostgresRepository) ListWebhookEndpoints(ctx context.Context, clientID string) ([]models.WebhookEndpoint, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+webhookColumns+" FROM webhook_endpoints WHERE client_id = $1 AND active ORDER BY created_at", clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := make([]models.WebhookEndpoint, 0)
+	for rows.Next() {
+		e, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, *e)
+	}
+	return endpoints, rows.Err()
+}
+
+// GetWebhookEndpoint 查询 API 客户端的 webhook 地址
+func (r *PostgresRepository) GetWebhookEndpoint(ctx context.Context, clientID, id string) (*models.WebhookEndpoint, error) {
+	return scanWebhook(r.db.QueryRowContext(ctx,
+		"SELECT "+webhookColumns+" FROM webhook_endpoints WHERE id = $1 AND client_id = $2 AND active", id, clientID))
+}
+
+// DeleteWebhookEndpoint 停用 webhook 地址，保留投递记录，未完成的投递不再发送
+func (r *PostgresRepository) DeleteWebhookEndpoint(ctx context.Context, clientID, id string) error {
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE webhook_endpoints SET active = FALSE, updated_at = NOW()
+        WHERE id = $1 AND client_id = $2 AND active
+    `, id, clientID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// CreateEvent 写入事件，并为订阅该事件的 webhook 地址创建投递。
+// endpointID 不为空时只投递给该地址（测试投递），返回创建的投递 ID
+func (r *PostgresRepository) CreateEvent(ctx context.Context, event *models.Event, endpointID string) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO outbox_events (id, type, client_id, payload, created_at)
+        VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+    `, event.ID, event.Type, event.ClientID, []byte(event.Data), event.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert event failed: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+        INSERT INTO webhook_deliveries (id, endpoint_id, event_id, status)
+        SELECT gen_random_uuid()::text, w.id, $1, $2
+        FROM webhook_endpoints w
+        WHERE w.active AND w.client_id = $3
+          AND (($4 = '' AND (cardinality(w.event_types) = 0 OR $5 = ANY(w.event_types))) OR w.id = $4)
+        RETURNING id
+    `, event.ID, models.DeliveryStatusPending, event.ClientID, endpointID, event.Type)
+	if err != nil {
+		return nil, fmt.Errorf("create deliveries failed: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, tx.Commit()
+}
+
+// ClaimDueDeliveries 领取到期的投递，并将下次投递时间推后 lease，避免多个实例重复投递
+func (r *PostgresRepository) ClaimDueDeliveries(ctx context.Context, lease time.Duration, limit int) ([]PendingDelivery, error) {
+	return r.claimDeliveries(ctx, `
+        SELECT id FROM webhook_deliveries
+        WHERE status = 'pending' AND next_attempt_at <= NOW()
+        ORDER BY next_attempt_at
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `, lease, limit)
+}
+
+// ClaimDelivery 立即领取指定的投递，用于测试投递
+func (r *PostgresRepository) ClaimDelivery(ctx context.Context, id string, lease time.Duration) (*PendingDelivery, error) {
+	deliveries, err := r.claimDeliveries(ctx, `
+        SELECT id FROM webhook_deliveries
+        WHERE id = $2 AND status = 'pending'
+        FOR UPDATE SKIP LOCKED
+    `, lease, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, ErrDeliveryNotFound
+	}
+	return &deliveries[0], nil
+}
+
+func (r *PostgresRepository) claimDeliveries(ctx context.Context, due string, lease time.Duration, arg interface{}) ([]PendingDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        WITH due AS (`+due+`)
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = NOW() + make_interval(secs => $1), updated_at = NOW()
+        FROM due, webhook_endpoints w, outbox_events e
+        WHERE d.id = due.id AND w.id = d.endpoint_id AND e.id = d.event_id AND w.active
+        RETURNING d.id, d.endpoint_id, w.url, w.secret, d.attempts, e.id, e.type, e.payload, e.created_at
+    `, lease.Seconds(), arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []PendingDelivery
+	for rows.Next() {
+		var d PendingDelivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.URL, &d.Secret, &d.Attempts,
+			&d.Event.ID, &d.Event.Type, &payload, &d.Event.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.Event.Data = payload
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordDeliveryAttempt 记录一次投递尝试并更新投递状态；retryAfter 为下次投递的等待时间
+func (r *PostgresRepository) RecordDeliveryAttempt(ctx context.Context, id string, attempt models.WebhookAttempt, status string, retryAfter time.Duration) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+        VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5)
+    `, id, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return fmt.Errorf("insert delivery attempt failed: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $1, attempts = $2, last_status_code = NULLIF($3, 0), last_error = NULLIF($4, ''),
+            next_attempt_at = NOW() + make_interval(secs => $5),
+            delivered_at = CASE WHEN $1 = 'succeeded' THEN NOW() ELSE delivered_at END,
+            updated_at = NOW()
+        WHERE id = $6
+    `, status, attempt.Attempt, attempt.StatusCode, attempt.Error, retryAfter.Seconds(), id)
+	if err != nil {
+		return fmt.Errorf("update delivery failed: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListDeliveries 查询 webhook 地址的投递记录，status 为空时返回全部
+func (r *PostgresRepository) ListDeliveries(ctx context.Context, endpointID, status string, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+deliveryColumns+`
+        FROM webhook_deliveries d JOIN outbox_events e ON e.id = d.event_id
+        WHERE d.endpoint_id = $1 AND ($2 = '' OR d.status = $2)
+        ORDER BY d.created_at DESC
+        LIMIT $3
+    `, endpointID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDelivery 查询投递及其尝试记录
+func (r *PostgresRepository) GetDelivery(ctx context.Context, endpointID, id string) (*models.WebhookDelivery, error) {
+	d, err := scanDelivery(r.db.QueryRowContext(ctx, `
+        SELECT `+deliveryColumns+`
+        FROM webhook_deliveries d JOIN outbox_events e ON e.id = d.event_id
+        WHERE d.id = $1 AND d.endpoint_id = $2
+    `, id, endpointID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT attempt, COALESCE(status_code, 0), COALESCE(error, ''), duration_ms, created_at
+        FROM webhook_delivery_attempts
+        WHERE delivery_id = $1
+        ORDER BY id
+    `, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.WebhookAttempt
+		if err := rows.Scan(&a.Attempt, &a.StatusCode, &a.Error, &a.DurationMs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+	return d, rows.Err()
+}
+
+// ResetDelivery 重新投递：恢复为待投递并清零尝试次数，死信也可重新投递
+func (r *PostgresRepository) ResetDelivery(ctx context.Context, endpointID, id string) error {
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $1, attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+        WHERE id = $2 AND endpoint_id = $3
+    `, models.DeliveryStatusPending, id, endpointID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// EventFilter 重放事件的筛选条件，零值字段不参与筛选
+type EventFilter struct {
+	IDs      []string
+	Since    *time.Time
+	Until    *time.Time
+	Type     string
+	ClientID string
+}
+
+// ReplayEvents 为符合条件的事件重新创建投递，按 webhook 地址当前的订阅匹配。
+// 测试事件不重放，返回创建的投递数量
+func (r *PostgresRepository) ReplayEvents(ctx context.Context, filter EventFilter) (int64, error) {
+	ids := filter.IDs
+	if ids == nil {
+		ids = []string{}
+	}
+	result, err := r.db.ExecContext(ctx, `
+        INSERT INTO webhook_deliveries (id, endpoint_id, event_id, status)
+        SELECT gen_random_uuid()::text, w.id, e.id, $1
+        FROM outbox_events e
+        JOIN webhook_endpoints w ON w.client_id = e.client_id AND w.active
+            AND (cardinality(w.event_types) = 0 OR e.type = ANY(w.event_types))
+        WHERE e.type <> $2
+          AND (cardinality($3::text[]) = 0 OR e.id = ANY($3))
+          AND ($4::timestamp IS NULL OR e.created_at >= $4)
+          AND ($5::timestamp IS NULL OR e.created_at < $5)
+          AND ($6 = '' OR e.type = $6)
+          AND ($7 = '' OR e.client_id = $7)
+    `, models.DeliveryStatusPending, models.EventWebhookTest, pq.Array(ids), filter.Since, filter.Until,
+		filter.Type, filter.ClientID)
+	if err != nil {
+		return 0, fmt.Errorf("replay events failed: %w", err)
+	}
+	return result.RowsAffected()
+}
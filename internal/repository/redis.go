@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"mywallet/pkg/logger"
 
@@ -11,9 +13,10 @@ import (
 )
 
 const (
-	balanceKeyPrefix = "wallet:balance:"
-	assetsKeyPrefix  = "wallet:assets:"
-	addBalanceScript = `
+	balanceKeyPrefix     = "wallet:balance:"
+	assetsKeyPrefix      = "wallet:assets:"
+	balanceViewKeyPrefix = "wallet:balance_view:"
+	addBalanceScript     = `
 		local balance = redis.call('GET', KEYS[1])
 		if not balance then
 			redis.call('SET', KEYS[1], ARGV[1])
@@ -21,22 +24,25 @@ const (
 		end
 		return redis.call('INCRBY', KEYS[1], ARGV[1])
 	`
+	// KEYS[2] 为冻结金额，只能扣减可用部分
 	subBalanceScript = `
 		local balance = redis.call('GET', KEYS[1])
 		if not balance then
 			return nil
 		end
-		if tonumber(balance) < tonumber(ARGV[1]) then
+		local reserved = tonumber(redis.call('GET', KEYS[2]) or '0')
+		if tonumber(balance) - reserved < tonumber(ARGV[1]) then
 			return nil
 		end
 		return redis.call('DECRBY', KEYS[1], ARGV[1])
 	`
+	// KEYS[3] 为转出方冻结金额
 	transferScript = `
 		local fromBalance = redis.call('GET', KEYS[1])
 		if not fromBalance then
 			return {err = 'no_balance'}
 		end
-		local fromNum = tonumber(fromBalance)
+		local fromNum = tonumber(fromBalance) - tonumber(redis.call('GET', KEYS[3]) or '0')
 		local transferNum = tonumber(ARGV[1])
 		if fromNum < transferNum then
 			return {err = 'insufficient_balance'}
@@ -53,13 +59,26 @@ type RedisRepository struct {
 	addScript      *redis.Script
 	subScript      *redis.Script
 	transferScript *redis.Script
+	reserveLimit   *redis.Script
+	releaseLimit   *redis.Script
+	tokenBucket    *redis.Script
+	createHold     *redis.Script
+	captureHold    *redis.Script
+	releaseHold    *redis.Script
 	logger         *logger.Logger
 }
 
 func NewRedisRepository(addr string, logger *logger.Logger) (*RedisRepository, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr: addr,
-	})
+	opts := &redis.Options{Addr: addr}
+	// 同时支持 host:port 与 redis:// URL 两种配置
+	if strings.Contains(addr, "://") {
+		parsed, err := redis.ParseURL(addr)
+		if err != nil {
+			return nil, err
+		}
+		opts = parsed
+	}
+	client := redis.NewClient(opts)
 
 	if err := client.Ping(context.Background()).Err(); err != nil {
 		return nil, err
@@ -70,6 +89,12 @@ func NewRedisRepository(addr string, logger *logger.Logger) (*RedisRepository, e
 		addScript:      redis.NewScript(addBalanceScript),
 		subScript:      redis.NewScript(subBalanceScript),
 		transferScript: redis.NewScript(transferScript),
+		reserveLimit:   redis.NewScript(reserveLimitScript),
+		releaseLimit:   redis.NewScript(releaseLimitScript),
+		tokenBucket:    redis.NewScript(tokenBucketScript),
+		createHold:     redis.NewScript(createHoldScript),
+		captureHold:    redis.NewScript(captureHoldScript),
+		releaseHold:    redis.NewScript(releaseHoldScript),
 		logger:         logger,
 	}, nil
 }
@@ -112,11 +137,12 @@ func (r *RedisRepository) SubBalance(ctx context.Context, address string, amount
 	key := r.getBalanceKey(address)
 	amountInt := amount.Mul(decimal.New(1, 8)).IntPart()
 
-	result, err := r.subScript.Run(ctx, r.client, []string{key}, amountInt).Result()
-	if err != nil {
-		return err
+	// 脚本成功时返回扣减后的余额，余额不足时返回 nil
+	_, err := r.subScript.Run(ctx, r.client, []string{key, r.getReservedKey(address)}, amountInt).Result()
+	if err == redis.Nil {
+		return r.checkLuaResult(nil)
 	}
-	return r.checkLuaResult(result)
+	return err
 }
 
 // Transfer 在两个账户之间转账
@@ -133,7 +159,7 @@ func (r *RedisRepository) Transfer(ctx context.Context, fromAddress, toAddress s
 	toKey := r.getBalanceKey(toAddress)
 	amountInt := amount.Mul(decimal.New(1, 8)).IntPart()
 
-	result, err := r.transferScript.Run(ctx, r.client, []string{fromKey, toKey}, amountInt).Result()
+	result, err := r.transferScript.Run(ctx, r.client, []string{fromKey, toKey, r.getReservedKey(fromAddress)}, amountInt).Result()
 	if err != nil {
 		return err
 	}
@@ -155,6 +181,15 @@ func (r *RedisRepository) GetBalance(ctx context.Context, address string) (decim
 	return decimal.New(result, -8), nil
 }
 
+// ResetBalance 以账本为准覆盖余额与冻结金额，用于对账修复
+func (r *RedisRepository) ResetBalance(ctx context.Context, address string, balance, reserved decimal.Decimal) error {
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, r.getBalanceKey(address), toMinorUnits(balance), 0)
+	pipe.Set(ctx, r.getReservedKey(address), toMinorUnits(reserved), 0)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 func (s *RedisRepository) getBalanceKey(address string) string {
 	return balanceKeyPrefix + address
 }
@@ -190,3 +225,32 @@ func (r *RedisRepository) DeleteAssets(ctx context.Context, address string) erro
 func (r *RedisRepository) getAssetsKey(address string) string {
 	return assetsKeyPrefix + address
 }
+
+// SetBalanceView 缓存余额视图
+func (r *RedisRepository) SetBalanceView(ctx context.Context, address, commitment, view string, ttl time.Duration) error {
+	return r.client.Set(ctx, r.getBalanceViewKey(address, commitment), view, ttl).Err()
+}
+
+// GetBalanceView 获取缓存的余额视图，未命中时返回空字符串
+func (r *RedisRepository) GetBalanceView(ctx context.Context, address, commitment string) (string, error) {
+	result, err := r.client.Get(ctx, r.getBalanceViewKey(address, commitment)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return result, err
+}
+
+// DeleteBalanceViews 删除地址在所有确认级别下的余额视图缓存
+func (r *RedisRepository) DeleteBalanceViews(ctx context.Context, addresses ...string) error {
+	keys := make([]string, 0, len(addresses)*3)
+	for _, address := range addresses {
+		for _, commitment := range []string{"processed", "confirmed", "finalized"} {
+			keys = append(keys, r.getBalanceViewKey(address, commitment))
+		}
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+func (r *RedisRepository) getBalanceViewKey(address, commitment string) string {
+	return balanceViewKeyPrefix + commitment + ":" + address
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	reservedKeyPrefix = "wallet:reserved:"
+	holdKeyPrefix     = "wallet:hold:"
+
+	// holdKeyGrace 冻结记录在到期后继续保留的时间，留给到期任务释放冻结金额
+	holdKeyGrace = 24 * time.Hour
+
+	// createHoldScript 检查可用余额并冻结。KEYS: balance, reserved, hold
+	// ARGV: amount, address, ttlMs
+	createHoldScript = `
+		local balance = tonumber(redis.call('GET', KEYS[1]) or '0')
+		local reserved = tonumber(redis.call('GET', KEYS[2]) or '0')
+		if redis.call('EXISTS', KEYS[3]) == 1 then
+			return 'hold_exists'
+		end
+		if balance - reserved < tonumber(ARGV[1]) then
+			return 'insufficient_balance'
+		end
+		redis.call('INCRBY', KEYS[2], ARGV[1])
+		redis.call('HSET', KEYS[3], 'address', ARGV[2], 'amount', ARGV[1])
+		redis.call('PEXPIRE', KEYS[3], ARGV[3])
+		return 'ok'
+	`
+	// captureHoldScript 扣款并解除整笔冻结。KEYS: balance, reserved, hold
+	// ARGV: captureAmount
+	captureHoldScript = `
+		local amount = redis.call('HGET', KEYS[3], 'amount')
+		if not amount then
+			return 'hold_not_found'
+		end
+		if tonumber(ARGV[1]) > tonumber(amount) then
+			return 'capture_exceeds_hold'
+		end
+		redis.call('DECRBY', KEYS[1], ARGV[1])
+		redis.call('DECRBY', KEYS[2], amount)
+		redis.call('DEL', KEYS[3])
+		return 'ok'
+	`
+	// releaseHoldScript 解除冻结。KEYS: reserved, hold
+	releaseHoldScript = `
+		local amount = redis.call('HGET', KEYS[2], 'amount')
+		if not amount then
+			return 'hold_not_found'
+		end
+		redis.call('DECRBY', KEYS[1], amount)
+		redis.call('DEL', KEYS[2])
+		return 'ok'
+	`
+)
+
+var errRedisHoldNotFound = errors.New("hold not found in redis")
+
+// CreateHold 在 Redis 中冻结资金
+func (r *RedisRepository) CreateHold(ctx context.Context, id, address string, amount decimal.Decimal, ttl time.Duration) error {
+	if !amount.IsPositive() {
+		return errors.New("amount must be positive")
+	}
+	keys := []string{r.getBalanceKey(address), r.getReservedKey(address), r.getHoldKey(id)}
+	result, err := r.createHold.Run(ctx, r.client, keys,
+		toMinorUnits(amount), address, (ttl + holdKeyGrace).Milliseconds()).Result()
+	if err != nil {
+		return err
+	}
+	return holdResult(result)
+}
+
+// CaptureHold 在 Redis 中扣款并解除冻结
+func (r *RedisRepository) CaptureHold(ctx context.Context, id, address string, amount decimal.Decimal) error {
+	keys := []string{r.getBalanceKey(address), r.getReservedKey(address), r.getHoldKey(id)}
+	result, err := r.captureHold.Run(ctx, r.client, keys, toMinorUnits(amount)).Result()
+	if err != nil {
+		return err
+	}
+	return holdResult(result)
+}
+
+// ReleaseHold 在 Redis 中解除冻结
+func (r *RedisRepository) ReleaseHold(ctx context.Context, id, address string) error {
+	keys := []string{r.getReservedKey(address), r.getHoldKey(id)}
+	result, err := r.releaseHold.Run(ctx, r.client, keys).Result()
+	if err != nil {
+		return err
+	}
+	return holdResult(result)
+}
+
+// GetReserved 获取冻结金额
+func (r *RedisRepository) GetReserved(ctx context.Context, address string) (decimal.Decimal, error) {
+	result, err := r.client.Get(ctx, r.getReservedKey(address)).Int64()
+	if err == redis.Nil {
+		return decimal.Zero, nil
+	}
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return fromMinorUnits(result), nil
+}
+
+func holdResult(result interface{}) error {
+	switch result {
+	case "ok":
+		return nil
+	case "insufficient_balance":
+		return errors.New("insufficient balance")
+	case "hold_exists":
+		return errors.New("hold already exists")
+	case "hold_not_found":
+		return errRedisHoldNotFound
+	case "capture_exceeds_hold":
+		return errors.New("capture amount exceeds hold amount")
+	default:
+		return errors.New("unknown hold error")
+	}
+}
+
+func (r *RedisRepository) getReservedKey(address string) string {
+	return reservedKeyPrefix + address
+}
+
+func (r *RedisRepository) getHoldKey(id string) string {
+	return holdKeyPrefix + id
+}
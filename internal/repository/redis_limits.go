@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	limitUsageKeyPrefix  = "wallet:limits:usage:"
+	limitLoadedKeyPrefix = "wallet:limits:loaded:"
+
+	LimitDayWindow   = 24 * time.Hour
+	LimitMonthWindow = 30 * 24 * time.Hour
+
+	// reserveLimitScript 原子地检查并占用多个主体（钱包、客户端）的滚动窗口限额。
+	// 每个 KEY 是一个有序集合，成员为 "<占用ID>:<金额>"，分值为毫秒时间戳。
+	// ARGV: now, amount, member, dayMs, monthMs, 然后每个 KEY 依次 5 个限额值
+	// (perTx, dailyAmount, dailyCount, monthlyAmount, monthlyCount)，-1 表示不限制。
+	// 返回 {0} 表示成功，否则 {KEY 序号, 限额类型, 限额, 已使用}。
+	reserveLimitScript = `
+		local now = tonumber(ARGV[1])
+		local amount = tonumber(ARGV[2])
+		local dayStart = now - tonumber(ARGV[4])
+		local monthStart = now - tonumber(ARGV[5])
+		for i, key in ipairs(KEYS) do
+			local base = 5 + (i - 1) * 5
+			local perTx = tonumber(ARGV[base + 1])
+			local dayAmt = tonumber(ARGV[base + 2])
+			local dayCnt = tonumber(ARGV[base + 3])
+			local monAmt = tonumber(ARGV[base + 4])
+			local monCnt = tonumber(ARGV[base + 5])
+			redis.call('ZREMRANGEBYSCORE', key, '-inf', '(' .. monthStart)
+			local entries = redis.call('ZRANGEBYSCORE', key, monthStart, '+inf', 'WITHSCORES')
+			local dUsed, dCount, mUsed, mCount = 0, 0, 0, 0
+			for j = 1, #entries, 2 do
+				local value = tonumber(string.match(entries[j], ':(%d+)$'))
+				local ts = tonumber(entries[j + 1])
+				mUsed = mUsed + value
+				mCount = mCount + 1
+				if ts >= dayStart then
+					dUsed = dUsed + value
+					dCount = dCount + 1
+				end
+			end
+			if perTx >= 0 and amount > perTx then
+				return {i, 'per_transaction', string.format('%.0f', perTx), '0'}
+			end
+			if dayAmt >= 0 and dUsed + amount > dayAmt then
+				return {i, 'daily_amount', string.format('%.0f', dayAmt), string.format('%.0f', dUsed)}
+			end
+			if dayCnt >= 0 and dCount + 1 > dayCnt then
+				return {i, 'daily_count', string.format('%.0f', dayCnt), string.format('%.0f', dCount)}
+			end
+			if monAmt >= 0 and mUsed + amount > monAmt then
+				return {i, 'monthly_amount', string.format('%.0f', monAmt), string.format('%.0f', mUsed)}
+			end
+			if monCnt >= 0 and mCount + 1 > monCnt then
+				return {i, 'monthly_count', string.format('%.0f', monCnt), string.format('%.0f', mCount)}
+			end
+		end
+		for _, key in ipairs(KEYS) do
+			redis.call('ZADD', key, now, ARGV[3] .. ':' .. ARGV[2])
+			redis.call('PEXPIRE', key, ARGV[5])
+		end
+		return {0}
+	`
+	// releaseLimitScript 删除指定占用ID的记录
+	releaseLimitScript = `
+		local removed = 0
+		for _, key in ipairs(KEYS) do
+			local entries = redis.call('ZRANGE', key, 0, -1)
+			for _, member in ipairs(entries) do
+				if string.sub(member, 1, #ARGV[1] + 1) == ARGV[1] .. ':' then
+					removed = removed + redis.call('ZREM', key, member)
+				end
+			end
+		end
+		return removed
+	`
+)
+
+// LimitSubject 参与限额检查的主体及其生效限额
+type LimitSubject struct {
+	Scope   string
+	Subject string
+	Asset   string
+	Limit   *models.Limit
+}
+
+// LimitViolation Lua 脚本返回的超限信息，金额与次数均为最小单位
+type LimitViolation struct {
+	Subject LimitSubject
+	Kind    string
+	Limit   decimal.Decimal
+	Used    decimal.Decimal
+}
+
+// ReserveLimit 原子地检查所有主体的限额并记录一次占用，超限时返回 LimitViolation
+func (r *RedisRepository) ReserveLimit(ctx context.Context, id string, subjects []LimitSubject, amount decimal.Decimal, now time.Time) (*LimitViolation, error) {
+	keys := make([]string, len(subjects))
+	args := []interface{}{
+		now.UnixMilli(),
+		toMinorUnits(amount),
+		id,
+		LimitDayWindow.Milliseconds(),
+		LimitMonthWindow.Milliseconds(),
+	}
+	for i, s := range subjects {
+		keys[i] = r.getLimitUsageKey(s.Scope, s.Subject, s.Asset)
+		args = append(args, limitArgs(s.Limit)...)
+	}
+
+	result, err := r.reserveLimit.Run(ctx, r.client, keys, args...).Slice()
+	if err != nil {
+		return nil, err
+	}
+	index, _ := result[0].(int64)
+	if index == 0 {
+		return nil, nil
+	}
+	if len(result) != 4 || int(index) > len(subjects) {
+		return nil, fmt.Errorf("unexpected limit script result: %v", result)
+	}
+
+	kind, _ := result[1].(string)
+	limit, err := parseMinorUnits(result[2], kind)
+	if err != nil {
+		return nil, err
+	}
+	used, err := parseMinorUnits(result[3], kind)
+	if err != nil {
+		return nil, err
+	}
+	return &LimitViolation{Subject: subjects[index-1], Kind: kind, Limit: limit, Used: used}, nil
+}
+
+// ReleaseLimit 撤销一次限额占用
+func (r *RedisRepository) ReleaseLimit(ctx context.Context, id string, subjects []LimitSubject) error {
+	keys := make([]string, len(subjects))
+	for i, s := range subjects {
+		keys[i] = r.getLimitUsageKey(s.Scope, s.Subject, s.Asset)
+	}
+	return r.releaseLimit.Run(ctx, r.client, keys, id).Err()
+}
+
+// LimitUsageLoaded 检查 Redis 中的限额计数是否已从 Postgres 加载
+func (r *RedisRepository) LimitUsageLoaded(ctx context.Context, s LimitSubject) (bool, error) {
+	n, err := r.client.Exists(ctx, r.getLimitLoadedKey(s.Scope, s.Subject, s.Asset)).Result()
+	return n > 0, err
+}
+
+// LoadLimitUsage 用 Postgres 中的使用记录重建 Redis 计数
+func (r *RedisRepository) LoadLimitUsage(ctx context.Context, s LimitSubject, usages []models.LimitUsage) error {
+	key := r.getLimitUsageKey(s.Scope, s.Subject, s.Asset)
+	pipe := r.client.TxPipeline()
+	for _, u := range usages {
+		pipe.ZAdd(ctx, key, &redis.Z{
+			Score:  float64(u.CreatedAt.UnixMilli()),
+			Member: u.ID + ":" + strconv.FormatInt(toMinorUnits(u.Amount), 10),
+		})
+	}
+	pipe.PExpire(ctx, key, LimitMonthWindow)
+	pipe.Set(ctx, r.getLimitLoadedKey(s.Scope, s.Subject, s.Asset), 1, LimitMonthWindow)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LimitUsage 返回滚动窗口内已使用的金额与次数
+func (r *RedisRepository) LimitUsage(ctx context.Context, s LimitSubject, window time.Duration, now time.Time) (decimal.Decimal, int64, error) {
+	key := r.getLimitUsageKey(s.Scope, s.Subject, s.Asset)
+	members, err := r.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: strconv.FormatInt(now.Add(-window).UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return decimal.Zero, 0, err
+	}
+
+	var total int64
+	for _, m := range members {
+		idx := strings.LastIndexByte(m, ':')
+		v, err := strconv.ParseInt(m[idx+1:], 10, 64)
+		if err != nil {
+			return decimal.Zero, 0, err
+		}
+		total += v
+	}
+	return fromMinorUnits(total), int64(len(members)), nil
+}
+
+func (r *RedisRepository) getLimitUsageKey(scope, subject, asset string) string {
+	return limitUsageKeyPrefix + scope + ":" + subject + ":" + asset
+}
+
+func (r *RedisRepository) getLimitLoadedKey(scope, subject, asset string) string {
+	return limitLoadedKeyPrefix + scope + ":" + subject + ":" + asset
+}
+
+// limitArgs 将限额配置转换为脚本参数，金额使用最小单位
+func limitArgs(l *models.Limit) []interface{} {
+	amount := func(d *decimal.Decimal) int64 {
+		if d == nil {
+			return -1
+		}
+		return toMinorUnits(*d)
+	}
+	count := func(c *int64) int64 {
+		if c == nil {
+			return -1
+		}
+		return *c
+	}
+	if l == nil {
+		return []interface{}{-1, -1, -1, -1, -1}
+	}
+	return []interface{}{
+		amount(l.PerTxMax),
+		amount(l.DailyAmount),
+		count(l.DailyCount),
+		amount(l.MonthlyAmount),
+		count(l.MonthlyCount),
+	}
+}
+
+// parseMinorUnits 解析脚本返回值，次数类限额不做单位换算
+func parseMinorUnits(v interface{}, kind string) (decimal.Decimal, error) {
+	s, _ := v.(string)
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid limit script value %v: %w", v, err)
+	}
+	if strings.HasSuffix(kind, "_count") {
+		return d, nil
+	}
+	return d.Shift(-8), nil
+}
+
+// toMinorUnits 转换为 Redis 中使用的最小单位 (1e-8)
+func toMinorUnits(amount decimal.Decimal) int64 {
+	return amount.Mul(decimal.New(1, 8)).IntPart()
+}
+
+func fromMinorUnits(v int64) decimal.Decimal {
+	return decimal.New(v, -8)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	rateLimitKeyPrefix = "wallet:ratelimit:"
+
+	// tokenBucketScript 对多个令牌桶做原子检查，只有全部允许时才扣减。
+	// 每个桶保存为 hash {tokens, ts}，按时间差补充令牌。
+	// ARGV: now(ms), cost, 然后每个 KEY 依次 capacity, refillPerMs。
+	// 返回每个桶的 {allowed, remaining, retryAfterMs, resetMs}，展平为一个列表。
+	tokenBucketScript = `
+		local now = tonumber(ARGV[1])
+		local cost = tonumber(ARGV[2])
+		local allowed = 1
+		local state = {}
+		for i, key in ipairs(KEYS) do
+			local capacity = tonumber(ARGV[2 + (i - 1) * 2 + 1])
+			local rate = tonumber(ARGV[2 + (i - 1) * 2 + 2])
+			local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+			local tokens = tonumber(bucket[1])
+			local ts = tonumber(bucket[2])
+			if tokens == nil then
+				tokens = capacity
+				ts = now
+			end
+			if now > ts then
+				tokens = math.min(capacity, tokens + (now - ts) * rate)
+			end
+			local retry = 0
+			if tokens < cost then
+				allowed = 0
+				retry = math.ceil((cost - tokens) / rate)
+			end
+			state[i] = {tokens, capacity, rate, retry}
+		end
+		local out = {}
+		for i, key in ipairs(KEYS) do
+			local tokens, capacity, rate, retry = state[i][1], state[i][2], state[i][3], state[i][4]
+			if allowed == 1 then
+				tokens = tokens - cost
+			end
+			local reset = math.ceil((capacity - tokens) / rate)
+			redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', ARGV[1])
+			redis.call('PEXPIRE', key, math.max(reset, 1000))
+			table.insert(out, allowed)
+			table.insert(out, math.floor(tokens))
+			table.insert(out, retry)
+			table.insert(out, reset)
+		end
+		return out
+	`
+)
+
+// TokenBucket 令牌桶配置
+type TokenBucket struct {
+	Key      string
+	Capacity int64
+	// Refill 每个 Period 补充的令牌数
+	Refill int64
+	Period time.Duration
+}
+
+// TokenBucketResult 单个令牌桶的检查结果
+type TokenBucketResult struct {
+	Allowed    bool
+	Remaining  int64
+	RetryAfter time.Duration
+	// Reset 桶补满所需时间
+	Reset time.Duration
+}
+
+// TakeTokens 从所有桶中各取 cost 个令牌，任意一个不足时都不扣减
+func (r *RedisRepository) TakeTokens(ctx context.Context, buckets []TokenBucket, cost int64, now time.Time) ([]TokenBucketResult, error) {
+	keys := make([]string, len(buckets))
+	args := []interface{}{now.UnixMilli(), cost}
+	for i, b := range buckets {
+		keys[i] = rateLimitKeyPrefix + b.Key
+		args = append(args, b.Capacity, float64(b.Refill)/float64(b.Period.Milliseconds()))
+	}
+
+	values, err := r.tokenBucket.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4*len(buckets) {
+		return nil, fmt.Errorf("unexpected token bucket result: %v", values)
+	}
+
+	results := make([]TokenBucketResult, len(buckets))
+	for i := range buckets {
+		v := values[i*4 : i*4+4]
+		results[i] = TokenBucketResult{
+			Allowed:    v[0] == 1,
+			Remaining:  v[1],
+			RetryAfter: time.Duration(v[2]) * time.Millisecond,
+			Reset:      time.Duration(v[3]) * time.Millisecond,
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mywallet/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// streamEventsKey 事件日志，用于断线续传
+	streamEventsKey = "wallet:stream:events"
+	// StreamChannel 实时事件广播频道，各实例订阅后推送给本地连接
+	StreamChannel = "wallet:stream:live"
+	// streamMaxLen 事件日志保留的大致条数
+	streamMaxLen = 10000
+)
+
+// AppendStreamEvent 写入事件日志并广播，写入后 event.ID 为日志中的 ID
+func (r *RedisRepository) AppendStreamEvent(ctx context.Context, event *models.StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	id, err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamEventsKey,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": data},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("append stream event failed: %w", err)
+	}
+
+	event.ID = id
+	if data, err = json.Marshal(event); err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, StreamChannel, data).Err()
+}
+
+// StreamEventsAfter 按顺序返回 lastID 之后最多 count 条事件。
+// lastID 早于日志中保留的最早事件时 truncated 为 true，说明中间的事件已被裁剪
+func (r *RedisRepository) StreamEventsAfter(ctx context.Context, lastID string, count int64) (events []models.StreamEvent, truncated bool, err error) {
+	if _, _, err := parseStreamID(lastID); err != nil {
+		return nil, false, err
+	}
+
+	oldest, err := r.client.XRangeN(ctx, streamEventsKey, "-", "+", 1).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(oldest) > 0 && CompareStreamIDs(oldest[0].ID, lastID) > 0 {
+		truncated = true
+	}
+
+	// XRANGE 包含起点，多取一条后跳过 lastID 本身
+	messages, err := r.client.XRangeN(ctx, streamEventsKey, lastID, "+", count+1).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	events = make([]models.StreamEvent, 0, len(messages))
+	for _, msg := range messages {
+		if msg.ID == lastID {
+			continue
+		}
+		raw, _ := msg.Values["event"].(string)
+		var event models.StreamEvent
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			continue
+		}
+		event.ID = msg.ID
+		events = append(events, event)
+		if int64(len(events)) == count {
+			break
+		}
+	}
+	return events, truncated, nil
+}
+
+// SubscribeStream 订阅实时事件广播
+func (r *RedisRepository) SubscribeStream(ctx context.Context) *redis.PubSub {
+	return r.client.Subscribe(ctx, StreamChannel)
+}
+
+// CompareStreamIDs 比较两个事件 ID 的先后，a 在前返回 -1，相同返回 0，a 在后返回 1。
+// 无效的 ID 视为最早
+func CompareStreamIDs(a, b string) int {
+	ams, aseq, _ := parseStreamID(a)
+	bms, bseq, _ := parseStreamID(b)
+	switch {
+	case ams != bms:
+		if ams < bms {
+			return -1
+		}
+		return 1
+	case aseq != bseq:
+		if aseq < bseq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// parseStreamID 解析 Redis stream ID（毫秒时间戳-序号）
+func parseStreamID(id string) (uint64, uint64, error) {
+	msPart, seqPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid event id: %s", id)
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid event id: %s", id)
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid event id: %s", id)
+	}
+	return ms, seq, nil
+}
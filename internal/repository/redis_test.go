@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/pkg/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) *RedisRepository {
+	mr := miniredis.RunT(t)
+	repo, err := NewRedisRepository(mr.Addr(), logger.NewLogger())
+	require.NoError(t, err)
+	return repo
+}
+
+func decimalPtr(s string) *decimal.Decimal {
+	d := decimal.RequireFromString(s)
+	return &d
+}
+
+func TestReserveLimit(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRedis(t)
+	dailyCount := int64(3)
+
+	wallet := LimitSubject{
+		Scope:   models.LimitScopeWallet,
+		Subject: "wallet-a",
+		Asset:   models.AssetSOL,
+		Limit: &models.Limit{
+			PerTxMax:    decimalPtr("5"),
+			DailyAmount: decimalPtr("8"),
+			DailyCount:  &dailyCount,
+		},
+	}
+	subjects := []LimitSubject{wallet}
+	now := time.Now()
+
+	v, err := repo.ReserveLimit(ctx, "r1", subjects, decimal.RequireFromString("6"), now)
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	assert.Equal(t, "per_transaction", v.Kind)
+	assert.True(t, v.Limit.Equal(decimal.NewFromInt(5)))
+
+	v, err = repo.ReserveLimit(ctx, "r2", subjects, decimal.RequireFromString("4.5"), now)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = repo.ReserveLimit(ctx, "r3", subjects, decimal.RequireFromString("4"), now)
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	assert.Equal(t, "daily_amount", v.Kind)
+	assert.True(t, v.Used.Equal(decimal.RequireFromString("4.5")), v.Used.String())
+
+	// 释放后额度恢复
+	require.NoError(t, repo.ReleaseLimit(ctx, "r2", subjects))
+	v, err = repo.ReserveLimit(ctx, "r4", subjects, decimal.RequireFromString("4"), now)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	// 超过滚动窗口的记录不再计入当日额度
+	later := now.Add(LimitDayWindow + time.Minute)
+	used, count, err := repo.LimitUsage(ctx, wallet, LimitDayWindow, later)
+	require.NoError(t, err)
+	assert.True(t, used.IsZero())
+	assert.Zero(t, count)
+	used, count, err = repo.LimitUsage(ctx, wallet, LimitMonthWindow, later)
+	require.NoError(t, err)
+	assert.True(t, used.Equal(decimal.NewFromInt(4)))
+	assert.Equal(t, int64(1), count)
+}
+
+func TestReserveLimitChecksAllSubjects(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRedis(t)
+	monthlyCount := int64(1)
+
+	subjects := []LimitSubject{
+		{Scope: models.LimitScopeWallet, Subject: "wallet-a", Asset: models.AssetSOL},
+		{Scope: models.LimitScopeClient, Subject: "client-a", Asset: models.AssetSOL,
+			Limit: &models.Limit{MonthlyCount: &monthlyCount}},
+	}
+	now := time.Now()
+
+	v, err := repo.ReserveLimit(ctx, "r1", subjects, decimal.NewFromInt(1), now)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = repo.ReserveLimit(ctx, "r2", subjects, decimal.NewFromInt(1), now)
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	assert.Equal(t, models.LimitScopeClient, v.Subject.Scope)
+	assert.Equal(t, "monthly_count", v.Kind)
+
+	// 失败的占用不应写入任何主体
+	used, count, err := repo.LimitUsage(ctx, subjects[0], LimitMonthWindow, now)
+	require.NoError(t, err)
+	assert.True(t, used.Equal(decimal.NewFromInt(1)))
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTakeTokens(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRedis(t)
+
+	client := TokenBucket{Key: "client:a", Capacity: 2, Refill: 1, Period: time.Second}
+	route := TokenBucket{Key: "route:a", Capacity: 5, Refill: 5, Period: time.Second}
+	buckets := []TokenBucket{client, route}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		results, err := repo.TakeTokens(ctx, buckets, 1, now)
+		require.NoError(t, err)
+		assert.True(t, results[0].Allowed)
+		assert.True(t, results[1].Allowed)
+	}
+
+	results, err := repo.TakeTokens(ctx, buckets, 1, now)
+	require.NoError(t, err)
+	assert.False(t, results[0].Allowed)
+	assert.Equal(t, time.Second, results[0].RetryAfter)
+	// 被拒绝的请求不消耗其他桶的令牌
+	assert.Equal(t, int64(3), results[1].Remaining)
+
+	results, err = repo.TakeTokens(ctx, buckets, 1, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, results[0].Allowed)
+	assert.Equal(t, int64(0), results[0].Remaining)
+}
+
+func TestHolds(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRedis(t)
+	address := "wallet-a"
+
+	require.NoError(t, repo.AddBalance(ctx, address, decimal.NewFromInt(10)))
+	require.NoError(t, repo.CreateHold(ctx, "h1", address, decimal.NewFromInt(6), time.Minute))
+
+	// 冻结部分不可提现或再次冻结
+	assert.Error(t, repo.SubBalance(ctx, address, decimal.NewFromInt(5)))
+	assert.Error(t, repo.CreateHold(ctx, "h2", address, decimal.NewFromInt(5), time.Minute))
+	require.NoError(t, repo.SubBalance(ctx, address, decimal.NewFromInt(4)))
+
+	// 部分扣款后剩余冻结金额回到可用余额
+	require.NoError(t, repo.CaptureHold(ctx, "h1", address, decimal.NewFromInt(2)))
+	balance, err := repo.GetBalance(ctx, address)
+	require.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt(4)))
+	reserved, err := repo.GetReserved(ctx, address)
+	require.NoError(t, err)
+	assert.True(t, reserved.IsZero())
+
+	require.NoError(t, repo.CreateHold(ctx, "h3", address, decimal.NewFromInt(4), time.Minute))
+	require.NoError(t, repo.ReleaseHold(ctx, "h3", address))
+	assert.Error(t, repo.ReleaseHold(ctx, "h3", address))
+	require.NoError(t, repo.SubBalance(ctx, address, decimal.NewFromInt(4)))
+}
+
+func TestStreamEventsAfter(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRedis(t)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		event := &models.StreamEvent{Type: models.StreamEventBalance, Addresses: []string{"addr"}}
+		require.NoError(t, repo.AppendStreamEvent(ctx, event))
+		require.NotEmpty(t, event.ID)
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, -1, CompareStreamIDs(ids[0], ids[1]))
+	assert.Equal(t, 1, CompareStreamIDs(ids[2], ids[1]))
+
+	events, truncated, err := repo.StreamEventsAfter(ctx, ids[0], 10)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	require.Len(t, events, 2)
+	assert.Equal(t, ids[1], events[0].ID)
+	assert.Equal(t, ids[2], events[1].ID)
+	assert.Equal(t, []string{"addr"}, events[0].Addresses)
+
+	events, _, err = repo.StreamEventsAfter(ctx, ids[0], 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, ids[1], events[0].ID)
+
+	// 早于日志中最早事件的断点
+	_, truncated, err = repo.StreamEventsAfter(ctx, "1-0", 10)
+	require.NoError(t, err)
+	assert.True(t, truncated)
+
+	_, _, err = repo.StreamEventsAfter(ctx, "not-an-id", 10)
+	assert.Error(t, err)
+}
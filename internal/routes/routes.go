@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"context"
 	"mywallet/internal/api"
 	"mywallet/internal/config"
 	"mywallet/pkg/logger"
@@ -18,25 +19,122 @@ func InitRouter() *gin.Engine {
 	route.Use(sessions.Sessions("mywallet-session", store))
 	route.StaticFS("/static", http.Dir("./static"))
 
-	//App应用路由
-	InitAppRouter(route)
+	cfg, _ := config.Load()
+	logger := logger.NewLogger()
+	server := api.NewServer(cfg, logger)
+	if server != nil {
+		server.StartWorkers(context.Background())
+		//App应用路由
+		InitAppRouter(route, server)
+		//管理路由
+		InitAdminRouter(route, server)
+	}
 
 	return route
 }
 
 // 初始化App应用路由
-func InitAppRouter(r *gin.Engine) {
+func InitAppRouter(r *gin.Engine, server *api.Server) {
 	app_api := r.Group("api/wallet")
+	app_api.Use(server.RequestMeta(), server.ClientAuth(), server.RateLimit())
+	{
+		app_api.POST("/deposit", server.Deposit)
+		app_api.POST("/withdraw", server.Withdraw)
+		app_api.POST("/transfer", server.Transfer)
+		app_api.POST("/transfer/simulate", server.SimulateTransfer)
+		app_api.GET("/balance/:address", server.GetBalance)
+		app_api.GET("/transactions/lookup", server.LookupTransactions)
+		app_api.GET("/transactions/:address", server.GetTransactions)
+		app_api.GET("/stream", server.Stream)
+
+		app_api.POST("/holds", server.CreateHold)
+		app_api.GET("/holds", server.ListHolds)
+		app_api.GET("/holds/:id", server.GetHold)
+		app_api.POST("/holds/:id/capture", server.CaptureHold)
+		app_api.POST("/holds/:id/release", server.ReleaseHold)
+
+		app_api.POST("/withdrawals/offline", server.CreateOfflineWithdrawal)
+		app_api.GET("/withdrawals/offline", server.ListOfflineWithdrawals)
+		app_api.GET("/withdrawals/offline/:id", server.GetOfflineWithdrawal)
+		app_api.POST("/withdrawals/offline/:id/submit", server.SubmitOfflineWithdrawal)
+		app_api.POST("/withdrawals/offline/:id/cancel", server.CancelOfflineWithdrawal)
+		app_api.POST("/withdrawals/offline/:id/advance-nonce", server.AdvanceOfflineNonce)
+
+		app_api.POST("/payouts", server.CreatePayout)
+		app_api.GET("/payouts/:id", server.GetPayout)
+		app_api.POST("/payouts/:id/retry", server.RetryPayout)
+
+		app_api.POST("/schedules", server.CreateSchedule)
+		app_api.GET("/schedules", server.ListSchedules)
+		app_api.GET("/schedules/:id", server.GetSchedule)
+		app_api.GET("/schedules/:id/executions", server.ListScheduleExecutions)
+		app_api.POST("/schedules/:id/pause", server.PauseSchedule)
+		app_api.POST("/schedules/:id/resume", server.ResumeSchedule)
+		app_api.POST("/schedules/:id/cancel", server.CancelSchedule)
+
+		app_api.GET("/approvals", server.ListApprovals)
+		app_api.GET("/approvals/:id", server.GetApproval)
+
+		app_api.GET("/address-book", server.ListAddressBook)
+		app_api.POST("/address-book", server.AddAddress)
+		app_api.DELETE("/address-book/:id", server.DeleteAddress)
+
+		app_api.POST("/deposit-addresses", server.CreateDepositAddress)
+		app_api.POST("/deposit-addresses/batch", server.CreateDepositAddresses)
+		app_api.GET("/deposit-addresses", server.ListDepositAddresses)
+		app_api.GET("/fees", server.GetSponsoredFees)
+
+		app_api.POST("/webhooks", server.CreateWebhook)
+		app_api.GET("/webhooks", server.ListWebhooks)
+		app_api.DELETE("/webhooks/:id", server.DeleteWebhook)
+		app_api.POST("/webhooks/:id/test", server.TestWebhook)
+		app_api.GET("/webhooks/:id/deliveries", server.ListWebhookDeliveries)
+		app_api.GET("/webhooks/:id/deliveries/:delivery_id", server.GetWebhookDelivery)
+		app_api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", server.RedeliverWebhook)
+	}
+}
+
+// 初始化管理路由
+func InitAdminRouter(r *gin.Engine, server *api.Server) {
+	admin_api := r.Group("api/admin")
+	admin_api.Use(server.RequestMeta(), server.AdminAuth())
 	{
-		cfg, _ := config.Load()
-		logger := logger.NewLogger()
-		server := api.NewServer(cfg, logger)
-		if server != nil {
-			app_api.POST("/deposit", server.Deposit)
-			app_api.POST("/withdraw", server.Withdraw)
-			app_api.POST("/transfer", server.Transfer)
-			app_api.GET("/balance/:address", server.GetBalance)
-			app_api.GET("/transactions/:address", server.GetTransactions)
-		}
+		admin_api.POST("/clients", server.CreateClient)
+
+		admin_api.POST("/wallets", server.CreateManagedWallet)
+		admin_api.GET("/wallets", server.ListManagedWallets)
+
+		admin_api.GET("/limits", server.ListLimits)
+		admin_api.PUT("/limits", server.SetLimit)
+		admin_api.DELETE("/limits/:id", server.DeleteLimit)
+		admin_api.GET("/limits/status", server.GetLimitStatus)
+
+		admin_api.GET("/approval-policies", server.ListApprovalPolicies)
+		admin_api.PUT("/approval-policies", server.SetApprovalPolicy)
+		admin_api.DELETE("/approval-policies/:id", server.DeleteApprovalPolicy)
+		admin_api.GET("/approvals", server.ListApprovals)
+		admin_api.GET("/approvals/:id", server.GetApproval)
+		admin_api.POST("/approvals/:id/approve", server.ApproveRequest)
+		admin_api.POST("/approvals/:id/reject", server.RejectRequest)
+
+		admin_api.GET("/address-book", server.ListAddressBook)
+		admin_api.POST("/address-book", server.AddAddress)
+		admin_api.DELETE("/address-book/:id", server.DeleteAddress)
+
+		admin_api.GET("/withdrawals/offline", server.ListOfflineWithdrawals)
+		admin_api.GET("/withdrawals/offline/:id", server.GetOfflineWithdrawal)
+		admin_api.POST("/withdrawals/offline/:id/submit", server.SubmitOfflineWithdrawal)
+		admin_api.POST("/withdrawals/offline/:id/cancel", server.CancelOfflineWithdrawal)
+		admin_api.POST("/withdrawals/offline/:id/advance-nonce", server.AdvanceOfflineNonce)
+
+		admin_api.GET("/deposit-addresses", server.ListDepositAddresses)
+		admin_api.GET("/treasury", server.GetTreasury)
+		admin_api.GET("/treasury/movements", server.ListTreasuryMovements)
+		admin_api.POST("/treasury/sweep", server.Sweep)
+		admin_api.GET("/fees", server.SummarizeSponsoredFees)
+		admin_api.GET("/rpc", server.ListRPCEndpoints)
+
+		admin_api.GET("/audit", server.ListAudit)
+		admin_api.GET("/audit/verify", server.VerifyAudit)
 	}
 }
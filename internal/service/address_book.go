@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	maxAddressLabels   = 10
+	maxAddressLabelLen = 64
+	maxAddressMemoLen  = 255
+)
+
+var (
+	ErrAddressBookEntryNotFound = repository.ErrAddressBookEntryNotFound
+	ErrAddressBookEntryExists   = repository.ErrAddressBookEntryExists
+	ErrAddressNotWhitelisted    = errors.New("destination address is not in the address book")
+	ErrAddressCoolingDown       = errors.New("destination address is still in its cool-down period")
+)
+
+// AddressBookRequest 新增地址簿记录的参数
+type AddressBookRequest struct {
+	// Scope、Subject 只有管理员可以指定，API 客户端固定为自己的 client 地址簿
+	Scope   string
+	Subject string
+	Address string
+	Labels  []string
+	Memo    string
+	// Cooldown 只有管理员可以指定，nil 时使用 WHITELIST_COOLDOWN
+	Cooldown *time.Duration
+}
+
+// AddAddress 新增地址簿记录，冷却期结束后才能作为转账目标
+func (s *WalletService) AddAddress(ctx context.Context, req AddressBookRequest) (_ *models.AddressBookEntry, err error) {
+	actor := ActorFromContext(ctx)
+	if actor.Type == models.ActorAdmin {
+		switch req.Scope {
+		case models.AddressScopeWallet:
+			if _, err := solana.PublicKeyFromBase58(req.Subject); err != nil {
+				return nil, fmt.Errorf("invalid subject wallet address: %w", err)
+			}
+		case models.AddressScopeClient:
+			if req.Subject == "" {
+				return nil, fmt.Errorf("subject is required")
+			}
+		default:
+			return nil, fmt.Errorf("scope must be wallet or client")
+		}
+	} else {
+		clientID := ClientIDFromContext(ctx)
+		if clientID == "" {
+			return nil, ErrClientRequired
+		}
+		if req.Cooldown != nil {
+			return nil, fmt.Errorf("cool-down can only be set by an admin")
+		}
+		req.Scope, req.Subject = models.AddressScopeClient, clientID
+	}
+
+	if _, err := solana.PublicKeyFromBase58(req.Address); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	labels, err := normalizeLabels(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+	memo := strings.TrimSpace(req.Memo)
+	if len(memo) > maxAddressMemoLen {
+		return nil, fmt.Errorf("memo is longer than %d bytes", maxAddressMemoLen)
+	}
+	cooldown := s.cfg.WhitelistCooldown
+	if req.Cooldown != nil {
+		if *req.Cooldown < 0 {
+			return nil, fmt.Errorf("cool-down must not be negative")
+		}
+		cooldown = *req.Cooldown
+	}
+
+	now := time.Now()
+	entry := &models.AddressBookEntry{
+		ID:        uuid.NewString(),
+		Scope:     req.Scope,
+		Subject:   req.Subject,
+		Address:   req.Address,
+		Labels:    labels,
+		Memo:      memo,
+		CreatedBy: actor.Type + ":" + actor.ID,
+		ActiveAt:  now.Add(cooldown),
+		CreatedAt: now,
+	}
+
+	audit := s.beginAudit(ctx, models.AuditAddressBookCreate, decimal.Zero, req, addressBookAuditAddresses(entry)...)
+	audit.entry.Reference = entry.ID
+	defer func() { audit.finish(err) }()
+
+	if err := s.postgres.CreateAddressBookEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	entry.Active = cooldown == 0
+
+	s.logger.Logger.Info("address book entry created",
+		zap.String("id", entry.ID),
+		zap.String("scope", entry.Scope),
+		zap.String("subject", entry.Subject),
+		zap.String("address", entry.Address),
+		zap.Time("active_at", entry.ActiveAt))
+	s.publishAddressBook(ctx, models.EventAddressBookCreated, entry)
+	return entry, nil
+}
+
+// ListAddressBook 查询地址簿。API 客户端只能查询自己的 client 地址簿
+func (s *WalletService) ListAddressBook(ctx context.Context, scope, subject string) ([]models.AddressBookEntry, error) {
+	if ActorFromContext(ctx).Type != models.ActorAdmin {
+		if subject = ClientIDFromContext(ctx); subject == "" {
+			return nil, ErrClientRequired
+		}
+		scope = models.AddressScopeClient
+	}
+	if scope != models.AddressScopeWallet && scope != models.AddressScopeClient {
+		return nil, fmt.Errorf("scope must be wallet or client")
+	}
+
+	entries, err := s.postgres.ListAddressBook(ctx, scope, subject)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range entries {
+		entries[i].Active = !entries[i].ActiveAt.After(now)
+	}
+	return entries, nil
+}
+
+// DeleteAddress 删除地址簿记录，之后转往该地址将被拒绝。API 客户端只能删除自己的记录
+func (s *WalletService) DeleteAddress(ctx context.Context, id string) (err error) {
+	entry, err := s.postgres.GetAddressBookEntry(ctx, id)
+	if err != nil {
+		return err
+	}
+	if ActorFromContext(ctx).Type != models.ActorAdmin {
+		clientID := ClientIDFromContext(ctx)
+		if clientID == "" {
+			return ErrClientRequired
+		}
+		if entry.Scope != models.AddressScopeClient || entry.Subject != clientID {
+			return ErrAddressBookEntryNotFound
+		}
+	}
+
+	audit := s.beginAudit(ctx, models.AuditAddressBookDelete, decimal.Zero,
+		map[string]string{"id": id}, addressBookAuditAddresses(entry)...)
+	audit.entry.Reference = id
+	defer func() { audit.finish(err) }()
+
+	if err := s.postgres.DeleteAddressBookEntry(ctx, id); err != nil {
+		return err
+	}
+	s.logger.Logger.Info("address book entry deleted",
+		zap.String("id", id),
+		zap.String("address", entry.Address))
+	s.publishAddressBook(ctx, models.EventAddressBookDeleted, entry)
+	return nil
+}
+
+// checkDestination 检查目标地址是否在发送方钱包或 API 客户端的地址簿中且已过冷却期，返回转账需要附带的 memo。
+// 未开启 WHITELIST_ENFORCED 时不拒绝，但地址簿中记录的 memo 仍然生效
+func (s *WalletService) checkDestination(ctx context.Context, from, to string) (string, error) {
+	entries, err := s.postgres.FindAddressBookEntries(ctx, from, ClientIDFromContext(ctx), to)
+	if err != nil {
+		return "", fmt.Errorf("failed to check address book: %w", err)
+	}
+
+	now := time.Now()
+	for _, e := range entries {
+		if !e.ActiveAt.After(now) {
+			return e.Memo, nil
+		}
+	}
+	if !s.cfg.WhitelistEnforced {
+		if len(entries) > 0 {
+			return entries[0].Memo, nil
+		}
+		return "", nil
+	}
+	if len(entries) > 0 {
+		return "", fmt.Errorf("%w: %s is usable after %s",
+			ErrAddressCoolingDown, to, entries[0].ActiveAt.Format(time.RFC3339))
+	}
+	return "", fmt.Errorf("%w: %s", ErrAddressNotWhitelisted, to)
+}
+
+// publishAddressBook 通知地址簿所属的 API 客户端
+func (s *WalletService) publishAddressBook(ctx context.Context, eventType string, entry *models.AddressBookEntry) {
+	if entry.Scope == models.AddressScopeClient {
+		ctx = WithClientID(ctx, entry.Subject)
+	}
+	s.webhooks.Publish(ctx, eventType, entry)
+}
+
+func addressBookAuditAddresses(entry *models.AddressBookEntry) []string {
+	if entry.Scope == models.AddressScopeWallet {
+		return []string{entry.Subject, entry.Address}
+	}
+	return []string{entry.Address}
+}
+
+// normalizeLabels 去掉空白与重复的标签
+func normalizeLabels(labels []string) ([]string, error) {
+	seen := make(map[string]bool)
+	out := make([]string, 0, len(labels))
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		if len(label) > maxAddressLabelLen {
+			return nil, fmt.Errorf("label %q is longer than %d bytes", label, maxAddressLabelLen)
+		}
+		seen[label] = true
+		out = append(out, label)
+	}
+	if len(out) > maxAddressLabels {
+		return nil, fmt.Errorf("at most %d labels are allowed", maxAddressLabels)
+	}
+	return out, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mywallet/internal/config"
+	"mywallet/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAddressValidation(t *testing.T) {
+	s := &WalletService{cfg: &config.Config{WhitelistCooldown: 24 * time.Hour}}
+	address := "11111111111111111111111111111111"
+	negative := -time.Second
+	admin := WithActor(context.Background(), models.Actor{Type: models.ActorAdmin, ID: "ops"})
+	client := WithClientID(context.Background(), "client-1")
+
+	_, err := s.AddAddress(context.Background(), AddressBookRequest{Address: address})
+	assert.ErrorIs(t, err, ErrClientRequired)
+
+	invalid := []struct {
+		ctx context.Context
+		req AddressBookRequest
+	}{
+		{client, AddressBookRequest{Address: "not-an-address"}},
+		// API 客户端不能指定冷却期
+		{client, AddressBookRequest{Address: address, Cooldown: new(time.Duration)}},
+		{client, AddressBookRequest{Address: address, Labels: []string{strings.Repeat("x", maxAddressLabelLen+1)}}},
+		{client, AddressBookRequest{Address: address, Memo: strings.Repeat("x", maxAddressMemoLen+1)}},
+		{admin, AddressBookRequest{Scope: "team", Subject: "x", Address: address}},
+		{admin, AddressBookRequest{Scope: models.AddressScopeWallet, Subject: "not-an-address", Address: address}},
+		{admin, AddressBookRequest{Scope: models.AddressScopeClient, Address: address}},
+		{admin, AddressBookRequest{Scope: models.AddressScopeClient, Subject: "client-1", Address: address, Cooldown: &negative}},
+	}
+	for _, tc := range invalid {
+		_, err := s.AddAddress(tc.ctx, tc.req)
+		assert.Error(t, err, "%+v", tc.req)
+	}
+}
+
+func TestNormalizeLabels(t *testing.T) {
+	labels, err := normalizeLabels([]string{" exchange ", "", "exchange", "cold"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"exchange", "cold"}, labels)
+
+	_, err = normalizeLabels(strings.Split("a b c d e f g h i j k", " "))
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// AdjustmentRequest 手动调账参数
+type AdjustmentRequest struct {
+	Address string
+	// Amount 正数为入账，负数为出账
+	Amount decimal.Decimal
+	// Reason 调账原因，必填，与操作人一起写入调账记录
+	Reason   string
+	Operator string
+}
+
+// AdjustBalance 手动入账或出账，不占用交易限额
+func (s *WalletService) AdjustBalance(ctx context.Context, req AdjustmentRequest) (_ *models.BalanceAdjustment, err error) {
+	if _, err := solana.PublicKeyFromBase58(req.Address); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	if req.Amount.IsZero() {
+		return nil, fmt.Errorf("adjustment amount must not be 0")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, fmt.Errorf("adjustment reason is required")
+	}
+	if strings.TrimSpace(req.Operator) == "" {
+		return nil, fmt.Errorf("operator is required")
+	}
+
+	now := time.Now()
+	record := &models.Transaction{
+		ID:          newTransactionID(),
+		FromWallet:  "adjustment",
+		ToWallet:    req.Address,
+		Amount:      req.Amount.Abs(),
+		Asset:       models.AssetSOL,
+		Type:        "adjustment",
+		Status:      "completed",
+		CreatedAt:   now,
+		CompletedAt: now,
+	}
+	if req.Amount.IsNegative() {
+		record.FromWallet, record.ToWallet = req.Address, "adjustment"
+	}
+	adj := &models.BalanceAdjustment{
+		ID:            uuid.NewString(),
+		Address:       req.Address,
+		Amount:        req.Amount,
+		Reason:        strings.TrimSpace(req.Reason),
+		Operator:      strings.TrimSpace(req.Operator),
+		TransactionID: record.ID,
+		CreatedAt:     now,
+	}
+
+	// 调账的审计主体始终为操作人
+	ctx = WithActor(ctx, models.Actor{Type: models.ActorAdmin, ID: adj.Operator})
+	audit := s.beginAudit(ctx, models.AuditAdjustment, req.Amount, map[string]string{
+		"address": req.Address, "amount": req.Amount.String(), "reason": adj.Reason, "operator": adj.Operator,
+	}, req.Address)
+	audit.entry.Reference = adj.ID
+	defer func() { audit.finish(err) }()
+
+	// 先更新 Redis，出账时由脚本检查可用余额
+	if req.Amount.IsPositive() {
+		err := s.redis.AddBalance(ctx, req.Address, req.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update redis balance: %w", err)
+		}
+	} else if err := s.redis.SubBalance(ctx, req.Address, req.Amount.Abs()); err != nil {
+		return nil, fmt.Errorf("failed to update redis balance: %w", err)
+	}
+
+	if err := s.postgres.CreateAdjustment(ctx, adj, record); err != nil {
+		var rollbackErr error
+		if req.Amount.IsPositive() {
+			rollbackErr = s.redis.SubBalance(ctx, req.Address, req.Amount)
+		} else {
+			rollbackErr = s.redis.AddBalance(ctx, req.Address, req.Amount.Abs())
+		}
+		if rollbackErr != nil {
+			s.logger.Logger.Error("failed to rollback redis balance",
+				zap.String("address", req.Address),
+				zap.Error(rollbackErr))
+			audit.rollbackFailed(rollbackErr)
+		}
+		return nil, fmt.Errorf("failed to adjust balance: %w", err)
+	}
+
+	s.logger.Logger.Info("balance adjusted",
+		zap.String("address", req.Address),
+		zap.String("amount", req.Amount.String()),
+		zap.String("operator", adj.Operator),
+		zap.String("reason", adj.Reason))
+	s.balancesChanged(ctx, req.Address)
+	s.streams.PublishTransaction(ctx, record)
+	return adj, nil
+}
+
+// InspectBalance 对比地址在 Redis、账本与链上的余额。withChain 为 false 时不查询链上余额
+func (s *WalletService) InspectBalance(ctx context.Context, address string, withChain bool) (*models.BalanceInspection, error) {
+	if _, err := solana.PublicKeyFromBase58(address); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	inspection := &models.BalanceInspection{Address: address}
+	var err error
+	if inspection.Redis, err = s.redis.GetBalance(ctx, address); err != nil {
+		return nil, fmt.Errorf("failed to get redis balance: %w", err)
+	}
+	if inspection.RedisReserved, err = s.redis.GetReserved(ctx, address); err != nil {
+		return nil, fmt.Errorf("failed to get redis reserved: %w", err)
+	}
+	if inspection.Ledger, inspection.LedgerReserved, err = s.postgres.GetBalances(ctx, address); err != nil {
+		return nil, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+	if inspection.Pending, err = s.postgres.GetPendingAmount(ctx, address); err != nil {
+		return nil, fmt.Errorf("failed to get pending amount: %w", err)
+	}
+	inspection.Consistent = inspection.Redis.Equal(inspection.Ledger) &&
+		inspection.RedisReserved.Equal(inspection.LedgerReserved)
+
+	if withChain {
+		balance, _, err := s.solana.GetBalanceAt(ctx, address, rpc.CommitmentFinalized)
+		if err != nil {
+			inspection.OnChainError = err.Error()
+		} else {
+			inspection.OnChain = &balance
+		}
+	}
+	return inspection, nil
+}
+
+// Reconcile 对比 Redis 与账本余额，返回不一致的地址。addresses 为空时检查账本中的全部钱包。
+// fix 为 true 时以账本为准修正 Redis
+func (s *WalletService) Reconcile(ctx context.Context, addresses []string, withChain, fix bool) (checked int, mismatched []models.BalanceInspection, err error) {
+	if len(addresses) == 0 {
+		if addresses, err = s.postgres.ListWalletAddresses(ctx); err != nil {
+			return 0, nil, fmt.Errorf("failed to list wallets: %w", err)
+		}
+	}
+
+	for _, address := range addresses {
+		if ctx.Err() != nil {
+			return checked, mismatched, ctx.Err()
+		}
+		inspection, err := s.InspectBalance(ctx, address, withChain)
+		if err != nil {
+			return checked, mismatched, err
+		}
+		checked++
+
+		onChainMismatch := inspection.OnChain != nil && !inspection.OnChain.Equal(inspection.Ledger)
+		if onChainMismatch {
+			// 充值地址的链上余额已归集到热钱包，与账本不一致是正常的
+			deposit, err := s.postgres.IsDepositAddress(ctx, address)
+			if err != nil {
+				return checked, mismatched, fmt.Errorf("failed to check deposit address: %w", err)
+			}
+			onChainMismatch = !deposit
+		}
+		if inspection.Consistent && !onChainMismatch {
+			continue
+		}
+		mismatched = append(mismatched, *inspection)
+
+		if fix && !inspection.Consistent {
+			if err := s.resetRedisBalance(ctx, inspection); err != nil {
+				return checked, mismatched, err
+			}
+		}
+	}
+	return checked, mismatched, nil
+}
+
+// resetRedisBalance 以账本为准修正 Redis 余额，审计金额为对 Redis 余额的修正量
+func (s *WalletService) resetRedisBalance(ctx context.Context, inspection *models.BalanceInspection) (err error) {
+	audit := s.beginAudit(ctx, models.AuditReconcile, inspection.Ledger.Sub(inspection.Redis), inspection, inspection.Address)
+	audit.entry.Reference = "redis"
+	defer func() { audit.finish(err) }()
+
+	if err := s.redis.ResetBalance(ctx, inspection.Address, inspection.Ledger, inspection.LedgerReserved); err != nil {
+		return fmt.Errorf("failed to reset redis balance for %s: %w", inspection.Address, err)
+	}
+	s.balancesChanged(ctx, inspection.Address)
+	return nil
+}
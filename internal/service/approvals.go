@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultApprovalTTL = 24 * time.Hour
+	// maxApprovalTTL 审批期间资金通过冻结占用，不能超过冻结的最长有效期
+	maxApprovalTTL = maxHoldTTL
+	// approvalListLimit 列表接口返回的最大数量
+	approvalListLimit = 200
+)
+
+var (
+	ErrApprovalPolicyNotFound = repository.ErrApprovalPolicyNotFound
+	ErrApprovalNotFound       = repository.ErrApprovalNotFound
+	ErrApprovalNotPending     = repository.ErrApprovalNotPending
+	ErrNotApprover            = repository.ErrNotApprover
+	ErrAlreadyDecided         = repository.ErrAlreadyDecided
+	// ErrApprovalRequiresManagedWallet 需要审批的转账在批准后由服务签名，发送方必须是托管钱包
+	ErrApprovalRequiresManagedWallet = errors.New("transfer requires approval and can only be sent from a managed wallet")
+	// ErrScheduleRequiresApproval 定时转账无人值守，金额达到审批档位时不执行
+	ErrScheduleRequiresApproval = errors.New("scheduled transfer amount requires approval")
+)
+
+// PendingApprovalError 出账金额达到审批档位，已创建审批请求并冻结资金，批准后执行
+type PendingApprovalError struct {
+	Approval *models.ApprovalRequest
+}
+
+func (e *PendingApprovalError) Error() string {
+	return fmt.Sprintf("%s requires %d approvals, request %s is awaiting approval",
+		e.Approval.Operation, e.Approval.RequiredApprovals, e.Approval.ID)
+}
+
+// SetApprovalPolicy 创建或更新审批策略，同一地址与金额档位只有一条
+func (s *WalletService) SetApprovalPolicy(ctx context.Context, p *models.ApprovalPolicy) (*models.ApprovalPolicy, error) {
+	if p.Address != "" {
+		if _, err := solana.PublicKeyFromBase58(p.Address); err != nil {
+			return nil, fmt.Errorf("invalid address: %w", err)
+		}
+	}
+	if p.MinAmount.IsNegative() {
+		return nil, fmt.Errorf("min_amount must not be negative")
+	}
+
+	// 去重并去掉空白
+	seen := make(map[string]bool)
+	approvers := make([]string, 0, len(p.Approvers))
+	for _, approver := range p.Approvers {
+		approver = strings.TrimSpace(approver)
+		if approver == "" || seen[approver] {
+			continue
+		}
+		seen[approver] = true
+		approvers = append(approvers, approver)
+	}
+	p.Approvers = approvers
+	if p.RequiredApprovals <= 0 {
+		return nil, fmt.Errorf("required_approvals must be greater than 0")
+	}
+	if p.RequiredApprovals > len(p.Approvers) {
+		return nil, fmt.Errorf("required_approvals is greater than the number of approvers")
+	}
+
+	if p.TTLSeconds == 0 {
+		p.TTLSeconds = int64(defaultApprovalTTL / time.Second)
+	}
+	if ttl := time.Duration(p.TTLSeconds) * time.Second; ttl <= 0 || ttl > maxApprovalTTL {
+		return nil, fmt.Errorf("ttl_seconds must be between 1 and %d", int64(maxApprovalTTL/time.Second))
+	}
+
+	return s.postgres.UpsertApprovalPolicy(ctx, p)
+}
+
+// ListApprovalPolicies 查询审批策略，address 为空时返回全部
+func (s *WalletService) ListApprovalPolicies(ctx context.Context, address string) ([]models.ApprovalPolicy, error) {
+	return s.postgres.ListApprovalPolicies(ctx, address)
+}
+
+// DeleteApprovalPolicy 删除审批策略，已创建的审批请求不受影响
+func (s *WalletService) DeleteApprovalPolicy(ctx context.Context, id int64) error {
+	return s.postgres.DeleteApprovalPolicy(ctx, id)
+}
+
+// requestApproval 金额达到审批档位时冻结资金并创建审批请求，不需要审批时返回 nil
+func (s *WalletService) requestApproval(ctx context.Context, operation, from, to string, amount decimal.Decimal) (*models.ApprovalRequest, error) {
+	policy, err := s.postgres.MatchApprovalPolicy(ctx, from, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match approval policy: %w", err)
+	}
+	if policy == nil {
+		return nil, nil
+	}
+
+	if operation == models.ApprovalTransfer {
+		if _, err := s.managedKey(ctx, from); err != nil {
+			if errors.Is(err, ErrManagedWalletNotFound) || errors.Is(err, ErrKeystoreDisabled) {
+				return nil, ErrApprovalRequiresManagedWallet
+			}
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	ttl := time.Duration(policy.TTLSeconds) * time.Second
+	approval := &models.ApprovalRequest{
+		ID:                uuid.NewString(),
+		Operation:         operation,
+		ClientID:          ClientIDFromContext(ctx),
+		ClientReference:   ClientReferenceFromContext(ctx),
+		FromAddress:       from,
+		ToAddress:         to,
+		Amount:            amount,
+		PolicyID:          policy.ID,
+		RequiredApprovals: policy.RequiredApprovals,
+		Approvers:         policy.Approvers,
+		Status:            models.ApprovalAwaiting,
+		ExpiresAt:         now.Add(ttl),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	// 冻结有效期与审批一致，审批期间资金不可用。系统冻结只由审批流程和到期任务释放
+	hold, err := s.createHold(ctx, from, amount, ttl, "approval:"+approval.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	approval.HoldID = hold.ID
+
+	if err := s.postgres.CreateApprovalRequest(ctx, approval); err != nil {
+		if _, releaseErr := s.releaseHold(ctx, hold.ID, models.HoldStatusReleased); releaseErr != nil {
+			s.logger.Logger.Error("failed to release approval hold",
+				zap.String("hold", hold.ID),
+				zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+
+	s.logger.Logger.Info("approval requested",
+		zap.String("approval", approval.ID),
+		zap.String("operation", operation),
+		zap.String("from", from),
+		zap.String("amount", amount.String()))
+	return approval, nil
+}
+
+// GetApproval 查询审批请求。API 客户端只能查询自己发起的请求
+func (s *WalletService) GetApproval(ctx context.Context, id string) (*models.ApprovalRequest, error) {
+	approval, err := s.postgres.GetApprovalRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin && approval.ClientID != ClientIDFromContext(ctx) {
+		return nil, ErrApprovalNotFound
+	}
+	return approval, nil
+}
+
+// ListApprovals 查询审批请求，status 为空时返回全部。管理员可按 clientID 筛选，API 客户端只返回自己的请求
+func (s *WalletService) ListApprovals(ctx context.Context, clientID, status string) ([]models.ApprovalRequest, error) {
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin {
+		if clientID = ClientIDFromContext(ctx); clientID == "" {
+			return nil, ErrClientRequired
+		}
+	}
+	return s.postgres.ListApprovalRequests(ctx, clientID, status, approvalListLimit)
+}
+
+// ApproveRequest 批准请求，批准人数达到要求时立即执行
+func (s *WalletService) ApproveRequest(ctx context.Context, id, comment string) (*models.ApprovalRequest, error) {
+	return s.decideApproval(ctx, id, models.DecisionApprove, comment)
+}
+
+// RejectRequest 拒绝请求并释放冻结资金，任一审批人拒绝即拒绝
+func (s *WalletService) RejectRequest(ctx context.Context, id, comment string) (*models.ApprovalRequest, error) {
+	return s.decideApproval(ctx, id, models.DecisionReject, comment)
+}
+
+func (s *WalletService) decideApproval(ctx context.Context, id, decision, comment string) (*models.ApprovalRequest, error) {
+	// 审批人为管理令牌对应的操作人，共用令牌不能审批
+	actor := ActorFromContext(ctx)
+	if actor.Type != models.ActorAdmin || actor.ID == "" || actor.ID == SharedAdminOperator {
+		return nil, ErrNotApprover
+	}
+
+	approval, err := s.postgres.DecideApproval(ctx, id, models.ApprovalDecision{
+		Approver: actor.ID,
+		Decision: decision,
+		Comment:  strings.TrimSpace(comment),
+	}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Logger.Info("approval decided",
+		zap.String("approval", id),
+		zap.String("approver", actor.ID),
+		zap.String("decision", decision),
+		zap.String("status", approval.Status))
+
+	switch approval.Status {
+	case models.ApprovalRejected:
+		s.releaseApprovalHold(ctx, approval)
+	case models.ApprovalApproved:
+		s.executeApproval(ctx, approval)
+	}
+	return approval, nil
+}
+
+// executeApproval 执行已批准的请求，结果写回审批请求
+func (s *WalletService) executeApproval(ctx context.Context, approval *models.ApprovalRequest) {
+	// 批准后执行不随请求取消中断；限额、事件与客户端引用按发起请求的 API 客户端计算
+	ctx = WithClientID(context.WithoutCancel(ctx), approval.ClientID)
+	ctx = WithClientReference(ctx, approval.ClientReference)
+
+	// 解除冻结后立即执行，提现引用交易 ID，转账引用链上签名
+	reference := ""
+	_, err := s.releaseHold(ctx, approval.HoldID, models.HoldStatusReleased)
+	if err == nil {
+		var tx *models.Transaction
+		switch approval.Operation {
+		case models.ApprovalWithdraw:
+			if tx, err = s.withdraw(ctx, approval.FromAddress, approval.Amount); tx != nil {
+				reference = tx.ID
+			}
+		case models.ApprovalTransfer:
+			var key solanaclient.Key
+			if key, err = s.managedKey(ctx, approval.FromAddress); err == nil {
+				if tx, err = s.transfer(ctx, key, approval.ToAddress, approval.Amount); tx != nil {
+					reference = tx.ChainSignature
+				}
+			}
+		default:
+			err = fmt.Errorf("unknown operation: %s", approval.Operation)
+		}
+	}
+
+	// 有交易 ID 说明资金已转出，即使之后记账失败也视为已执行
+	approval.Status = models.ApprovalExecuted
+	if reference == "" {
+		approval.Status = models.ApprovalFailed
+	}
+	approval.Reference = reference
+	if err != nil {
+		approval.Error = err.Error()
+		s.logger.Logger.Error("failed to execute approved request",
+			zap.String("approval", approval.ID),
+			zap.String("reference", reference),
+			zap.Error(err))
+	}
+	if err := s.postgres.FinishApprovalRequest(ctx, approval.ID, approval.Status, reference, approval.Error); err != nil {
+		s.logger.Logger.Error("failed to finish approval request",
+			zap.String("approval", approval.ID),
+			zap.Error(err))
+	}
+}
+
+func (s *WalletService) releaseApprovalHold(ctx context.Context, approval *models.ApprovalRequest) {
+	if _, err := s.releaseHold(ctx, approval.HoldID, models.HoldStatusReleased); err != nil && !errors.Is(err, ErrHoldNotActive) {
+		s.logger.Logger.Error("failed to release approval hold",
+			zap.String("approval", approval.ID),
+			zap.String("hold", approval.HoldID),
+			zap.Error(err))
+	}
+}
+
+// ExpireApprovals 将过期的待审批请求标记为 expired 并释放冻结资金，返回处理数量
+func (s *WalletService) ExpireApprovals(ctx context.Context) (int, error) {
+	approvals, err := s.postgres.ExpireApprovalRequests(ctx, time.Now(), holdExpiryBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx = WithActor(ctx, models.Actor{Type: models.ActorSystem, ID: "approval-expirer"})
+	for i := range approvals {
+		s.releaseApprovalHold(ctx, &approvals[i])
+	}
+	return len(approvals), nil
+}
+
+// RunApprovalExpirer 定期处理过期的审批请求，直到 ctx 取消
+func (s *WalletService) RunApprovalExpirer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.ExpireApprovals(ctx); err != nil {
+				s.logger.Logger.Error("failed to expire approvals", zap.Error(err))
+			} else if n > 0 {
+				s.logger.Logger.Info("expired approvals", zap.Int("count", n))
+			}
+		}
+	}
+}
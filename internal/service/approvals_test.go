@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalPolicyValidation(t *testing.T) {
+	s := &WalletService{}
+	ctx := context.Background()
+
+	invalid := []models.ApprovalPolicy{
+		{Address: "not-an-address", RequiredApprovals: 1, Approvers: []string{"alice"}},
+		{MinAmount: decimal.NewFromInt(-1), RequiredApprovals: 1, Approvers: []string{"alice"}},
+		{RequiredApprovals: 0, Approvers: []string{"alice"}},
+		// 重复的审批人只算一个
+		{RequiredApprovals: 2, Approvers: []string{"alice", " alice", ""}},
+		{RequiredApprovals: 1, Approvers: []string{"alice"}, TTLSeconds: -1},
+		{RequiredApprovals: 1, Approvers: []string{"alice"}, TTLSeconds: 8 * 24 * 3600},
+	}
+	for _, p := range invalid {
+		p := p
+		_, err := s.SetApprovalPolicy(ctx, &p)
+		assert.Error(t, err, "%+v", p)
+	}
+}
+
+func TestDecideApprovalRequiresAdmin(t *testing.T) {
+	s := &WalletService{}
+
+	_, err := s.ApproveRequest(context.Background(), "id", "")
+	assert.ErrorIs(t, err, ErrNotApprover)
+
+	ctx := WithClientID(context.Background(), "client-1")
+	_, err = s.RejectRequest(ctx, "id", "")
+	assert.ErrorIs(t, err, ErrNotApprover)
+
+	// 共用管理令牌无法对应到具体操作人
+	ctx = WithActor(context.Background(), models.Actor{Type: models.ActorAdmin, ID: SharedAdminOperator})
+	_, err = s.ApproveRequest(ctx, "id", "")
+	assert.ErrorIs(t, err, ErrNotApprover)
+}
+
+func TestApprovalHoldIsSystemOwned(t *testing.T) {
+	s := newServiceWithPostgres(t)
+	_, client := newTestClient(t, s, "approval-hold")
+
+	address := solana.NewWallet().PublicKey().String()
+	_, err := s.Deposit(context.Background(), address, decimal.NewFromInt(5))
+	require.NoError(t, err)
+
+	// 与 requestApproval 相同，冻结归属发起请求的客户端，但为系统冻结
+	hold, err := s.createHold(client, address, decimal.NewFromInt(2), time.Minute, "approval:test", true)
+	require.NoError(t, err)
+
+	got, err := s.GetHold(client, hold.ID)
+	require.NoError(t, err)
+	assert.True(t, got.System)
+	_, err = s.CaptureHold(client, hold.ID, nil)
+	assert.ErrorIs(t, err, ErrSystemHold)
+	_, err = s.ReleaseHold(client, hold.ID)
+	assert.ErrorIs(t, err, ErrSystemHold)
+	admin := WithActor(context.Background(), models.Actor{Type: models.ActorAdmin, ID: "alice"})
+	_, err = s.ReleaseHold(admin, hold.ID)
+	assert.ErrorIs(t, err, ErrSystemHold)
+
+	released, err := s.releaseHold(client, hold.ID, models.HoldStatusReleased)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusReleased, released.Status)
+}
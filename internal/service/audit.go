@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/audit"
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// auditVerifyBatch 校验哈希链时每次读取的记录数
+	auditVerifyBatch = 1000
+)
+
+// auditOp 一次需要审计的操作。beginAudit 记录操作前的账本余额，finish 记录操作后的余额并写入审计日志
+type auditOp struct {
+	s           *WalletService
+	ctx         context.Context
+	entry       *models.AuditEntry
+	rollbackErr error
+}
+
+// beginAudit 开始审计操作。HTTP 请求使用请求体的哈希，其他调用方使用 params 的 JSON 哈希
+func (s *WalletService) beginAudit(ctx context.Context, operation string, amount decimal.Decimal, params interface{}, addresses ...string) *auditOp {
+	// 请求取消后仍需写入审计日志
+	ctx = context.WithoutCancel(ctx)
+	meta := RequestMetaFromContext(ctx)
+	if meta.PayloadHash == "" {
+		meta.PayloadHash = hashParams(params)
+	}
+
+	entry := &models.AuditEntry{
+		ID:            uuid.NewString(),
+		Actor:         ActorFromContext(ctx),
+		Operation:     operation,
+		Addresses:     addresses,
+		Amount:        amount,
+		PayloadHash:   meta.PayloadHash,
+		IP:            meta.IP,
+		CorrelationID: meta.CorrelationID,
+		CreatedAt:     time.Now(),
+	}
+	entry.BalancesBefore = s.auditBalances(ctx, addresses)
+	return &auditOp{s: s, ctx: ctx, entry: entry}
+}
+
+// rollbackFailed 记录回滚失败，审计结果标记为 rollback_failed
+func (op *auditOp) rollbackFailed(err error) {
+	op.rollbackErr = errors.Join(op.rollbackErr, err)
+}
+
+// finish 记录操作结果与操作后的余额。写入失败只记录日志，不影响业务结果
+func (op *auditOp) finish(err error) {
+	e := op.entry
+	e.Outcome = models.AuditSucceeded
+	if err != nil {
+		e.Outcome = models.AuditFailed
+		e.Error = err.Error()
+	}
+	if op.rollbackErr != nil {
+		e.Outcome = models.AuditRollbackFailed
+		e.Error = fmt.Sprintf("%s; rollback: %s", e.Error, op.rollbackErr)
+	}
+	e.BalancesAfter = op.s.auditBalances(op.ctx, e.Addresses)
+
+	if err := op.s.postgres.AppendAudit(op.ctx, e); err != nil {
+		op.s.logger.Logger.Error("failed to append audit entry",
+			zap.String("operation", e.Operation),
+			zap.String("id", e.ID),
+			zap.Strings("addresses", e.Addresses),
+			zap.String("outcome", e.Outcome),
+			zap.Error(err))
+	}
+}
+
+// auditBalances 查询地址的账本余额，查询失败的地址不记录
+func (s *WalletService) auditBalances(ctx context.Context, addresses []string) map[string]models.AuditBalance {
+	balances := make(map[string]models.AuditBalance, len(addresses))
+	for _, address := range addresses {
+		balance, reserved, err := s.postgres.GetBalances(ctx, address)
+		if err != nil {
+			s.logger.Logger.Error("failed to get audit balance",
+				zap.String("address", address),
+				zap.Error(err))
+			continue
+		}
+		balances[address] = models.AuditBalance{Balance: balance, Reserved: reserved}
+	}
+	return balances
+}
+
+func hashParams(params interface{}) string {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ListAudit 查询审计日志，按 seq 倒序
+func (s *WalletService) ListAudit(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	return s.postgres.ListAudit(ctx, filter)
+}
+
+// VerifyAudit 从第一条记录开始校验哈希链，返回最后一条通过校验的记录。
+// 发现篡改时 Valid 为 false，BrokenAt 为第一条校验失败的记录
+func (s *WalletService) VerifyAudit(ctx context.Context) (*models.AuditVerification, error) {
+	result := &models.AuditVerification{HeadHash: audit.GenesisHash, Valid: true}
+	for {
+		entries, err := s.postgres.AuditEntriesAfter(ctx, result.HeadSeq, auditVerifyBatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		head, err := audit.Verify(result.HeadHash, entries)
+		if err != nil {
+			var chainErr *audit.ChainError
+			if !errors.As(err, &chainErr) {
+				return nil, err
+			}
+			for _, e := range entries {
+				if e.Seq == chainErr.Seq {
+					break
+				}
+				result.Checked++
+				result.HeadSeq = e.Seq
+			}
+			result.HeadHash = head
+			result.Valid = false
+			result.BrokenAt = chainErr.Seq
+			result.Error = chainErr.Reason
+			return result, nil
+		}
+
+		result.Checked += int64(len(entries))
+		result.HeadHash = head
+		if len(entries) > 0 {
+			result.HeadSeq = entries[len(entries)-1].Seq
+		}
+		if len(entries) < auditVerifyBatch {
+			return result, nil
+		}
+	}
+}
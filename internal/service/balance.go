@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"go.uber.org/zap"
+)
+
+// BalanceOptions 余额查询选项
+type BalanceOptions struct {
+	// Fresh 跳过缓存，直接查询账本和链上余额
+	Fresh bool
+	// Commitment 链上余额的确认级别，默认 finalized
+	Commitment string
+}
+
+// GetBalanceView 返回账本可用、冻结、待处理余额以及链上余额，优先读取缓存
+func (s *WalletService) GetBalanceView(ctx context.Context, address string, opts BalanceOptions) (*models.BalanceView, error) {
+	// 验证地址
+	if _, err := solana.PublicKeyFromBase58(address); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	commitment := rpc.CommitmentType(opts.Commitment)
+	switch commitment {
+	case "":
+		commitment = rpc.CommitmentFinalized
+	case rpc.CommitmentFinalized, rpc.CommitmentConfirmed, rpc.CommitmentProcessed:
+	default:
+		return nil, fmt.Errorf("invalid commitment: %s", opts.Commitment)
+	}
+
+	if !opts.Fresh {
+		if view := s.cachedBalanceView(ctx, address, string(commitment)); view != nil {
+			return view, nil
+		}
+	}
+
+	view, err := s.loadBalanceView(ctx, address, commitment)
+	if err != nil {
+		return nil, err
+	}
+
+	// 链上查询失败的结果不缓存，下次请求重新查询
+	if view.OnChain != nil && s.cfg.BalanceCacheTTL > 0 {
+		if data, err := json.Marshal(view); err == nil {
+			if err := s.redis.SetBalanceView(ctx, address, string(commitment), string(data), s.cfg.BalanceCacheTTL); err != nil {
+				s.logger.Logger.Warn("failed to cache balance view",
+					zap.String("address", address),
+					zap.Error(err))
+			}
+		}
+	}
+	return view, nil
+}
+
+func (s *WalletService) cachedBalanceView(ctx context.Context, address, commitment string) *models.BalanceView {
+	data, err := s.redis.GetBalanceView(ctx, address, commitment)
+	if err != nil {
+		s.logger.Logger.Warn("failed to read balance cache",
+			zap.String("address", address),
+			zap.Error(err))
+		return nil
+	}
+	if data == "" {
+		return nil
+	}
+	var view models.BalanceView
+	if err := json.Unmarshal([]byte(data), &view); err != nil {
+		return nil
+	}
+	view.Cached = true
+	return &view
+}
+
+func (s *WalletService) loadBalanceView(ctx context.Context, address string, commitment rpc.CommitmentType) (*models.BalanceView, error) {
+	// 账本余额以 Postgres 为准
+	ledger, reserved, err := s.postgres.GetBalances(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+	pending, err := s.postgres.GetPendingAmount(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending amount: %w", err)
+	}
+	holds, err := s.postgres.ListHolds(ctx, address, models.HoldStatusActive, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holds: %w", err)
+	}
+
+	view := &models.BalanceView{
+		Address:   address,
+		Available: ledger.Sub(reserved),
+		Reserved:  reserved,
+		Pending:   pending,
+		Holds:     holds,
+		AsOf:      time.Now(),
+	}
+
+	balance, slot, err := s.solana.GetBalanceAt(ctx, address, commitment)
+	if err != nil {
+		s.logger.Logger.Warn("failed to get on-chain balance",
+			zap.String("address", address),
+			zap.Error(err))
+		view.OnChainError = err.Error()
+	} else {
+		view.OnChain = &models.OnChainBalance{
+			Balance:    balance,
+			Commitment: string(commitment),
+			Slot:       slot,
+		}
+	}
+	return view, nil
+}
+
+// balancesChanged 余额变动后清除缓存并推送最新余额
+func (s *WalletService) balancesChanged(ctx context.Context, addresses ...string) {
+	if err := s.redis.DeleteBalanceViews(ctx, addresses...); err != nil {
+		s.logger.Logger.Warn("failed to invalidate balance cache",
+			zap.Strings("addresses", addresses),
+			zap.Error(err))
+	}
+	s.streams.PublishBalances(ctx, addresses...)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	"mywallet/pkg/logger"
+)
+
+// apiKeyPrefix 便于在日志和配置中识别 API Key
+const apiKeyPrefix = "mw_"
+
+var ErrClientNotFound = repository.ErrClientNotFound
+
+type ClientService struct {
+	logger   *logger.Logger
+	postgres *repository.PostgresRepository
+}
+
+func NewClientService(logger *logger.Logger, postgres *repository.PostgresRepository) *ClientService {
+	return &ClientService{logger: logger, postgres: postgres}
+}
+
+// CreateClient 创建 API 客户端，返回的明文 API Key 只在此时可见
+func (s *ClientService) CreateClient(ctx context.Context, name string) (*models.APIClient, string, error) {
+	if name == "" {
+		return nil, "", fmt.Errorf("client name is required")
+	}
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	client, err := s.postgres.CreateAPIClient(ctx, name, HashAPIKey(key))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create api client: %w", err)
+	}
+	return client, key, nil
+}
+
+// ListClients 查询全部 API 客户端
+func (s *ClientService) ListClients(ctx context.Context) ([]models.APIClient, error) {
+	return s.postgres.ListAPIClients(ctx)
+}
+
+// RotateAPIKey 为客户端生成新的 API Key，旧 Key 立即失效。返回的明文 API Key 只在此时可见
+func (s *ClientService) RotateAPIKey(ctx context.Context, id string) (*models.APIClient, string, error) {
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	client, err := s.postgres.RotateAPIClientKey(ctx, id, HashAPIKey(key))
+	if err != nil {
+		return nil, "", err
+	}
+	return client, key, nil
+}
+
+// Authenticate 校验 API Key，无效时返回 nil
+func (s *ClientService) Authenticate(ctx context.Context, apiKey string) (*models.APIClient, error) {
+	return s.postgres.GetAPIClientByKeyHash(ctx, HashAPIKey(apiKey))
+}
+
+// HashAPIKey 计算 API Key 的存储摘要
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+
+	"mywallet/internal/models"
+)
+
+type contextKey int
+
+const (
+	clientIDKey contextKey = iota
+	actorKey
+	requestMetaKey
+	clientReferenceKey
+)
+
+// WithClientID 在 context 中记录发起请求的 API 客户端
+func WithClientID(ctx context.Context, clientID string) context.Context {
+	return context.WithValue(ctx, clientIDKey, clientID)
+}
+
+// ClientIDFromContext 获取发起请求的 API 客户端 ID，匿名请求返回空字符串
+func ClientIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(clientIDKey).(string)
+	return id
+}
+
+// WithActor 在 context 中记录发起操作的主体，用于审计日志
+func WithActor(ctx context.Context, actor models.Actor) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext 获取发起操作的主体。未显式设置时按 API 客户端识别，都没有时为匿名
+func ActorFromContext(ctx context.Context) models.Actor {
+	if actor, ok := ctx.Value(actorKey).(models.Actor); ok {
+		return actor
+	}
+	if id := ClientIDFromContext(ctx); id != "" {
+		return models.Actor{Type: models.ActorAPIClient, ID: id}
+	}
+	return models.Actor{Type: models.ActorAnonymous}
+}
+
+// SharedAdminOperator 使用共用管理令牌时记录的操作人，无法对应到具体的人，不能作为审批人
+const SharedAdminOperator = "admin-token"
+
+// RequestMeta 请求的来源信息，写入审计日志
+type RequestMeta struct {
+	IP            string
+	CorrelationID string
+	// PayloadHash 请求体的 SHA-256
+	PayloadHash string
+}
+
+// WithRequestMeta 在 context 中记录请求来源信息
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey, meta)
+}
+
+// RequestMetaFromContext 获取请求来源信息，非 HTTP 请求返回零值
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey).(RequestMeta)
+	return meta
+}
+
+// WithClientReference 在 context 中记录客户端提交的交易引用
+func WithClientReference(ctx context.Context, ref string) context.Context {
+	return context.WithValue(ctx, clientReferenceKey, ref)
+}
+
+// ClientReferenceFromContext 获取客户端提交的交易引用，未提交时返回空字符串
+func ClientReferenceFromContext(ctx context.Context) string {
+	ref, _ := ctx.Value(clientReferenceKey).(string)
+	return ref
+}
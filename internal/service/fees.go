@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// sponsoredTransferFee 代付转账的手续费：发送方与代付钱包各一个签名
+var sponsoredTransferFee = decimal.New(2*lamportsPerSignature, -9)
+
+// feePayerFor 返回为托管钱包交易代付手续费的密钥，以及已计入 API 客户端本月额度的代付记录。
+// 以下情况返回 nil，由发送方自行支付：未配置代付钱包、发送方不是托管钱包、代付钱包余额不足、
+// API 客户端本月代付已达上限。交易未发出时由调用方 releaseSponsoredFee 退回额度
+func (s *WalletService) feePayerFor(ctx context.Context, from string) (*solanaclient.Key, *models.SponsoredFee) {
+	if s.cfg.FeePayerAddress == "" || s.signer == nil || from == s.cfg.FeePayerAddress {
+		return nil, nil
+	}
+	if _, err := s.postgres.GetManagedWallet(ctx, from); err != nil {
+		if !errors.Is(err, ErrManagedWalletNotFound) {
+			s.logger.Logger.Error("failed to check managed wallet", zap.String("address", from), zap.Error(err))
+		}
+		return nil, nil
+	}
+
+	balance, err := s.solana.GetBalance(ctx, s.cfg.FeePayerAddress)
+	if err != nil {
+		s.logger.Logger.Warn("failed to get fee payer balance, sender pays the fee", zap.Error(err))
+		return nil, nil
+	}
+	if balance.LessThan(s.cfg.FeePayerMinBalance) {
+		s.logger.Logger.Warn("fee payer is underfunded, sender pays the fee",
+			zap.String("fee_payer", s.cfg.FeePayerAddress),
+			zap.String("balance", balance.String()))
+		return nil, nil
+	}
+
+	key, err := s.managedKey(ctx, s.cfg.FeePayerAddress)
+	if err != nil {
+		s.logger.Logger.Error("failed to load fee payer", zap.Error(err))
+		return nil, nil
+	}
+
+	// 发送前占用额度，并发的交易不会同时通过上限检查
+	fee := &models.SponsoredFee{
+		ClientID:  ClientIDFromContext(ctx),
+		Address:   from,
+		FeePayer:  s.cfg.FeePayerAddress,
+		Amount:    sponsoredTransferFee,
+		CreatedAt: time.Now().UTC(),
+	}
+	since, _ := monthRange(fee.CreatedAt)
+	ok, err := s.postgres.ReserveSponsoredFee(ctx, fee, since, s.cfg.FeeSponsorMonthlyCap)
+	if err != nil {
+		s.logger.Logger.Error("failed to reserve sponsored fee", zap.String("client", fee.ClientID), zap.Error(err))
+		return nil, nil
+	}
+	if !ok {
+		s.logger.Logger.Info("fee sponsorship cap reached, sender pays the fee",
+			zap.String("client", fee.ClientID))
+		return nil, nil
+	}
+	return &key, fee
+}
+
+// recordSponsoredFee 记录代付交易的签名。交易已发送，失败只记录日志
+func (s *WalletService) recordSponsoredFee(ctx context.Context, fee *models.SponsoredFee, signature string) {
+	if err := s.postgres.SetSponsoredFeeSignature(ctx, fee.ID, signature); err != nil {
+		s.logger.Logger.Error("failed to record sponsored fee",
+			zap.String("client", fee.ClientID),
+			zap.String("signature", signature),
+			zap.Error(err))
+	}
+}
+
+// releaseSponsoredFee 交易未发出时删除代付记录，退回额度
+func (s *WalletService) releaseSponsoredFee(ctx context.Context, fee *models.SponsoredFee) {
+	if err := s.postgres.DeleteSponsoredFee(ctx, fee.ID); err != nil {
+		s.logger.Logger.Error("failed to release sponsored fee",
+			zap.String("client", fee.ClientID),
+			zap.Int64("id", fee.ID),
+			zap.Error(err))
+	}
+}
+
+// SponsoredFees 查询 API 客户端本月的代付手续费与上限
+func (s *WalletService) SponsoredFees(ctx context.Context) (*models.SponsoredFeeSummary, error) {
+	clientID := ClientIDFromContext(ctx)
+	if clientID == "" {
+		return nil, ErrClientRequired
+	}
+	since, until := monthRange(time.Now())
+	count, total, err := s.postgres.SponsoredFeeTotal(ctx, clientID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SponsoredFeeSummary{
+		ClientID:    clientID,
+		Count:       count,
+		Total:       total,
+		Cap:         s.cfg.FeeSponsorMonthlyCap,
+		PeriodStart: since,
+		PeriodEnd:   until,
+	}, nil
+}
+
+// SummarizeSponsoredFees 按 API 客户端汇总 [since, until) 内的代付手续费，默认为本月
+func (s *WalletService) SummarizeSponsoredFees(ctx context.Context, since, until *time.Time) ([]models.SponsoredFeeSummary, error) {
+	start, end := monthRange(time.Now())
+	if since != nil {
+		start = since.UTC()
+	}
+	if until != nil {
+		end = until.UTC()
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("since must be before until")
+	}
+
+	summaries, err := s.postgres.SummarizeSponsoredFees(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for i := range summaries {
+		summaries[i].Cap = s.cfg.FeeSponsorMonthlyCap
+	}
+	return summaries, nil
+}
+
+// monthRange 返回 t 所在 UTC 自然月的起止时间
+func monthRange(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mywallet/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonthRange(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	// 东八区 3 月 1 日凌晨仍属于 UTC 的 2 月
+	start, end := monthRange(time.Date(2024, 3, 1, 2, 0, 0, 0, loc))
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), end)
+
+	start, end = monthRange(time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), end)
+}
+
+func TestFeePayerDisabled(t *testing.T) {
+	s := &WalletService{cfg: &config.Config{}}
+	key, fee := s.feePayerFor(context.Background(), "11111111111111111111111111111111")
+	assert.Nil(t, key)
+	assert.Nil(t, fee)
+
+	_, err := s.SponsoredFees(context.Background())
+	assert.ErrorIs(t, err, ErrClientRequired)
+
+	now := time.Now()
+	_, err = s.SummarizeSponsoredFees(context.Background(), &now, &now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"mywallet/internal/config"
+	"mywallet/internal/models"
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+	"go.uber.org/zap"
+)
+
+// defaultHDGap 恢复时连续多少个未使用的派生地址后停止扫描
+const defaultHDGap = 20
+
+var ErrHDDisabled = errors.New("hd wallets are disabled: HD_MNEMONIC is not set")
+
+// HDRecoverOptions HD 钱包恢复参数
+type HDRecoverOptions struct {
+	// Start 起始派生序号
+	Start int64
+	// Gap 连续未使用的地址数达到该值后停止，默认 20
+	Gap int
+	// Restore 将链上有记录但数据库中缺失的地址恢复为托管钱包
+	Restore bool
+}
+
+// deriveKey 分配派生序号并派生私钥
+func (s *WalletService) deriveKey(ctx context.Context) (solana.PrivateKey, *int64, error) {
+	index, err := s.postgres.NextDerivationIndex(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to allocate derivation index: %w", err)
+	}
+	if index > solanaclient.MaxDerivationIndex {
+		return nil, nil, fmt.Errorf("derivation index %d is out of range", index)
+	}
+	key, err := solanaclient.DeriveKey(s.seed, uint32(index))
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, &index, nil
+}
+
+// RecoverHDWallets 从助记词重新派生地址并扫描链上记录，直到连续 Gap 个地址未使用。
+// 数据库中已有的地址视为已使用；Restore 时恢复缺失的地址并推进派生序号，避免之后重复分配
+func (s *WalletService) RecoverHDWallets(ctx context.Context, opts HDRecoverOptions) (*models.HDRecoveryReport, error) {
+	if s.seed == nil {
+		return nil, ErrHDDisabled
+	}
+	if opts.Restore && s.keys == nil {
+		return nil, ErrKeystoreDisabled
+	}
+	if opts.Start < 0 || opts.Start > solanaclient.MaxDerivationIndex {
+		return nil, fmt.Errorf("invalid start index %d", opts.Start)
+	}
+	if opts.Gap <= 0 {
+		opts.Gap = defaultHDGap
+	}
+
+	report := &models.HDRecoveryReport{Wallets: make([]models.HDWallet, 0)}
+	last := opts.Start - 1
+	for index := opts.Start; index <= solanaclient.MaxDerivationIndex && index-last <= int64(opts.Gap); index++ {
+		wallet, err := s.scanHDWallet(ctx, index, opts.Restore)
+		if err != nil {
+			return report, err
+		}
+		report.Scanned++
+		if wallet.Status == models.HDWalletUnused {
+			continue
+		}
+		last = index
+		switch wallet.Status {
+		case models.HDWalletRestored:
+			report.Restored++
+		case models.HDWalletMissing:
+			report.Missing++
+		case models.HDWalletMismatch:
+			report.Mismatched++
+		}
+		report.Wallets = append(report.Wallets, *wallet)
+	}
+	report.NextIndex = last + 1
+
+	if opts.Restore && last >= opts.Start {
+		if err := s.postgres.AdvanceDerivationIndex(ctx, last); err != nil {
+			return report, fmt.Errorf("failed to advance derivation index: %w", err)
+		}
+	}
+	s.logger.Logger.Info("hd wallets scanned",
+		zap.Int("scanned", report.Scanned),
+		zap.Int("restored", report.Restored),
+		zap.Int("missing", report.Missing),
+		zap.Int("mismatched", report.Mismatched),
+		zap.Int64("next_index", report.NextIndex))
+	return report, nil
+}
+
+// scanHDWallet 派生 index 对应的地址，查询数据库与链上记录
+func (s *WalletService) scanHDWallet(ctx context.Context, index int64, restore bool) (*models.HDWallet, error) {
+	key, err := solanaclient.DeriveKey(s.seed, uint32(index))
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	address := key.PublicKey().String()
+	wallet := &models.HDWallet{Index: index, Path: solanaclient.DerivationPath(uint32(index)), Address: address}
+
+	if wallet.Balance, err = s.solana.GetBalance(ctx, address); err != nil {
+		return nil, fmt.Errorf("failed to get balance of %s: %w", address, err)
+	}
+	if wallet.Used, err = s.solana.HasTransactions(ctx, key.PublicKey()); err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", address, err)
+	}
+	wallet.Used = wallet.Used || wallet.Balance.IsPositive()
+
+	_, err = s.postgres.GetManagedWallet(ctx, address)
+	switch {
+	case err == nil:
+		wallet.Status = models.HDWalletKnown
+		return wallet, nil
+	case !errors.Is(err, ErrManagedWalletNotFound):
+		return nil, err
+	}
+	if other, err := s.postgres.GetManagedWalletByIndex(ctx, index); err == nil {
+		s.logger.Logger.Warn("derivation index is held by another address",
+			zap.Int64("index", index), zap.String("derived", address), zap.String("stored", other.Address))
+		wallet.Status = models.HDWalletMismatch
+		return wallet, nil
+	} else if !errors.Is(err, ErrManagedWalletNotFound) {
+		return nil, err
+	}
+	if !wallet.Used {
+		wallet.Status = models.HDWalletUnused
+		return wallet, nil
+	}
+	if !restore {
+		wallet.Status = models.HDWalletMissing
+		return wallet, nil
+	}
+
+	encrypted, err := s.keys.Encrypt(address, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	version := s.keys.Version()
+	w := &models.ManagedWallet{Address: address, Signer: config.SignerKeystore, KeyID: address,
+		DerivationIndex: &index, KeyVersion: &version}
+	if err := s.postgres.CreateManagedWallet(ctx, w, encrypted); err != nil {
+		return nil, fmt.Errorf("failed to restore %s: %w", address, err)
+	}
+	s.logger.Logger.Info("hd wallet restored", zap.Int64("index", index), zap.String("address", address))
+	wallet.Status = models.HDWalletRestored
+	return wallet, nil
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHoldTTL = 15 * time.Minute
+	maxHoldTTL     = 7 * 24 * time.Hour
+	// holdExpiryBatch 到期任务每轮处理的最大数量
+	holdExpiryBatch = 100
+)
+
+var (
+	ErrHoldNotFound  = repository.ErrHoldNotFound
+	ErrHoldNotActive = repository.ErrHoldNotActive
+	// ErrSystemHold 系统冻结只能由创建它的内部流程释放
+	ErrSystemHold = repository.ErrSystemHold
+)
+
+// CreateHold 冻结资金，ttl 为 0 时使用默认有效期。冻结归属发起请求的 API 客户端；
+// 托管钱包只有归属客户端可以冻结
+func (s *WalletService) CreateHold(ctx context.Context, address string, amount decimal.Decimal, ttl time.Duration, reference string) (*models.Hold, error) {
+	if ClientIDFromContext(ctx) == "" {
+		return nil, ErrClientRequired
+	}
+	if err := s.checkWalletOwner(ctx, address); err != nil {
+		return nil, err
+	}
+	return s.createHold(ctx, address, amount, ttl, reference, false)
+}
+
+// createHold 冻结资金，不检查调用方权限，供审批等内部流程使用。system 为 true 时为系统冻结，
+// 冻结接口不能扣款或释放
+func (s *WalletService) createHold(ctx context.Context, address string, amount decimal.Decimal, ttl time.Duration, reference string, system bool) (_ *models.Hold, err error) {
+	// 验证金额
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("hold amount must be greater than 0")
+	}
+
+	// 验证地址
+	if _, err := solana.PublicKeyFromBase58(address); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	if ttl == 0 {
+		ttl = defaultHoldTTL
+	}
+	if ttl < 0 || ttl > maxHoldTTL {
+		return nil, fmt.Errorf("hold ttl must be between 0 and %s", maxHoldTTL)
+	}
+
+	now := time.Now()
+	hold := &models.Hold{
+		ID:        uuid.NewString(),
+		ClientID:  ClientIDFromContext(ctx),
+		System:    system,
+		Address:   address,
+		Amount:    amount,
+		Status:    models.HoldStatusActive,
+		Reference: reference,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	audit := s.beginAudit(ctx, models.AuditHoldCreate, amount, map[string]string{
+		"address": address, "amount": amount.String(), "ttl": ttl.String(), "reference": reference,
+	}, address)
+	audit.entry.Reference = hold.ID
+	defer func() { audit.finish(err) }()
+
+	// Lua 脚本检查可用余额并冻结
+	if err := s.redis.CreateHold(ctx, hold.ID, address, amount, ttl); err != nil {
+		return nil, fmt.Errorf("failed to create redis hold: %w", err)
+	}
+
+	if err := s.postgres.CreateHold(ctx, hold); err != nil {
+		// Redis 回滚
+		if rollbackErr := s.redis.ReleaseHold(ctx, hold.ID, address); rollbackErr != nil {
+			s.logger.Logger.Error("failed to rollback redis hold",
+				zap.String("hold", hold.ID),
+				zap.Error(rollbackErr))
+			audit.rollbackFailed(rollbackErr)
+		}
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+	s.balancesChanged(ctx, address)
+
+	return hold, nil
+}
+
+// GetHold 查询冻结记录。API 客户端只能查询自己创建的冻结
+func (s *WalletService) GetHold(ctx context.Context, id string) (*models.Hold, error) {
+	hold, err := s.postgres.GetHold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin && hold.ClientID != ClientIDFromContext(ctx) {
+		return nil, ErrHoldNotFound
+	}
+	return hold, nil
+}
+
+// ListHolds 查询钱包的冻结记录，API 客户端只返回自己创建的
+func (s *WalletService) ListHolds(ctx context.Context, address, status string) ([]models.Hold, error) {
+	if _, err := solana.PublicKeyFromBase58(address); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	var clientID string
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin {
+		if clientID = ClientIDFromContext(ctx); clientID == "" {
+			return nil, ErrClientRequired
+		}
+	}
+	return s.postgres.ListHolds(ctx, address, status, clientID)
+}
+
+// CaptureHold 从冻结资金中扣款，amount 为 nil 时扣除全部冻结金额，剩余部分解除冻结。
+// 只有创建冻结的 API 客户端可以扣款，且扣款时仍需有权使用该钱包
+func (s *WalletService) CaptureHold(ctx context.Context, id string, amount *decimal.Decimal) (_ *models.Hold, err error) {
+	hold, err := s.GetHold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hold.System {
+		return nil, ErrSystemHold
+	}
+	if err := s.checkWalletOwner(ctx, hold.Address); err != nil {
+		if errors.Is(err, ErrManagedWalletNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, err
+	}
+
+	capture := hold.Amount
+	if amount != nil {
+		capture = *amount
+	}
+	if capture.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("capture amount must be greater than 0")
+	}
+
+	audit := s.beginAudit(ctx, models.AuditHoldCapture, capture,
+		map[string]string{"hold": id, "amount": capture.String()}, hold.Address)
+	audit.entry.Reference = id
+	defer func() { audit.finish(err) }()
+
+	now := time.Now()
+	record := &models.Transaction{
+		ID:          newTransactionID(),
+		FromWallet:  hold.Address,
+		ToWallet:    "capture",
+		Amount:      capture,
+		Type:        "capture",
+		Status:      "completed",
+		CreatedAt:   now,
+		CompletedAt: now,
+	}
+
+	// 以数据库为准，成功后同步 Redis
+	hold, err = s.postgres.CaptureHold(ctx, id, capture, record)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.CaptureHold(ctx, id, hold.Address, capture); err != nil {
+		s.logger.Logger.Error("failed to capture redis hold",
+			zap.String("hold", id),
+			zap.Error(err))
+	}
+	s.balancesChanged(ctx, hold.Address)
+
+	return hold, nil
+}
+
+// ReleaseHold 解除冻结。只有创建冻结的 API 客户端可以释放，系统冻结不能释放
+func (s *WalletService) ReleaseHold(ctx context.Context, id string) (*models.Hold, error) {
+	hold, err := s.GetHold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hold.System {
+		return nil, ErrSystemHold
+	}
+	return s.releaseHold(ctx, id, models.HoldStatusReleased)
+}
+
+func (s *WalletService) releaseHold(ctx context.Context, id, status string) (_ *models.Hold, err error) {
+	hold, err := s.postgres.GetHold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != models.HoldStatusActive {
+		return nil, ErrHoldNotActive
+	}
+	operation := models.AuditHoldRelease
+	if status == models.HoldStatusExpired {
+		operation = models.AuditHoldExpire
+	}
+	audit := s.beginAudit(ctx, operation, hold.Amount, map[string]string{"hold": id}, hold.Address)
+	audit.entry.Reference = id
+	defer func() { audit.finish(err) }()
+
+	hold, err = s.postgres.ReleaseHold(ctx, id, status)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.ReleaseHold(ctx, id, hold.Address); err != nil {
+		s.logger.Logger.Error("failed to release redis hold",
+			zap.String("hold", id),
+			zap.Error(err))
+	}
+	s.balancesChanged(ctx, hold.Address)
+	return hold, nil
+}
+
+// ExpireHolds 释放所有已到期的冻结，返回处理数量
+func (s *WalletService) ExpireHolds(ctx context.Context) (int, error) {
+	ids, err := s.postgres.ListExpiredHoldIDs(ctx, time.Now(), holdExpiryBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx = WithActor(ctx, models.Actor{Type: models.ActorSystem, ID: "hold-expirer"})
+	expired := 0
+	for _, id := range ids {
+		if _, err := s.releaseHold(ctx, id, models.HoldStatusExpired); err != nil {
+			// 可能已被并发扣款或释放
+			if !errors.Is(err, ErrHoldNotActive) {
+				s.logger.Logger.Error("failed to expire hold",
+					zap.String("hold", id),
+					zap.Error(err))
+			}
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// RunHoldExpirer 定期释放到期的冻结，直到 ctx 取消
+func (s *WalletService) RunHoldExpirer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.ExpireHolds(ctx); err != nil {
+				s.logger.Logger.Error("failed to expire holds", zap.Error(err))
+			} else if n > 0 {
+				s.logger.Logger.Info("expired holds", zap.Int("count", n))
+			}
+		}
+	}
+}
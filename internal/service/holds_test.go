@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mywallet/internal/models"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateHoldRequiresClient(t *testing.T) {
+	s := &WalletService{}
+
+	_, err := s.CreateHold(context.Background(), solana.NewWallet().PublicKey().String(), decimal.NewFromInt(1), 0, "")
+	assert.ErrorIs(t, err, ErrClientRequired)
+}
+
+func TestHoldOwnership(t *testing.T) {
+	s := newServiceWithPostgres(t)
+	_, owner := newTestClient(t, s, "hold-owner")
+	_, other := newTestClient(t, s, "hold-other")
+
+	address := solana.NewWallet().PublicKey().String()
+	_, err := s.Deposit(context.Background(), address, decimal.NewFromInt(5))
+	require.NoError(t, err)
+
+	hold, err := s.CreateHold(owner, address, decimal.NewFromInt(2), time.Minute, "order-1")
+	require.NoError(t, err)
+
+	// 其他客户端看不到也无法操作该冻结
+	_, err = s.GetHold(other, hold.ID)
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+	amount := decimal.NewFromInt(1)
+	_, err = s.CaptureHold(other, hold.ID, &amount)
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+	_, err = s.ReleaseHold(other, hold.ID)
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+	holds, err := s.ListHolds(other, address, "")
+	require.NoError(t, err)
+	assert.Empty(t, holds)
+
+	got, err := s.GetHold(owner, hold.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusActive, got.Status)
+
+	released, err := s.ReleaseHold(owner, hold.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusReleased, released.Status)
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// keyRotationLockKey 重新加密任务选主使用的 advisory lock
+	keyRotationLockKey = 7_240_385_005
+	// keyRotationBatch 每次查询待重新加密私钥的数量
+	keyRotationBatch = 100
+)
+
+var ErrKeyRotationRunning = errors.New("a key rotation is already running")
+
+// KeystoreStatus 按主密钥版本统计托管钱包，报告轮换进度与可下线的版本
+func (s *WalletService) KeystoreStatus(ctx context.Context) (*models.KeystoreStatus, error) {
+	if s.keys == nil {
+		return nil, ErrKeystoreDisabled
+	}
+	counts, err := s.postgres.CountManagedWalletKeyVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := s.keys.Version()
+	configured := make(map[int]bool)
+	for _, version := range s.keys.Versions() {
+		configured[version] = true
+	}
+	versions := s.keys.Versions()
+	for version := range counts {
+		if !configured[version] {
+			versions = append(versions, version)
+		}
+	}
+	sort.Ints(versions)
+
+	status := &models.KeystoreStatus{ActiveVersion: active, Versions: make([]models.KeyVersionStatus, 0, len(versions))}
+	for _, version := range versions {
+		v := models.KeyVersionStatus{
+			Version:    version,
+			Wallets:    counts[version],
+			Configured: configured[version],
+			Active:     version == active,
+		}
+		v.Retirable = v.Configured && !v.Active && v.Wallets == 0
+		if !v.Active {
+			status.Pending += v.Wallets
+		}
+		status.Versions = append(status.Versions, v)
+	}
+	return status, nil
+}
+
+// RotateKeys 将旧版本主密钥加密的私钥重新加密为当前版本。
+// 通过 advisory lock 选主，其他实例正在执行时返回 ErrKeyRotationRunning
+func (s *WalletService) RotateKeys(ctx context.Context) (*models.KeyRotationReport, error) {
+	if s.keys == nil {
+		return nil, ErrKeystoreDisabled
+	}
+
+	report := &models.KeyRotationReport{}
+	locked, err := s.postgres.WithAdvisoryLock(ctx, keyRotationLockKey, func(ctx context.Context) error {
+		return s.rotateKeys(ctx, report)
+	})
+	if err != nil {
+		return report, err
+	}
+	if !locked {
+		return nil, ErrKeyRotationRunning
+	}
+	return report, nil
+}
+
+func (s *WalletService) rotateKeys(ctx context.Context, report *models.KeyRotationReport) error {
+	var stale []int
+	for _, version := range s.keys.Versions() {
+		if version != s.keys.Version() {
+			stale = append(stale, version)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	// 按地址翻页，重新加密失败的私钥不会在本轮重复处理
+	after := ""
+	for {
+		keys, err := s.postgres.ListStaleManagedWalletKeys(ctx, stale, after, keyRotationBatch)
+		if err != nil {
+			return fmt.Errorf("failed to list stale keys: %w", err)
+		}
+		for _, key := range keys {
+			after = key.Address
+			rewrapped, err := s.rewrapKey(ctx, key)
+			if err != nil {
+				report.Failed++
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", key.Address, err))
+				continue
+			}
+			if rewrapped {
+				report.Rewrapped++
+			}
+		}
+		if len(keys) < keyRotationBatch {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *WalletService) rewrapKey(ctx context.Context, key repository.ManagedWalletKey) (bool, error) {
+	encrypted, err := s.keys.Rewrap(key.Version, key.Address, key.EncryptedKey)
+	if err != nil {
+		return false, err
+	}
+	return s.postgres.RewrapManagedWalletKey(ctx, key.Address, key.Version, encrypted, s.keys.Version())
+}
+
+// RunKeyRotation 定期重新加密旧版本主密钥加密的私钥，直到 ctx 取消
+func (s *WalletService) RunKeyRotation(ctx context.Context, interval time.Duration) {
+	if s.keys == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := s.RotateKeys(ctx)
+		if errors.Is(err, ErrKeyRotationRunning) {
+			continue
+		}
+		if err != nil {
+			s.logger.Logger.Error("failed to rotate keys", zap.Error(err))
+			continue
+		}
+		for _, e := range report.Errors {
+			s.logger.Logger.Warn("key rotation error", zap.String("error", e))
+		}
+		if report.Rewrapped > 0 || report.Failed > 0 {
+			s.logger.Logger.Info("keys rewrapped",
+				zap.Int("rewrapped", report.Rewrapped),
+				zap.Int("failed", report.Failed),
+				zap.Int("version", s.keys.Version()))
+		}
+	}
+}
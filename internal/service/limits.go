@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	"mywallet/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// ErrLimitNotFound 限额配置不存在
+var ErrLimitNotFound = errors.New("limit not found")
+
+// LimitExceededError 超出限额时返回，包含剩余额度信息
+type LimitExceededError struct {
+	Scope     string          `json:"scope"`
+	Subject   string          `json:"subject"`
+	Asset     string          `json:"asset"`
+	Kind      string          `json:"kind"`
+	Limit     decimal.Decimal `json:"limit"`
+	Used      decimal.Decimal `json:"used"`
+	Requested decimal.Decimal `json:"requested"`
+	Remaining decimal.Decimal `json:"remaining"`
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s limit exceeded for %s %s: limit %s, used %s, remaining %s",
+		e.Kind, e.Scope, e.Subject, e.Limit, e.Used, e.Remaining)
+}
+
+// LimitReservation 一次已占用的限额，操作失败时需要释放
+type LimitReservation struct {
+	ID       string
+	subjects []repository.LimitSubject
+}
+
+// LimitStatus 某个主体当前的限额与使用情况
+type LimitStatus struct {
+	Scope        string          `json:"scope"`
+	Subject      string          `json:"subject"`
+	Asset        string          `json:"asset"`
+	Limit        *models.Limit   `json:"limit"`
+	DailyUsed    decimal.Decimal `json:"daily_used"`
+	DailyCount   int64           `json:"daily_count"`
+	MonthlyUsed  decimal.Decimal `json:"monthly_used"`
+	MonthlyCount int64           `json:"monthly_count"`
+}
+
+type LimitService struct {
+	logger   *logger.Logger
+	postgres *repository.PostgresRepository
+	redis    *repository.RedisRepository
+	now      func() time.Time
+}
+
+func NewLimitService(logger *logger.Logger, postgres *repository.PostgresRepository, redis *repository.RedisRepository) *LimitService {
+	return &LimitService{
+		logger:   logger,
+		postgres: postgres,
+		redis:    redis,
+		now:      time.Now,
+	}
+}
+
+// Reserve 检查钱包与当前 API 客户端的限额并占用额度
+func (s *LimitService) Reserve(ctx context.Context, address, asset string, amount decimal.Decimal) (*LimitReservation, error) {
+	subjects := []repository.LimitSubject{{Scope: models.LimitScopeWallet, Subject: address, Asset: asset}}
+	if clientID := ClientIDFromContext(ctx); clientID != "" {
+		subjects = append(subjects, repository.LimitSubject{Scope: models.LimitScopeClient, Subject: clientID, Asset: asset})
+	}
+
+	now := s.now()
+	for i := range subjects {
+		limit, err := s.postgres.GetEffectiveLimit(ctx, subjects[i].Scope, subjects[i].Subject, asset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load limit: %w", err)
+		}
+		subjects[i].Limit = limit
+		if err := s.ensureLoaded(ctx, subjects[i], now); err != nil {
+			return nil, fmt.Errorf("failed to load limit usage: %w", err)
+		}
+	}
+
+	id := uuid.NewString()
+	violation, err := s.redis.ReserveLimit(ctx, id, subjects, amount, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve limit: %w", err)
+	}
+	if violation != nil {
+		return nil, newLimitExceededError(violation, amount)
+	}
+
+	usages := make([]models.LimitUsage, len(subjects))
+	for i, sub := range subjects {
+		usages[i] = models.LimitUsage{
+			ID:        id,
+			Scope:     sub.Scope,
+			Subject:   sub.Subject,
+			Asset:     asset,
+			Amount:    amount,
+			CreatedAt: now,
+		}
+	}
+	if err := s.postgres.RecordLimitUsage(ctx, usages); err != nil {
+		if releaseErr := s.redis.ReleaseLimit(ctx, id, subjects); releaseErr != nil {
+			s.logger.Logger.Error("failed to rollback redis limit usage",
+				zap.String("reservation", id),
+				zap.Error(releaseErr))
+		}
+		return nil, fmt.Errorf("failed to record limit usage: %w", err)
+	}
+
+	return &LimitReservation{ID: id, subjects: subjects}, nil
+}
+
+// Release 释放占用的额度，用于操作失败后的回滚
+func (s *LimitService) Release(ctx context.Context, res *LimitReservation) {
+	if res == nil {
+		return
+	}
+	if err := s.redis.ReleaseLimit(ctx, res.ID, res.subjects); err != nil {
+		s.logger.Logger.Error("failed to release redis limit usage",
+			zap.String("reservation", res.ID),
+			zap.Error(err))
+	}
+	if err := s.postgres.DeleteLimitUsage(ctx, res.ID); err != nil {
+		s.logger.Logger.Error("failed to release limit usage",
+			zap.String("reservation", res.ID),
+			zap.Error(err))
+	}
+}
+
+// ReleaseID 按 ID 释放占用的额度，用于跨请求的操作在之后失败时回滚
+func (s *LimitService) ReleaseID(ctx context.Context, id string) {
+	usages, err := s.postgres.GetLimitUsage(ctx, id)
+	if err != nil {
+		s.logger.Logger.Error("failed to load limit usage",
+			zap.String("reservation", id),
+			zap.Error(err))
+		return
+	}
+	subjects := make([]repository.LimitSubject, len(usages))
+	for i, u := range usages {
+		subjects[i] = repository.LimitSubject{Scope: u.Scope, Subject: u.Subject, Asset: u.Asset}
+	}
+	s.Release(ctx, &LimitReservation{ID: id, subjects: subjects})
+}
+
+// ensureLoaded Redis 计数丢失时从 Postgres 重建
+func (s *LimitService) ensureLoaded(ctx context.Context, sub repository.LimitSubject, now time.Time) error {
+	loaded, err := s.redis.LimitUsageLoaded(ctx, sub)
+	if err != nil || loaded {
+		return err
+	}
+	usages, err := s.postgres.ListLimitUsage(ctx, sub.Scope, sub.Subject, sub.Asset, now.Add(-repository.LimitMonthWindow))
+	if err != nil {
+		return err
+	}
+	return s.redis.LoadLimitUsage(ctx, sub, usages)
+}
+
+// SetLimit 创建或更新限额配置
+func (s *LimitService) SetLimit(ctx context.Context, l *models.Limit) (*models.Limit, error) {
+	if l.Scope != models.LimitScopeWallet && l.Scope != models.LimitScopeClient {
+		return nil, fmt.Errorf("invalid scope: %s", l.Scope)
+	}
+	if l.Subject == "" {
+		return nil, fmt.Errorf("subject is required")
+	}
+	if l.Asset == "" {
+		l.Asset = models.AssetSOL
+	}
+	for _, d := range []*decimal.Decimal{l.PerTxMax, l.DailyAmount, l.MonthlyAmount} {
+		if d != nil && d.IsNegative() {
+			return nil, fmt.Errorf("limit amounts must not be negative")
+		}
+	}
+	for _, c := range []*int64{l.DailyCount, l.MonthlyCount} {
+		if c != nil && *c < 0 {
+			return nil, fmt.Errorf("limit counts must not be negative")
+		}
+	}
+	return s.postgres.UpsertLimit(ctx, l)
+}
+
+// ListLimits 查询限额配置
+func (s *LimitService) ListLimits(ctx context.Context, scope, subject string) ([]models.Limit, error) {
+	return s.postgres.ListLimits(ctx, scope, subject)
+}
+
+// DeleteLimit 删除限额配置
+func (s *LimitService) DeleteLimit(ctx context.Context, id int64) error {
+	err := s.postgres.DeleteLimit(ctx, id)
+	if err == sql.ErrNoRows {
+		return ErrLimitNotFound
+	}
+	return err
+}
+
+// Status 查询某个主体的生效限额与滚动窗口使用量
+func (s *LimitService) Status(ctx context.Context, scope, subject, asset string) (*LimitStatus, error) {
+	if asset == "" {
+		asset = models.AssetSOL
+	}
+	sub := repository.LimitSubject{Scope: scope, Subject: subject, Asset: asset}
+	limit, err := s.postgres.GetEffectiveLimit(ctx, scope, subject, asset)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if err := s.ensureLoaded(ctx, sub, now); err != nil {
+		return nil, err
+	}
+	status := &LimitStatus{Scope: scope, Subject: subject, Asset: asset, Limit: limit}
+	if status.DailyUsed, status.DailyCount, err = s.redis.LimitUsage(ctx, sub, repository.LimitDayWindow, now); err != nil {
+		return nil, err
+	}
+	if status.MonthlyUsed, status.MonthlyCount, err = s.redis.LimitUsage(ctx, sub, repository.LimitMonthWindow, now); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func newLimitExceededError(v *repository.LimitViolation, amount decimal.Decimal) *LimitExceededError {
+	remaining := v.Limit.Sub(v.Used)
+	if remaining.IsNegative() {
+		remaining = decimal.Zero
+	}
+	requested := amount
+	if v.Kind == "daily_count" || v.Kind == "monthly_count" {
+		requested = decimal.NewFromInt(1)
+	}
+	return &LimitExceededError{
+		Scope:     v.Subject.Scope,
+		Subject:   v.Subject.Subject,
+		Asset:     v.Subject.Asset,
+		Kind:      v.Kind,
+		Limit:     v.Limit,
+		Used:      v.Used,
+		Requested: requested,
+		Remaining: remaining,
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"mywallet/internal/config"
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrKeystoreDisabled      = errors.New("managed wallets are disabled: KEYSTORE_MASTER_KEY is not set")
+	ErrManagedWalletNotFound = repository.ErrManagedWalletNotFound
+	// ErrKeyImportUnsupported 私钥由外部签名服务保管时不能导入私钥
+	ErrKeyImportUnsupported = errors.New("private keys cannot be imported into an external signer: create the key on the signer and register it by key_id")
+)
+
+// CreateManagedWallet 创建托管钱包。keystore 模式下 privateKey 为空时生成新密钥（配置助记词时按 HD 路径派生），否则导入；
+// 外部签名服务模式下 keyID 为空时在签名服务上生成新密钥，否则登记签名服务上已有的密钥。
+// clientID 为钱包归属的 API 客户端，为空时为平台钱包
+func (s *WalletService) CreateManagedWallet(ctx context.Context, privateKey, keyID, clientID string) (*models.ManagedWallet, error) {
+	if s.signer == nil {
+		return nil, ErrKeystoreDisabled
+	}
+
+	wallet := &models.ManagedWallet{ClientID: clientID, Signer: s.signerBackend()}
+	var encrypted []byte
+	if s.keys != nil {
+		if keyID != "" {
+			return nil, errors.New("key_id is only supported with an external signer")
+		}
+		var key solana.PrivateKey
+		var err error
+		switch {
+		case privateKey != "":
+			if key, err = solana.PrivateKeyFromBase58(privateKey); err != nil {
+				return nil, fmt.Errorf("invalid private key: %w", err)
+			}
+		case s.seed != nil:
+			if key, wallet.DerivationIndex, err = s.deriveKey(ctx); err != nil {
+				return nil, err
+			}
+		default:
+			key = solana.NewWallet().PrivateKey
+		}
+		wallet.Address = key.PublicKey().String()
+		wallet.KeyID = wallet.Address
+		if encrypted, err = s.keys.Encrypt(wallet.Address, key); err != nil {
+			return nil, fmt.Errorf("failed to encrypt key: %w", err)
+		}
+		version := s.keys.Version()
+		wallet.KeyVersion = &version
+	} else {
+		if privateKey != "" {
+			return nil, ErrKeyImportUnsupported
+		}
+		var pub solana.PublicKey
+		var err error
+		if keyID == "" {
+			generator, ok := s.signer.(solanaclient.KeyGenerator)
+			if !ok {
+				return nil, errors.New("signer does not support key generation: key_id is required")
+			}
+			keyID = "mywallet-" + uuid.NewString()
+			pub, err = generator.GenerateKey(ctx, keyID)
+		} else {
+			pub, err = s.signer.PublicKey(ctx, keyID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signing key: %w", err)
+		}
+		wallet.Address = pub.String()
+		wallet.KeyID = keyID
+	}
+
+	if err := s.postgres.CreateManagedWallet(ctx, wallet, encrypted); err != nil {
+		return nil, fmt.Errorf("failed to create managed wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+// ListManagedWallets 查询托管钱包
+func (s *WalletService) ListManagedWallets(ctx context.Context) ([]models.ManagedWallet, error) {
+	return s.postgres.ListManagedWallets(ctx)
+}
+
+// signerBackend 返回当前使用的签名服务类型
+func (s *WalletService) signerBackend() string {
+	if s.keys != nil {
+		return config.SignerKeystore
+	}
+	return s.cfg.SignerBackend
+}
+
+// ownedManagedKey 返回调用方可以使用的托管钱包密钥。API 客户端只能使用归属自己的钱包，
+// 其他钱包（含平台钱包）按不存在处理；管理员不受限制
+func (s *WalletService) ownedManagedKey(ctx context.Context, address string) (solanaclient.Key, error) {
+	if s.signer == nil {
+		return solanaclient.Key{}, ErrKeystoreDisabled
+	}
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin {
+		clientID := ClientIDFromContext(ctx)
+		if clientID == "" {
+			return solanaclient.Key{}, ErrManagedWalletNotFound
+		}
+		wallet, err := s.postgres.GetManagedWallet(ctx, address)
+		if err != nil {
+			return solanaclient.Key{}, err
+		}
+		if wallet.ClientID != clientID {
+			return solanaclient.Key{}, ErrManagedWalletNotFound
+		}
+	}
+	return s.managedKey(ctx, address)
+}
+
+// checkWalletOwner 检查调用方能否动用地址上的资金：托管钱包只有归属的 API 客户端与管理员可以使用，
+// 其他客户端按不存在处理；非托管地址不受限制
+func (s *WalletService) checkWalletOwner(ctx context.Context, address string) error {
+	if actor := ActorFromContext(ctx); actor.Type == models.ActorAdmin {
+		return nil
+	}
+	wallet, err := s.postgres.GetManagedWallet(ctx, address)
+	if errors.Is(err, ErrManagedWalletNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check managed wallet: %w", err)
+	}
+	if clientID := ClientIDFromContext(ctx); clientID == "" || wallet.ClientID != clientID {
+		return ErrManagedWalletNotFound
+	}
+	return nil
+}
+
+// managedKey 返回托管钱包在签名服务上的密钥，私钥不离开签名服务
+func (s *WalletService) managedKey(ctx context.Context, address string) (solanaclient.Key, error) {
+	if s.signer == nil {
+		return solanaclient.Key{}, ErrKeystoreDisabled
+	}
+	wallet, err := s.postgres.GetManagedWallet(ctx, address)
+	if err != nil {
+		return solanaclient.Key{}, err
+	}
+	if backend := s.signerBackend(); wallet.Signer != backend {
+		return solanaclient.Key{}, fmt.Errorf("managed wallet %s is held by the %s signer, not %s", address, wallet.Signer, backend)
+	}
+	pub, err := solana.PublicKeyFromBase58(wallet.Address)
+	if err != nil {
+		return solanaclient.Key{}, fmt.Errorf("invalid managed wallet address: %w", err)
+	}
+	return solanaclient.Key{ID: wallet.KeyID, PublicKey: pub, Signer: s.signer}, nil
+}
@@ -0,0 +1,534 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	// offlineListLimit 列表接口返回的最大数量
+	offlineListLimit = 200
+	// maxOfflineSettle 每轮确认的已提交或取消中离线出账上限
+	maxOfflineSettle = 1000
+	// offlineClockSkew 查找 nonce 使用记录时允许的链上时间与本机时间偏差
+	offlineClockSkew = 10 * time.Minute
+)
+
+var (
+	ErrOfflineSigningDisabled    = errors.New("offline signing is disabled: OFFLINE_SIGNER_ADDRESS or NONCE_ACCOUNTS is not set")
+	ErrNoNonceAvailable          = errors.New("all nonce accounts are used by offline withdrawals awaiting signature")
+	ErrTransactionMismatch       = errors.New("signed transaction does not match the prepared transaction")
+	ErrOfflineWithdrawalNotFound = repository.ErrOfflineWithdrawalNotFound
+	ErrOfflineWithdrawalState    = repository.ErrOfflineWithdrawalState
+)
+
+// PrepareOfflineWithdrawal 扣减账本余额并生成由冷钱包付款的未签名交易。交易使用 durable nonce，
+// 离线签名不受 blockhash 有效期限制，导入后广播
+func (s *WalletService) PrepareOfflineWithdrawal(ctx context.Context, address, toAddress string, amount decimal.Decimal) (_ *models.OfflineWithdrawal, err error) {
+	if s.cfg.OfflineSignerAddress == "" || len(s.cfg.NonceAccounts) == 0 {
+		return nil, ErrOfflineSigningDisabled
+	}
+	signer, err := solana.PublicKeyFromBase58(s.cfg.OfflineSignerAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OFFLINE_SIGNER_ADDRESS: %w", err)
+	}
+	if _, err := solana.PublicKeyFromBase58(address); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	to, err := solana.PublicKeyFromBase58(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to address: %w", err)
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("withdraw amount must be greater than 0")
+	}
+	if !amount.Shift(9).IsInteger() {
+		return nil, fmt.Errorf("amount has more than 9 decimal places")
+	}
+
+	audit := s.beginAudit(ctx, models.AuditOfflinePrepare, amount,
+		map[string]string{"address": address, "to": toAddress, "amount": amount.String()}, address)
+	defer func() { audit.finish(err) }()
+
+	// 地址簿要求的 memo 随交易上链
+	memo, err := s.checkDestination(ctx, address, toAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation, err := s.limits.Reserve(ctx, address, models.AssetSOL, amount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.SubBalance(ctx, address, amount); err != nil {
+		s.limits.Release(ctx, reservation)
+		return nil, fmt.Errorf("failed to update redis balance: %w", err)
+	}
+	if err := s.postgres.SubBalance(ctx, address, amount); err != nil {
+		if rollbackErr := s.redis.AddBalance(ctx, address, amount); rollbackErr != nil {
+			s.logger.Logger.Error("failed to rollback redis balance",
+				zap.String("address", address),
+				zap.Error(rollbackErr))
+			audit.rollbackFailed(rollbackErr)
+		}
+		s.limits.Release(ctx, reservation)
+		return nil, fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	now := time.Now().UTC()
+	w := &models.OfflineWithdrawal{
+		ID:               uuid.NewString(),
+		ClientID:         ClientIDFromContext(ctx),
+		Address:          address,
+		Signer:           signer.String(),
+		ToAddress:        toAddress,
+		Amount:           amount,
+		Memo:             memo,
+		LimitReservation: reservation.ID,
+		Status:           models.OfflinePrepared,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.createOfflineTransfer(ctx, w, signer, to); err != nil {
+		if refundErr := s.refundOffline(ctx, w); refundErr != nil {
+			audit.rollbackFailed(refundErr)
+		}
+		return nil, err
+	}
+	audit.entry.Reference = w.ID
+	s.balancesChanged(ctx, address)
+
+	s.attachQRPayload(w)
+	return w, nil
+}
+
+// createOfflineTransfer 依次尝试 nonce 账户，为第一个当前 nonce 未被使用的账户生成交易并保存
+func (s *WalletService) createOfflineTransfer(ctx context.Context, w *models.OfflineWithdrawal, signer, to solana.PublicKey) error {
+	for _, account := range s.cfg.NonceAccounts {
+		nonceAccount, err := solana.PublicKeyFromBase58(account)
+		if err != nil {
+			return fmt.Errorf("invalid nonce account %s: %w", account, err)
+		}
+		nonce, err := s.solana.GetNonceAccount(ctx, nonceAccount)
+		if err != nil {
+			return err
+		}
+		if !nonce.Authority.Equals(signer) {
+			return fmt.Errorf("nonce account %s is not controlled by the offline signer", account)
+		}
+
+		tx, err := solanaclient.BuildOfflineTransfer(solanaclient.OfflineTransfer{
+			From:         signer,
+			To:           to,
+			Amount:       w.Amount,
+			Memo:         w.Memo,
+			NonceAccount: nonceAccount,
+			Nonce:        nonce.Nonce,
+		})
+		if err != nil {
+			return err
+		}
+		if w.Transaction, err = solanaclient.EncodeTransaction(tx); err != nil {
+			return err
+		}
+		w.NonceAccount, w.Nonce = account, nonce.Nonce.String()
+
+		err = s.postgres.CreateOfflineWithdrawal(ctx, w)
+		if errors.Is(err, repository.ErrNonceInUse) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create offline withdrawal: %w", err)
+		}
+		return nil
+	}
+	return ErrNoNonceAvailable
+}
+
+// attachQRPayload 填充交易的二维码编码
+func (s *WalletService) attachQRPayload(w *models.OfflineWithdrawal) {
+	if w.Status != models.OfflinePrepared {
+		return
+	}
+	tx, err := solanaclient.DecodeTransaction(w.Transaction)
+	if err == nil {
+		w.QRPayload, err = solanaclient.EncodeTransactionQR(tx)
+	}
+	if err != nil {
+		s.logger.Logger.Error("failed to encode offline transaction",
+			zap.String("id", w.ID),
+			zap.Error(err))
+	}
+}
+
+// GetOfflineWithdrawal 查询离线出账。API 客户端只能查询自己发起的出账
+func (s *WalletService) GetOfflineWithdrawal(ctx context.Context, id string) (*models.OfflineWithdrawal, error) {
+	w, err := s.postgres.GetOfflineWithdrawal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin && w.ClientID != ClientIDFromContext(ctx) {
+		return nil, ErrOfflineWithdrawalNotFound
+	}
+	s.attachQRPayload(w)
+	return w, nil
+}
+
+// ListOfflineWithdrawals 查询离线出账，status 为空时返回全部。管理员可按 clientID 筛选，API 客户端只返回自己的出账
+func (s *WalletService) ListOfflineWithdrawals(ctx context.Context, clientID, status string) ([]models.OfflineWithdrawal, error) {
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin {
+		if clientID = ClientIDFromContext(ctx); clientID == "" {
+			return nil, ErrClientRequired
+		}
+	}
+	return s.postgres.ListOfflineWithdrawals(ctx, clientID, status, offlineListLimit)
+}
+
+// SubmitOfflineWithdrawal 导入离线签名的交易，校验与准备的交易一致且签名有效后广播。
+// 重复导入已广播的同一交易时直接返回
+func (s *WalletService) SubmitOfflineWithdrawal(ctx context.Context, id, payload string) (_ *models.OfflineWithdrawal, err error) {
+	w, err := s.GetOfflineWithdrawal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := solanaclient.DecodeTransaction(payload)
+	if err != nil {
+		return nil, err
+	}
+	prepared, err := solanaclient.DecodeTransaction(w.Transaction)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prepared transaction: %w", err)
+	}
+	if !solanaclient.SameMessage(prepared, signed) {
+		return nil, ErrTransactionMismatch
+	}
+	if err := signed.VerifySignatures(); err != nil {
+		return nil, fmt.Errorf("invalid transaction signature: %w", err)
+	}
+	signature := signed.Signatures[0].String()
+	if w.Status == models.OfflineSubmitted && w.Signature == signature {
+		return w, nil
+	}
+
+	audit := s.beginAudit(ctx, models.AuditOfflineSubmit, w.Amount,
+		map[string]string{"id": id, "signature": signature}, w.Address)
+	audit.entry.Reference = id
+	defer func() { audit.finish(err) }()
+
+	// 先记录签名再广播，广播结果未知时由 RunOfflineTracker 按签名确认
+	if w, err = s.postgres.UpdateOfflineWithdrawal(ctx, id, models.OfflinePrepared, models.OfflineSubmitted, signature, ""); err != nil {
+		return nil, err
+	}
+	err = s.solana.SendSigned(ctx, signed)
+	if errors.Is(err, ErrSendUnconfirmed) {
+		s.logger.Logger.Warn("offline withdrawal outcome unknown, tracking by signature",
+			zap.String("id", id),
+			zap.String("signature", signature),
+			zap.Error(err))
+		return w, nil
+	}
+	if err != nil {
+		// 交易未上链，nonce 未推进，可以修正后重新导入
+		if _, revertErr := s.postgres.UpdateOfflineWithdrawal(ctx, id, models.OfflineSubmitted, models.OfflinePrepared, "", err.Error()); revertErr != nil {
+			s.logger.Logger.Error("failed to revert offline withdrawal",
+				zap.String("id", id),
+				zap.Error(revertErr))
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// CancelOfflineWithdrawal 取消尚未导入的离线出账。交易可能已经离线签名，nonce 推进前仍可能被广播上链，
+// 因此出账只进入 cancelling 状态，余额暂不退回：导入 nonce 推进交易（AdvanceOfflineNonce）后由
+// RunOfflineTracker 确认 nonce 的去向再退回余额或按完成处理
+func (s *WalletService) CancelOfflineWithdrawal(ctx context.Context, id string) (_ *models.OfflineWithdrawal, err error) {
+	w, err := s.GetOfflineWithdrawal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := s.beginAudit(ctx, models.AuditOfflineCancel, w.Amount, map[string]string{"id": id}, w.Address)
+	audit.entry.Reference = id
+	defer func() { audit.finish(err) }()
+
+	return s.postgres.UpdateOfflineWithdrawal(ctx, id, models.OfflinePrepared, models.OfflineCancelling, "", "")
+}
+
+// AdvanceOfflineNonce 导入离线签名的 nonce 推进交易并广播。交易上链后同一 nonce 的出账交易不可能再上链，
+// 由 RunOfflineTracker 退回余额
+func (s *WalletService) AdvanceOfflineNonce(ctx context.Context, id, payload string) (_ *models.OfflineWithdrawal, err error) {
+	w, err := s.GetOfflineWithdrawal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if w.Status != models.OfflineCancelling {
+		return nil, fmt.Errorf("withdrawal is %s, cancel it first: %w", w.Status, ErrOfflineWithdrawalState)
+	}
+	signed, err := solanaclient.DecodeTransaction(payload)
+	if err != nil {
+		return nil, err
+	}
+	advance, err := solanaclient.ParseNonceAdvance(signed)
+	if err != nil {
+		return nil, err
+	}
+	if advance.NonceAccount.String() != w.NonceAccount || advance.Authority.String() != w.Signer || advance.Nonce.String() != w.Nonce {
+		return nil, ErrTransactionMismatch
+	}
+	if err := signed.VerifySignatures(); err != nil {
+		return nil, fmt.Errorf("invalid transaction signature: %w", err)
+	}
+	signature := signed.Signatures[0].String()
+
+	audit := s.beginAudit(ctx, models.AuditOfflineAdvanceNonce, w.Amount,
+		map[string]string{"id": id, "signature": signature}, w.Address)
+	audit.entry.Reference = id
+	defer func() { audit.finish(err) }()
+
+	err = s.solana.SendSigned(ctx, signed)
+	if errors.Is(err, ErrSendUnconfirmed) {
+		s.logger.Logger.Warn("nonce advance outcome unknown, tracking by nonce",
+			zap.String("id", id),
+			zap.String("signature", signature),
+			zap.Error(err))
+		return w, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// refundOffline 退回离线出账扣减的余额并释放限额
+func (s *WalletService) refundOffline(ctx context.Context, w *models.OfflineWithdrawal) error {
+	s.limits.ReleaseID(ctx, w.LimitReservation)
+	if err := s.postgres.AddBalance(ctx, w.Address, w.Amount); err != nil {
+		s.logger.Logger.Error("failed to refund offline withdrawal",
+			zap.String("id", w.ID),
+			zap.String("address", w.Address),
+			zap.Error(err))
+		return err
+	}
+	if err := s.redis.AddBalance(ctx, w.Address, w.Amount); err != nil {
+		s.logger.Logger.Error("failed to refund redis balance",
+			zap.String("id", w.ID),
+			zap.String("address", w.Address),
+			zap.Error(err))
+		return err
+	}
+	s.balancesChanged(ctx, w.Address)
+	return nil
+}
+
+// RunOfflineTracker 定期确认已广播和取消中的离线出账，直到 ctx 取消
+func (s *WalletService) RunOfflineTracker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx = WithActor(ctx, models.Actor{Type: models.ActorSystem, ID: "offline-tracker"})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.settleOfflineWithdrawals(ctx); err != nil {
+			s.logger.Logger.Error("failed to settle offline withdrawals", zap.Error(err))
+		}
+		if _, err := s.settleCancellingOffline(ctx); err != nil {
+			s.logger.Logger.Error("failed to settle cancelling offline withdrawals", zap.Error(err))
+		}
+	}
+}
+
+// settleOfflineWithdrawals 查询已广播交易的链上结果，返回处理数量。链上查不到交易且 nonce
+// 已被其他交易推进时，交易不可能再上链，视为失败
+func (s *WalletService) settleOfflineWithdrawals(ctx context.Context) (int, error) {
+	submitted, err := s.postgres.ListOfflineWithdrawals(ctx, "", models.OfflineSubmitted, maxOfflineSettle)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list submitted offline withdrawals: %w", err)
+	}
+	if len(submitted) == 0 {
+		return 0, nil
+	}
+
+	sigs := make([]solana.Signature, len(submitted))
+	for i, w := range submitted {
+		if sigs[i], err = solana.SignatureFromBase58(w.Signature); err != nil {
+			return 0, fmt.Errorf("invalid offline withdrawal signature %s: %w", w.Signature, err)
+		}
+	}
+	statuses, err := s.solana.SignatureStatuses(ctx, sigs)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for i := range submitted {
+		w, status := &submitted[i], statuses[i]
+		if status == nil {
+			spent, st, err := s.offlineNonceSpent(ctx, w, sigs[i])
+			if err != nil {
+				s.logger.Logger.Warn("failed to check offline withdrawal nonce",
+					zap.String("id", w.ID),
+					zap.Error(err))
+			}
+			if !spent {
+				continue
+			}
+			status = st
+		}
+		switch {
+		case status == nil:
+			s.finishOffline(ctx, w, models.OfflineSubmitted, models.OfflineFailed, "", "nonce was advanced by another transaction")
+		case status.Err != nil:
+			s.finishOffline(ctx, w, models.OfflineSubmitted, models.OfflineFailed, "", fmt.Sprintf("transaction failed: %v", status.Err))
+		case status.ConfirmationStatus == rpc.ConfirmationStatusFinalized:
+			s.finishOffline(ctx, w, models.OfflineSubmitted, models.OfflineCompleted, "", "")
+		default:
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// offlineNonceSpent 检查链上查不到的交易的 nonce 是否已推进，未推进时交易仍可能上链。
+// nonce 已推进时再次查询签名，避免交易恰好在两次查询之间上链
+func (s *WalletService) offlineNonceSpent(ctx context.Context, w *models.OfflineWithdrawal, sig solana.Signature) (bool, *rpc.SignatureStatusesResult, error) {
+	account, err := solana.PublicKeyFromBase58(w.NonceAccount)
+	if err != nil {
+		return false, nil, err
+	}
+	nonce, err := s.solana.GetNonceAccount(ctx, account)
+	if err != nil {
+		return false, nil, err
+	}
+	if nonce.Nonce.String() == w.Nonce {
+		return false, nil, nil
+	}
+	statuses, err := s.solana.SignatureStatuses(ctx, []solana.Signature{sig})
+	if err != nil {
+		return false, nil, err
+	}
+	return true, statuses[0], nil
+}
+
+// settleCancellingOffline 处理取消中的离线出账，返回处理数量。nonce 推进后在 nonce 账户的历史中查找
+// 使用该 nonce 的交易：是准备的交易且执行成功时按完成处理，否则准备的交易不可能再上链，退回余额
+func (s *WalletService) settleCancellingOffline(ctx context.Context) (int, error) {
+	cancelling, err := s.postgres.ListOfflineWithdrawals(ctx, "", models.OfflineCancelling, maxOfflineSettle)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list cancelling offline withdrawals: %w", err)
+	}
+
+	settled := 0
+	for i := range cancelling {
+		w := &cancelling[i]
+		use, err := s.offlineNonceUse(ctx, w)
+		if err != nil {
+			s.logger.Logger.Warn("failed to check cancelled offline withdrawal nonce",
+				zap.String("id", w.ID),
+				zap.Error(err))
+			continue
+		}
+		if use == nil {
+			continue
+		}
+		prepared, err := solanaclient.DecodeTransaction(w.Transaction)
+		if err != nil {
+			return settled, fmt.Errorf("invalid prepared transaction %s: %w", w.ID, err)
+		}
+		if !use.Failed && solanaclient.SameMessage(prepared, use.Transaction) {
+			s.finishOffline(ctx, w, models.OfflineCancelling, models.OfflineCompleted, use.Signature.String(), "")
+		} else {
+			s.finishOffline(ctx, w, models.OfflineCancelling, models.OfflineCancelled, "", "")
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// offlineNonceUse 返回使用出账 nonce 的链上交易，nonce 尚未推进时返回 nil
+func (s *WalletService) offlineNonceUse(ctx context.Context, w *models.OfflineWithdrawal) (*solanaclient.NonceUse, error) {
+	account, err := solana.PublicKeyFromBase58(w.NonceAccount)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := s.solana.GetNonceAccount(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if nonce.Nonce.String() == w.Nonce {
+		return nil, nil
+	}
+	value, err := solana.HashFromBase58(w.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce %s: %w", w.Nonce, err)
+	}
+	// nonce 在准备出账时读取，使用它的交易不会早于出账创建，留出时钟偏差
+	use, err := s.solana.FindNonceUse(ctx, account, value, w.CreatedAt.Add(-offlineClockSkew))
+	if err != nil {
+		return nil, err
+	}
+	if use == nil {
+		return nil, fmt.Errorf("nonce %s was advanced but no transaction using it was found", w.Nonce)
+	}
+	return use, nil
+}
+
+// finishOffline 结束已广播或取消中的离线出账：完成时写入交易记录，失败或取消时退回余额
+func (s *WalletService) finishOffline(ctx context.Context, w *models.OfflineWithdrawal, from, status, signature, errMsg string) {
+	ctx = WithClientID(ctx, w.ClientID)
+	updated, err := s.postgres.UpdateOfflineWithdrawal(ctx, w.ID, from, status, signature, errMsg)
+	if err != nil {
+		// 其他实例已处理
+		if !errors.Is(err, ErrOfflineWithdrawalState) {
+			s.logger.Logger.Error("failed to update offline withdrawal",
+				zap.String("id", w.ID),
+				zap.Error(err))
+		}
+		return
+	}
+
+	if status != models.OfflineCompleted {
+		audit := s.beginAudit(ctx, models.AuditOfflineRefund, w.Amount,
+			map[string]string{"id": w.ID, "status": status, "error": errMsg}, w.Address)
+		audit.entry.Reference = w.ID
+		audit.finish(s.refundOffline(ctx, updated))
+		return
+	}
+
+	tx := &models.Transaction{
+		ID:             newTransactionID(),
+		FromWallet:     updated.Address,
+		ToWallet:       updated.ToAddress,
+		Amount:         updated.Amount,
+		Type:           "withdraw",
+		Status:         "completed",
+		ChainSignature: updated.Signature,
+		ClientID:       updated.ClientID,
+		CreatedAt:      updated.CreatedAt,
+		CompletedAt:    time.Now(),
+	}
+	if err := s.postgres.CreateTransaction(ctx, tx); err != nil {
+		s.logger.Logger.Error("failed to create transaction record",
+			zap.String("id", w.ID),
+			zap.Error(err))
+	}
+	s.webhooks.Publish(ctx, models.EventWithdrawalCompleted, tx)
+	s.streams.PublishTransaction(ctx, tx)
+}
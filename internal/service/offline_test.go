@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"mywallet/internal/config"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineWithdrawalValidation(t *testing.T) {
+	ctx := context.Background()
+	from := solana.NewWallet().PublicKey().String()
+	to := solana.NewWallet().PublicKey().String()
+
+	s := &WalletService{cfg: &config.Config{}}
+	_, err := s.PrepareOfflineWithdrawal(ctx, from, to, decimal.NewFromInt(1))
+	assert.ErrorIs(t, err, ErrOfflineSigningDisabled)
+
+	s.cfg = &config.Config{
+		OfflineSignerAddress: solana.NewWallet().PublicKey().String(),
+		NonceAccounts:        []string{solana.NewWallet().PublicKey().String()},
+	}
+	for _, amount := range []decimal.Decimal{decimal.Zero, decimal.NewFromInt(-1), decimal.RequireFromString("0.0000000001")} {
+		_, err = s.PrepareOfflineWithdrawal(ctx, from, to, amount)
+		assert.Error(t, err, amount.String())
+	}
+	_, err = s.PrepareOfflineWithdrawal(ctx, from, "not-an-address", decimal.NewFromInt(1))
+	assert.Error(t, err)
+
+	_, err = s.ListOfflineWithdrawals(ctx, "", "")
+	assert.ErrorIs(t, err, ErrClientRequired)
+}
@@ -0,0 +1,721 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	maxPayoutItems = 1000
+	// lamportsPerSignature 每个签名的基础手续费
+	lamportsPerSignature = 5000
+	// payoutAmountPlaces 账本金额精度为 DECIMAL(20,8)
+	payoutAmountPlaces = 8
+
+	payoutConfirmTimeout = time.Minute
+	payoutPollInterval   = 2 * time.Second
+	// payoutProcessTimeout 单次后台处理的最长时间
+	payoutProcessTimeout = 5 * time.Minute
+	// payoutStaleAfter 处理中的批次超过该时间未更新，视为进程中断，允许重试
+	payoutStaleAfter = 10 * time.Minute
+	// payoutResendAfter 已提交但链上查不到的交易，超过该时间后 blockhash 已过期，重发不会重复付款
+	payoutResendAfter = 2 * time.Minute
+)
+
+var (
+	ErrPayoutNotFound   = repository.ErrPayoutNotFound
+	ErrPayoutInProgress = repository.ErrPayoutInProgress
+)
+
+// PayoutRequest 批量付款中的单个付款项
+type PayoutRequest struct {
+	Recipient string `json:"recipient"`
+	Amount    string `json:"amount"`
+	// Asset 为空时为 SOL
+	Asset     string `json:"asset"`
+	Reference string `json:"reference"`
+}
+
+// PayoutItemError 付款项校验错误
+type PayoutItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// PayoutValidationError 批量付款校验失败，列出所有无效的付款项
+type PayoutValidationError struct {
+	Items []PayoutItemError `json:"items"`
+}
+
+func (e *PayoutValidationError) Error() string {
+	return fmt.Sprintf("%d payout items are invalid", len(e.Items))
+}
+
+// preparedPayout 已签名、已占用限额、等待发送的付款
+type preparedPayout struct {
+	from  string
+	items []models.PayoutItem
+	txs   []*solanaclient.PaymentTransaction
+	// acquired 本次新占用限额的付款项，之前的占用在重试时复用
+	acquired []models.PayoutItem
+}
+
+// CreatePayout 校验全部付款项并创建批次，交易在后台发送与确认
+func (s *WalletService) CreatePayout(ctx context.Context, fromPrivateKeyStr string, reqs []PayoutRequest) (_ *models.PayoutBatch, err error) {
+	fromPrivateKey, err := solana.PrivateKeyFromBase58(fromPrivateKeyStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from private key: %w", err)
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("payout has no items")
+	}
+	if len(reqs) > maxPayoutItems {
+		return nil, fmt.Errorf("payout has more than %d items", maxPayoutItems)
+	}
+
+	now := time.Now()
+	batch := &models.PayoutBatch{
+		ID:          uuid.NewString(),
+		ClientID:    ClientIDFromContext(ctx),
+		FromAddress: fromPrivateKey.PublicKey().String(),
+		Status:      models.PayoutStatusProcessing,
+		Items:       make([]models.PayoutItem, len(reqs)),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	// 先校验全部付款项，一次性返回所有错误
+	var invalid []PayoutItemError
+	references := make(map[string]int)
+	for i, req := range reqs {
+		item, err := s.payoutItem(req)
+		if err == nil && item.Reference != "" {
+			if prev, ok := references[item.Reference]; ok {
+				err = fmt.Errorf("duplicate reference, same as item %d", prev)
+			}
+			references[item.Reference] = i
+		}
+		if err == nil {
+			err = s.checkPayoutRecipient(ctx, batch.FromAddress, item.Recipient)
+		}
+		if err != nil {
+			invalid = append(invalid, PayoutItemError{Index: i, Error: err.Error()})
+			continue
+		}
+		// 付款项 ID 同时作为交易 ID
+		item.ID = newTransactionID()
+		item.BatchID = batch.ID
+		item.Seq = i
+		item.Status = models.PayoutItemPending
+		item.CreatedAt = now
+		item.UpdatedAt = now
+		batch.Items[i] = *item
+	}
+	if len(invalid) > 0 {
+		return nil, &PayoutValidationError{Items: invalid}
+	}
+
+	audit := s.beginAudit(ctx, models.AuditPayout, payoutSOLTotal(batch.Items), reqs, batch.FromAddress)
+	audit.entry.Reference = batch.ID
+	defer func() { audit.finish(err) }()
+
+	prepared, err := s.preparePayout(ctx, fromPrivateKey, batch.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.postgres.CreatePayoutBatch(ctx, batch); err != nil {
+		s.releasePayoutItems(ctx, prepared.from, prepared.acquired)
+		return nil, fmt.Errorf("failed to create payout batch: %w", err)
+	}
+
+	go s.runPayout(batch.ID, prepared)
+
+	batch.Summary = summarizePayout(batch.Items)
+	return batch, nil
+}
+
+// checkPayoutRecipient 批量付款的收款方同样需要通过白名单，批量交易不支持逐笔 memo
+func (s *WalletService) checkPayoutRecipient(ctx context.Context, from, recipient string) error {
+	memo, err := s.checkDestination(ctx, from, recipient)
+	if err != nil {
+		return err
+	}
+	if memo != "" {
+		return fmt.Errorf("recipient requires a memo, send it with a transfer")
+	}
+	return nil
+}
+
+// GetPayout 查询批次状态与各付款项结果
+func (s *WalletService) GetPayout(ctx context.Context, id string) (*models.PayoutBatch, error) {
+	batch, err := s.postgres.GetPayoutBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	batch.Summary = summarizePayout(batch.Items)
+	return batch, nil
+}
+
+// RetryPayout 重新发送失败的付款项。已提交的交易先查询链上结果，确认未上链且 blockhash 过期后才重发
+func (s *WalletService) RetryPayout(ctx context.Context, id, fromPrivateKeyStr string) (_ *models.PayoutBatch, err error) {
+	fromPrivateKey, err := solana.PrivateKeyFromBase58(fromPrivateKeyStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from private key: %w", err)
+	}
+
+	batch, err := s.postgres.GetPayoutBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if batch.FromAddress != fromPrivateKey.PublicKey().String() {
+		return nil, fmt.Errorf("private key does not match payout sender")
+	}
+
+	audit := s.beginAudit(ctx, models.AuditPayout, payoutSOLTotal(batch.Items),
+		map[string]string{"payout": id, "retry": "true"}, batch.FromAddress)
+	audit.entry.Reference = id
+	defer func() { audit.finish(err) }()
+
+	if err := s.postgres.ClaimPayoutBatch(ctx, id, payoutStaleAfter); err != nil {
+		return nil, err
+	}
+
+	retry, err := s.resolvePayoutItems(ctx, batch)
+	if err == nil && len(retry) > 0 {
+		var prepared *preparedPayout
+		prepared, err = s.preparePayout(ctx, fromPrivateKey, retry)
+		if err == nil {
+			// 先记录新占用的限额再发送，进程中断后重试可复用
+			if err = s.postgres.SetPayoutItemReservations(ctx, prepared.acquired); err != nil {
+				s.releasePayoutItems(ctx, prepared.from, prepared.acquired)
+				err = fmt.Errorf("failed to record payout reservations: %w", err)
+			}
+		}
+		if err == nil {
+			go s.runPayout(id, prepared)
+			return s.GetPayout(ctx, id)
+		}
+	}
+
+	// 无需重发或准备失败时恢复批次状态
+	s.finishPayout(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPayout(ctx, id)
+}
+
+// payoutSOLTotal 计算批次中 SOL 付款的合计，用于审计日志
+func payoutSOLTotal(items []models.PayoutItem) decimal.Decimal {
+	total := decimal.Zero
+	for _, item := range items {
+		if item.Asset == models.AssetSOL {
+			total = total.Add(item.Amount)
+		}
+	}
+	return total
+}
+
+// payoutItem 校验付款请求并转换为付款项
+func (s *WalletService) payoutItem(req PayoutRequest) (*models.PayoutItem, error) {
+	if _, err := solana.PublicKeyFromBase58(req.Recipient); err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount")
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("amount must be greater than 0")
+	}
+	if len(req.Reference) > 255 {
+		return nil, fmt.Errorf("reference is longer than 255 characters")
+	}
+
+	asset := req.Asset
+	if asset == "" {
+		asset = models.AssetSOL
+	}
+	decimals := int32(9)
+	if asset != models.AssetSOL {
+		token, ok := s.cfg.SPLTokens[asset]
+		if !ok {
+			return nil, fmt.Errorf("unsupported asset: %s", asset)
+		}
+		decimals = int32(token.Decimals)
+	}
+	if decimals > payoutAmountPlaces {
+		decimals = payoutAmountPlaces
+	}
+	if !amount.Equal(amount.Truncate(decimals)) {
+		return nil, fmt.Errorf("amount has more than %d decimal places", decimals)
+	}
+
+	return &models.PayoutItem{
+		Recipient: req.Recipient,
+		Amount:    amount,
+		Asset:     asset,
+		Reference: req.Reference,
+	}, nil
+}
+
+// payment 付款项对应的链上转账
+func (s *WalletService) payment(item models.PayoutItem) (solanaclient.Payment, error) {
+	recipient, err := solana.PublicKeyFromBase58(item.Recipient)
+	if err != nil {
+		return solanaclient.Payment{}, fmt.Errorf("invalid recipient: %w", err)
+	}
+	p := solanaclient.Payment{Recipient: recipient, Amount: item.Amount}
+	if item.Asset == models.AssetSOL {
+		return p, nil
+	}
+
+	token, ok := s.cfg.SPLTokens[item.Asset]
+	if !ok {
+		return solanaclient.Payment{}, fmt.Errorf("unsupported asset: %s", item.Asset)
+	}
+	if p.Mint, err = solana.PublicKeyFromBase58(token.Mint); err != nil {
+		return solanaclient.Payment{}, fmt.Errorf("invalid mint for %s: %w", item.Asset, err)
+	}
+	p.Decimals = token.Decimals
+	return p, nil
+}
+
+// preparePayout 打包签名交易，检查发送方链上余额（含手续费），并为尚未占用限额的付款项占用限额、
+// 扣减 Redis 与账本可用余额（SOL），占用记录写入 items 的 LimitReservation
+func (s *WalletService) preparePayout(ctx context.Context, from solana.PrivateKey, items []models.PayoutItem) (*preparedPayout, error) {
+	payments := make([]solanaclient.Payment, len(items))
+	for i, item := range items {
+		p, err := s.payment(item)
+		if err != nil {
+			return nil, err
+		}
+		payments[i] = p
+	}
+
+	txs, err := s.solana.BuildPayments(ctx, from, payments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build payout transactions: %w", err)
+	}
+
+	// 按资产汇总所需金额，手续费与新建代币账户的租金以 SOL 支付
+	fromAddress := from.PublicKey().String()
+	required := make(map[string]decimal.Decimal)
+	for _, item := range items {
+		required[item.Asset] = required[item.Asset].Add(item.Amount)
+	}
+	var fees uint64
+	for _, tx := range txs {
+		fees += tx.Fee(lamportsPerSignature)
+	}
+	required[models.AssetSOL] = required[models.AssetSOL].Add(decimal.NewFromInt(int64(fees)).Shift(-9))
+
+	for asset, amount := range required {
+		var balance decimal.Decimal
+		if asset == models.AssetSOL {
+			balance, err = s.solana.GetBalance(ctx, fromAddress)
+		} else {
+			token := s.cfg.SPLTokens[asset]
+			balance, err = s.solana.GetTokenBalance(ctx, from.PublicKey(), solana.MustPublicKeyFromBase58(token.Mint), token.Decimals)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sender %s balance: %w", asset, err)
+		}
+		if balance.LessThan(amount) {
+			return nil, fmt.Errorf("insufficient %s balance: need %s, have %s", asset, amount, balance)
+		}
+	}
+
+	// 每个付款项单独占用限额并扣减 Redis 与账本可用余额（冻结、待审批与离线出账的资金不可用），
+	// 失败的付款项可单独释放。上次超时未确认的付款项仍占用着限额与余额，直接复用
+	var reserved []int
+	for i := range items {
+		if items[i].LimitReservation != "" {
+			continue
+		}
+		res, err := s.limits.Reserve(ctx, fromAddress, items[i].Asset, items[i].Amount)
+		if err == nil && items[i].Asset == models.AssetSOL {
+			err = s.debitPayoutItem(ctx, fromAddress, items[i])
+			if err != nil {
+				s.limits.Release(ctx, res)
+				err = fmt.Errorf("insufficient ledger balance for item %d: %w", items[i].Seq, err)
+			}
+		}
+		if err != nil {
+			released := make([]models.PayoutItem, len(reserved))
+			for k, j := range reserved {
+				released[k] = items[j]
+				items[j].LimitReservation = ""
+			}
+			s.releasePayoutItems(ctx, fromAddress, released)
+			return nil, err
+		}
+		items[i].LimitReservation = res.ID
+		reserved = append(reserved, i)
+	}
+	acquired := make([]models.PayoutItem, len(reserved))
+	for k, i := range reserved {
+		acquired[k] = items[i]
+	}
+
+	return &preparedPayout{
+		from:     fromAddress,
+		items:    items,
+		txs:      txs,
+		acquired: acquired,
+	}, nil
+}
+
+// debitPayoutItem 发送前扣减 Redis 与账本余额，账本扣减失败时退回 Redis
+func (s *WalletService) debitPayoutItem(ctx context.Context, from string, item models.PayoutItem) error {
+	if err := s.redis.SubBalance(ctx, from, item.Amount); err != nil {
+		return err
+	}
+	if err := s.postgres.SubBalance(ctx, from, item.Amount); err != nil {
+		if rollbackErr := s.redis.AddBalance(ctx, from, item.Amount); rollbackErr != nil {
+			s.logger.Logger.Error("failed to rollback redis balance for payout item",
+				zap.String("item", item.ID),
+				zap.Error(rollbackErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// runPayout 在后台发送并确认交易
+func (s *WalletService) runPayout(batchID string, p *preparedPayout) {
+	ctx, cancel := context.WithTimeout(context.Background(), payoutProcessTimeout)
+	defer cancel()
+	// 超时后仍需更新批次状态
+	defer s.finishPayout(context.Background(), batchID)
+
+	var sent []*solanaclient.PaymentTransaction
+	for _, tx := range p.txs {
+		ids := p.itemIDs(tx)
+		// 先记录签名再发送，进程中断后可根据签名查询结果，避免重复付款
+		if err := s.postgres.MarkPayoutItemsSubmitted(ctx, ids, tx.Signature.String()); err != nil {
+			s.logger.Logger.Error("failed to record payout submission",
+				zap.String("batch", batchID),
+				zap.Error(err))
+			s.failPayoutItems(ctx, p, tx, err)
+			continue
+		}
+		s.publishPayoutItems(ctx, p.from, p.itemsOf(tx), models.PayoutItemSubmitted)
+		err := s.solana.SendPayment(ctx, tx)
+		if errors.Is(err, ErrSendUnconfirmed) {
+			// 交易可能已发出，按签名确认结果，不能释放资金
+			s.logger.Logger.Warn("payout transaction outcome unknown, tracking by signature",
+				zap.String("batch", batchID),
+				zap.String("signature", tx.Signature.String()),
+				zap.Error(err))
+			sent = append(sent, tx)
+			continue
+		}
+		if err != nil {
+			s.logger.Logger.Warn("failed to send payout transaction",
+				zap.String("batch", batchID),
+				zap.String("signature", tx.Signature.String()),
+				zap.Error(err))
+			s.failPayoutItems(ctx, p, tx, err)
+			continue
+		}
+		sent = append(sent, tx)
+	}
+
+	s.confirmPayout(ctx, batchID, p, sent)
+}
+
+// confirmPayout 轮询交易状态直到全部确认或超时，超时的付款项保持 submitted，重试时再查询
+func (s *WalletService) confirmPayout(ctx context.Context, batchID string, p *preparedPayout, sent []*solanaclient.PaymentTransaction) {
+	deadline := time.Now().Add(payoutConfirmTimeout)
+	for len(sent) > 0 && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(payoutPollInterval):
+		}
+
+		sigs := make([]solana.Signature, len(sent))
+		for i, tx := range sent {
+			sigs[i] = tx.Signature
+		}
+		statuses, err := s.solana.SignatureStatuses(ctx, sigs)
+		if err != nil {
+			s.logger.Logger.Warn("failed to get payout statuses",
+				zap.String("batch", batchID),
+				zap.Error(err))
+			continue
+		}
+
+		var waiting []*solanaclient.PaymentTransaction
+		for i, tx := range sent {
+			status := statuses[i]
+			if status == nil || status.ConfirmationStatus == rpc.ConfirmationStatusProcessed {
+				waiting = append(waiting, tx)
+				continue
+			}
+			if status.Err != nil {
+				s.failPayoutItems(ctx, p, tx, fmt.Errorf("transaction failed: %v", status.Err))
+				continue
+			}
+			s.completePayoutItems(ctx, p.from, tx.Signature.String(), p.itemsOf(tx))
+		}
+		sent = waiting
+	}
+}
+
+// resolvePayoutItems 查询已提交交易的链上结果，返回需要重新发送的付款项
+func (s *WalletService) resolvePayoutItems(ctx context.Context, batch *models.PayoutBatch) ([]models.PayoutItem, error) {
+	bySignature := make(map[string][]models.PayoutItem)
+	var retry []models.PayoutItem
+	for _, item := range batch.Items {
+		switch {
+		case item.Status == models.PayoutItemCompleted:
+		case item.Signature == "":
+			// 从未提交
+			retry = append(retry, item)
+		case item.Status == models.PayoutItemSubmitted || item.Status == models.PayoutItemFailed:
+			bySignature[item.Signature] = append(bySignature[item.Signature], item)
+		default:
+			retry = append(retry, item)
+		}
+	}
+	if len(bySignature) == 0 {
+		return retry, nil
+	}
+
+	sigs := make([]solana.Signature, 0, len(bySignature))
+	for sig := range bySignature {
+		parsed, err := solana.SignatureFromBase58(sig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payout signature %s: %w", sig, err)
+		}
+		sigs = append(sigs, parsed)
+	}
+	statuses, err := s.solana.SignatureStatuses(ctx, sigs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, sig := range sigs {
+		items := bySignature[sig.String()]
+		status := statuses[i]
+		switch {
+		case status != nil && status.Err == nil:
+			// 已上链，只是之前未确认
+			s.completePayoutItems(ctx, batch.FromAddress, sig.String(), items)
+		case status != nil:
+			retry = append(retry, items...)
+		case time.Since(items[0].UpdatedAt) > payoutResendAfter:
+			retry = append(retry, items...)
+		}
+	}
+	return retry, nil
+}
+
+// completePayoutItems 标记已上链的付款项完成并写入交易记录。Redis 与账本余额在发送前已扣减，
+// 失败后已退回的付款项（之后发现已上链）在此重新扣减
+func (s *WalletService) completePayoutItems(ctx context.Context, from, signature string, items []models.PayoutItem) {
+	now := time.Now()
+	records := make([]models.Transaction, len(items))
+	for i, item := range items {
+		records[i] = models.Transaction{
+			ID:             item.ID,
+			FromWallet:     from,
+			ToWallet:       item.Recipient,
+			Amount:         item.Amount,
+			Asset:          item.Asset,
+			Type:           "payout",
+			Status:         "completed",
+			ChainSignature: signature,
+			CreatedAt:      item.CreatedAt,
+			CompletedAt:    now,
+		}
+	}
+
+	completed, err := s.postgres.CompletePayoutItems(ctx, from, records)
+	if err != nil {
+		ids := make([]string, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+		s.logger.Logger.Error("failed to complete payout items",
+			zap.Strings("items", ids),
+			zap.Error(err))
+		return
+	}
+
+	done := make(map[string]bool, len(completed))
+	debit := decimal.Zero
+	for _, item := range completed {
+		done[item.ID] = true
+		if item.LimitReservation == "" && item.Asset == models.AssetSOL {
+			debit = debit.Add(item.Amount)
+		}
+	}
+	if debit.IsPositive() {
+		if err := s.redis.SubBalance(ctx, from, debit); err != nil {
+			s.logger.Logger.Error("failed to debit redis balance for payout",
+				zap.String("address", from),
+				zap.String("amount", debit.String()),
+				zap.Error(err))
+		}
+	}
+
+	addresses := []string{from}
+	for i := range records {
+		if !done[records[i].ID] {
+			continue
+		}
+		addresses = append(addresses, records[i].ToWallet)
+		s.streams.PublishTransaction(ctx, &records[i])
+	}
+	s.balancesChanged(ctx, addresses...)
+}
+
+// failPayoutItems 标记交易内的付款项失败并释放其限额。标记失败时占用保留在付款项上，重试时复用
+func (s *WalletService) failPayoutItems(ctx context.Context, p *preparedPayout, tx *solanaclient.PaymentTransaction, cause error) {
+	ids := p.itemIDs(tx)
+	failed, err := s.postgres.MarkPayoutItemsFailed(ctx, ids, cause.Error())
+	if err != nil {
+		s.logger.Logger.Error("failed to mark payout items failed",
+			zap.Strings("items", ids),
+			zap.Error(err))
+		return
+	}
+	s.publishPayoutItems(ctx, p.from, p.itemsOf(tx), models.PayoutItemFailed)
+	s.releasePayoutItems(ctx, p.from, failed)
+}
+
+// finishPayout 根据付款项结果更新批次状态
+func (s *WalletService) finishPayout(ctx context.Context, batchID string) {
+	batch, err := s.postgres.GetPayoutBatch(ctx, batchID)
+	if err == nil {
+		batch.Summary = summarizePayout(batch.Items)
+		batch.Status = payoutStatus(batch.Summary)
+		err = s.postgres.UpdatePayoutBatchStatus(ctx, batchID, batch.Status)
+	}
+	if err != nil {
+		s.logger.Logger.Error("failed to update payout batch status",
+			zap.String("batch", batchID),
+			zap.Error(err))
+		return
+	}
+
+	// 仍有未确认的交易时等待重试后再通知
+	if batch.Status != models.PayoutStatusUnconfirmed {
+		s.webhooks.Publish(WithClientID(ctx, batch.ClientID), models.EventPayoutFinished, map[string]interface{}{
+			"id":      batch.ID,
+			"status":  batch.Status,
+			"summary": batch.Summary,
+		})
+	}
+}
+
+// publishPayoutItems 推送付款项的状态变化
+func (s *WalletService) publishPayoutItems(ctx context.Context, from string, items []models.PayoutItem, status string) {
+	for _, item := range items {
+		s.streams.PublishTransaction(ctx, &models.Transaction{
+			ID:         item.ID,
+			FromWallet: from,
+			ToWallet:   item.Recipient,
+			Amount:     item.Amount,
+			Asset:      item.Asset,
+			Type:       "payout",
+			Status:     status,
+			CreatedAt:  item.CreatedAt,
+		})
+	}
+}
+
+// releasePayoutItems 释放付款项占用的限额，并退回 SOL 付款项扣减的账本与 Redis 余额
+func (s *WalletService) releasePayoutItems(ctx context.Context, from string, items []models.PayoutItem) {
+	refund := decimal.Zero
+	for _, item := range items {
+		if item.LimitReservation == "" {
+			continue
+		}
+		s.limits.ReleaseID(ctx, item.LimitReservation)
+		if item.Asset == models.AssetSOL {
+			refund = refund.Add(item.Amount)
+		}
+	}
+	if !refund.IsPositive() {
+		return
+	}
+	if err := s.postgres.AddBalance(ctx, from, refund); err != nil {
+		s.logger.Logger.Error("failed to refund payout ledger balance",
+			zap.String("address", from),
+			zap.String("amount", refund.String()),
+			zap.Error(err))
+		return
+	}
+	if err := s.redis.AddBalance(ctx, from, refund); err != nil {
+		s.logger.Logger.Error("failed to refund payout redis balance",
+			zap.String("address", from),
+			zap.String("amount", refund.String()),
+			zap.Error(err))
+		return
+	}
+	s.balancesChanged(ctx, from)
+}
+
+func (p *preparedPayout) itemsOf(tx *solanaclient.PaymentTransaction) []models.PayoutItem {
+	items := make([]models.PayoutItem, len(tx.Payments))
+	for i, idx := range tx.Payments {
+		items[i] = p.items[idx]
+	}
+	return items
+}
+
+func (p *preparedPayout) itemIDs(tx *solanaclient.PaymentTransaction) []string {
+	ids := make([]string, len(tx.Payments))
+	for i, idx := range tx.Payments {
+		ids[i] = p.items[idx].ID
+	}
+	return ids
+}
+
+func summarizePayout(items []models.PayoutItem) models.PayoutSummary {
+	summary := models.PayoutSummary{Total: len(items)}
+	for _, item := range items {
+		switch item.Status {
+		case models.PayoutItemPending:
+			summary.Pending++
+		case models.PayoutItemSubmitted:
+			summary.Submitted++
+		case models.PayoutItemCompleted:
+			summary.Completed++
+		case models.PayoutItemFailed:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// payoutStatus 处理结束后的批次状态
+func payoutStatus(summary models.PayoutSummary) string {
+	switch {
+	case summary.Completed == summary.Total:
+		return models.PayoutStatusCompleted
+	case summary.Submitted > 0 || summary.Pending > 0:
+		return models.PayoutStatusUnconfirmed
+	case summary.Failed == summary.Total:
+		return models.PayoutStatusFailed
+	default:
+		return models.PayoutStatusPartial
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+
+	"mywallet/internal/config"
+	"mywallet/internal/models"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayoutItemValidation(t *testing.T) {
+	s := &WalletService{cfg: &config.Config{SPLTokens: map[string]config.SPLToken{
+		"USDC": {Mint: "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", Decimals: 6},
+	}}}
+	recipient := solana.NewWallet().PublicKey().String()
+
+	item, err := s.payoutItem(PayoutRequest{Recipient: recipient, Amount: "0.5"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AssetSOL, item.Asset)
+
+	item, err = s.payoutItem(PayoutRequest{Recipient: recipient, Amount: "1.250000", Asset: "USDC"})
+	require.NoError(t, err)
+	assert.Equal(t, "USDC", item.Asset)
+
+	invalid := []PayoutRequest{
+		{Recipient: "not-an-address", Amount: "1"},
+		{Recipient: recipient, Amount: "0"},
+		{Recipient: recipient, Amount: "abc"},
+		{Recipient: recipient, Amount: "1", Asset: "DOGE"},
+		{Recipient: recipient, Amount: "0.0000001", Asset: "USDC"},
+		{Recipient: recipient, Amount: "0.000000001"},
+	}
+	for _, req := range invalid {
+		_, err := s.payoutItem(req)
+		assert.Error(t, err, "%+v", req)
+	}
+}
+
+func TestPayoutStatus(t *testing.T) {
+	assert.Equal(t, models.PayoutStatusCompleted, payoutStatus(models.PayoutSummary{Total: 2, Completed: 2}))
+	assert.Equal(t, models.PayoutStatusPartial, payoutStatus(models.PayoutSummary{Total: 2, Completed: 1, Failed: 1}))
+	assert.Equal(t, models.PayoutStatusFailed, payoutStatus(models.PayoutSummary{Total: 2, Failed: 2}))
+	assert.Equal(t, models.PayoutStatusUnconfirmed, payoutStatus(models.PayoutSummary{Total: 2, Completed: 1, Submitted: 1}))
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mywallet/internal/config"
+	"mywallet/pkg/logger"
+	solanaclient "mywallet/pkg/solana"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUpstreamUnavailable RPC 服务不可用（熔断中或重试后仍失败）
+	ErrUpstreamUnavailable = solanaclient.ErrUpstreamUnavailable
+	// ErrSendUnconfirmed 交易发送结果未知，可能已上链
+	ErrSendUnconfirmed = solanaclient.ErrSendUnconfirmed
+)
+
+// unconfirmedSendWait 发送结果未知时等待交易签名的时间，超过 blockhash 有效期后未上链的交易不会再上链
+const unconfirmedSendWait = 90 * time.Second
+
+// rpcPolicy 根据配置生成 RPC 超时、重试与熔断策略
+func rpcPolicy(cfg *config.Config) solanaclient.Policy {
+	toCallPolicy := func(p config.RPCCallPolicy) solanaclient.CallPolicy {
+		return solanaclient.CallPolicy{Timeout: p.Timeout, Retries: p.Retries, Backoff: p.Backoff, MaxBackoff: p.MaxBackoff}
+	}
+	policy := solanaclient.Policy{
+		Default:          toCallPolicy(cfg.RPCDefaultPolicy),
+		Methods:          make(map[string]solanaclient.CallPolicy, len(cfg.RPCMethodPolicies)),
+		BreakerThreshold: cfg.RPCBreakerThreshold,
+		BreakerCooldown:  cfg.RPCBreakerCooldown,
+	}
+	for method, p := range cfg.RPCMethodPolicies {
+		policy.Methods[method] = toCallPolicy(p)
+	}
+	return policy
+}
+
+// newSolanaClient 配置了多个 RPC 节点时使用节点池，否则只连接 SolanaRPC
+func newSolanaClient(cfg *config.Config, logger *logger.Logger) (*solanaclient.Client, error) {
+	if len(cfg.SolanaEndpoints) == 0 {
+		return solanaclient.NewClientWithPolicy(cfg.SolanaRPC, rpcPolicy(cfg), logger), nil
+	}
+
+	endpoints := make([]solanaclient.Endpoint, len(cfg.SolanaEndpoints))
+	for i, e := range cfg.SolanaEndpoints {
+		endpoints[i] = solanaclient.Endpoint{URL: e.URL, RateLimit: e.RateLimit, Burst: e.Burst}
+	}
+	pool, err := solanaclient.NewPool(endpoints, solanaclient.PoolOptions{
+		HedgeDelay: cfg.RPCHedgeDelay,
+		SendFanout: cfg.RPCSendFanout,
+		MaxSlotLag: cfg.RPCMaxSlotLag,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rpc pool: %w", err)
+	}
+	return solanaclient.NewPoolClient(pool, rpcPolicy(cfg), logger), nil
+}
+
+// RPCEndpoints 返回各 RPC 节点的健康状态，按评分从优到劣排列；未使用节点池时为空
+func (s *WalletService) RPCEndpoints() []solanaclient.EndpointStats {
+	pool := s.solana.Pool()
+	if pool == nil {
+		return []solanaclient.EndpointStats{}
+	}
+	return pool.Stats()
+}
+
+// resolveUnconfirmedSend 等待结果未知的交易：已上链返回 nil，确定未上链返回原错误，
+// 仍无法确定时返回的错误包含 ErrSendUnconfirmed，资金可能已转出，不能释放或重试
+func (s *WalletService) resolveUnconfirmedSend(ctx context.Context, signature string, sendErr error) error {
+	// 调用方取消后仍需等到结果，避免账本与链上不一致
+	wctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unconfirmedSendWait+10*time.Second)
+	defer cancel()
+
+	landed, err := s.solana.WaitForSignature(wctx, signature, unconfirmedSendWait)
+	switch {
+	case err != nil:
+		s.logger.Logger.Error("transaction outcome unknown, reconcile manually",
+			zap.String("signature", signature),
+			zap.Error(err))
+		return fmt.Errorf("transaction %s: %w", signature, sendErr)
+	case landed:
+		s.logger.Logger.Warn("transaction landed despite send error",
+			zap.String("signature", signature),
+			zap.Error(sendErr))
+		return nil
+	default:
+		// 不再包含 ErrSendUnconfirmed，调用方可以释放资金
+		return fmt.Errorf("transaction %s did not land: %v", signature, sendErr)
+	}
+}
+
+// RPCBreaker 返回 RPC 熔断器状态
+func (s *WalletService) RPCBreaker() solanaclient.BreakerStatus {
+	return s.solana.Breaker()
+}
+
+// RunRPCProbe 定期探测各 RPC 节点的 slot，空闲节点的延迟与落后程度也能及时更新
+func (s *WalletService) RunRPCProbe(ctx context.Context, interval time.Duration) {
+	pool := s.solana.Pool()
+	if pool == nil {
+		return
+	}
+	pool.Probe(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pool.Probe(ctx)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultScheduleRetries    = 3
+	maxScheduleRetries        = 10
+	defaultScheduleRetryDelay = time.Minute
+	// maxScheduleRetryDelay 指数退避的上限
+	maxScheduleRetryDelay = 24 * time.Hour
+	// scheduleBatch 每轮最多执行的定时转账数量
+	scheduleBatch = 50
+	// scheduleExecutionTimeout 单次转账的最长时间
+	scheduleExecutionTimeout = 2 * time.Minute
+	// scheduleStaleAfter 超过该时间仍在执行的记录视为进程中断
+	scheduleStaleAfter   = 10 * time.Minute
+	scheduleHistoryLimit = 100
+	// schedulerLockKey 调度器选主使用的 advisory lock，与迁移使用的锁不同
+	schedulerLockKey = 7_240_385_002
+)
+
+var (
+	ErrScheduleNotFound = repository.ErrScheduleNotFound
+	ErrScheduleStatus   = repository.ErrScheduleStatus
+)
+
+// cronParser 标准五段 cron 表达式，支持 @daily、@monthly 等描述符
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ScheduleRequest 创建定时转账的参数
+type ScheduleRequest struct {
+	// FromAddress 转出的托管钱包地址
+	FromAddress string
+	ToAddress   string
+	Amount      decimal.Decimal
+	// RunAt 单次转账的执行时间；周期转账时为首次执行的最早时间
+	RunAt *time.Time
+	// Cron 为空时只执行一次
+	Cron string
+	// MaxRetries 为 nil 时使用默认值
+	MaxRetries *int
+	RetryDelay time.Duration
+	Reference  string
+}
+
+// CreateSchedule 创建单次或周期转账。API 客户端只能从归属自己的托管钱包转出
+func (s *WalletService) CreateSchedule(ctx context.Context, req ScheduleRequest) (*models.TransferSchedule, error) {
+	if req.Amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("transfer amount must be greater than 0")
+	}
+	if _, err := solana.PublicKeyFromBase58(req.ToAddress); err != nil {
+		return nil, fmt.Errorf("invalid to address: %w", err)
+	}
+	if req.ToAddress == req.FromAddress {
+		return nil, fmt.Errorf("from and to address must be different")
+	}
+	if _, err := s.ownedManagedKey(ctx, req.FromAddress); err != nil {
+		return nil, err
+	}
+	if _, err := s.checkDestination(ctx, req.FromAddress, req.ToAddress); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var next time.Time
+	if req.Cron != "" {
+		sched, err := cronParser.Parse(req.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron: %w", err)
+		}
+		start := now
+		if req.RunAt != nil && req.RunAt.After(now) {
+			start = req.RunAt.UTC()
+		}
+		next = sched.Next(start)
+	} else {
+		if req.RunAt == nil {
+			return nil, fmt.Errorf("run_at or cron is required")
+		}
+		next = req.RunAt.UTC()
+	}
+
+	retries := defaultScheduleRetries
+	if req.MaxRetries != nil {
+		retries = *req.MaxRetries
+	}
+	if retries < 0 || retries > maxScheduleRetries {
+		return nil, fmt.Errorf("max retries must be between 0 and %d", maxScheduleRetries)
+	}
+	delay := req.RetryDelay
+	if delay == 0 {
+		delay = defaultScheduleRetryDelay
+	}
+	if delay < time.Second || delay > maxScheduleRetryDelay {
+		return nil, fmt.Errorf("retry delay must be between 1s and %s", maxScheduleRetryDelay)
+	}
+
+	schedule := &models.TransferSchedule{
+		ID:                uuid.NewString(),
+		ClientID:          ClientIDFromContext(ctx),
+		FromAddress:       req.FromAddress,
+		ToAddress:         req.ToAddress,
+		Amount:            req.Amount,
+		Cron:              req.Cron,
+		Status:            models.ScheduleStatusActive,
+		NextRunAt:         &next,
+		MaxRetries:        retries,
+		RetryDelaySeconds: int(delay / time.Second),
+		Reference:         req.Reference,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := s.postgres.CreateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// GetSchedule 查询定时转账。API 客户端只能查询自己创建的定时转账
+func (s *WalletService) GetSchedule(ctx context.Context, id string) (*models.TransferSchedule, error) {
+	schedule, err := s.postgres.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin && schedule.ClientID != ClientIDFromContext(ctx) {
+		return nil, ErrScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// ListSchedules 查询托管钱包的定时转账，API 客户端只返回自己创建的
+func (s *WalletService) ListSchedules(ctx context.Context, fromAddress string) ([]models.TransferSchedule, error) {
+	if _, err := solana.PublicKeyFromBase58(fromAddress); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	var clientID string
+	if actor := ActorFromContext(ctx); actor.Type != models.ActorAdmin {
+		if clientID = ClientIDFromContext(ctx); clientID == "" {
+			return nil, ErrClientRequired
+		}
+	}
+	return s.postgres.ListSchedules(ctx, fromAddress, clientID)
+}
+
+// ListScheduleExecutions 查询定时转账最近的执行记录
+func (s *WalletService) ListScheduleExecutions(ctx context.Context, id string) ([]models.ScheduleExecution, error) {
+	if _, err := s.GetSchedule(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.postgres.ListExecutions(ctx, id, scheduleHistoryLimit)
+}
+
+// PauseSchedule 暂停定时转账，正在进行的执行不受影响
+func (s *WalletService) PauseSchedule(ctx context.Context, id string) (*models.TransferSchedule, error) {
+	if _, err := s.GetSchedule(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.postgres.SetScheduleStatus(ctx, id,
+		[]string{models.ScheduleStatusActive}, models.ScheduleStatusPaused, nil)
+}
+
+// ResumeSchedule 恢复定时转账。周期转账跳过暂停期间错过的执行，从下一个周期开始
+func (s *WalletService) ResumeSchedule(ctx context.Context, id string) (*models.TransferSchedule, error) {
+	schedule, err := s.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	next := now
+	if schedule.Cron != "" {
+		sched, err := cronParser.Parse(schedule.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron: %w", err)
+		}
+		next = sched.Next(now)
+	} else if schedule.NextRunAt != nil && schedule.NextRunAt.After(now) {
+		next = *schedule.NextRunAt
+	}
+
+	return s.postgres.SetScheduleStatus(ctx, id,
+		[]string{models.ScheduleStatusPaused}, models.ScheduleStatusActive, &next)
+}
+
+// CancelSchedule 取消定时转账
+func (s *WalletService) CancelSchedule(ctx context.Context, id string) (*models.TransferSchedule, error) {
+	if _, err := s.GetSchedule(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.postgres.SetScheduleStatus(ctx, id,
+		[]string{models.ScheduleStatusActive, models.ScheduleStatusPaused}, models.ScheduleStatusCancelled, nil)
+}
+
+// RunDueSchedules 执行到期的定时转账。通过 advisory lock 选主，多实例部署时同一时间只有一个实例执行。
+// 返回执行数量
+func (s *WalletService) RunDueSchedules(ctx context.Context) (int, error) {
+	executed := 0
+	_, err := s.postgres.WithAdvisoryLock(ctx, schedulerLockKey, func(ctx context.Context) error {
+		// 进程中断的执行结果未知，暂停对应的定时转账等待人工确认
+		interrupted, err := s.postgres.InterruptStaleExecutions(ctx, time.Now().UTC().Add(-scheduleStaleAfter))
+		if err != nil {
+			return fmt.Errorf("failed to interrupt stale executions: %w", err)
+		}
+		for _, id := range interrupted {
+			s.logger.Logger.Warn("paused schedule after interrupted execution", zap.String("schedule", id))
+		}
+
+		due, err := s.postgres.ListDueSchedules(ctx, time.Now().UTC(), scheduleBatch)
+		if err != nil {
+			return fmt.Errorf("failed to list due schedules: %w", err)
+		}
+		for i := range due {
+			if ctx.Err() != nil {
+				break
+			}
+			s.executeSchedule(ctx, &due[i])
+			executed++
+		}
+		return nil
+	})
+	return executed, err
+}
+
+// RunScheduler 定期执行到期的定时转账，直到 ctx 取消
+func (s *WalletService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.RunDueSchedules(ctx); err != nil {
+				s.logger.Logger.Error("failed to run schedules", zap.Error(err))
+			} else if n > 0 {
+				s.logger.Logger.Info("executed schedules", zap.Int("count", n))
+			}
+		}
+	}
+}
+
+func (s *WalletService) executeSchedule(ctx context.Context, schedule *models.TransferSchedule) {
+	now := time.Now().UTC()
+	exec := &models.ScheduleExecution{
+		ID:           uuid.NewString(),
+		ScheduleID:   schedule.ID,
+		ScheduledFor: *schedule.NextRunAt,
+		Attempt:      schedule.Attempt + 1,
+		Status:       models.ExecutionStatusRunning,
+		StartedAt:    now,
+	}
+	if err := s.postgres.StartExecution(ctx, exec); err != nil {
+		s.logger.Logger.Error("failed to start schedule execution",
+			zap.String("schedule", schedule.ID),
+			zap.Error(err))
+		return
+	}
+
+	var signature string
+	key, err := s.managedKey(ctx, schedule.FromAddress)
+	if err == nil {
+		// 定时转账无人值守，金额达到审批档位时不执行
+		var policy *models.ApprovalPolicy
+		if policy, err = s.postgres.MatchApprovalPolicy(ctx, schedule.FromAddress, schedule.Amount); err == nil && policy != nil {
+			err = ErrScheduleRequiresApproval
+		}
+	}
+	if err == nil {
+		// 限额按创建定时转账的 API 客户端计算，审计主体为调度任务
+		tctx := WithActor(WithClientID(ctx, schedule.ClientID),
+			models.Actor{Type: models.ActorSystem, ID: "schedule:" + schedule.ID})
+		tctx, cancel := context.WithTimeout(tctx, scheduleExecutionTimeout)
+		var tx *models.Transaction
+		tx, err = s.transfer(tctx, key, schedule.ToAddress, schedule.Amount)
+		cancel()
+		if tx != nil {
+			signature = tx.ChainSignature
+		}
+	}
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	// 有签名说明链上转账已成功，即使之后记账失败也不能重试
+	status := models.ExecutionStatusFailed
+	if signature != "" {
+		status = models.ExecutionStatusSucceeded
+	}
+	if err := s.postgres.FinishExecution(ctx, exec.ID, status, signature, errMsg); err != nil {
+		s.logger.Logger.Error("failed to finish schedule execution",
+			zap.String("schedule", schedule.ID),
+			zap.String("execution", exec.ID),
+			zap.Error(err))
+	}
+
+	next, scheduleStatus, attempt := nextScheduleRun(schedule, signature != "", time.Now().UTC())
+	if errors.Is(err, ErrSendUnconfirmed) {
+		// 转账可能已上链，重试可能重复付款，暂停等待人工核对后恢复
+		next, scheduleStatus = nil, models.ScheduleStatusPaused
+		s.logger.Logger.Error("scheduled transfer outcome unknown, schedule paused",
+			zap.String("schedule", schedule.ID),
+			zap.Error(err))
+	}
+	if signature != "" && err != nil {
+		s.logger.Logger.Error("scheduled transfer succeeded on chain but failed to update ledger",
+			zap.String("schedule", schedule.ID),
+			zap.String("signature", signature),
+			zap.Error(err))
+	}
+	if err := s.postgres.UpdateScheduleAfterRun(ctx, schedule.ID, scheduleStatus, next, attempt, errMsg); err != nil {
+		s.logger.Logger.Error("failed to update schedule",
+			zap.String("schedule", schedule.ID),
+			zap.Error(err))
+	}
+}
+
+// nextScheduleRun 根据执行结果计算下次执行时间、状态与失败次数。
+// 失败后按 retry_delay 指数退避重试，重试用尽后单次转账失败，周期转账等待下一个周期
+func nextScheduleRun(schedule *models.TransferSchedule, succeeded bool, now time.Time) (*time.Time, string, int) {
+	var sched cron.Schedule
+	if schedule.Cron != "" {
+		var err error
+		if sched, err = cronParser.Parse(schedule.Cron); err != nil {
+			return nil, models.ScheduleStatusFailed, schedule.Attempt
+		}
+	}
+
+	if !succeeded {
+		attempt := schedule.Attempt + 1
+		if attempt <= schedule.MaxRetries {
+			delay := time.Duration(schedule.RetryDelaySeconds) * time.Second << (attempt - 1)
+			if delay <= 0 || delay > maxScheduleRetryDelay {
+				delay = maxScheduleRetryDelay
+			}
+			next := now.Add(delay)
+			return &next, models.ScheduleStatusActive, attempt
+		}
+		if sched == nil {
+			return nil, models.ScheduleStatusFailed, attempt
+		}
+	}
+
+	if sched == nil {
+		return nil, models.ScheduleStatusCompleted, 0
+	}
+	next := sched.Next(now)
+	return &next, models.ScheduleStatusActive, 0
+}
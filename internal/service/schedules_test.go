@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mywallet/internal/config"
+	"mywallet/internal/keystore"
+	"mywallet/internal/models"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextScheduleRun(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	once := &models.TransferSchedule{MaxRetries: 2, RetryDelaySeconds: 60}
+	monthly := &models.TransferSchedule{Cron: "0 0 1 * *", MaxRetries: 2, RetryDelaySeconds: 60}
+
+	// 单次转账成功后完成
+	next, status, attempt := nextScheduleRun(once, true, now)
+	assert.Nil(t, next)
+	assert.Equal(t, models.ScheduleStatusCompleted, status)
+	assert.Equal(t, 0, attempt)
+
+	// 失败后指数退避
+	next, status, attempt = nextScheduleRun(once, false, now)
+	require.NotNil(t, next)
+	assert.Equal(t, now.Add(time.Minute), *next)
+	assert.Equal(t, models.ScheduleStatusActive, status)
+	assert.Equal(t, 1, attempt)
+
+	once.Attempt = 1
+	next, _, attempt = nextScheduleRun(once, false, now)
+	assert.Equal(t, now.Add(2*time.Minute), *next)
+	assert.Equal(t, 2, attempt)
+
+	// 重试用尽
+	once.Attempt = 2
+	next, status, _ = nextScheduleRun(once, false, now)
+	assert.Nil(t, next)
+	assert.Equal(t, models.ScheduleStatusFailed, status)
+
+	// 周期转账成功或重试用尽后进入下一个周期
+	next, status, attempt = nextScheduleRun(monthly, true, now)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), *next)
+	assert.Equal(t, models.ScheduleStatusActive, status)
+	assert.Equal(t, 0, attempt)
+
+	monthly.Attempt = 2
+	next, status, attempt = nextScheduleRun(monthly, false, now)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), *next)
+	assert.Equal(t, models.ScheduleStatusActive, status)
+	assert.Equal(t, 0, attempt)
+}
+
+func TestCreateScheduleRequiresClient(t *testing.T) {
+	keys, err := keystore.New(make([]byte, 32))
+	require.NoError(t, err)
+	s := &WalletService{cfg: &config.Config{}, signer: keystore.NewSigner(keys, nil)}
+
+	runAt := time.Now().Add(time.Hour)
+	req := ScheduleRequest{
+		FromAddress: solana.NewWallet().PublicKey().String(),
+		ToAddress:   solana.NewWallet().PublicKey().String(),
+		Amount:      decimal.NewFromInt(1),
+		RunAt:       &runAt,
+	}
+	// 匿名请求不能使用任何托管钱包
+	_, err = s.CreateSchedule(context.Background(), req)
+	assert.ErrorIs(t, err, ErrManagedWalletNotFound)
+}
+
+func TestScheduleOwnership(t *testing.T) {
+	s := newServiceWithPostgres(t)
+	ownerID, owner := newTestClient(t, s, "schedule-owner")
+	_, other := newTestClient(t, s, "schedule-other")
+	admin := WithActor(context.Background(), models.Actor{Type: models.ActorAdmin, ID: "ops"})
+
+	wallet, err := s.CreateManagedWallet(admin, "", "", ownerID)
+	require.NoError(t, err)
+	platform, err := s.CreateManagedWallet(admin, "", "", "")
+	require.NoError(t, err)
+
+	runAt := time.Now().Add(time.Hour)
+	req := ScheduleRequest{
+		FromAddress: wallet.Address,
+		ToAddress:   solana.NewWallet().PublicKey().String(),
+		Amount:      decimal.NewFromInt(1),
+		RunAt:       &runAt,
+	}
+
+	// 其他客户端不能从该钱包转出，平台钱包只供管理员使用
+	_, err = s.CreateSchedule(other, req)
+	assert.ErrorIs(t, err, ErrManagedWalletNotFound)
+	platformReq := req
+	platformReq.FromAddress = platform.Address
+	_, err = s.CreateSchedule(owner, platformReq)
+	assert.ErrorIs(t, err, ErrManagedWalletNotFound)
+
+	schedule, err := s.CreateSchedule(owner, req)
+	require.NoError(t, err)
+	assert.Equal(t, ownerID, schedule.ClientID)
+
+	// 其他客户端查询和操作都按不存在处理
+	_, err = s.GetSchedule(other, schedule.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	_, err = s.ListScheduleExecutions(other, schedule.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	_, err = s.PauseSchedule(other, schedule.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	_, err = s.ResumeSchedule(other, schedule.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	_, err = s.CancelSchedule(other, schedule.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	schedules, err := s.ListSchedules(other, wallet.Address)
+	require.NoError(t, err)
+	assert.Empty(t, schedules)
+
+	// 操作失败后状态不变
+	got, err := s.GetSchedule(owner, schedule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleStatusActive, got.Status)
+
+	paused, err := s.PauseSchedule(owner, schedule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleStatusPaused, paused.Status)
+	cancelled, err := s.CancelSchedule(admin, schedule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleStatusCancelled, cancelled.Status)
+}
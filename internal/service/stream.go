@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	"mywallet/pkg/logger"
+
+	"github.com/gagliardetto/solana-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// StreamMaxAddresses 单个连接最多订阅的地址数
+	StreamMaxAddresses = 50
+	// streamBuffer 每个连接缓存的实时事件数，写满说明连接过慢，断开后由客户端续传
+	streamBuffer      = 64
+	streamReplayPage  = 500
+	streamReconnectAt = 5 * time.Second
+)
+
+// StreamService 地址余额与交易状态的实时推送。事件写入 Redis 并通过 pub/sub 广播，
+// 每个实例只订阅一次频道，再分发给本地连接
+type StreamService struct {
+	logger   *logger.Logger
+	postgres *repository.PostgresRepository
+	redis    *repository.RedisRepository
+
+	mu          sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+}
+
+type streamSubscriber struct {
+	addresses map[string]struct{}
+	live      chan models.StreamEvent
+}
+
+func NewStreamService(logger *logger.Logger, postgres *repository.PostgresRepository, redis *repository.RedisRepository) *StreamService {
+	return &StreamService{
+		logger:      logger,
+		postgres:    postgres,
+		redis:       redis,
+		subscribers: make(map[*streamSubscriber]struct{}),
+	}
+}
+
+// Publish 推送地址事件，失败只记录日志
+func (s *StreamService) Publish(ctx context.Context, eventType string, addresses []string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		s.logger.Logger.Error("failed to encode stream event", zap.String("type", eventType), zap.Error(err))
+		return
+	}
+	event := &models.StreamEvent{
+		Type:      eventType,
+		Addresses: addresses,
+		Data:      payload,
+		CreatedAt: time.Now(),
+	}
+	if err := s.redis.AppendStreamEvent(ctx, event); err != nil {
+		s.logger.Logger.Error("failed to publish stream event",
+			zap.String("type", eventType),
+			zap.Strings("addresses", addresses),
+			zap.Error(err))
+	}
+}
+
+// PublishBalances 推送地址的最新账本余额
+func (s *StreamService) PublishBalances(ctx context.Context, addresses ...string) {
+	for _, address := range addresses {
+		update, err := s.balance(ctx, address)
+		if err != nil {
+			s.logger.Logger.Warn("failed to load balance for stream",
+				zap.String("address", address),
+				zap.Error(err))
+			continue
+		}
+		s.Publish(ctx, models.StreamEventBalance, []string{address}, update)
+	}
+}
+
+// PublishTransaction 推送交易状态，转出与转入地址的订阅方都会收到
+func (s *StreamService) PublishTransaction(ctx context.Context, tx *models.Transaction) {
+	s.Publish(ctx, models.StreamEventTransaction, []string{tx.FromWallet, tx.ToWallet}, tx)
+}
+
+// Subscribe 订阅地址事件。lastEventID 为空时先推送各地址的当前余额，
+// 否则从该事件之后续传；断点已被裁剪时先推送 reset 事件。
+// 返回的 channel 在 ctx 取消或连接过慢被断开时关闭
+func (s *StreamService) Subscribe(ctx context.Context, addresses []string, lastEventID string) (<-chan models.StreamEvent, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("at least one address is required")
+	}
+	if len(addresses) > StreamMaxAddresses {
+		return nil, fmt.Errorf("at most %d addresses per stream", StreamMaxAddresses)
+	}
+	sub := &streamSubscriber{
+		addresses: make(map[string]struct{}, len(addresses)),
+		live:      make(chan models.StreamEvent, streamBuffer),
+	}
+	for _, address := range addresses {
+		if _, err := solana.PublicKeyFromBase58(address); err != nil {
+			return nil, fmt.Errorf("invalid address: %w", err)
+		}
+		sub.addresses[address] = struct{}{}
+	}
+
+	// 先注册再读取历史，衔接处的事件按 ID 去重
+	s.register(sub)
+	backlog, cursor, err := s.backlog(ctx, sub, lastEventID)
+	if err != nil {
+		s.unregister(sub)
+		return nil, err
+	}
+
+	out := make(chan models.StreamEvent)
+	go func() {
+		defer close(out)
+		defer s.unregister(sub)
+
+		for _, event := range backlog {
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.live:
+				if !ok {
+					return
+				}
+				if cursor != "" && repository.CompareStreamIDs(event.ID, cursor) <= 0 {
+					continue
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Run 订阅 Redis 广播并分发给本实例的连接，直到 ctx 取消
+func (s *StreamService) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		s.listen(ctx)
+		select {
+		case <-ctx.Done():
+		case <-time.After(streamReconnectAt):
+		}
+	}
+}
+
+func (s *StreamService) listen(ctx context.Context) {
+	pubsub := s.redis.SubscribeStream(ctx)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		if ctx.Err() == nil {
+			s.logger.Logger.Error("failed to subscribe stream channel", zap.Error(err))
+		}
+		return
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var event models.StreamEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				s.logger.Logger.Warn("invalid stream event", zap.Error(err))
+				continue
+			}
+			s.dispatch(event)
+		}
+	}
+}
+
+// dispatch 分发给订阅了相关地址的连接，缓存已满的连接直接断开
+func (s *StreamService) dispatch(event models.StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.live <- event:
+		default:
+			delete(s.subscribers, sub)
+			close(sub.live)
+		}
+	}
+}
+
+func (s *StreamService) register(sub *streamSubscriber) {
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+}
+
+func (s *StreamService) unregister(sub *streamSubscriber) {
+	s.mu.Lock()
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.live)
+	}
+	s.mu.Unlock()
+}
+
+// backlog 返回连接建立时需要先推送的事件，以及已回放到的事件 ID，之前的实时事件不再推送
+func (s *StreamService) backlog(ctx context.Context, sub *streamSubscriber, lastEventID string) ([]models.StreamEvent, string, error) {
+	var events []models.StreamEvent
+	if lastEventID == "" {
+		for address := range sub.addresses {
+			update, err := s.balance(ctx, address)
+			if err != nil {
+				return nil, "", err
+			}
+			data, _ := json.Marshal(update)
+			events = append(events, models.StreamEvent{
+				Type:      models.StreamEventBalance,
+				Addresses: []string{address},
+				Data:      data,
+				CreatedAt: time.Now(),
+			})
+		}
+		return events, "", nil
+	}
+
+	cursor := lastEventID
+	for {
+		page, truncated, err := s.redis.StreamEventsAfter(ctx, cursor, streamReplayPage)
+		if err != nil {
+			return nil, "", err
+		}
+		if truncated && cursor == lastEventID {
+			events = append(events, models.StreamEvent{Type: models.StreamEventReset, CreatedAt: time.Now()})
+		}
+		for _, event := range page {
+			if sub.matches(event) {
+				events = append(events, event)
+			}
+		}
+		if len(page) > 0 {
+			cursor = page[len(page)-1].ID
+		}
+		if len(page) < streamReplayPage {
+			break
+		}
+	}
+	return events, cursor, nil
+}
+
+func (s *StreamService) balance(ctx context.Context, address string) (*models.BalanceUpdate, error) {
+	ledger, reserved, err := s.postgres.GetBalances(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+	return &models.BalanceUpdate{
+		Address:   address,
+		Available: ledger.Sub(reserved),
+		Reserved:  reserved,
+	}, nil
+}
+
+func (sub *streamSubscriber) matches(event models.StreamEvent) bool {
+	for _, address := range event.Addresses {
+		if _, ok := sub.addresses[address]; ok {
+			return true
+		}
+	}
+	return false
+}
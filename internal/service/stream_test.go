@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	"mywallet/pkg/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamResumeAndLive(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redis, err := repository.NewRedisRepository(mr.Addr(), logger.NewLogger())
+	require.NoError(t, err)
+	s := NewStreamService(logger.NewLogger(), nil, redis)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	const watched = "9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin"
+	const other = "4Nd1mBQtrMJVYVfKf2PJy9NZUZdTAsp7D4xWLs4gDB4T"
+
+	s.Publish(ctx, models.StreamEventBalance, []string{watched}, map[string]string{"n": "1"})
+	first, _, err := redis.StreamEventsAfter(ctx, "0-0", 10)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	s.Publish(ctx, models.StreamEventBalance, []string{other}, map[string]string{"n": "2"})
+	s.Publish(ctx, models.StreamEventTransaction, []string{other, watched}, map[string]string{"n": "3"})
+
+	events, err := s.Subscribe(ctx, []string{watched}, first[0].ID)
+	require.NoError(t, err)
+
+	replayed := receiveStreamEvent(t, events)
+	assert.Equal(t, models.StreamEventTransaction, replayed.Type)
+	assert.JSONEq(t, `{"n":"3"}`, string(replayed.Data))
+
+	// 等待频道订阅生效后再发布实时事件
+	require.Eventually(t, func() bool {
+		return len(mr.PubSubChannels("")) > 0
+	}, time.Second, 10*time.Millisecond)
+	s.Publish(ctx, models.StreamEventBalance, []string{other}, map[string]string{"n": "4"})
+	s.Publish(ctx, models.StreamEventBalance, []string{watched}, map[string]string{"n": "5"})
+
+	live := receiveStreamEvent(t, events)
+	assert.JSONEq(t, `{"n":"5"}`, string(live.Data))
+	assert.Equal(t, 1, repository.CompareStreamIDs(live.ID, replayed.ID))
+
+	_, err = s.Subscribe(ctx, []string{"invalid"}, "")
+	assert.Error(t, err)
+}
+
+func receiveStreamEvent(t *testing.T, events <-chan models.StreamEvent) models.StreamEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok)
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for stream event")
+	}
+	return models.StreamEvent{}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"go.uber.org/zap"
+)
+
+// RunSubscriptions 维持链上事件订阅连接，启用归集时订阅所有充值地址，直到 ctx 取消；未配置 SOLANA_WS_URL 时直接返回
+func (s *WalletService) RunSubscriptions(ctx context.Context) {
+	if s.subs == nil {
+		return
+	}
+	if s.cfg.HotWalletAddress != "" {
+		addresses, err := s.postgres.ListDepositAddresses(ctx, "")
+		if err != nil {
+			// 仍可依靠定期归集，只是不能及时发现入账
+			s.logger.Logger.Error("failed to list deposit addresses to watch", zap.Error(err))
+		}
+		for _, d := range addresses {
+			s.watchDepositAddress(d.Address)
+		}
+	}
+	s.subs.Run(ctx)
+}
+
+// SubscriptionStatus 返回链上事件订阅状态，未启用时为 nil
+func (s *WalletService) SubscriptionStatus() *solanaclient.SubscriptionStatus {
+	if s.subs == nil {
+		return nil
+	}
+	status := s.subs.Status()
+	return &status
+}
+
+// watchDepositAddress 订阅充值地址的 finalized 余额，余额增加或可能漏收通知时提前触发归集
+func (s *WalletService) watchDepositAddress(address string) {
+	account, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return
+	}
+	sub := s.subs.SubscribeAccount(account, rpc.CommitmentFinalized)
+	go func() {
+		var last uint64
+		for {
+			select {
+			case u, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if u.Lamports > last {
+					s.kickSweep()
+				}
+				last = u.Lamports
+			case <-sub.Resync:
+				s.kickSweep()
+			}
+		}
+	}()
+}
+
+// kickSweep 请求归集任务尽快执行一次，已有待处理的请求时合并
+func (s *WalletService) kickSweep() {
+	select {
+	case s.sweepKick <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// maxClientReferenceLength 与 transactions.client_reference 列长度一致
+const maxClientReferenceLength = 255
+
+var (
+	ErrTransactionNotFound = repository.ErrTransactionNotFound
+	ErrDuplicateReference  = repository.ErrDuplicateReference
+	ErrInvalidLookup       = errors.New("exactly one of id, chain_signature and client_reference is required")
+)
+
+// newTransactionID 生成按时间排序的交易 ID
+func newTransactionID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// newTransaction 创建已完成的交易记录，API 客户端与客户端引用取自 ctx
+func newTransaction(ctx context.Context, typ, from, to string, amount decimal.Decimal) *models.Transaction {
+	now := time.Now()
+	return &models.Transaction{
+		ID:              newTransactionID(),
+		FromWallet:      from,
+		ToWallet:        to,
+		Amount:          amount,
+		Type:            typ,
+		Status:          "completed",
+		ClientID:        ClientIDFromContext(ctx),
+		ClientReference: ClientReferenceFromContext(ctx),
+		CreatedAt:       now,
+		CompletedAt:     now,
+	}
+}
+
+// checkClientReference 检查客户端引用是否已使用，用于进入审批前尽早拒绝重复请求。
+// 执行时由 claimClientReference 占用引用
+func (s *WalletService) checkClientReference(ctx context.Context) error {
+	ref := ClientReferenceFromContext(ctx)
+	if ref == "" {
+		return nil
+	}
+	if len(ref) > maxClientReferenceLength {
+		return fmt.Errorf("client_reference must be at most %d bytes", maxClientReferenceLength)
+	}
+	_, err := s.postgres.GetTransactionByReference(ctx, ClientIDFromContext(ctx), ref)
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrDuplicateReference, ref)
+	}
+	if errors.Is(err, ErrTransactionNotFound) {
+		return nil
+	}
+	return fmt.Errorf("failed to check client reference: %w", err)
+}
+
+// claimClientReference 在资金变动前将 tx 以 pending 状态写入，占用其客户端引用。并发请求使用同一引用时
+// 由唯一索引返回 ErrDuplicateReference，不会产生任何资金变动。未提交引用时不写入
+func (s *WalletService) claimClientReference(ctx context.Context, tx *models.Transaction) error {
+	ref := tx.ClientReference
+	if ref == "" {
+		return nil
+	}
+	if len(ref) > maxClientReferenceLength {
+		return fmt.Errorf("client_reference must be at most %d bytes", maxClientReferenceLength)
+	}
+	pending := *tx
+	pending.Status = "pending"
+	if err := s.postgres.CreateTransaction(ctx, &pending); err != nil {
+		if errors.Is(err, ErrDuplicateReference) {
+			return fmt.Errorf("%w: %s", ErrDuplicateReference, ref)
+		}
+		return fmt.Errorf("failed to claim client reference: %w", err)
+	}
+	return nil
+}
+
+// releaseClientReference 删除资金未变动的 pending 交易记录，释放客户端引用以便重试
+func (s *WalletService) releaseClientReference(ctx context.Context, tx *models.Transaction) {
+	if tx.ClientReference == "" {
+		return
+	}
+	if err := s.postgres.DeletePendingTransaction(ctx, tx.ID); err != nil {
+		s.logger.Logger.Error("failed to release client reference",
+			zap.String("id", tx.ID),
+			zap.String("client_reference", tx.ClientReference),
+			zap.Error(err))
+	}
+}
+
+// recordTransaction 写入交易记录，已占用客户端引用时更新 pending 记录
+func (s *WalletService) recordTransaction(ctx context.Context, tx *models.Transaction) error {
+	if tx.ClientReference == "" {
+		return s.postgres.CreateTransaction(ctx, tx)
+	}
+	return s.postgres.UpdatePendingTransaction(ctx, tx)
+}
+
+// TransactionLookup 交易查询条件，只能指定一项
+type TransactionLookup struct {
+	ID              string
+	ChainSignature  string
+	ClientReference string
+}
+
+// LookupTransactions 按交易 ID、链上签名或客户端引用查询交易。
+// 客户端引用只在发起请求的 API 客户端内查询；批量付款的多笔交易共用一个签名
+func (s *WalletService) LookupTransactions(ctx context.Context, q TransactionLookup) ([]models.Transaction, error) {
+	set := 0
+	for _, v := range []string{q.ID, q.ChainSignature, q.ClientReference} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, ErrInvalidLookup
+	}
+
+	var tx *models.Transaction
+	var err error
+	switch {
+	case q.ID != "":
+		tx, err = s.postgres.GetTransaction(ctx, q.ID)
+	case q.ClientReference != "":
+		tx, err = s.postgres.GetTransactionByReference(ctx, ClientIDFromContext(ctx), q.ClientReference)
+	default:
+		txs, err := s.postgres.ListTransactionsBySignature(ctx, q.ChainSignature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup transactions: %w", err)
+		}
+		if len(txs) == 0 {
+			return nil, ErrTransactionNotFound
+		}
+		return txs, nil
+	}
+	if errors.Is(err, ErrTransactionNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup transaction: %w", err)
+	}
+	return []models.Transaction{*tx}, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransaction(t *testing.T) {
+	ctx := WithClientReference(WithClientID(context.Background(), "client-1"), "order-42")
+	first := newTransaction(ctx, "withdraw", "from", "withdraw", decimal.NewFromInt(1))
+	second := newTransaction(context.Background(), "deposit", "deposit", "to", decimal.NewFromInt(1))
+
+	id, err := uuid.Parse(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), id.Version())
+	// UUIDv7 按生成时间排序
+	assert.Less(t, first.ID, second.ID)
+
+	assert.Equal(t, "client-1", first.ClientID)
+	assert.Equal(t, "order-42", first.ClientReference)
+	assert.Empty(t, second.ClientID)
+	assert.Empty(t, second.ClientReference)
+}
+
+func TestLookupTransactionsValidation(t *testing.T) {
+	s := &WalletService{}
+	ctx := context.Background()
+
+	_, err := s.LookupTransactions(ctx, TransactionLookup{})
+	assert.ErrorIs(t, err, ErrInvalidLookup)
+	_, err = s.LookupTransactions(ctx, TransactionLookup{ID: "a", ClientReference: "b"})
+	assert.ErrorIs(t, err, ErrInvalidLookup)
+}
+
+func TestCheckClientReferenceLength(t *testing.T) {
+	s := &WalletService{}
+	assert.NoError(t, s.checkClientReference(context.Background()))
+
+	ctx := WithClientReference(context.Background(), strings.Repeat("x", maxClientReferenceLength+1))
+	assert.Error(t, s.checkClientReference(ctx))
+}
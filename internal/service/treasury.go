@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	solanaclient "mywallet/pkg/solana"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	// sweeperLockKey 归集任务选主使用的 advisory lock
+	sweeperLockKey = 7_240_385_004
+	// movementExpireAfter 已提交但链上查不到的归集交易，超过该时间后 blockhash 已过期，视为失败
+	movementExpireAfter = 2 * time.Minute
+	// maxTreasuryMovements 单次查询的归集记录上限
+	maxTreasuryMovements = 1000
+	maxUserRefLen        = 255
+	// maxDepositAddressBatch 单次批量分配充值地址的上限
+	maxDepositAddressBatch = 100
+)
+
+var (
+	ErrTreasuryDisabled       = errors.New("treasury is disabled: HOT_WALLET_ADDRESS is not set")
+	ErrSweepRunning           = errors.New("a sweep is already running")
+	ErrDepositAddressNotFound = repository.ErrDepositAddressNotFound
+)
+
+// CreateDepositAddress 为 API 客户端的用户分配充值地址（新的托管钱包），同一 userRef 重复调用返回已分配的地址
+func (s *WalletService) CreateDepositAddress(ctx context.Context, userRef string) (*models.DepositAddress, error) {
+	if s.cfg.HotWalletAddress == "" {
+		return nil, ErrTreasuryDisabled
+	}
+	clientID := ClientIDFromContext(ctx)
+	if clientID == "" {
+		return nil, ErrClientRequired
+	}
+	userRef, err := normalizeUserRef(userRef)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.postgres.GetDepositAddressByRef(ctx, clientID, userRef)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, ErrDepositAddressNotFound) {
+		return nil, err
+	}
+
+	wallet, err := s.CreateManagedWallet(ctx, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	d := &models.DepositAddress{
+		Address:   wallet.Address,
+		ClientID:  clientID,
+		UserRef:   userRef,
+		CreatedAt: wallet.CreatedAt,
+	}
+	created, err := s.postgres.CreateDepositAddress(ctx, d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deposit address: %w", err)
+	}
+	if !created {
+		// 并发请求已为该用户分配地址，新建的托管钱包不再使用
+		return s.postgres.GetDepositAddressByRef(ctx, clientID, userRef)
+	}
+
+	s.logger.Logger.Info("deposit address created",
+		zap.String("client", clientID),
+		zap.String("user_ref", userRef),
+		zap.String("address", d.Address))
+	if s.subs != nil {
+		s.watchDepositAddress(d.Address)
+	}
+	return d, nil
+}
+
+// CreateDepositAddresses 批量分配充值地址，结果与 userRefs 顺序一致。
+// 中途失败时已分配的地址保留，重试时按 userRef 返回已分配的地址
+func (s *WalletService) CreateDepositAddresses(ctx context.Context, userRefs []string) ([]models.DepositAddress, error) {
+	if len(userRefs) == 0 {
+		return nil, fmt.Errorf("user_refs is required")
+	}
+	if len(userRefs) > maxDepositAddressBatch {
+		return nil, fmt.Errorf("at most %d user_refs per request", maxDepositAddressBatch)
+	}
+	for i, ref := range userRefs {
+		var err error
+		if userRefs[i], err = normalizeUserRef(ref); err != nil {
+			return nil, fmt.Errorf("user_refs[%d]: %w", i, err)
+		}
+	}
+
+	addresses := make([]models.DepositAddress, 0, len(userRefs))
+	for _, ref := range userRefs {
+		d, err := s.CreateDepositAddress(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("user_ref %s: %w", ref, err)
+		}
+		addresses = append(addresses, *d)
+	}
+	return addresses, nil
+}
+
+func normalizeUserRef(userRef string) (string, error) {
+	userRef = strings.TrimSpace(userRef)
+	if userRef == "" {
+		return "", fmt.Errorf("user_ref is required")
+	}
+	if len(userRef) > maxUserRefLen {
+		return "", fmt.Errorf("user_ref is longer than %d bytes", maxUserRefLen)
+	}
+	return userRef, nil
+}
+
+// ListDepositAddresses 查询充值地址。管理员可按 clientID 筛选，API 客户端只返回自己的地址
+func (s *WalletService) ListDepositAddresses(ctx context.Context, clientID string) ([]models.DepositAddress, error) {
+	if ActorFromContext(ctx).Type != models.ActorAdmin {
+		if clientID = ClientIDFromContext(ctx); clientID == "" {
+			return nil, ErrClientRequired
+		}
+	}
+	return s.postgres.ListDepositAddresses(ctx, clientID)
+}
+
+// ListTreasuryMovements 查询归集记录，limit 默认 100，最大 1000
+func (s *WalletService) ListTreasuryMovements(ctx context.Context, kind, status string, limit int) ([]models.TreasuryMovement, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > maxTreasuryMovements {
+		limit = maxTreasuryMovements
+	}
+	return s.postgres.ListTreasuryMovements(ctx, kind, status, limit)
+}
+
+// TreasuryStatus 查询热钱包链上余额、阈值与未完成的补充请求
+func (s *WalletService) TreasuryStatus(ctx context.Context) (*models.TreasuryStatus, error) {
+	if s.cfg.HotWalletAddress == "" {
+		return nil, ErrTreasuryDisabled
+	}
+	balance, err := s.solana.GetBalance(ctx, s.cfg.HotWalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hot wallet balance: %w", err)
+	}
+	status := &models.TreasuryStatus{
+		HotWallet:  s.cfg.HotWalletAddress,
+		ColdWallet: s.cfg.ColdWalletAddress,
+		HotBalance: balance,
+		HotMin:     s.cfg.HotWalletMin,
+		HotMax:     s.cfg.HotWalletMax,
+	}
+	open, err := s.postgres.ListTreasuryMovements(ctx, models.MovementTopUp, models.MovementRequested, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(open) > 0 {
+		status.TopUp = &open[0]
+	}
+	return status, nil
+}
+
+// Sweep 执行一轮归集：确认已提交的交易，将充值地址的余额归集到热钱包，再按阈值转入冷钱包或生成补充请求。
+// 通过 advisory lock 选主，其他实例正在归集时返回 ErrSweepRunning
+func (s *WalletService) Sweep(ctx context.Context) (*models.SweepReport, error) {
+	if s.cfg.HotWalletAddress == "" {
+		return nil, ErrTreasuryDisabled
+	}
+
+	report := &models.SweepReport{Swept: make([]models.TreasuryMovement, 0)}
+	locked, err := s.postgres.WithAdvisoryLock(ctx, sweeperLockKey, func(ctx context.Context) error {
+		return s.sweep(ctx, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, ErrSweepRunning
+	}
+	return report, nil
+}
+
+// RunSweeper 定期执行归集，订阅到充值入账时提前执行，直到 ctx 取消
+func (s *WalletService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx = WithActor(ctx, models.Actor{Type: models.ActorSystem, ID: "sweeper"})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.sweepKick:
+		}
+
+		report, err := s.Sweep(ctx)
+		if errors.Is(err, ErrSweepRunning) {
+			continue
+		}
+		if err != nil {
+			s.logger.Logger.Error("failed to sweep", zap.Error(err))
+			continue
+		}
+		for _, e := range report.Errors {
+			s.logger.Logger.Warn("sweep error", zap.String("error", e))
+		}
+		if len(report.Swept) > 0 || report.Cold != nil {
+			s.logger.Logger.Info("sweep finished",
+				zap.Int("swept", len(report.Swept)),
+				zap.Bool("cold", report.Cold != nil))
+		}
+	}
+}
+
+func (s *WalletService) sweep(ctx context.Context, report *models.SweepReport) error {
+	hot, err := solana.PublicKeyFromBase58(s.cfg.HotWalletAddress)
+	if err != nil {
+		return fmt.Errorf("invalid HOT_WALLET_ADDRESS: %w", err)
+	}
+	var feePayer *solanaclient.Key
+	if s.cfg.SweepFeePayer != "" {
+		key, err := s.managedKey(ctx, s.cfg.SweepFeePayer)
+		if err != nil {
+			return fmt.Errorf("failed to load fee payer: %w", err)
+		}
+		feePayer = &key
+	}
+
+	settled, pending, err := s.settleMovements(ctx)
+	if err != nil {
+		return err
+	}
+	report.Settled = settled
+
+	addresses, err := s.postgres.ListDepositAddresses(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list deposit addresses: %w", err)
+	}
+	for _, d := range addresses {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// 上一次归集尚未确认，链上余额还未扣减
+		if pending[d.Address] {
+			continue
+		}
+		m, err := s.sweepAddress(ctx, d.Address, hot, feePayer)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", d.Address, err))
+			continue
+		}
+		if m != nil {
+			report.Swept = append(report.Swept, *m)
+		}
+	}
+
+	return s.rebalanceHotWallet(ctx, feePayer, pending[s.cfg.HotWalletAddress], report)
+}
+
+// settleMovements 查询已提交交易的链上结果，返回处理数量与仍在等待确认的转出地址
+func (s *WalletService) settleMovements(ctx context.Context) (int, map[string]bool, error) {
+	pending := make(map[string]bool)
+	submitted, err := s.postgres.ListTreasuryMovements(ctx, "", models.MovementSubmitted, maxTreasuryMovements)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list submitted movements: %w", err)
+	}
+	if len(submitted) == 0 {
+		return 0, pending, nil
+	}
+
+	sigs := make([]solana.Signature, len(submitted))
+	for i, m := range submitted {
+		if sigs[i], err = solana.SignatureFromBase58(m.Signature); err != nil {
+			return 0, nil, fmt.Errorf("invalid movement signature %s: %w", m.Signature, err)
+		}
+	}
+	statuses, err := s.solana.SignatureStatuses(ctx, sigs)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	settled := 0
+	for i, m := range submitted {
+		status := statuses[i]
+		var to, errMsg string
+		switch {
+		case status != nil && status.Err != nil:
+			to, errMsg = models.MovementFailed, fmt.Sprintf("transaction failed: %v", status.Err)
+		case status != nil && status.ConfirmationStatus == rpc.ConfirmationStatusFinalized:
+			// 归集按 finalized 余额计算，未 finalized 前不能再次归集同一地址
+			to = models.MovementConfirmed
+		case status == nil && time.Since(m.CreatedAt) > movementExpireAfter:
+			to, errMsg = models.MovementFailed, "transaction expired"
+		default:
+			pending[m.FromAddress] = true
+			continue
+		}
+		if err := s.postgres.FinishTreasuryMovement(ctx, m.ID, models.MovementSubmitted, to, errMsg); err != nil {
+			s.logger.Logger.Error("failed to update treasury movement",
+				zap.String("id", m.ID),
+				zap.Error(err))
+			pending[m.FromAddress] = true
+			continue
+		}
+		settled++
+	}
+	return settled, pending, nil
+}
+
+// sweepAddress 将充值地址的余额转入热钱包，保留免租金额；无手续费支付钱包时同时保留手续费。
+// 可归集金额低于 SWEEP_MIN_AMOUNT 时不归集，返回 nil
+func (s *WalletService) sweepAddress(ctx context.Context, address string, hot solana.PublicKey, feePayer *solanaclient.Key) (*models.TreasuryMovement, error) {
+	balance, err := s.solana.GetBalance(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	keep := decimal.New(solanaclient.SystemAccountRent, -9)
+	if feePayer == nil {
+		keep = keep.Add(decimal.New(lamportsPerSignature, -9))
+	}
+	amount := balance.Sub(keep)
+	if !amount.IsPositive() || amount.LessThan(s.cfg.SweepMinAmount) {
+		return nil, nil
+	}
+
+	key, err := s.managedKey(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return s.submitMovement(ctx, models.MovementSweep, key, feePayer, hot, amount)
+}
+
+// rebalanceHotWallet 热钱包余额超过上限时将超出部分转入冷钱包，低于下限时生成补充请求
+func (s *WalletService) rebalanceHotWallet(ctx context.Context, feePayer *solanaclient.Key, coldPending bool, report *models.SweepReport) error {
+	balance, err := s.solana.GetBalance(ctx, s.cfg.HotWalletAddress)
+	if err != nil {
+		return fmt.Errorf("failed to get hot wallet balance: %w", err)
+	}
+
+	excess := balance.Sub(s.cfg.HotWalletMax)
+	if s.cfg.ColdWalletAddress != "" && s.cfg.HotWalletMax.IsPositive() && !coldPending &&
+		excess.IsPositive() && !excess.LessThan(s.cfg.SweepMinAmount) {
+		m, err := s.moveToCold(ctx, feePayer, excess)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", s.cfg.HotWalletAddress, err))
+		} else {
+			report.Cold = m
+			balance = s.cfg.HotWalletMax
+		}
+	}
+
+	report.TopUp, err = s.checkTopUp(ctx, balance)
+	return err
+}
+
+func (s *WalletService) moveToCold(ctx context.Context, feePayer *solanaclient.Key, amount decimal.Decimal) (*models.TreasuryMovement, error) {
+	cold, err := solana.PublicKeyFromBase58(s.cfg.ColdWalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid COLD_WALLET_ADDRESS: %w", err)
+	}
+	key, err := s.managedKey(ctx, s.cfg.HotWalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hot wallet: %w", err)
+	}
+	return s.submitMovement(ctx, models.MovementCold, key, feePayer, cold, amount)
+}
+
+// checkTopUp 热钱包余额低于下限时生成补充请求，补充到上下限的中间值（未设置上限时为下限的两倍）。
+// 同一时间只有一个未完成的请求，余额恢复后自动完成
+func (s *WalletService) checkTopUp(ctx context.Context, balance decimal.Decimal) (*models.TreasuryMovement, error) {
+	minBalance := s.cfg.HotWalletMin
+	if !minBalance.IsPositive() {
+		return nil, nil
+	}
+
+	open, err := s.postgres.ListTreasuryMovements(ctx, models.MovementTopUp, models.MovementRequested, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top-up request: %w", err)
+	}
+	if len(open) > 0 {
+		if balance.LessThan(minBalance) {
+			return &open[0], nil
+		}
+		if err := s.postgres.FinishTreasuryMovement(ctx, open[0].ID,
+			models.MovementRequested, models.MovementFulfilled, ""); err != nil {
+			return nil, fmt.Errorf("failed to finish top-up request: %w", err)
+		}
+		return nil, nil
+	}
+	if !balance.LessThan(minBalance) {
+		return nil, nil
+	}
+
+	target := minBalance.Mul(decimal.NewFromInt(2))
+	if s.cfg.HotWalletMax.GreaterThan(minBalance) {
+		target = minBalance.Add(s.cfg.HotWalletMax).Div(decimal.NewFromInt(2))
+	}
+	now := time.Now().UTC()
+	m := &models.TreasuryMovement{
+		ID:          uuid.NewString(),
+		Kind:        models.MovementTopUp,
+		FromAddress: s.cfg.ColdWalletAddress,
+		ToAddress:   s.cfg.HotWalletAddress,
+		Amount:      target.Sub(balance),
+		Status:      models.MovementRequested,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.postgres.CreateTreasuryMovement(ctx, m); err != nil {
+		return nil, fmt.Errorf("failed to create top-up request: %w", err)
+	}
+	s.logger.Logger.Warn("hot wallet is below minimum, top-up requested",
+		zap.String("hot_wallet", s.cfg.HotWalletAddress),
+		zap.String("balance", balance.String()),
+		zap.String("amount", m.Amount.String()))
+	return m, nil
+}
+
+// submitMovement 发送归集交易并记录，feePayer 为 nil 时由转出地址支付手续费
+func (s *WalletService) submitMovement(ctx context.Context, kind string, from solanaclient.Key, feePayer *solanaclient.Key, to solana.PublicKey, amount decimal.Decimal) (_ *models.TreasuryMovement, err error) {
+	fromAddress := from.PublicKey.String()
+	operation := models.AuditTreasurySweep
+	if kind == models.MovementCold {
+		operation = models.AuditTreasuryCold
+	}
+	audit := s.beginAudit(ctx, operation, amount,
+		map[string]string{"from": fromAddress, "to": to.String(), "amount": amount.String()}, fromAddress, to.String())
+	defer func() { audit.finish(err) }()
+
+	signature, err := s.solana.TransferFrom(ctx, from, feePayer, to, amount, "")
+	// 发送结果未知时仍按已提交记录，由 settleMovements 根据签名确认，避免重复归集
+	unconfirmed := errors.Is(err, ErrSendUnconfirmed) && signature != ""
+	if err != nil && !unconfirmed {
+		return nil, fmt.Errorf("failed to send %s transaction: %w", kind, err)
+	}
+	if unconfirmed {
+		s.logger.Logger.Warn("treasury transaction outcome unknown, tracking by signature",
+			zap.String("kind", kind),
+			zap.String("signature", signature),
+			zap.Error(err))
+		err = nil
+	}
+	audit.entry.Reference = signature
+
+	now := time.Now().UTC()
+	m := &models.TreasuryMovement{
+		ID:          uuid.NewString(),
+		Kind:        kind,
+		FromAddress: fromAddress,
+		ToAddress:   to.String(),
+		Amount:      amount,
+		Status:      models.MovementSubmitted,
+		Signature:   signature,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.postgres.CreateTreasuryMovement(ctx, m); err != nil {
+		// 交易已发送但没有记录，确认前可能再次归集同一地址
+		s.logger.Logger.Error("failed to record treasury movement",
+			zap.String("kind", kind),
+			zap.String("signature", signature),
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to record %s transaction %s: %w", kind, signature, err)
+	}
+
+	s.logger.Logger.Info("treasury movement submitted",
+		zap.String("kind", kind),
+		zap.String("from", fromAddress),
+		zap.String("to", to.String()),
+		zap.String("amount", amount.String()),
+		zap.String("signature", signature))
+	return m, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mywallet/internal/config"
+	"mywallet/pkg/logger"
+	solanaclient "mywallet/pkg/solana"
+	"mywallet/pkg/solana/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreasuryDisabled(t *testing.T) {
+	s := &WalletService{cfg: &config.Config{}}
+	ctx := WithClientID(context.Background(), "client-1")
+
+	_, err := s.CreateDepositAddress(ctx, "user-1")
+	assert.ErrorIs(t, err, ErrTreasuryDisabled)
+	_, err = s.Sweep(ctx)
+	assert.ErrorIs(t, err, ErrTreasuryDisabled)
+	_, err = s.TreasuryStatus(ctx)
+	assert.ErrorIs(t, err, ErrTreasuryDisabled)
+}
+
+func TestCreateDepositAddressValidation(t *testing.T) {
+	s := &WalletService{cfg: &config.Config{HotWalletAddress: "11111111111111111111111111111111"}}
+
+	_, err := s.CreateDepositAddress(context.Background(), "user-1")
+	assert.ErrorIs(t, err, ErrClientRequired)
+
+	ctx := WithClientID(context.Background(), "client-1")
+	for _, ref := range []string{"", "  ", strings.Repeat("x", maxUserRefLen+1)} {
+		_, err = s.CreateDepositAddress(ctx, ref)
+		assert.Error(t, err, "%q", ref)
+	}
+
+	_, err = s.CreateDepositAddresses(ctx, nil)
+	assert.ErrorContains(t, err, "user_refs is required")
+	_, err = s.CreateDepositAddresses(ctx, make([]string, maxDepositAddressBatch+1))
+	assert.ErrorContains(t, err, "at most")
+	_, err = s.CreateDepositAddresses(ctx, []string{"user-1", " "})
+	assert.ErrorContains(t, err, "user_refs[1]")
+}
+
+func TestDepositWatcherKicksSweep(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	log := logger.NewLogger()
+	s := &WalletService{
+		cfg:       &config.Config{},
+		logger:    log,
+		subs:      solanaclient.NewSubscriptionManager(sim.WSURL(), solanaclient.SubscriptionOptions{}, log),
+		sweepKick: make(chan struct{}, 1),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.RunSubscriptions(ctx)
+
+	deposit := solana.NewWallet().PublicKey()
+	s.watchDepositAddress(deposit.String())
+	require.Eventually(t, func() bool { return sim.Subscriptions() == 1 }, 2*time.Second, 5*time.Millisecond)
+
+	sim.Airdrop(deposit, solana.LAMPORTS_PER_SOL)
+	select {
+	case <-s.sweepKick:
+	case <-time.After(2 * time.Second):
+		t.Fatal("deposit did not trigger a sweep")
+	}
+	assert.Equal(t, 1, s.SubscriptionStatus().Subscribers)
+}
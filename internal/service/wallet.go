@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"time"
 
+	"mywallet/internal/config"
+	"mywallet/internal/keystore"
 	"mywallet/internal/models"
 	"mywallet/internal/repository"
 	"mywallet/pkg/logger"
@@ -18,72 +21,169 @@ import (
 )
 
 type WalletService struct {
+	cfg      *config.Config
 	logger   *logger.Logger
 	solana   *solanaclient.Client
 	postgres *repository.PostgresRepository
 	redis    *repository.RedisRepository
+	limits   *LimitService
+	webhooks *WebhookService
+	streams  *StreamService
+	// keys 托管钱包私钥加密，未配置主密钥或使用外部签名服务时为 nil
+	keys *keystore.Keystore
+	// signer 托管钱包签名服务，keystore 模式下未配置主密钥时为 nil
+	signer solanaclient.Signer
+	// seed HD 钱包种子，未配置助记词时为 nil
+	seed []byte
+	// subs 链上事件订阅，未配置 SOLANA_WS_URL 时为 nil
+	subs *solanaclient.SubscriptionManager
+	// sweepKick 充值到账后提前触发归集
+	sweepKick chan struct{}
 }
 
 func NewWalletService(
 	logger *logger.Logger,
-	rpcURL string,
+	cfg *config.Config,
 	postgres *repository.PostgresRepository,
 	redis *repository.RedisRepository,
 ) (*WalletService, error) {
-	solanaClient := solanaclient.NewClient(rpcURL, logger)
+	solanaClient, err := newSolanaClient(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys *keystore.Keystore
+	var signer solanaclient.Signer
+	switch cfg.SignerBackend {
+	case config.SignerRemote:
+		signer = solanaclient.NewRemoteSigner(cfg.SignerURL, cfg.SignerToken)
+	case config.SignerPKCS11:
+		signer = solanaclient.NewPKCS11Signer(cfg.SignerURL, cfg.PKCS11Slot, cfg.PKCS11PIN)
+	default:
+		if len(cfg.KeystoreMasterKeys) > 0 {
+			if keys, err = keystore.NewVersioned(cfg.KeystoreMasterKeys, cfg.KeystoreKeyVersion); err != nil {
+				return nil, fmt.Errorf("failed to create keystore: %w", err)
+			}
+			signer = keystore.NewSigner(keys, postgres.GetManagedWalletKey)
+		}
+	}
+
+	var seed []byte
+	if cfg.HDMnemonic != "" {
+		if seed, err = solanaclient.SeedFromMnemonic(cfg.HDMnemonic, cfg.HDPassphrase); err != nil {
+			return nil, fmt.Errorf("invalid HD_MNEMONIC: %w", err)
+		}
+	}
+
+	var subs *solanaclient.SubscriptionManager
+	if cfg.SolanaWS != "" {
+		subs = solanaclient.NewSubscriptionManager(cfg.SolanaWS, solanaclient.SubscriptionOptions{}, logger)
+	}
 
 	return &WalletService{
-		logger:   logger,
-		solana:   solanaClient,
-		postgres: postgres,
-		redis:    redis,
+		cfg:       cfg,
+		logger:    logger,
+		solana:    solanaClient,
+		postgres:  postgres,
+		redis:     redis,
+		limits:    NewLimitService(logger, postgres, redis),
+		webhooks:  NewWebhookService(logger, postgres, cfg.WebhookAllowInsecure),
+		streams:   NewStreamService(logger, postgres, redis),
+		keys:      keys,
+		signer:    signer,
+		seed:      seed,
+		subs:      subs,
+		sweepKick: make(chan struct{}, 1),
 	}, nil
 }
 
-func (s *WalletService) Deposit(ctx context.Context, address string, amount decimal.Decimal) error {
+// Limits 返回限额服务
+func (s *WalletService) Limits() *LimitService {
+	return s.limits
+}
+
+// Webhooks 返回 webhook 服务
+func (s *WalletService) Webhooks() *WebhookService {
+	return s.webhooks
+}
+
+// Streams 返回实时推送服务
+func (s *WalletService) Streams() *StreamService {
+	return s.streams
+}
+
+// Deposit 入账并返回交易记录
+func (s *WalletService) Deposit(ctx context.Context, address string, amount decimal.Decimal) (tx *models.Transaction, err error) {
 	// 验证金额
 	if amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("deposit amount must be greater than 0")
+		return nil, fmt.Errorf("deposit amount must be greater than 0")
 	}
 
 	// 验证地址
 	if _, err := solana.PublicKeyFromBase58(address); err != nil {
-		return fmt.Errorf("invalid address: %w", err)
+		return nil, fmt.Errorf("invalid address: %w", err)
 	}
 
-	err := s.redis.AddBalance(ctx, address, amount)
+	audit := s.beginAudit(ctx, models.AuditDeposit, amount,
+		map[string]string{"address": address, "amount": amount.String()}, address)
+	defer func() { audit.finish(err) }()
+
+	// 先占用客户端引用，重复请求在资金变动前失败
+	record := newTransaction(ctx, "deposit", "deposit", address, amount)
+	if err := s.claimClientReference(ctx, record); err != nil {
+		return nil, err
+	}
+	audit.entry.Reference = record.ID
+	defer func() {
+		if err != nil {
+			s.releaseClientReference(ctx, record)
+		}
+	}()
+
+	err = s.redis.AddBalance(ctx, address, amount)
 	if err != nil {
-		return fmt.Errorf("failed to update redis balance: %w", err)
+		return nil, fmt.Errorf("failed to update redis balance: %w", err)
 	}
 
 	// 更新数据库余额
 	if err := s.postgres.AddBalance(ctx, address, amount); err != nil {
 		// Redis 回滚
-		err := s.redis.SubBalance(ctx, address, amount)
-		if err != nil {
+		if rollbackErr := s.redis.SubBalance(ctx, address, amount); rollbackErr != nil {
 			s.logger.Logger.Error("failed to rollback redis balance",
 				zap.String("address", address),
-				zap.Error(err))
+				zap.Error(rollbackErr))
+			audit.rollbackFailed(rollbackErr)
 		}
-		return fmt.Errorf("failed to update balance: %w", err)
+		return nil, fmt.Errorf("failed to update balance: %w", err)
 	}
+	s.balancesChanged(ctx, address)
 
 	// 创建交易记录
-	tx := &models.Transaction{
-		ID:          solana.NewWallet().PublicKey().String(), // 使用随机公钥作为ID
-		FromWallet:  "deposit",
-		ToWallet:    address,
-		Amount:      amount,
-		Type:        "deposit",
-		Status:      "completed",
-		CreatedAt:   time.Now(),
-		CompletedAt: time.Now(),
-	}
+	if err := s.recordTransaction(ctx, record); err != nil {
+		// 没有交易记录的入账无法追溯，撤销本次入账
+		s.rollbackDeposit(ctx, audit, address, amount)
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	s.webhooks.Publish(ctx, models.EventDepositCompleted, record)
+	s.streams.PublishTransaction(ctx, record)
+	return record, nil
+}
 
-	if err := s.postgres.CreateTransaction(ctx, tx); err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+func (s *WalletService) rollbackDeposit(ctx context.Context, audit *auditOp, address string, amount decimal.Decimal) {
+	if err := s.postgres.SubBalance(ctx, address, amount); err != nil {
+		s.logger.Logger.Error("failed to rollback balance",
+			zap.String("address", address),
+			zap.Error(err))
+		audit.rollbackFailed(err)
+		return
 	}
-	return nil
+	if err := s.redis.SubBalance(ctx, address, amount); err != nil {
+		s.logger.Logger.Error("failed to rollback redis balance",
+			zap.String("address", address),
+			zap.Error(err))
+		audit.rollbackFailed(err)
+	}
+	s.balancesChanged(ctx, address)
 }
 
 func (s *WalletService) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
@@ -211,51 +311,167 @@ func (s *WalletService) validateTransactionData(tx *rpc.GetTransactionResult) er
 	return nil
 }
 
-func (s *WalletService) Transfer(ctx context.Context, fromPrivateKeyStr, toAddress string, amount decimal.Decimal) error {
-	// 解析私钥
+// Transfer 执行链上转账并返回交易记录
+func (s *WalletService) Transfer(ctx context.Context, fromPrivateKeyStr, toAddress string, amount decimal.Decimal) (*models.Transaction, error) {
+	fromPrivateKey, err := solana.PrivateKeyFromBase58(fromPrivateKeyStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from private key: %w", err)
+	}
+	if _, err := solana.PublicKeyFromBase58(toAddress); err != nil {
+		return nil, fmt.Errorf("invalid to address: %w", err)
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("transfer amount must be greater than 0")
+	}
+	// 未通过白名单或引用已使用的转账不进入审批
+	if _, err := s.checkDestination(ctx, fromPrivateKey.PublicKey().String(), toAddress); err != nil {
+		return nil, err
+	}
+	if err := s.checkClientReference(ctx); err != nil {
+		return nil, err
+	}
+
+	// 达到审批档位时冻结资金，批准后由服务使用托管密钥签名
+	approval, err := s.requestApproval(ctx, models.ApprovalTransfer, fromPrivateKey.PublicKey().String(), toAddress, amount)
+	if err != nil {
+		return nil, err
+	}
+	if approval != nil {
+		return nil, &PendingApprovalError{Approval: approval}
+	}
+
+	return s.transfer(ctx, solanaclient.LocalKey(fromPrivateKey), toAddress, amount)
+}
+
+// SimulateTransfer 模拟 Transfer 将发送的链上交易，不读写 Redis 与 Postgres，
+// 因此不检查白名单、限额与审批，也不使用代付钱包
+func (s *WalletService) SimulateTransfer(ctx context.Context, fromPrivateKeyStr, toAddress string, amount decimal.Decimal) (*solanaclient.TransferSimulation, error) {
 	fromPrivateKey, err := solana.PrivateKeyFromBase58(fromPrivateKeyStr)
 	if err != nil {
-		return fmt.Errorf("invalid from private key: %w", err)
+		return nil, fmt.Errorf("invalid from private key: %w", err)
+	}
+	toPubKey, err := solana.PublicKeyFromBase58(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to address: %w", err)
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("transfer amount must be greater than 0")
+	}
+
+	result, err := s.solana.SimulateTransfer(ctx, fromPrivateKey, toPubKey, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transfer: %w", err)
 	}
+	return result, nil
+}
 
+// transfer 执行链上转账并更新账本，返回交易记录。链上转账成功后的账本错误同时返回交易记录
+func (s *WalletService) transfer(ctx context.Context, from solanaclient.Key, toAddress string, amount decimal.Decimal) (tx *models.Transaction, err error) {
 	// 获取发送方公钥
-	fromAddress := fromPrivateKey.PublicKey().String()
+	fromAddress := from.PublicKey.String()
 	// 解析接收方地址
 	toPubKey, err := solana.PublicKeyFromBase58(toAddress)
 	if err != nil {
-		return fmt.Errorf("invalid to address: %w", err)
+		return nil, fmt.Errorf("invalid to address: %w", err)
 	}
 
 	// 验证转账金额
 	if amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("transfer amount must be greater than 0")
+		return nil, fmt.Errorf("transfer amount must be greater than 0")
+	}
+
+	audit := s.beginAudit(ctx, models.AuditTransfer, amount,
+		map[string]string{"from": fromAddress, "to": toAddress, "amount": amount.String()}, fromAddress, toAddress)
+	defer func() {
+		if tx != nil {
+			audit.entry.Reference = tx.ChainSignature
+		}
+		audit.finish(err)
+	}()
+
+	// 定时转账与审批执行同样需要通过白名单，地址簿要求的 memo 随交易上链
+	memo, err := s.checkDestination(ctx, fromAddress, toAddress)
+	if err != nil {
+		return nil, err
 	}
 
+	// 先占用客户端引用，重复请求在链上转账前失败
+	record := newTransaction(ctx, "transfer", fromAddress, toAddress, amount)
+	if err := s.claimClientReference(ctx, record); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// 链上转账未发出时释放引用；发送结果未知时保留，避免重试重复转账
+		if tx == nil && err != nil && !errors.Is(err, ErrSendUnconfirmed) {
+			s.releaseClientReference(ctx, record)
+		}
+	}()
+
 	// 检查发送方余额
 	fromBalance, err := s.GetBalance(ctx, fromAddress)
 	if err != nil {
-		return fmt.Errorf("failed to get sender balance: %w", err)
+		return nil, fmt.Errorf("failed to get sender balance: %w", err)
 	}
 	if fromBalance.LessThan(amount) {
-		return fmt.Errorf("insufficient balance")
+		return nil, fmt.Errorf("insufficient balance")
 	}
 
-	// 调用 Solana 客户端执行实际转账，使用私钥
-	signature, err := s.solana.Transfer(ctx, fromPrivateKey, toPubKey, amount)
+	// 托管钱包的手续费由代付钱包支付
+	feePayer, fee := s.feePayerFor(ctx, fromAddress)
+
+	// 检查并占用限额
+	reservation, err := s.limits.Reserve(ctx, fromAddress, models.AssetSOL, amount)
 	if err != nil {
-		return fmt.Errorf("failed to execute transfer on blockchain: %w", err)
+		if fee != nil {
+			s.releaseSponsoredFee(ctx, fee)
+		}
+		return nil, err
+	}
+
+	// 调用 Solana 客户端执行实际转账，由发送方与代付钱包的签名服务签名
+	signature, err := s.solana.TransferFrom(ctx, from, feePayer, toPubKey, amount, memo)
+	if err != nil && signature != "" {
+		// 发送结果未知，确认交易是否上链后再决定记账或释放限额
+		err = s.resolveUnconfirmedSend(ctx, signature, err)
+	}
+	if err != nil {
+		if !errors.Is(err, ErrSendUnconfirmed) {
+			s.limits.Release(ctx, reservation)
+			if fee != nil {
+				s.releaseSponsoredFee(ctx, fee)
+			}
+		} else if fee != nil {
+			s.recordSponsoredFee(ctx, fee, signature)
+		}
+		return nil, fmt.Errorf("failed to execute transfer on blockchain: %w", err)
+	}
+	if fee != nil {
+		s.recordSponsoredFee(ctx, fee, signature)
+	}
+	tx = record
+	tx.ChainSignature = signature
+	if tx.ClientReference != "" {
+		// 账本更新失败时仍可按引用查到链上签名
+		pending := *tx
+		pending.Status = "pending"
+		if err := s.postgres.UpdatePendingTransaction(ctx, &pending); err != nil {
+			s.logger.Logger.Error("failed to record transfer signature",
+				zap.String("id", tx.ID),
+				zap.String("signature", signature),
+				zap.Error(err))
+		}
 	}
 
 	// Lua 脚本执行转账
 	err = s.redis.Transfer(ctx, fromAddress, toAddress, amount)
 	if err != nil {
-		return fmt.Errorf("failed to execute redis transfer: %w", err)
+		return tx, fmt.Errorf("failed to execute redis transfer: %w", err)
 	}
 
 	// 开始转账操作
 	// 1. 扣除发送方余额
 	if err := s.postgres.SubBalance(ctx, fromAddress, amount); err != nil {
-		return fmt.Errorf("failed to update sender balance: %w", err)
+		return tx, fmt.Errorf("failed to update sender balance: %w", err)
 	}
 
 	// 2. 增加接收方余额
@@ -265,45 +481,81 @@ func (s *WalletService) Transfer(ctx context.Context, fromPrivateKeyStr, toAddre
 			s.logger.Logger.Error("failed to rollback sender balance",
 				zap.String("from", fromAddress),
 				zap.Error(rollbackErr))
+			audit.rollbackFailed(rollbackErr)
 		}
-		return fmt.Errorf("failed to update receiver balance: %w", err)
+		return tx, fmt.Errorf("failed to update receiver balance: %w", err)
 	}
+	s.balancesChanged(ctx, fromAddress, toAddress)
 
 	// 3. 创建交易记录
-	tx := &models.Transaction{
-		ID:          signature,
-		FromWallet:  fromAddress,
-		ToWallet:    toAddress,
-		Amount:      amount,
-		Type:        "transfer",
-		Status:      "completed",
-		CreatedAt:   time.Now(),
-		CompletedAt: time.Now(),
-	}
-
-	if err := s.postgres.CreateTransaction(ctx, tx); err != nil {
+	tx.CompletedAt = time.Now()
+	if err := s.recordTransaction(ctx, tx); err != nil {
 		s.logger.Logger.Error("failed to create transaction record", zap.Error(err))
-		return fmt.Errorf("failed to create transaction record: %w", err)
+		return tx, fmt.Errorf("failed to create transaction record: %w", err)
 	}
+	s.webhooks.Publish(ctx, models.EventTransferCompleted, tx)
+	s.streams.PublishTransaction(ctx, tx)
 
-	return nil
+	return tx, nil
 }
 
-func (s *WalletService) Withdraw(ctx context.Context, address string, amount decimal.Decimal) error {
+// Withdraw 扣减余额并返回交易记录
+func (s *WalletService) Withdraw(ctx context.Context, address string, amount decimal.Decimal) (*models.Transaction, error) {
 	// 验证金额
 	if amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("withdraw amount must be greater than 0")
+		return nil, fmt.Errorf("withdraw amount must be greater than 0")
 	}
 
 	// 验证地址
 	if _, err := solana.PublicKeyFromBase58(address); err != nil {
-		return fmt.Errorf("invalid address: %w", err)
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	if err := s.checkClientReference(ctx); err != nil {
+		return nil, err
+	}
+
+	// 达到审批档位时冻结资金，批准后执行
+	approval, err := s.requestApproval(ctx, models.ApprovalWithdraw, address, "", amount)
+	if err != nil {
+		return nil, err
+	}
+	if approval != nil {
+		return nil, &PendingApprovalError{Approval: approval}
+	}
+
+	return s.withdraw(ctx, address, amount)
+}
+
+// withdraw 扣减余额并记录提现，返回交易记录
+func (s *WalletService) withdraw(ctx context.Context, address string, amount decimal.Decimal) (tx *models.Transaction, err error) {
+	audit := s.beginAudit(ctx, models.AuditWithdraw, amount,
+		map[string]string{"address": address, "amount": amount.String()}, address)
+	defer func() { audit.finish(err) }()
+
+	// 先占用客户端引用，重复请求在资金变动前失败
+	record := newTransaction(ctx, "withdraw", address, "withdraw", amount)
+	if err := s.claimClientReference(ctx, record); err != nil {
+		return nil, err
+	}
+	audit.entry.Reference = record.ID
+	defer func() {
+		if err != nil {
+			s.releaseClientReference(ctx, record)
+		}
+	}()
+
+	// 检查并占用限额
+	reservation, err := s.limits.Reserve(ctx, address, models.AssetSOL, amount)
+	if err != nil {
+		return nil, err
 	}
 
 	// 执行 Lua 脚本检查和扣减余额
-	err := s.redis.SubBalance(ctx, address, amount)
+	err = s.redis.SubBalance(ctx, address, amount)
 	if err != nil {
-		return fmt.Errorf("failed to update redis balance: %w", err)
+		s.limits.Release(ctx, reservation)
+		return nil, fmt.Errorf("failed to update redis balance: %w", err)
 	}
 
 	// 更新数据库余额
@@ -314,31 +566,40 @@ func (s *WalletService) Withdraw(ctx context.Context, address string, amount dec
 			s.logger.Logger.Error("failed to rollback redis balance",
 				zap.String("address", address),
 				zap.Error(rollbackErr))
+			audit.rollbackFailed(rollbackErr)
 		}
-		return fmt.Errorf("failed to update balance: %w", err)
+		s.limits.Release(ctx, reservation)
+		return nil, fmt.Errorf("failed to update balance: %w", err)
 	}
+	s.balancesChanged(ctx, address)
 
 	// 创建交易记录
-	tx := &models.Transaction{
-		ID:          solana.NewWallet().PublicKey().String(), // 使用随机公钥作为ID
-		FromWallet:  address,
-		ToWallet:    "withdraw",
-		Amount:      amount,
-		Type:        "withdraw",
-		Status:      "completed",
-		CreatedAt:   time.Now(),
-		CompletedAt: time.Now(),
-	}
-
-	if err := s.postgres.CreateTransaction(ctx, tx); err != nil {
+	if err := s.recordTransaction(ctx, record); err != nil {
 		// 如果创建交易记录失败，回滚余额
-		if rollbackErr := s.postgres.AddBalance(ctx, address, amount); rollbackErr != nil {
-			s.logger.Logger.Error("failed to rollback balance after transaction creation failure",
-				zap.String("address", address),
-				zap.Error(rollbackErr))
-		}
-		return fmt.Errorf("failed to create transaction: %w", err)
+		s.rollbackWithdraw(ctx, audit, address, amount)
+		s.limits.Release(ctx, reservation)
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
+	tx = record
+	s.webhooks.Publish(ctx, models.EventWithdrawalCompleted, tx)
+	s.streams.PublishTransaction(ctx, tx)
 
-	return nil
+	return tx, nil
+}
+
+func (s *WalletService) rollbackWithdraw(ctx context.Context, audit *auditOp, address string, amount decimal.Decimal) {
+	if err := s.postgres.AddBalance(ctx, address, amount); err != nil {
+		s.logger.Logger.Error("failed to rollback balance after transaction creation failure",
+			zap.String("address", address),
+			zap.Error(err))
+		audit.rollbackFailed(err)
+		return
+	}
+	if err := s.redis.AddBalance(ctx, address, amount); err != nil {
+		s.logger.Logger.Error("failed to rollback redis balance",
+			zap.String("address", address),
+			zap.Error(err))
+		audit.rollbackFailed(err)
+	}
+	s.balancesChanged(ctx, address)
 }
@@ -2,18 +2,26 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"os"
 	"testing"
 	"time"
 
 	"mywallet/internal/config"
+	"mywallet/internal/migrate"
 	"mywallet/internal/models"
 	"mywallet/internal/repository"
 	"mywallet/pkg/logger"
+	solanaclient "mywallet/pkg/solana"
+	"mywallet/pkg/solana/simulator"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gagliardetto/solana-go"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock Postgres Repository
@@ -67,8 +75,17 @@ func (m *MockRedisRepository) GetCachedTransactions(ctx context.Context, address
 }
 
 func GetService(t *testing.T) (*WalletService, error) {
+	service, _, err := GetServiceWithChain(t)
+	return service, err
+}
+
+// GetServiceWithChain 使用本地模拟链创建服务，测试不再访问 Solana 主网
+func GetServiceWithChain(t *testing.T) (*WalletService, *simulator.Server, error) {
 	logger := logger.NewLogger()
 	cfg, _ := config.Load()
+	chain := simulator.NewServer()
+	t.Cleanup(chain.Close)
+	cfg.SolanaRPC = chain.URL
 	mockPostgres, err := repository.NewPostgresRepository(cfg.PostgresURL, logger)
 	if err != nil {
 		t.Fatalf("failed to create postgres repository: %v", err) // 错误处理
@@ -77,10 +94,53 @@ func GetService(t *testing.T) (*WalletService, error) {
 	if err != nil {
 		t.Fatalf("failed to create postgres repository: %v", err) // 错误处理
 	}
-	service, err := NewWalletService(logger, cfg.SolanaRPC,
+	service, err := NewWalletService(logger, cfg,
 		mockPostgres, mockRedis)
 	assert.NoError(t, err)
-	return service, nil
+	return service, chain, nil
+}
+
+// newServiceWithPostgres 使用 TEST_POSTGRES_URL 指向的数据库（启动时执行迁移）、miniredis 与本地模拟链创建服务，
+// 托管钱包使用随机主密钥的 keystore。未配置数据库时跳过
+func newServiceWithPostgres(t *testing.T) *WalletService {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	log := logger.NewLogger()
+
+	db, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrate.New(db, log)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(context.Background()))
+
+	postgres, err := repository.NewPostgresRepository(url, log)
+	require.NoError(t, err)
+	redis, err := repository.NewRedisRepository(miniredis.RunT(t).Addr(), log)
+	require.NoError(t, err)
+	chain := simulator.NewServer()
+	t.Cleanup(chain.Close)
+
+	masterKey := make([]byte, 32)
+	_, err = rand.Read(masterKey)
+	require.NoError(t, err)
+	s, err := NewWalletService(log, &config.Config{
+		SolanaRPC:          chain.URL,
+		SignerBackend:      config.SignerKeystore,
+		KeystoreMasterKeys: map[int][]byte{1: masterKey},
+		KeystoreKeyVersion: 1,
+	}, postgres, redis)
+	require.NoError(t, err)
+	return s
+}
+
+// newTestClient 创建 API 客户端，返回带该客户端身份的 context
+func newTestClient(t *testing.T, s *WalletService, name string) (string, context.Context) {
+	client, _, err := NewClientService(s.logger, s.postgres).CreateClient(context.Background(), name)
+	require.NoError(t, err)
+	return client.ID, WithClientID(context.Background(), client.ID)
 }
 
 func TestDeposit(t *testing.T) {
@@ -95,19 +155,21 @@ func TestDeposit(t *testing.T) {
 	amount := decimal.NewFromFloat(1.0)
 
 	// 执行测试
-	err = service.Deposit(ctx, validAddress, amount)
+	_, err = service.Deposit(ctx, validAddress, amount)
 	assert.NoError(t, err)
 }
 
 func TestGetBalance(t *testing.T) {
 	// 设置
 	ctx := context.Background()
-	service, err := GetService(t)
+	service, chain, err := GetServiceWithChain(t)
 	if err != nil {
 		t.Fatalf("failed to create service: %v", err) // 错误处理
 	}
 
-	validAddress := solana.NewWallet().PublicKey().String()
+	wallet := solana.NewWallet().PublicKey()
+	chain.Airdrop(wallet, solana.LAMPORTS_PER_SOL)
+	validAddress := wallet.String()
 	expectedBalance := decimal.NewFromFloat(1.0)
 
 	// 执行测试
@@ -116,6 +178,18 @@ func TestGetBalance(t *testing.T) {
 	assert.Equal(t, expectedBalance, balance)
 }
 
+// 未入过账的钱包按零余额返回，不报错
+func TestGetBalanceViewUnknownWallet(t *testing.T) {
+	s := newServiceWithPostgres(t)
+
+	view, err := s.GetBalanceView(context.Background(), solana.NewWallet().PublicKey().String(), BalanceOptions{Fresh: true})
+	require.NoError(t, err)
+	assert.True(t, view.Available.IsZero())
+	assert.True(t, view.Reserved.IsZero())
+	assert.True(t, view.Pending.IsZero())
+	assert.Empty(t, view.Holds)
+}
+
 func TestGetTransactions(t *testing.T) {
 	// 设置
 	ctx := context.Background()
@@ -142,3 +216,23 @@ func TestGetTransactions(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectedTxs, txs)
 }
+
+// 模拟转账不依赖 Redis 与 Postgres
+func TestSimulateTransfer(t *testing.T) {
+	chain := simulator.NewServer()
+	defer chain.Close()
+	s := &WalletService{cfg: &config.Config{}, solana: solanaclient.NewClient(chain.URL, logger.NewLogger())}
+
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	chain.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	result, err := s.SimulateTransfer(context.Background(), from.PrivateKey.String(), to.String(), decimal.NewFromFloat(0.5))
+	require.NoError(t, err)
+	assert.Nil(t, result.Err)
+	assert.Len(t, result.BalanceChanges, 2)
+	assert.Zero(t, chain.Balance(to))
+
+	_, err = s.SimulateTransfer(context.Background(), from.PrivateKey.String(), to.String(), decimal.Zero)
+	assert.Error(t, err)
+}
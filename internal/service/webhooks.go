@@ -0,0 +1,426 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/internal/repository"
+	"mywallet/pkg/logger"
+	"mywallet/pkg/webhook"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 8
+	webhookRetryBase   = 30 * time.Second
+	webhookMaxRetry    = 6 * time.Hour
+	// webhookLease 领取后在该时间内不会被其他实例再次领取，需大于 webhookTimeout
+	webhookLease       = time.Minute
+	webhookBatch       = 50
+	webhookConcurrency = 8
+	webhookListLimit   = 100
+	// webhookErrorBody 记录失败响应体的最大长度
+	webhookErrorBody = 512
+)
+
+var (
+	ErrWebhookNotFound  = repository.ErrWebhookNotFound
+	ErrDeliveryNotFound = repository.ErrDeliveryNotFound
+	ErrClientRequired   = errors.New("an API client is required, authenticate with X-API-Key")
+	// ErrWebhookTarget webhook 地址指向回环、内网、链路本地等非公网地址
+	ErrWebhookTarget = errors.New("webhook url must resolve to a public address")
+)
+
+// webhookBlockedPrefixes IsPrivate/IsLoopback 等方法未覆盖的非公网地址段
+var webhookBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// WebhookService webhook 订阅管理与事件投递
+type WebhookService struct {
+	logger   *logger.Logger
+	postgres *repository.PostgresRepository
+	client   *http.Client
+	// allowInsecure 允许 http 与非公网地址，仅用于本地开发
+	allowInsecure bool
+}
+
+func NewWebhookService(logger *logger.Logger, postgres *repository.PostgresRepository, allowInsecure bool) *WebhookService {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowInsecure {
+		// 在建立连接时检查实际连接的 IP，防止注册后 DNS 改为指向内网；不经过代理，否则检查的是代理地址
+		dialer := &net.Dialer{Timeout: webhookTimeout, Control: webhookDialControl}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+	return &WebhookService{
+		logger:   logger,
+		postgres: postgres,
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: transport,
+			// 不跟随重定向，避免投递到注册地址之外
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowInsecure: allowInsecure,
+	}
+}
+
+// CreateEndpoint 为当前 API 客户端注册 webhook 地址，返回的 Secret 只在创建时可见
+func (s *WebhookService) CreateEndpoint(ctx context.Context, rawURL string, eventTypes []string) (*models.WebhookEndpoint, error) {
+	clientID := ClientIDFromContext(ctx)
+	if clientID == "" {
+		return nil, ErrClientRequired
+	}
+
+	u, err := s.checkEndpointURL(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	for _, t := range eventTypes {
+		if !validEventType(t) {
+			return nil, fmt.Errorf("unknown event type: %s", t)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	now := time.Now()
+	endpoint := &models.WebhookEndpoint{
+		ID:         uuid.NewString(),
+		ClientID:   clientID,
+		URL:        u.String(),
+		Secret:     "whsec_" + hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.postgres.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return endpoint, nil
+}
+
+// ListEndpoints 查询当前 API 客户端的 webhook 地址
+func (s *WebhookService) ListEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	clientID := ClientIDFromContext(ctx)
+	if clientID == "" {
+		return nil, ErrClientRequired
+	}
+	return s.postgres.ListWebhookEndpoints(ctx, clientID)
+}
+
+// DeleteEndpoint 删除 webhook 地址，未完成的投递不再发送
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, id string) error {
+	clientID := ClientIDFromContext(ctx)
+	if clientID == "" {
+		return ErrClientRequired
+	}
+	return s.postgres.DeleteWebhookEndpoint(ctx, clientID, id)
+}
+
+// ListDeliveries 查询投递记录，status 为 dead 时即死信列表
+func (s *WebhookService) ListDeliveries(ctx context.Context, endpointID, status string) ([]models.WebhookDelivery, error) {
+	if _, err := s.endpoint(ctx, endpointID); err != nil {
+		return nil, err
+	}
+	return s.postgres.ListDeliveries(ctx, endpointID, status, webhookListLimit)
+}
+
+// GetDelivery 查询投递及各次尝试
+func (s *WebhookService) GetDelivery(ctx context.Context, endpointID, id string) (*models.WebhookDelivery, error) {
+	if _, err := s.endpoint(ctx, endpointID); err != nil {
+		return nil, err
+	}
+	return s.postgres.GetDelivery(ctx, endpointID, id)
+}
+
+// Redeliver 手动重新投递，由后台任务立即发送
+func (s *WebhookService) Redeliver(ctx context.Context, endpointID, id string) (*models.WebhookDelivery, error) {
+	if _, err := s.endpoint(ctx, endpointID); err != nil {
+		return nil, err
+	}
+	if err := s.postgres.ResetDelivery(ctx, endpointID, id); err != nil {
+		return nil, err
+	}
+	return s.postgres.GetDelivery(ctx, endpointID, id)
+}
+
+// TestEndpoint 向 webhook 地址发送一条测试事件并同步返回投递结果
+func (s *WebhookService) TestEndpoint(ctx context.Context, endpointID string) (*models.WebhookDelivery, error) {
+	endpoint, err := s.endpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(map[string]string{"endpoint_id": endpoint.ID, "message": "test delivery"})
+	event := &models.Event{
+		ID:        uuid.NewString(),
+		Type:      models.EventWebhookTest,
+		ClientID:  endpoint.ClientID,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+	ids, err := s.postgres.CreateEvent(ctx, event, endpoint.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	pending, err := s.postgres.ClaimDelivery(ctx, ids[0], webhookLease)
+	if err != nil {
+		return nil, err
+	}
+	s.deliver(ctx, pending)
+	return s.postgres.GetDelivery(ctx, endpoint.ID, ids[0])
+}
+
+// Publish 写入事件并为订阅方创建投递，事件归属 ctx 中的 API 客户端。失败只记录日志，不影响业务
+func (s *WebhookService) Publish(ctx context.Context, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		s.logger.Logger.Error("failed to encode event", zap.String("type", eventType), zap.Error(err))
+		return
+	}
+	event := &models.Event{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		ClientID:  ClientIDFromContext(ctx),
+		Data:      payload,
+		CreatedAt: time.Now(),
+	}
+	if _, err := s.postgres.CreateEvent(ctx, event, ""); err != nil {
+		s.logger.Logger.Error("failed to publish event",
+			zap.String("type", eventType),
+			zap.Error(err))
+	}
+}
+
+// ReplayEvents 为发件箱中的历史事件重新创建投递，返回创建的投递数量。
+// 必须指定事件 ID 或起始时间，避免误重放全部事件
+func (s *WebhookService) ReplayEvents(ctx context.Context, filter repository.EventFilter) (int64, error) {
+	if len(filter.IDs) == 0 && filter.Since == nil {
+		return 0, fmt.Errorf("event ids or since is required")
+	}
+	if filter.Type != "" && !validEventType(filter.Type) {
+		return 0, fmt.Errorf("unknown event type: %s", filter.Type)
+	}
+	return s.postgres.ReplayEvents(ctx, filter)
+}
+
+// DispatchDue 投递到期的 webhook，返回处理数量
+func (s *WebhookService) DispatchDue(ctx context.Context) (int, error) {
+	deliveries, err := s.postgres.ClaimDueDeliveries(ctx, webhookLease, webhookBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, webhookConcurrency)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(d *repository.PendingDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.deliver(ctx, d)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// RunDispatcher 定期投递 webhook，直到 ctx 取消
+func (s *WebhookService) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DispatchDue(ctx); err != nil {
+				s.logger.Logger.Error("failed to dispatch webhooks", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *WebhookService) endpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	clientID := ClientIDFromContext(ctx)
+	if clientID == "" {
+		return nil, ErrClientRequired
+	}
+	return s.postgres.GetWebhookEndpoint(ctx, clientID, id)
+}
+
+// deliver 发送一次投递并记录结果，失败时按指数退避安排重试，用尽后进入死信
+func (s *WebhookService) deliver(ctx context.Context, d *repository.PendingDelivery) {
+	start := time.Now()
+	code, err := s.send(ctx, d.URL, d.Secret, d.ID, &d.Event)
+
+	attempt := models.WebhookAttempt{
+		Attempt:    d.Attempts + 1,
+		StatusCode: code,
+		DurationMs: int(time.Since(start).Milliseconds()),
+	}
+	status := models.DeliveryStatusSucceeded
+	var retryAfter time.Duration
+	if err != nil {
+		attempt.Error = err.Error()
+		status = models.DeliveryStatusPending
+		retryAfter = webhookBackoff(attempt.Attempt)
+		if attempt.Attempt >= webhookMaxAttempts {
+			status = models.DeliveryStatusDead
+			retryAfter = 0
+		}
+	}
+
+	if err := s.postgres.RecordDeliveryAttempt(ctx, d.ID, attempt, status, retryAfter); err != nil {
+		s.logger.Logger.Error("failed to record webhook delivery",
+			zap.String("delivery", d.ID),
+			zap.Error(err))
+	}
+	if status == models.DeliveryStatusDead {
+		s.logger.Logger.Warn("webhook delivery moved to dead letter",
+			zap.String("delivery", d.ID),
+			zap.String("endpoint", d.EndpointID),
+			zap.String("error", attempt.Error))
+	}
+}
+
+// send 签名并发送事件，非 2xx 响应视为失败
+func (s *WebhookService) send(ctx context.Context, target, secret, deliveryID string, event *models.Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MyWallet-Webhook/1.0")
+	req.Header.Set(webhook.HeaderEvent, event.Type)
+	req.Header.Set(webhook.HeaderDelivery, deliveryID)
+	req.Header.Set(webhook.HeaderTimestamp, fmt.Sprintf("%d", now.Unix()))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(secret, now, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorBody))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, snippet)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookErrorBody))
+	return resp.StatusCode, nil
+}
+
+// checkEndpointURL 校验 webhook 地址：必须为 https，主机解析出的全部地址都必须是公网地址
+func (s *WebhookService) checkEndpointURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid url: must be an absolute http(s) url")
+	}
+	if s.allowInsecure {
+		return u, nil
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid url: must use https")
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", ErrWebhookTarget, u.Hostname(), addr)
+		}
+	}
+	return u, nil
+}
+
+// webhookDialControl 拒绝连接非公网地址，投递与测试事件都经过这里
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWebhookTarget, address)
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrWebhookTarget, addrPort.Addr())
+	}
+	return nil
+}
+
+// publicAddr 判断地址是否为可投递的公网地址
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range webhookBlockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookBackoff 第 attempt 次失败后的等待时间
+func webhookBackoff(attempt int) time.Duration {
+	delay := webhookRetryBase << (attempt - 1)
+	if delay <= 0 || delay > webhookMaxRetry {
+		return webhookMaxRetry
+	}
+	return delay
+}
+
+func validEventType(t string) bool {
+	for _, known := range models.EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mywallet/internal/models"
+	"mywallet/pkg/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSendSignsPayload(t *testing.T) {
+	const secret = "whsec_test"
+	status := http.StatusOK
+	var received models.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := webhook.Verify(secret, r.Header.Get(webhook.HeaderSignature),
+			r.Header.Get(webhook.HeaderTimestamp), body, webhook.DefaultTolerance)
+		assert.NoError(t, err)
+		assert.Equal(t, "delivery-1", r.Header.Get(webhook.HeaderDelivery))
+		assert.Equal(t, models.EventDepositCompleted, r.Header.Get(webhook.HeaderEvent))
+		assert.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	// httptest 监听回环地址，需要开发模式
+	s := NewWebhookService(nil, nil, true)
+	event := &models.Event{
+		ID:        "event-1",
+		Type:      models.EventDepositCompleted,
+		ClientID:  "client-1",
+		Data:      json.RawMessage(`{"amount":"1.5"}`),
+		CreatedAt: time.Now(),
+	}
+
+	code, err := s.send(context.Background(), srv.URL, secret, "delivery-1", event)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, event.ID, received.ID)
+	assert.JSONEq(t, `{"amount":"1.5"}`, string(received.Data))
+	assert.Empty(t, received.ClientID)
+
+	status = http.StatusInternalServerError
+	code, err = s.send(context.Background(), srv.URL, secret, "delivery-1", event)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, code)
+}
+
+func TestWebhookTargetRestrictions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request to loopback address must be blocked")
+	}))
+	defer srv.Close()
+
+	s := NewWebhookService(nil, nil, false)
+	ctx := context.Background()
+	_, err := s.send(ctx, srv.URL, "whsec_test", "delivery-1", &models.Event{ID: "event-1", Type: models.EventWebhookTest})
+	assert.ErrorIs(t, err, ErrWebhookTarget)
+
+	_, err = s.checkEndpointURL(ctx, "http://93.184.216.34/hook")
+	assert.Error(t, err)
+	for _, raw := range []string{
+		"https://127.0.0.1/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.0.0.1/hook",
+		"https://192.168.1.10:8443/hook",
+		"https://100.64.0.1/hook",
+		"https://[::1]/hook",
+		"https://[fd00::1]/hook",
+		"https://[::ffff:127.0.0.1]/hook",
+	} {
+		_, err := s.checkEndpointURL(ctx, raw)
+		assert.ErrorIs(t, err, ErrWebhookTarget, raw)
+	}
+	_, err = s.checkEndpointURL(ctx, "https://93.184.216.34/hook")
+	assert.NoError(t, err)
+
+	dev := NewWebhookService(nil, nil, true)
+	_, err = dev.checkEndpointURL(ctx, "http://localhost:8080/hook")
+	assert.NoError(t, err)
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookBackoff(1))
+	assert.Equal(t, time.Minute, webhookBackoff(2))
+	assert.Equal(t, 32*time.Minute, webhookBackoff(7))
+	assert.Equal(t, webhookMaxRetry, webhookBackoff(20))
+	assert.Equal(t, webhookMaxRetry, webhookBackoff(80))
+}
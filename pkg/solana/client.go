@@ -2,83 +2,126 @@ package solana
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"mywallet/pkg/logger"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
 	"github.com/shopspring/decimal"
 )
 
 type Client struct {
 	client *rpc.Client
+	guard  *guardedClient
+	// pool is nil when the client talks to a single endpoint.
+	pool   *Pool
 	logger *logger.Logger
 }
 
+// unconfirmedPollInterval is how often WaitForSignature looks up a signature.
+const unconfirmedPollInterval = 2 * time.Second
+
 func NewClient(rpcURL string, logger *logger.Logger) *Client {
+	return NewClientWithPolicy(rpcURL, DefaultPolicy(), logger)
+}
+
+// NewClientWithPolicy returns a single-endpoint client whose calls follow
+// policy.
+func NewClientWithPolicy(rpcURL string, policy Policy, logger *logger.Logger) *Client {
+	return newClient(jsonrpc.NewClient(rpcURL), nil, policy, logger)
+}
+
+// NewPoolClient returns a client whose calls are spread over the endpoints
+// of pool and follow policy. The breaker then trips only when the pool as a
+// whole keeps failing.
+func NewPoolClient(pool *Pool, policy Policy, logger *logger.Logger) *Client {
+	return newClient(pool, pool, policy, logger)
+}
+
+func newClient(transport rpc.JSONRPCClient, pool *Pool, policy Policy, logger *logger.Logger) *Client {
+	guard := newGuardedClient(transport, policy)
 	return &Client{
-		client: rpc.New(rpcURL),
+		client: rpc.NewWithCustomRPCClient(guard),
+		guard:  guard,
+		pool:   pool,
 		logger: logger,
 	}
 }
 
+// Breaker returns the state of the circuit breaker.
+func (c *Client) Breaker() BreakerStatus {
+	return c.guard.breaker.status()
+}
+
+// Pool returns the endpoint pool, or nil for a single-endpoint client.
+func (c *Client) Pool() *Pool {
+	return c.pool
+}
+
 func (c *Client) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
+	balance, _, err := c.GetBalanceAt(ctx, address, rpc.CommitmentFinalized)
+	return balance, err
+}
+
+// GetBalanceAt returns the SOL balance at the given commitment level together
+// with the slot the node evaluated it at.
+func (c *Client) GetBalanceAt(ctx context.Context, address string, commitment rpc.CommitmentType) (decimal.Decimal, uint64, error) {
 	pubKey, err := solana.PublicKeyFromBase58(address)
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("invalid address: %w", err)
+		return decimal.Zero, 0, fmt.Errorf("invalid address: %w", err)
 	}
 
 	balance, err := c.client.GetBalance(
 		ctx,
 		pubKey,
-		rpc.CommitmentFinalized,
+		commitment,
 	)
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("failed to get balance: %w", err)
+		return decimal.Zero, 0, fmt.Errorf("failed to get balance: %w", err)
 	}
 
 	// Convert lamports to SOL (1 SOL = 1e9 lamports)
 	solBalance := decimal.NewFromInt(int64(balance.Value)).
 		Div(decimal.NewFromInt(1e9))
 
-	return solBalance, nil
+	return solBalance, balance.Context.Slot, nil
 }
 
 func (c *Client) Transfer(ctx context.Context, fromPrivateKey solana.PrivateKey, toPublicKey solana.PublicKey, amount decimal.Decimal) (string, error) {
-	// Convert SOL to lamports
-	lamports := amount.Mul(decimal.NewFromInt(1e9)).IntPart()
+	return c.TransferWithMemo(ctx, fromPrivateKey, toPublicKey, amount, "")
+}
 
-	recent, err := c.client.GetRecentBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		return "", fmt.Errorf("failed to get recent blockhash: %w", err)
-	}
+// TransferWithMemo sends a SOL transfer. A non-empty memo is attached with the
+// memo program, signed by the sender, as exchanges require for deposits.
+func (c *Client) TransferWithMemo(ctx context.Context, fromPrivateKey solana.PrivateKey, toPublicKey solana.PublicKey, amount decimal.Decimal, memo string) (string, error) {
+	return c.TransferFrom(ctx, LocalKey(fromPrivateKey), nil, toPublicKey, amount, memo)
+}
 
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{
-			system.NewTransferInstruction(
-				uint64(lamports),
-				fromPrivateKey.PublicKey(),
-				toPublicKey,
-			).Build(),
-		},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(fromPrivateKey.PublicKey()),
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to create transaction: %w", err)
-	}
+// TransferWithFeePayer sends a SOL transfer whose fee is paid and co-signed by
+// feePayer, so the sender needs no SOL beyond the amount it moves. The
+// transaction carries two signatures and costs the fee payer twice the base fee.
+func (c *Client) TransferWithFeePayer(ctx context.Context, fromPrivateKey, feePayer solana.PrivateKey, toPublicKey solana.PublicKey, amount decimal.Decimal, memo string) (string, error) {
+	payer := LocalKey(feePayer)
+	return c.TransferFrom(ctx, LocalKey(fromPrivateKey), &payer, toPublicKey, amount, memo)
+}
 
-	// Sign transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(fromPrivateKey.PublicKey()) {
-			return &fromPrivateKey
-		}
-		return nil
-	})
+// TransferFrom sends a SOL transfer signed through the signers of from and,
+// when not nil, of feePayer, which then pays the fee. When the send fails with
+// ErrSendUnconfirmed the signature is returned along with the error, so the
+// caller can look up whether the transaction landed.
+func (c *Client) TransferFrom(ctx context.Context, from Key, feePayer *Key, toPublicKey solana.PublicKey, amount decimal.Decimal, memo string) (string, error) {
+	payer := from
+	if feePayer != nil {
+		payer = *feePayer
+	}
+	tx, err := c.buildTransfer(ctx, from, payer, toPublicKey, amount, memo)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %w", err)
+		return "", err
 	}
 
 	// Send transaction
@@ -88,6 +131,9 @@ func (c *Client) Transfer(ctx context.Context, fromPrivateKey solana.PrivateKey,
 			PreflightCommitment: rpc.CommitmentFinalized,
 		},
 	)
+	if errors.Is(err, ErrSendUnconfirmed) {
+		return tx.Signatures[0].String(), fmt.Errorf("failed to send transaction: %w", err)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to send transaction: %w", err)
 	}
@@ -95,6 +141,44 @@ func (c *Client) Transfer(ctx context.Context, fromPrivateKey solana.PrivateKey,
 	return sig.String(), nil
 }
 
+// buildTransfer creates and signs a transfer against the latest finalized
+// blockhash.
+func (c *Client) buildTransfer(ctx context.Context, from, payer Key, toPublicKey solana.PublicKey, amount decimal.Decimal, memo string) (*solana.Transaction, error) {
+	// Convert SOL to lamports
+	lamports := amount.Mul(decimal.NewFromInt(1e9)).IntPart()
+
+	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent blockhash: %w", err)
+	}
+
+	instructions := []solana.Instruction{
+		system.NewTransferInstruction(
+			uint64(lamports),
+			from.PublicKey,
+			toPublicKey,
+		).Build(),
+	}
+	if memo != "" {
+		instructions = append(instructions, MemoInstruction(memo, from.PublicKey))
+	}
+
+	tx, err := solana.NewTransaction(
+		instructions,
+		recent.Value.Blockhash,
+		solana.TransactionPayer(payer.PublicKey),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	// Sign transaction
+	if err := SignTransaction(ctx, tx, from, payer); err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return tx, nil
+}
+
 func (c *Client) GetTransaction(ctx context.Context, signature string) (*rpc.GetTransactionResult, error) {
 	sig, err := solana.SignatureFromBase58(signature)
 	if err != nil {
@@ -110,3 +194,59 @@ func (c *Client) GetTransaction(ctx context.Context, signature string) (*rpc.Get
 
 	return tx, nil
 }
+
+// HasTransactions reports whether address appears in any transaction the
+// node still has history for.
+func (c *Client) HasTransactions(ctx context.Context, address solana.PublicKey) (bool, error) {
+	limit := 1
+	sigs, err := c.client.GetSignaturesForAddressWithOpts(ctx, address, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentFinalized,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get signatures: %w", err)
+	}
+	return len(sigs) > 0, nil
+}
+
+// WaitForSignature looks up signature until it is confirmed, failed or
+// timeout has passed, and reports whether it landed without error. Use it to
+// resolve sends that failed with ErrSendUnconfirmed; the timeout should
+// exceed the lifetime of the transaction's blockhash, after which an unknown
+// signature can no longer land.
+func (c *Client) WaitForSignature(ctx context.Context, signature string, timeout time.Duration) (bool, error) {
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		statuses, err := c.SignatureStatuses(ctx, []solana.Signature{sig})
+		if err == nil && len(statuses) == 1 && statuses[0] != nil &&
+			statuses[0].ConfirmationStatus != rpc.ConfirmationStatusProcessed {
+			return statuses[0].Err == nil, nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return false, err
+			}
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(unconfirmedPollInterval):
+		}
+	}
+}
+
+// MemoInstruction builds a memo program instruction carrying the raw UTF-8 memo.
+func MemoInstruction(memo string, signer solana.PublicKey) solana.Instruction {
+	return solana.NewInstruction(
+		solana.MemoProgramID,
+		solana.AccountMetaSlice{solana.Meta(signer).SIGNER()},
+		[]byte(memo),
+	)
+}
@@ -0,0 +1,151 @@
+package solana
+
+import (
+	"context"
+	"testing"
+
+	"mywallet/pkg/logger"
+	"mywallet/pkg/solana/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientAgainstSimulator(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	sim.Airdrop(from.PublicKey(), 2*solana.LAMPORTS_PER_SOL)
+
+	balance, err := client.GetBalance(ctx, from.PublicKey().String())
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(2).Equal(balance))
+
+	sig, err := client.Transfer(ctx, from.PrivateKey, to, decimal.NewFromFloat(0.5))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(solana.LAMPORTS_PER_SOL/2), sim.Balance(to))
+	assert.Equal(t, uint64(3*solana.LAMPORTS_PER_SOL/2-simulator.LamportsPerSignature), sim.Balance(from.PublicKey()))
+
+	tx, err := client.GetTransaction(ctx, sig)
+	require.NoError(t, err)
+	parsed, err := tx.Transaction.GetTransaction()
+	require.NoError(t, err)
+	assert.Equal(t, sig, parsed.Signatures[0].String())
+	assert.Equal(t, uint64(simulator.LamportsPerSignature), tx.Meta.Fee)
+}
+
+func TestTransferWithMemo(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	sig, err := client.TransferWithMemo(ctx, from.PrivateKey, to, decimal.NewFromFloat(0.25), "deposit-1234")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(solana.LAMPORTS_PER_SOL/4), sim.Balance(to))
+
+	tx, err := client.GetTransaction(ctx, sig)
+	require.NoError(t, err)
+	parsed, err := tx.Transaction.GetTransaction()
+	require.NoError(t, err)
+	require.Len(t, parsed.Message.Instructions, 2)
+	programID, err := parsed.ResolveProgramIDIndex(parsed.Message.Instructions[1].ProgramIDIndex)
+	require.NoError(t, err)
+	assert.Equal(t, solana.MemoProgramID, programID)
+	assert.Equal(t, []byte("deposit-1234"), []byte(parsed.Message.Instructions[1].Data))
+}
+
+func TestTransferWithFeePayer(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	from := solana.NewWallet()
+	payer := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+	sim.Airdrop(payer.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	// The sender can be drained completely, the fee payer covers both signatures.
+	_, err := client.TransferWithFeePayer(ctx, from.PrivateKey, payer.PrivateKey, to, decimal.NewFromInt(1), "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), sim.Balance(from.PublicKey()))
+	assert.Equal(t, uint64(solana.LAMPORTS_PER_SOL), sim.Balance(to))
+	assert.Equal(t, uint64(solana.LAMPORTS_PER_SOL-2*simulator.LamportsPerSignature), sim.Balance(payer.PublicKey()))
+}
+
+func TestSimulatorRejectsOverdraft(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	from := solana.NewWallet()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	_, err := client.Transfer(ctx, from.PrivateKey, solana.NewWallet().PublicKey(), decimal.NewFromInt(2))
+	assert.Error(t, err)
+	assert.Equal(t, uint64(solana.LAMPORTS_PER_SOL), sim.Balance(from.PublicKey()))
+}
+
+func TestBuildPaymentsPacksWithinSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	from := solana.NewWallet()
+	sim.Airdrop(from.PublicKey(), 10*solana.LAMPORTS_PER_SOL)
+
+	payments := make([]Payment, 50)
+	for i := range payments {
+		payments[i] = Payment{
+			Recipient: solana.NewWallet().PublicKey(),
+			Amount:    decimal.NewFromFloat(0.01),
+		}
+	}
+
+	txs, err := client.BuildPayments(ctx, from.PrivateKey, payments)
+	require.NoError(t, err)
+	require.Greater(t, len(txs), 1)
+
+	var packed []int
+	var fees uint64
+	for _, tx := range txs {
+		raw, err := tx.Transaction.MarshalBinary()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(raw), MaxTransactionSize)
+		packed = append(packed, tx.Payments...)
+		fees += tx.Fee(simulator.LamportsPerSignature)
+
+		require.NoError(t, client.SendPayment(ctx, tx))
+	}
+	assert.Len(t, packed, len(payments))
+
+	for _, p := range payments {
+		assert.Equal(t, uint64(solana.LAMPORTS_PER_SOL/100), sim.Balance(p.Recipient))
+	}
+	assert.Equal(t, uint64(10*solana.LAMPORTS_PER_SOL-50*solana.LAMPORTS_PER_SOL/100)-fees, sim.Balance(from.PublicKey()))
+
+	statuses, err := client.SignatureStatuses(ctx, []solana.Signature{txs[0].Signature})
+	require.NoError(t, err)
+	require.NotNil(t, statuses[0])
+	assert.Nil(t, statuses[0].Err)
+}
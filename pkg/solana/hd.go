@@ -0,0 +1,84 @@
+package solana
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// HD keys follow BIP44 with SLIP-0010 ed25519 derivation along the path
+// Solana wallets use, m/44'/501'/n'/0', so a mnemonic backed up from this
+// service can be imported into any wallet that supports it.
+
+const (
+	// MaxDerivationIndex is the largest account index n; every level of an
+	// ed25519 path is hardened, which leaves 31 bits.
+	MaxDerivationIndex = 1<<31 - 1
+
+	hardened = 1 << 31
+)
+
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// NewMnemonic returns a new 24-word BIP39 mnemonic.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// SeedFromMnemonic validates mnemonic and returns its BIP39 seed.
+func SeedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+	return bip39.NewSeed(mnemonic, passphrase), nil
+}
+
+// DerivationPath returns the derivation path of account index.
+func DerivationPath(index uint32) string {
+	return fmt.Sprintf("m/44'/501'/%d'/0'", index)
+}
+
+// DeriveKey derives the key of account index from seed along
+// m/44'/501'/index'/0'.
+func DeriveKey(seed []byte, index uint32) (solana.PrivateKey, error) {
+	if index > MaxDerivationIndex {
+		return nil, fmt.Errorf("derivation index %d is out of range", index)
+	}
+	return derivePath(seed, 44, 501, index, 0)
+}
+
+// derivePath implements SLIP-0010 for ed25519, which only defines hardened
+// children.
+func derivePath(seed []byte, path ...uint32) (solana.PrivateKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("invalid seed length %d", len(seed))
+	}
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chain := sum[:32], sum[32:]
+
+	data := make([]byte, 37)
+	for _, index := range path {
+		data[0] = 0
+		copy(data[1:33], key)
+		binary.BigEndian.PutUint32(data[33:], index|hardened)
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data)
+		sum = mac.Sum(nil)
+		key, chain = sum[:32], sum[32:]
+	}
+	return solana.PrivateKey(ed25519.NewKeyFromSeed(key)), nil
+}
@@ -0,0 +1,44 @@
+package solana
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKey(t *testing.T) {
+	// SLIP-0010 ed25519 test vector 1.
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	for want, path := range map[string][]uint32{
+		"68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3": {0},
+		"b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2": {0, 1},
+		"92a5b23c0b8a99e37d07df3fb9966917f5d06e02ddbd909c7e184371463e9fc9": {0, 1, 2},
+	} {
+		key, err := derivePath(seed, path...)
+		require.NoError(t, err)
+		assert.Equal(t, want, hex.EncodeToString(key[:32]))
+	}
+
+	// The address Solana wallets derive for the first account of this mnemonic.
+	seed, err := SeedFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+	require.NoError(t, err)
+	key, err := DeriveKey(seed, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "HAgk14JpMQLgt6rVgv7cBQFJWFto5Dqxi472uT3DKpqk", key.PublicKey().String())
+	assert.Equal(t, "m/44'/501'/0'/0'", DerivationPath(0))
+
+	other, err := DeriveKey(seed, 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, key.PublicKey(), other.PublicKey())
+	_, err = DeriveKey(seed, MaxDerivationIndex+1)
+	assert.Error(t, err)
+
+	_, err = SeedFromMnemonic("abandon abandon abandon", "")
+	assert.ErrorIs(t, err, ErrInvalidMnemonic)
+	mnemonic, err := NewMnemonic()
+	require.NoError(t, err)
+	_, err = SeedFromMnemonic(mnemonic, "passphrase")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,301 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// ErrNotNonceAccount is returned for accounts that are not initialized
+// durable nonce accounts.
+var ErrNotNonceAccount = errors.New("not an initialized nonce account")
+
+// qrEncoding encodes QR payloads. Upper-case base32 fits the alphanumeric
+// QR mode, which packs it denser than base64 fits the byte mode.
+var qrEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NonceAccount is the state of a durable nonce account.
+type NonceAccount struct {
+	Authority solana.PublicKey
+	Nonce     solana.Hash
+}
+
+// GetNonceAccount reads a durable nonce account at finalized commitment.
+func (c *Client) GetNonceAccount(ctx context.Context, account solana.PublicKey) (*NonceAccount, error) {
+	out, err := c.client.GetAccountInfoWithOpts(ctx, account, &rpc.GetAccountInfoOpts{
+		Commitment: rpc.CommitmentFinalized,
+		Encoding:   solana.EncodingBase64,
+	})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, fmt.Errorf("nonce account %s: %w", account, ErrNotNonceAccount)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce account: %w", err)
+	}
+
+	var state system.NonceAccount
+	data := out.Value.Data.GetBinary()
+	if !out.Value.Owner.Equals(solana.SystemProgramID) || len(data) == 0 {
+		return nil, fmt.Errorf("nonce account %s: %w", account, ErrNotNonceAccount)
+	}
+	if err := state.UnmarshalWithDecoder(bin.NewBinDecoder(data)); err != nil || state.State != 1 {
+		return nil, fmt.Errorf("nonce account %s: %w", account, ErrNotNonceAccount)
+	}
+	return &NonceAccount{Authority: state.AuthorizedPubkey, Nonce: solana.Hash(state.Nonce)}, nil
+}
+
+// OfflineTransfer is a SOL transfer that uses a durable nonce in place of a
+// recent blockhash. It stays valid until the nonce advances, so it can be
+// signed on a machine that never talks to the network.
+type OfflineTransfer struct {
+	From         solana.PublicKey
+	To           solana.PublicKey
+	Amount       decimal.Decimal
+	Memo         string
+	NonceAccount solana.PublicKey
+	Nonce        solana.Hash
+}
+
+// BuildOfflineTransfer builds the unsigned transaction for t. The sender
+// pays the fee and must be the nonce authority, so its signature is the only
+// one required.
+func BuildOfflineTransfer(t OfflineTransfer) (*solana.Transaction, error) {
+	lamports := t.Amount.Shift(9)
+	if !lamports.IsInteger() || !lamports.IsPositive() {
+		return nil, fmt.Errorf("invalid amount %s", t.Amount)
+	}
+
+	instructions := []solana.Instruction{
+		system.NewAdvanceNonceAccountInstruction(t.NonceAccount, solana.SysVarRecentBlockHashesPubkey, t.From).Build(),
+		system.NewTransferInstruction(uint64(lamports.IntPart()), t.From, t.To).Build(),
+	}
+	if t.Memo != "" {
+		instructions = append(instructions, MemoInstruction(t.Memo, t.From))
+	}
+	tx, err := solana.NewTransaction(instructions, t.Nonce, solana.TransactionPayer(t.From))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	return tx, nil
+}
+
+// ParseOfflineTransfer checks that tx has the shape BuildOfflineTransfer
+// produces and returns the transfer it makes. It does not check signatures.
+func ParseOfflineTransfer(tx *solana.Transaction) (*OfflineTransfer, error) {
+	msg := &tx.Message
+	n := len(msg.Instructions)
+	if n < 2 || n > 3 || msg.Header.NumRequiredSignatures != 1 || len(msg.AccountKeys) == 0 {
+		return nil, errors.New("not an offline transfer")
+	}
+	payer := msg.AccountKeys[0]
+
+	advance, ok := decodeSystem(msg, msg.Instructions[0]).(*system.AdvanceNonceAccount)
+	if !ok || !advance.GetNonceAuthorityAccount().PublicKey.Equals(payer) {
+		return nil, errors.New("offline transfer must start by advancing a nonce owned by the sender")
+	}
+	transfer, ok := decodeSystem(msg, msg.Instructions[1]).(*system.Transfer)
+	if !ok || !transfer.GetFundingAccount().PublicKey.Equals(payer) {
+		return nil, errors.New("offline transfer must transfer SOL from the fee payer")
+	}
+
+	t := &OfflineTransfer{
+		From:         payer,
+		To:           transfer.GetRecipientAccount().PublicKey,
+		Amount:       decimal.NewFromInt(int64(*transfer.Lamports)).Shift(-9),
+		NonceAccount: advance.GetNonceAccount().PublicKey,
+		Nonce:        msg.RecentBlockhash,
+	}
+	if n == 3 {
+		inst := msg.Instructions[2]
+		programID, err := msg.ResolveProgramIDIndex(inst.ProgramIDIndex)
+		if err != nil || !programID.Equals(solana.MemoProgramID) {
+			return nil, errors.New("unexpected instruction in offline transfer")
+		}
+		t.Memo = string(inst.Data)
+	}
+	return t, nil
+}
+
+// NonceAdvance is a transaction that only advances a durable nonce. Once it
+// lands, no transaction built on the same nonce can land, which is how an
+// offline transaction that was signed but should not be sent is revoked.
+type NonceAdvance struct {
+	NonceAccount solana.PublicKey
+	Authority    solana.PublicKey
+	Nonce        solana.Hash
+}
+
+// BuildNonceAdvance builds the unsigned transaction for a. The authority
+// pays the fee, so its signature is the only one required.
+func BuildNonceAdvance(a NonceAdvance) (*solana.Transaction, error) {
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewAdvanceNonceAccountInstruction(a.NonceAccount, solana.SysVarRecentBlockHashesPubkey, a.Authority).Build(),
+	}, a.Nonce, solana.TransactionPayer(a.Authority))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	return tx, nil
+}
+
+// ParseNonceAdvance checks that tx has the shape BuildNonceAdvance produces
+// and returns the advance it makes. It does not check signatures.
+func ParseNonceAdvance(tx *solana.Transaction) (*NonceAdvance, error) {
+	msg := &tx.Message
+	if len(msg.Instructions) != 1 || msg.Header.NumRequiredSignatures != 1 || len(msg.AccountKeys) == 0 {
+		return nil, errors.New("not a nonce advance")
+	}
+	payer := msg.AccountKeys[0]
+	advance, ok := decodeSystem(msg, msg.Instructions[0]).(*system.AdvanceNonceAccount)
+	if !ok || !advance.GetNonceAuthorityAccount().PublicKey.Equals(payer) {
+		return nil, errors.New("nonce advance must be paid by the nonce authority")
+	}
+	return &NonceAdvance{
+		NonceAccount: advance.GetNonceAccount().PublicKey,
+		Authority:    payer,
+		Nonce:        msg.RecentBlockhash,
+	}, nil
+}
+
+func decodeSystem(msg *solana.Message, inst solana.CompiledInstruction) interface{} {
+	programID, err := msg.ResolveProgramIDIndex(inst.ProgramIDIndex)
+	if err != nil || !programID.Equals(solana.SystemProgramID) {
+		return nil
+	}
+	accounts, err := inst.ResolveInstructionAccounts(msg)
+	if err != nil {
+		return nil
+	}
+	decoded, err := system.DecodeInstruction(accounts, inst.Data)
+	if err != nil {
+		return nil
+	}
+	return decoded.Impl
+}
+
+// SameMessage reports whether a and b sign the same message, that is
+// whether b is a (possibly) signed copy of a.
+func SameMessage(a, b *solana.Transaction) bool {
+	am, err := a.Message.MarshalBinary()
+	if err != nil {
+		return false
+	}
+	bm, err := b.Message.MarshalBinary()
+	return err == nil && bytes.Equal(am, bm)
+}
+
+// EncodeTransaction serializes tx, signed or not, as base64.
+func EncodeTransaction(tx *solana.Transaction) (string, error) {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// EncodeTransactionQR serializes tx as unpadded upper-case base32, suited to
+// QR codes.
+func EncodeTransactionQR(tx *solana.Transaction) (string, error) {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	return qrEncoding.EncodeToString(raw), nil
+}
+
+// DecodeTransaction parses a transaction encoded by EncodeTransaction or
+// EncodeTransactionQR.
+func DecodeTransaction(payload string) (*solana.Transaction, error) {
+	payload = strings.Join(strings.Fields(payload), "")
+	raw, err := qrEncoding.DecodeString(payload)
+	if err != nil {
+		if raw, err = base64.StdEncoding.DecodeString(payload); err != nil {
+			return nil, errors.New("transaction is neither base64 nor base32 encoded")
+		}
+	}
+	tx, err := solana.TransactionFromBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// NonceUse is the finalized transaction that consumed a durable nonce.
+type NonceUse struct {
+	Signature   solana.Signature
+	Transaction *solana.Transaction
+	// Failed is set when the transaction failed. A failed transaction still
+	// advances the nonce, but makes none of its other changes.
+	Failed bool
+}
+
+// nonceHistoryPage is the page size used to search nonce account history.
+const nonceHistoryPage = 100
+
+// FindNonceUse searches the history of nonceAccount, newest first, for the
+// transaction that used nonce in place of a blockhash. The search stops at
+// transactions older than since; it returns nil when none is found.
+func (c *Client) FindNonceUse(ctx context.Context, nonceAccount solana.PublicKey, nonce solana.Hash, since time.Time) (*NonceUse, error) {
+	limit := nonceHistoryPage
+	opts := &rpc.GetSignaturesForAddressOpts{Limit: &limit, Commitment: rpc.CommitmentFinalized}
+	for {
+		sigs, err := c.client.GetSignaturesForAddressWithOpts(ctx, nonceAccount, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signatures: %w", err)
+		}
+		for _, sig := range sigs {
+			if sig.BlockTime != nil && sig.BlockTime.Time().Before(since) {
+				return nil, nil
+			}
+			out, err := c.client.GetTransaction(ctx, sig.Signature, &rpc.GetTransactionOpts{
+				Commitment: rpc.CommitmentFinalized,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get transaction %s: %w", sig.Signature, err)
+			}
+			tx, err := out.Transaction.GetTransaction()
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode transaction %s: %w", sig.Signature, err)
+			}
+			if tx.Message.RecentBlockhash.Equals(nonce) {
+				failed := sig.Err != nil || (out.Meta != nil && out.Meta.Err != nil)
+				return &NonceUse{Signature: sig.Signature, Transaction: tx, Failed: failed}, nil
+			}
+		}
+		if len(sigs) < limit {
+			return nil, nil
+		}
+		opts.Before = sigs[len(sigs)-1].Signature
+	}
+}
+
+// SendSigned submits a transaction signed elsewhere. Like Transfer, it fails
+// with ErrSendUnconfirmed when the transaction may have been sent; its first
+// signature then tells whether it landed.
+func (c *Client) SendSigned(ctx context.Context, tx *solana.Transaction) error {
+	if err := tx.VerifySignatures(); err != nil {
+		return fmt.Errorf("invalid transaction signature: %w", err)
+	}
+	_, err := c.client.SendTransactionWithOpts(ctx, tx,
+		rpc.TransactionOpts{
+			SkipPreflight:       false,
+			PreflightCommitment: rpc.CommitmentFinalized,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return nil
+}
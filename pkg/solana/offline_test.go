@@ -0,0 +1,176 @@
+package solana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mywallet/pkg/logger"
+	"mywallet/pkg/solana/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineTransfer(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	cold := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	nonceAccount := solana.NewWallet().PublicKey()
+	sim.Airdrop(cold.PublicKey(), solana.LAMPORTS_PER_SOL)
+	sim.CreateNonceAccount(nonceAccount, cold.PublicKey())
+
+	nonce, err := client.GetNonceAccount(ctx, nonceAccount)
+	require.NoError(t, err)
+	assert.Equal(t, cold.PublicKey(), nonce.Authority)
+
+	unsigned, err := BuildOfflineTransfer(OfflineTransfer{
+		From:         cold.PublicKey(),
+		To:           to,
+		Amount:       decimal.NewFromFloat(0.25),
+		Memo:         "withdrawal 42",
+		NonceAccount: nonceAccount,
+		Nonce:        nonce.Nonce,
+	})
+	require.NoError(t, err)
+
+	// The payload survives both encodings and describes the transfer.
+	for _, encode := range []func(*solana.Transaction) (string, error){EncodeTransaction, EncodeTransactionQR} {
+		payload, err := encode(unsigned)
+		require.NoError(t, err)
+		decoded, err := DecodeTransaction(payload)
+		require.NoError(t, err)
+		assert.True(t, SameMessage(unsigned, decoded))
+	}
+	parsed, err := ParseOfflineTransfer(unsigned)
+	require.NoError(t, err)
+	assert.Equal(t, to, parsed.To)
+	assert.True(t, decimal.NewFromFloat(0.25).Equal(parsed.Amount))
+	assert.Equal(t, "withdrawal 42", parsed.Memo)
+	assert.Equal(t, nonce.Nonce, parsed.Nonce)
+
+	assert.Error(t, client.SendSigned(ctx, unsigned), "unsigned transactions are rejected")
+
+	payload, err := EncodeTransaction(unsigned)
+	require.NoError(t, err)
+	signed, err := DecodeTransaction(payload)
+	require.NoError(t, err)
+	_, err = signed.PartialSign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(cold.PublicKey()) {
+			return &cold.PrivateKey
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, client.SendSigned(ctx, signed))
+	assert.Equal(t, solana.LAMPORTS_PER_SOL/4, sim.Balance(to))
+	assert.NotEqual(t, nonce.Nonce, sim.Nonce(nonceAccount), "the nonce advances")
+
+	// A second transaction using the spent nonce cannot land.
+	parsed.Memo = ""
+	replay, err := BuildOfflineTransfer(*parsed)
+	require.NoError(t, err)
+	_, err = replay.PartialSign(func(solana.PublicKey) *solana.PrivateKey { return &cold.PrivateKey })
+	require.NoError(t, err)
+	assert.Error(t, client.SendSigned(ctx, replay))
+}
+
+func TestNonceAdvance(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	cold := solana.NewWallet()
+	nonceAccount := solana.NewWallet().PublicKey()
+	sim.Airdrop(cold.PublicKey(), solana.LAMPORTS_PER_SOL)
+	nonce := sim.CreateNonceAccount(nonceAccount, cold.PublicKey())
+	since := time.Now().Add(-time.Minute)
+	sign := func(tx *solana.Transaction) {
+		_, err := tx.PartialSign(func(solana.PublicKey) *solana.PrivateKey { return &cold.PrivateKey })
+		require.NoError(t, err)
+	}
+
+	transfer, err := BuildOfflineTransfer(OfflineTransfer{
+		From:         cold.PublicKey(),
+		To:           solana.NewWallet().PublicKey(),
+		Amount:       decimal.NewFromFloat(0.1),
+		NonceAccount: nonceAccount,
+		Nonce:        nonce,
+	})
+	require.NoError(t, err)
+	sign(transfer)
+
+	advance, err := BuildNonceAdvance(NonceAdvance{NonceAccount: nonceAccount, Authority: cold.PublicKey(), Nonce: nonce})
+	require.NoError(t, err)
+	parsed, err := ParseNonceAdvance(advance)
+	require.NoError(t, err)
+	assert.Equal(t, NonceAdvance{NonceAccount: nonceAccount, Authority: cold.PublicKey(), Nonce: nonce}, *parsed)
+	_, err = ParseNonceAdvance(transfer)
+	assert.Error(t, err, "a transfer is not a nonce advance")
+	_, err = ParseOfflineTransfer(advance)
+	assert.Error(t, err, "a nonce advance is not a transfer")
+
+	use, err := client.FindNonceUse(ctx, nonceAccount, nonce, since)
+	require.NoError(t, err)
+	assert.Nil(t, use, "the nonce is unused")
+
+	sign(advance)
+	require.NoError(t, client.SendSigned(ctx, advance))
+	assert.Error(t, client.SendSigned(ctx, transfer), "the advance revokes the signed transfer")
+
+	use, err = client.FindNonceUse(ctx, nonceAccount, nonce, since)
+	require.NoError(t, err)
+	require.NotNil(t, use)
+	assert.Equal(t, advance.Signatures[0], use.Signature)
+	assert.False(t, SameMessage(transfer, use.Transaction))
+
+	// A transfer that lands is found as the user of its nonce.
+	next := sim.Nonce(nonceAccount)
+	transfer, err = BuildOfflineTransfer(OfflineTransfer{
+		From:         cold.PublicKey(),
+		To:           solana.NewWallet().PublicKey(),
+		Amount:       decimal.NewFromFloat(0.1),
+		NonceAccount: nonceAccount,
+		Nonce:        next,
+	})
+	require.NoError(t, err)
+	sign(transfer)
+	require.NoError(t, client.SendSigned(ctx, transfer))
+	use, err = client.FindNonceUse(ctx, nonceAccount, next, since)
+	require.NoError(t, err)
+	require.NotNil(t, use)
+	assert.True(t, SameMessage(transfer, use.Transaction))
+	assert.False(t, use.Failed)
+}
+
+func TestOfflineTransferValidation(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	plain := solana.NewWallet().PublicKey()
+	sim.Airdrop(plain, solana.LAMPORTS_PER_SOL)
+	_, err := client.GetNonceAccount(ctx, plain)
+	assert.ErrorIs(t, err, ErrNotNonceAccount)
+	_, err = client.GetNonceAccount(ctx, solana.NewWallet().PublicKey())
+	assert.ErrorIs(t, err, ErrNotNonceAccount)
+
+	from := solana.NewWallet()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+	tx, err := client.buildTransfer(ctx, LocalKey(from.PrivateKey), LocalKey(from.PrivateKey), plain, decimal.NewFromInt(1), "")
+	require.NoError(t, err)
+	_, err = ParseOfflineTransfer(tx)
+	assert.Error(t, err, "a blockhash transfer is not an offline transfer")
+
+	_, err = DecodeTransaction("not a transaction")
+	assert.Error(t, err)
+}
@@ -0,0 +1,306 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// MaxTransactionSize is the largest serialized transaction a validator
+	// accepts (IPv6 MTU minus headers).
+	MaxTransactionSize = 1232
+
+	// TokenAccountRent is the rent-exempt minimum, in lamports, the payer
+	// funds when creating an associated token account.
+	TokenAccountRent = 2039280
+
+	// SystemAccountRent is the rent-exempt minimum, in lamports, of an
+	// account holding no data. Draining a wallet below it closes the account.
+	SystemAccountRent = 890880
+
+	// maxAccountsPerRequest is the getMultipleAccounts limit.
+	maxAccountsPerRequest = 100
+)
+
+// Payment is a single transfer inside a batch. A zero Mint pays native SOL;
+// otherwise Amount is paid in the SPL token with the given Mint and Decimals.
+type Payment struct {
+	Recipient solana.PublicKey
+	Amount    decimal.Decimal
+	Mint      solana.PublicKey
+	Decimals  uint8
+}
+
+// IsNative reports whether the payment is in SOL.
+func (p Payment) IsNative() bool {
+	return p.Mint.IsZero()
+}
+
+// BaseUnits returns the amount in lamports or token base units.
+func (p Payment) BaseUnits() uint64 {
+	decimals := p.Decimals
+	if p.IsNative() {
+		decimals = 9
+	}
+	return uint64(p.Amount.Shift(int32(decimals)).IntPart())
+}
+
+// PaymentTransaction is a signed transaction carrying one or more payments.
+type PaymentTransaction struct {
+	// Payments holds indexes into the slice passed to BuildPayments.
+	Payments    []int
+	Transaction *solana.Transaction
+	Signature   solana.Signature
+	// AccountsCreated is the number of recipient token accounts the
+	// transaction creates, each costing TokenAccountRent.
+	AccountsCreated int
+}
+
+// Fee returns the lamports the payer spends on the transaction, including
+// rent for any token accounts it creates.
+func (t *PaymentTransaction) Fee(lamportsPerSignature uint64) uint64 {
+	return uint64(len(t.Transaction.Signatures))*lamportsPerSignature +
+		uint64(t.AccountsCreated)*TokenAccountRent
+}
+
+// BuildPayments packs the payments into as few signed transactions as fit
+// within MaxTransactionSize, all sharing one recent blockhash. Recipients of
+// SPL payments without an associated token account get one created, paid for
+// by the sender.
+func (c *Client) BuildPayments(ctx context.Context, from solana.PrivateKey, payments []Payment) ([]*PaymentTransaction, error) {
+	if len(payments) == 0 {
+		return nil, errors.New("no payments")
+	}
+	payer := from.PublicKey()
+
+	missing, err := c.missingTokenAccounts(ctx, payments)
+	if err != nil {
+		return nil, err
+	}
+
+	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent blockhash: %w", err)
+	}
+	blockhash := recent.Value.Blockhash
+
+	var (
+		txs     []*PaymentTransaction
+		current = &PaymentTransaction{}
+		instrs  []solana.Instruction
+	)
+	flush := func() error {
+		if len(current.Payments) == 0 {
+			return nil
+		}
+		tx, err := newTransaction(instrs, blockhash, payer)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+			if key.Equals(payer) {
+				return &from
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to sign transaction: %w", err)
+		}
+		current.Transaction = tx
+		current.Signature = tx.Signatures[0]
+		txs = append(txs, current)
+		current = &PaymentTransaction{}
+		instrs = nil
+		return nil
+	}
+
+	for i, p := range payments {
+		pi, created, err := paymentInstructions(payer, p, missing)
+		if err != nil {
+			return nil, fmt.Errorf("payment %d: %w", i, err)
+		}
+
+		size, err := transactionSize(append(instrs[:len(instrs):len(instrs)], pi...), blockhash, payer)
+		if err != nil {
+			return nil, err
+		}
+		if size > MaxTransactionSize {
+			if len(current.Payments) == 0 {
+				return nil, fmt.Errorf("payment %d does not fit in a transaction", i)
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+
+		instrs = append(instrs, pi...)
+		current.Payments = append(current.Payments, i)
+		if created {
+			current.AccountsCreated++
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// SendPayment submits a transaction built by BuildPayments.
+func (c *Client) SendPayment(ctx context.Context, tx *PaymentTransaction) error {
+	_, err := c.client.SendTransactionWithOpts(ctx, tx.Transaction,
+		rpc.TransactionOpts{
+			SkipPreflight:       false,
+			PreflightCommitment: rpc.CommitmentFinalized,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return nil
+}
+
+// SignatureStatuses looks up the statuses of the given signatures, searching
+// the ledger history. Unknown signatures have a nil entry.
+func (c *Client) SignatureStatuses(ctx context.Context, signatures []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
+	out, err := c.client.GetSignatureStatuses(ctx, true, signatures...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signature statuses: %w", err)
+	}
+	return out.Value, nil
+}
+
+// GetTokenBalance returns the owner's balance of the given mint held in its
+// associated token account. A missing account has a zero balance.
+func (c *Client) GetTokenBalance(ctx context.Context, owner, mint solana.PublicKey, decimals uint8) (decimal.Decimal, error) {
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to derive token account: %w", err)
+	}
+	accounts, err := c.client.GetMultipleAccounts(ctx, ata)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get token account: %w", err)
+	}
+	if len(accounts.Value) == 0 || accounts.Value[0] == nil {
+		return decimal.Zero, nil
+	}
+
+	balance, err := c.client.GetTokenAccountBalance(ctx, ata, rpc.CommitmentFinalized)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get token balance: %w", err)
+	}
+	amount, err := decimal.NewFromString(balance.Value.Amount)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid token balance: %w", err)
+	}
+	return amount.Shift(-int32(decimals)), nil
+}
+
+// missingTokenAccounts returns the recipient token accounts of SPL payments
+// that do not exist yet.
+func (c *Client) missingTokenAccounts(ctx context.Context, payments []Payment) (map[solana.PublicKey]bool, error) {
+	var accounts []solana.PublicKey
+	seen := make(map[solana.PublicKey]bool)
+	for _, p := range payments {
+		if p.IsNative() {
+			continue
+		}
+		ata, _, err := solana.FindAssociatedTokenAddress(p.Recipient, p.Mint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive token account: %w", err)
+		}
+		if !seen[ata] {
+			seen[ata] = true
+			accounts = append(accounts, ata)
+		}
+	}
+
+	missing := make(map[solana.PublicKey]bool)
+	for start := 0; start < len(accounts); start += maxAccountsPerRequest {
+		end := start + maxAccountsPerRequest
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+		out, err := c.client.GetMultipleAccounts(ctx, accounts[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token accounts: %w", err)
+		}
+		for i, acc := range out.Value {
+			if acc == nil {
+				missing[accounts[start+i]] = true
+			}
+		}
+	}
+	return missing, nil
+}
+
+// paymentInstructions returns the instructions for one payment. The first
+// payment to a missing token account creates it and removes it from missing.
+func paymentInstructions(payer solana.PublicKey, p Payment, missing map[solana.PublicKey]bool) ([]solana.Instruction, bool, error) {
+	if p.IsNative() {
+		return []solana.Instruction{
+			system.NewTransferInstruction(p.BaseUnits(), payer, p.Recipient).Build(),
+		}, false, nil
+	}
+
+	source, _, err := solana.FindAssociatedTokenAddress(payer, p.Mint)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to derive token account: %w", err)
+	}
+	dest, _, err := solana.FindAssociatedTokenAddress(p.Recipient, p.Mint)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to derive token account: %w", err)
+	}
+
+	var instrs []solana.Instruction
+	created := missing[dest]
+	if created {
+		instrs = append(instrs, associatedtokenaccount.NewCreateInstruction(payer, p.Recipient, p.Mint).Build())
+		delete(missing, dest)
+	}
+	instrs = append(instrs, token.NewTransferCheckedInstruction(
+		p.BaseUnits(), p.Decimals, source, p.Mint, dest, payer, nil,
+	).Build())
+	return instrs, created, nil
+}
+
+func newTransaction(instrs []solana.Instruction, blockhash solana.Hash, payer solana.PublicKey) (*solana.Transaction, error) {
+	tx, err := solana.NewTransaction(instrs, blockhash, solana.TransactionPayer(payer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// transactionSize returns the serialized size of the signed transaction
+// without signing it.
+func transactionSize(instrs []solana.Instruction, blockhash solana.Hash, payer solana.PublicKey) (int, error) {
+	tx, err := newTransaction(instrs, blockhash, payer)
+	if err != nil {
+		return 0, err
+	}
+	msg, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	signatures := int(tx.Message.Header.NumRequiredSignatures)
+	return compactLen(signatures) + signatures*solana.SignatureLength + len(msg), nil
+}
+
+// compactLen is the length of n encoded as a compact-u16.
+func compactLen(n int) int {
+	switch {
+	case n < 0x80:
+		return 1
+	case n < 0x4000:
+		return 2
+	default:
+		return 3
+	}
+}
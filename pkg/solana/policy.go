@@ -0,0 +1,324 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// ErrUpstreamUnavailable is returned without calling the provider while the
+// circuit breaker is open, and when a call still failed after its retries.
+var ErrUpstreamUnavailable = errors.New("solana rpc unavailable")
+
+// ErrSendUnconfirmed marks a failed send that may still have reached the
+// cluster, such as a timeout after the request was written. The transaction
+// can land until its blockhash expires, so its outcome has to be looked up by
+// signature before the funds are released.
+var ErrSendUnconfirmed = errors.New("transaction may have been sent")
+
+const methodSendTransaction = "sendTransaction"
+
+// rateLimitedCode is the JSON-RPC error code providers use for HTTP 429.
+const rateLimitedCode = 429
+
+// CallPolicy configures the timeout and retries of one JSON-RPC method.
+type CallPolicy struct {
+	// Timeout bounds each attempt. Zero leaves only the caller's deadline.
+	Timeout time.Duration
+	// Retries is how many times a failed attempt is repeated.
+	Retries int
+	// Backoff is the delay before the first retry. It doubles with every
+	// retry up to MaxBackoff, and the actual delay is drawn at random below
+	// it so that clients do not retry in lockstep.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Policy configures how a Client calls its provider.
+type Policy struct {
+	Default CallPolicy
+	// Methods overrides Default per JSON-RPC method.
+	Methods map[string]CallPolicy
+	// BreakerThreshold is how many calls in a row must fail against the
+	// provider before the breaker opens. Zero disables the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before a single
+	// trial call is let through.
+	BreakerCooldown time.Duration
+}
+
+// DefaultPolicy returns the policy used by NewClient.
+func DefaultPolicy() Policy {
+	return Policy{
+		Default: CallPolicy{Timeout: 10 * time.Second, Retries: 2, Backoff: 200 * time.Millisecond, MaxBackoff: 2 * time.Second},
+		Methods: map[string]CallPolicy{
+			methodSendTransaction: {Timeout: 20 * time.Second, Retries: 2, Backoff: 200 * time.Millisecond, MaxBackoff: 2 * time.Second},
+		},
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+func (p Policy) forMethod(method string) CallPolicy {
+	if mp, ok := p.Methods[method]; ok {
+		return mp
+	}
+	return p.Default
+}
+
+// BreakerStatus is a snapshot of the circuit breaker.
+type BreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// guardedClient applies a Policy around another JSON-RPC client.
+type guardedClient struct {
+	next    rpc.JSONRPCClient
+	policy  Policy
+	breaker *breaker
+}
+
+func newGuardedClient(next rpc.JSONRPCClient, policy Policy) *guardedClient {
+	return &guardedClient{
+		next:    next,
+		policy:  policy,
+		breaker: &breaker{threshold: policy.BreakerThreshold, cooldown: policy.BreakerCooldown},
+	}
+}
+
+// CallForInto implements rpc.JSONRPCClient.
+func (g *guardedClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	return g.do(ctx, method, method == methodSendTransaction, func(ctx context.Context) error {
+		return g.next.CallForInto(ctx, out, method, params)
+	})
+}
+
+// CallWithCallback implements rpc.JSONRPCClient.
+func (g *guardedClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return g.do(ctx, method, method == methodSendTransaction, func(ctx context.Context) error {
+		return g.next.CallWithCallback(ctx, method, params, callback)
+	})
+}
+
+// CallBatch implements rpc.JSONRPCClient. A batch uses the default policy and
+// is treated as a send when any of its requests is one.
+func (g *guardedClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	send := false
+	for _, r := range requests {
+		send = send || r.Method == methodSendTransaction
+	}
+	var responses jsonrpc.RPCResponses
+	err := g.do(ctx, "", send, func(ctx context.Context) error {
+		var err error
+		responses, err = g.next.CallBatch(ctx, requests)
+		return err
+	})
+	return responses, err
+}
+
+// do runs fn under the method's policy. Reads are retried on transient
+// errors. Sends are retried only when the error proves the transaction never
+// reached the cluster, otherwise the error is marked ErrSendUnconfirmed.
+func (g *guardedClient) do(ctx context.Context, method string, send bool, fn func(ctx context.Context) error) error {
+	p := g.policy.forMethod(method)
+	for attempt := 0; ; attempt++ {
+		if !g.breaker.allow() {
+			return fmt.Errorf("%w: circuit breaker open", ErrUpstreamUnavailable)
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if p.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, p.Timeout)
+		}
+		err := fn(attemptCtx)
+		cancel()
+
+		switch {
+		case ctx.Err() != nil:
+			// Cancelled by the caller, which says nothing about the provider.
+			g.breaker.release()
+		case err != nil && transient(err):
+			g.breaker.failure()
+		default:
+			g.breaker.success()
+		}
+
+		if err == nil {
+			return nil
+		}
+		if send && !notSent(err) {
+			return fmt.Errorf("%w: %w", ErrSendUnconfirmed, err)
+		}
+		if ctx.Err() != nil || !transient(err) {
+			return err
+		}
+		if attempt >= p.Retries {
+			return fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the jittered delay before retry number attempt+1.
+func (p CallPolicy) backoff(attempt int) time.Duration {
+	if p.Backoff <= 0 {
+		return 0
+	}
+	d := p.Backoff << attempt
+	if p.MaxBackoff > 0 && (d > p.MaxBackoff || d <= 0) {
+		d = p.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// transient reports whether err is the provider's fault, such that the same
+// call may succeed later. Errors from a Pool that tried several endpoints are
+// transient when any endpoint failed transiently.
+func transient(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if transient(e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code == rateLimitedCode || nodeErrorCodes[rpcErr.Code]
+	}
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusTooManyRequests || httpErr.Code >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// notSent reports whether a failed send certainly never reached the cluster:
+// the node answered with an error, throttled the request, or could not be
+// connected to at all. Errors from a Pool qualify only when every endpoint's
+// error does.
+func notSent(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !notSent(e) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if errors.Is(err, ErrUpstreamUnavailable) {
+		return true
+	}
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return true
+	}
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusTooManyRequests
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// breaker opens after threshold consecutive failures and fails calls fast
+// until cooldown has passed. Then one trial call is let through: its success
+// closes the breaker, its failure opens it again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// release ends a call whose outcome is unknown.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *breaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := BreakerStatus{State: BreakerClosed, ConsecutiveFailures: b.failures}
+	if b.threshold > 0 && b.failures >= b.threshold {
+		s.State = BreakerHalfOpen
+		if time.Now().Before(b.openUntil) {
+			s.State = BreakerOpen
+			until := b.openUntil
+			s.OpenUntil = &until
+		}
+	}
+	return s
+}
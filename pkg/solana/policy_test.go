@@ -0,0 +1,223 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mywallet/pkg/logger"
+	"mywallet/pkg/solana/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// faultyServer proxies to a simulator and lets each test decide, per
+// JSON-RPC method, whether to answer with an error instead.
+type faultyServer struct {
+	*httptest.Server
+	calls map[string]*int32
+	// fault returns the status to fail the n-th call (1-based) of method
+	// with, or 0 to serve it. forwardFirst serves the call before failing.
+	fault        func(method string, n int32) int
+	forwardFirst bool
+}
+
+func newFaultyServer(sim *simulator.Server, fault func(method string, n int32) int) *faultyServer {
+	f := &faultyServer{calls: map[string]*int32{}, fault: fault}
+	for _, m := range []string{"getBalance", "getLatestBlockhash", "sendTransaction", "getSignatureStatuses"} {
+		f.calls[m] = new(int32)
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Method string `json:"method"`
+		}
+		_ = json.Unmarshal(body, &req)
+		n := int32(0)
+		if counter, ok := f.calls[req.Method]; ok {
+			n = atomic.AddInt32(counter, 1)
+		}
+
+		status := f.fault(req.Method, n)
+		if status == 0 || f.forwardFirst {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+			sim.Simulator.ServeHTTP(rec, r)
+			if status == 0 {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write(rec.Body.Bytes())
+				return
+			}
+		}
+		w.WriteHeader(status)
+	}))
+	return f
+}
+
+func (f *faultyServer) count(method string) int32 {
+	return atomic.LoadInt32(f.calls[method])
+}
+
+func testPolicy() Policy {
+	return Policy{
+		Default:          CallPolicy{Timeout: time.Second, Retries: 2, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond},
+		BreakerThreshold: 3,
+		BreakerCooldown:  100 * time.Millisecond,
+	}
+}
+
+func TestPolicyRetriesReads(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	server := newFaultyServer(sim, func(method string, n int32) int {
+		if method == "getBalance" && n <= 2 {
+			return http.StatusBadGateway
+		}
+		return 0
+	})
+	defer server.Close()
+
+	client := NewClientWithPolicy(server.URL, testPolicy(), logger.NewLogger())
+	account := solana.NewWallet().PublicKey()
+	sim.Airdrop(account, solana.LAMPORTS_PER_SOL)
+
+	balance, err := client.GetBalance(context.Background(), account.String())
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1).Equal(balance))
+	assert.Equal(t, int32(3), server.count("getBalance"))
+	assert.Equal(t, BreakerClosed, client.Breaker().State)
+}
+
+func TestPolicyTimesOutAttempts(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	policy := testPolicy()
+	policy.Default.Timeout = 20 * time.Millisecond
+	policy.Default.Retries = 1
+	client := NewClientWithPolicy(slow.URL, policy, logger.NewLogger())
+
+	start := time.Now()
+	_, err := client.GetBalance(context.Background(), solana.NewWallet().PublicKey().String())
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestPolicyRetriesThrottledSends(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	server := newFaultyServer(sim, func(method string, n int32) int {
+		if method == "sendTransaction" && n == 1 {
+			return http.StatusTooManyRequests
+		}
+		return 0
+	})
+	defer server.Close()
+
+	client := NewClientWithPolicy(server.URL, testPolicy(), logger.NewLogger())
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	_, err := client.Transfer(context.Background(), from.PrivateKey, to, decimal.NewFromFloat(0.5))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), server.count("sendTransaction"))
+	assert.Equal(t, solana.LAMPORTS_PER_SOL/2, sim.Balance(to))
+}
+
+func TestPolicyDoesNotRetryUnconfirmedSends(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	// The gateway fails after the node accepted the transaction.
+	server := newFaultyServer(sim, func(method string, n int32) int {
+		if method == "sendTransaction" {
+			return http.StatusBadGateway
+		}
+		return 0
+	})
+	server.forwardFirst = true
+	defer server.Close()
+
+	client := NewClientWithPolicy(server.URL, testPolicy(), logger.NewLogger())
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	signature, err := client.Transfer(ctx, from.PrivateKey, to, decimal.NewFromFloat(0.5))
+	require.ErrorIs(t, err, ErrSendUnconfirmed)
+	require.NotEmpty(t, signature)
+	assert.Equal(t, int32(1), server.count("sendTransaction"))
+
+	landed, err := client.WaitForSignature(ctx, signature, time.Second)
+	require.NoError(t, err)
+	assert.True(t, landed)
+	assert.Equal(t, solana.LAMPORTS_PER_SOL/2, sim.Balance(to))
+}
+
+func TestPolicyReturnsRejectionsAsIs(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	client := NewClientWithPolicy(sim.URL, testPolicy(), logger.NewLogger())
+
+	from := solana.NewWallet()
+	_, err := client.Transfer(context.Background(), from.PrivateKey, solana.NewWallet().PublicKey(), decimal.NewFromInt(1))
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrSendUnconfirmed))
+	assert.False(t, errors.Is(err, ErrUpstreamUnavailable))
+	assert.Zero(t, client.Breaker().ConsecutiveFailures)
+}
+
+func TestPolicyCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	var healthy atomic.Bool
+	server := newFaultyServer(sim, func(method string, n int32) int {
+		if healthy.Load() {
+			return 0
+		}
+		return http.StatusServiceUnavailable
+	})
+	defer server.Close()
+
+	policy := testPolicy()
+	policy.Default.Retries = 0
+	client := NewClientWithPolicy(server.URL, policy, logger.NewLogger())
+	account := solana.NewWallet().PublicKey().String()
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetBalance(ctx, account)
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	}
+	assert.Equal(t, BreakerOpen, client.Breaker().State)
+
+	// Open: calls fail fast without reaching the provider.
+	_, err := client.GetBalance(ctx, account)
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	assert.Equal(t, int32(3), server.count("getBalance"))
+
+	// After the cooldown a trial call goes through and closes the breaker.
+	healthy.Store(true)
+	time.Sleep(policy.BreakerCooldown)
+	assert.Equal(t, BreakerHalfOpen, client.Breaker().State)
+	_, err = client.GetBalance(ctx, account)
+	require.NoError(t, err)
+	assert.Equal(t, BreakerClosed, client.Breaker().State)
+}
@@ -0,0 +1,463 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// ewmaWeight is the weight of the newest sample in the latency and error
+	// rate averages.
+	ewmaWeight = 0.2
+
+	// defaultLatency is assumed for endpoints that have not answered yet.
+	defaultLatency = 200 * time.Millisecond
+
+	// errorPenalty and slotLagPenalty are added to an endpoint's score, in
+	// milliseconds, per unit of error rate and per slot behind.
+	errorPenalty   = 1000
+	slotLagPenalty = 10
+
+	// laggingPenalty keeps endpoints beyond MaxSlotLag behind every healthy one.
+	laggingPenalty = 1e6
+
+	// broadcastTimeout bounds the sends still running after the first
+	// endpoint accepted a transaction.
+	broadcastTimeout = 30 * time.Second
+
+	defaultSendFanout = 3
+	defaultMaxSlotLag = 50
+)
+
+// nodeErrorCodes are JSON-RPC errors that describe the node rather than the
+// request, so the call is worth retrying on another endpoint.
+var nodeErrorCodes = map[int]bool{
+	-32004: true, // block not available for slot
+	-32005: true, // node is behind
+	-32016: true, // minimum context slot has not been reached
+}
+
+// Endpoint configures one RPC provider of a Pool.
+type Endpoint struct {
+	URL string
+	// RateLimit caps the requests per second sent to the endpoint. Zero
+	// means unlimited.
+	RateLimit float64
+	// Burst is how many requests may be sent at once. Defaults to
+	// RateLimit rounded up.
+	Burst int
+}
+
+// PoolOptions tunes how a Pool routes requests.
+type PoolOptions struct {
+	// HedgeDelay is how long a read waits for the best endpoint before the
+	// same request also goes to the next one. Zero disables hedging.
+	HedgeDelay time.Duration
+	// SendFanout is how many endpoints each transaction is sent to.
+	SendFanout int
+	// MaxSlotLag is how many slots an endpoint may trail the highest slot in
+	// the pool before it is only used as a last resort.
+	MaxSlotLag uint64
+}
+
+// EndpointStats is a snapshot of an endpoint's health. Lower scores are
+// preferred.
+type EndpointStats struct {
+	URL       string  `json:"url"`
+	LatencyMS float64 `json:"latency_ms"`
+	ErrorRate float64 `json:"error_rate"`
+	Slot      uint64  `json:"slot"`
+	SlotLag   uint64  `json:"slot_lag"`
+	Requests  uint64  `json:"requests"`
+	Failures  uint64  `json:"failures"`
+	Score     float64 `json:"score"`
+}
+
+// Pool spreads JSON-RPC calls over several endpoints and implements
+// rpc.JSONRPCClient. Reads go to the best scored endpoint, failing over to
+// the next on errors and hedging when it is slow; transactions are sent to
+// several endpoints at once.
+type Pool struct {
+	endpoints []*endpoint
+	opts      PoolOptions
+}
+
+type endpoint struct {
+	// name is the URL without path and query, which often carry API keys.
+	name    string
+	client  rpc.JSONRPCClient
+	limiter *rate.Limiter
+
+	mu        sync.Mutex
+	latency   time.Duration
+	errorRate float64
+	slot      uint64
+	requests  uint64
+	failures  uint64
+}
+
+// attempt is the outcome of one call on one endpoint.
+type attempt struct {
+	endpoint *endpoint
+	result   json.RawMessage
+	err      error
+}
+
+// NewPool creates a pool over the given endpoints.
+func NewPool(endpoints []Endpoint, opts PoolOptions) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no rpc endpoints")
+	}
+	if opts.SendFanout <= 0 {
+		opts.SendFanout = defaultSendFanout
+	}
+	if opts.MaxSlotLag == 0 {
+		opts.MaxSlotLag = defaultMaxSlotLag
+	}
+
+	p := &Pool{opts: opts}
+	for _, e := range endpoints {
+		u, err := url.Parse(e.URL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid rpc endpoint %q", e.URL)
+		}
+		if e.RateLimit < 0 {
+			return nil, fmt.Errorf("rpc endpoint %s: rate limit must not be negative", u.Host)
+		}
+
+		ep := &endpoint{
+			name:   u.Scheme + "://" + u.Host,
+			client: jsonrpc.NewClient(e.URL),
+		}
+		if e.RateLimit > 0 {
+			burst := e.Burst
+			if burst <= 0 {
+				burst = int(math.Ceil(e.RateLimit))
+			}
+			ep.limiter = rate.NewLimiter(rate.Limit(e.RateLimit), burst)
+		}
+		p.endpoints = append(p.endpoints, ep)
+	}
+	return p, nil
+}
+
+// CallForInto implements rpc.JSONRPCClient.
+func (p *Pool) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	call := func(ctx context.Context, e *endpoint) (json.RawMessage, error) {
+		var raw json.RawMessage
+		err := e.client.CallForInto(ctx, &raw, method, params)
+		return raw, err
+	}
+
+	var (
+		result json.RawMessage
+		err    error
+	)
+	if method == "sendTransaction" {
+		result, err = p.broadcast(ctx, call)
+	} else {
+		result, err = p.call(ctx, true, call)
+	}
+	if err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		result = json.RawMessage("null")
+	}
+	return json.Unmarshal(result, out)
+}
+
+// CallWithCallback implements rpc.JSONRPCClient. The call fails over but is
+// never hedged, as the callback may have side effects.
+func (p *Pool) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	_, err := p.call(ctx, false, func(ctx context.Context, e *endpoint) (json.RawMessage, error) {
+		return nil, e.client.CallWithCallback(ctx, method, params, callback)
+	})
+	return err
+}
+
+// CallBatch implements rpc.JSONRPCClient. The batch fails over but is never
+// hedged.
+func (p *Pool) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	var responses jsonrpc.RPCResponses
+	_, err := p.call(ctx, false, func(ctx context.Context, e *endpoint) (json.RawMessage, error) {
+		r, err := e.client.CallBatch(ctx, requests)
+		if err == nil {
+			responses = r
+		}
+		return nil, err
+	})
+	return responses, err
+}
+
+// call runs fn on the endpoints in order of score until one succeeds or
+// returns an error that another endpoint would repeat. With hedge set, the
+// next endpoint is also tried when no answer arrived within HedgeDelay, and
+// the first answer wins.
+func (p *Pool) call(ctx context.Context, hedge bool, fn func(ctx context.Context, e *endpoint) (json.RawMessage, error)) (json.RawMessage, error) {
+	ranked := p.ranked()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	attempts := make(chan attempt, len(ranked))
+	next, inflight := 0, 0
+	start := func(e *endpoint) {
+		inflight++
+		go func() {
+			result, err := e.do(ctx, fn)
+			attempts <- attempt{endpoint: e, result: result, err: err}
+		}()
+	}
+	// launch starts the next endpoint with rate limit budget left.
+	launch := func() {
+		for next < len(ranked) {
+			e := ranked[next]
+			next++
+			if e.allow() {
+				start(e)
+				return
+			}
+		}
+	}
+
+	launch()
+	if inflight == 0 {
+		// Every endpoint is at its rate limit, queue on the best one.
+		if err := ranked[0].limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		start(ranked[0])
+	}
+
+	var hedgeC <-chan time.Time
+	if hedge && p.opts.HedgeDelay > 0 {
+		timer := time.NewTimer(p.opts.HedgeDelay)
+		defer timer.Stop()
+		hedgeC = timer.C
+	}
+
+	var errs []error
+	for inflight > 0 {
+		select {
+		case a := <-attempts:
+			inflight--
+			if a.err == nil || !retryable(a.err) || ctx.Err() != nil {
+				return a.result, a.err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", a.endpoint.name, a.err))
+			launch()
+		case <-hedgeC:
+			hedgeC = nil
+			launch()
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// broadcast sends a transaction to the SendFanout best endpoints at once and
+// returns the first acceptance. The other sends finish in the background, as
+// every extra copy improves the chance the transaction lands.
+func (p *Pool) broadcast(ctx context.Context, fn func(ctx context.Context, e *endpoint) (json.RawMessage, error)) (json.RawMessage, error) {
+	ranked := p.ranked()
+	var targets []*endpoint
+	for _, e := range ranked {
+		if len(targets) == p.opts.SendFanout {
+			break
+		}
+		if e.allow() {
+			targets = append(targets, e)
+		}
+	}
+	if len(targets) == 0 {
+		if err := ranked[0].limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		targets = ranked[:1]
+	}
+
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), broadcastTimeout)
+	attempts := make(chan attempt, len(targets))
+	var wg sync.WaitGroup
+	for _, e := range targets {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			result, err := e.do(sendCtx, fn)
+			attempts <- attempt{endpoint: e, result: result, err: err}
+		}(e)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+	}()
+
+	var (
+		errs     []error
+		rejected error
+	)
+	for range targets {
+		select {
+		case a := <-attempts:
+			if a.err == nil {
+				return a.result, nil
+			}
+			if !retryable(a.err) && rejected == nil {
+				rejected = a.err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", a.endpoint.name, a.err))
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	// A rejection such as a failed preflight is what every endpoint would say.
+	if rejected != nil {
+		return nil, rejected
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Probe asks every endpoint for its latest processed slot, which routing
+// uses to avoid endpoints that fell behind. Endpoints at their rate limit
+// are skipped.
+func (p *Pool) Probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		if !e.allow() {
+			continue
+		}
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			var slot uint64
+			_, err := e.do(ctx, func(ctx context.Context, e *endpoint) (json.RawMessage, error) {
+				return nil, e.client.CallForInto(ctx, &slot, "getSlot",
+					[]interface{}{map[string]string{"commitment": string(rpc.CommitmentProcessed)}})
+			})
+			if err == nil {
+				e.mu.Lock()
+				e.slot = slot
+				e.mu.Unlock()
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+// Stats returns the health of every endpoint, best first.
+func (p *Pool) Stats() []EndpointStats {
+	highest := p.highestSlot()
+	stats := make([]EndpointStats, len(p.endpoints))
+	for i, e := range p.endpoints {
+		stats[i] = e.stats(highest, p.opts.MaxSlotLag)
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Score < stats[j].Score })
+	return stats
+}
+
+// ranked returns the endpoints ordered by score, best first.
+func (p *Pool) ranked() []*endpoint {
+	highest := p.highestSlot()
+	scores := make(map[*endpoint]float64, len(p.endpoints))
+	ranked := make([]*endpoint, len(p.endpoints))
+	for i, e := range p.endpoints {
+		scores[e] = e.stats(highest, p.opts.MaxSlotLag).Score
+		ranked[i] = e
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i]] < scores[ranked[j]] })
+	return ranked
+}
+
+func (p *Pool) highestSlot() uint64 {
+	var highest uint64
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		if e.slot > highest {
+			highest = e.slot
+		}
+		e.mu.Unlock()
+	}
+	return highest
+}
+
+// do runs fn and records its latency and outcome. Calls cancelled by the
+// caller, or by a faster hedged attempt, say nothing about the endpoint.
+func (e *endpoint) do(ctx context.Context, fn func(ctx context.Context, e *endpoint) (json.RawMessage, error)) (json.RawMessage, error) {
+	start := time.Now()
+	result, err := fn(ctx, e)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return result, err
+	}
+	e.record(time.Since(start), err)
+	return result, err
+}
+
+func (e *endpoint) record(elapsed time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests++
+	failure := 0.0
+	if err != nil && retryable(err) {
+		e.failures++
+		failure = 1
+	} else if e.latency == 0 {
+		e.latency = elapsed
+	} else {
+		e.latency = time.Duration((1-ewmaWeight)*float64(e.latency) + ewmaWeight*float64(elapsed))
+	}
+	e.errorRate = (1-ewmaWeight)*e.errorRate + ewmaWeight*failure
+}
+
+// stats scores the endpoint by its average latency in milliseconds plus
+// penalties for its error rate and for each slot it trails highest.
+func (e *endpoint) stats(highest, maxLag uint64) EndpointStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	latency := e.latency
+	if latency == 0 {
+		latency = defaultLatency
+	}
+	s := EndpointStats{
+		URL:       e.name,
+		LatencyMS: float64(latency) / float64(time.Millisecond),
+		ErrorRate: e.errorRate,
+		Slot:      e.slot,
+		Requests:  e.requests,
+		Failures:  e.failures,
+	}
+	if e.slot > 0 && highest > e.slot {
+		s.SlotLag = highest - e.slot
+	}
+	s.Score = s.LatencyMS + errorPenalty*s.ErrorRate + slotLagPenalty*float64(s.SlotLag)
+	if s.SlotLag > maxLag {
+		s.Score += laggingPenalty
+	}
+	return s
+}
+
+func (e *endpoint) allow() bool {
+	return e.limiter == nil || e.limiter.Allow()
+}
+
+// retryable reports whether another endpoint may answer differently. JSON-RPC
+// errors are answers to the request itself, except those about node health.
+func retryable(err error) bool {
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return nodeErrorCodes[rpcErr.Code]
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package solana
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mywallet/pkg/logger"
+	"mywallet/pkg/solana/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func statsByURL(p *Pool) map[string]EndpointStats {
+	out := make(map[string]EndpointStats)
+	for _, s := range p.Stats() {
+		out[s.URL] = s
+	}
+	return out
+}
+
+func TestPoolFailsOver(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	pool, err := NewPool([]Endpoint{{URL: dead.URL}, {URL: sim.URL}}, PoolOptions{})
+	require.NoError(t, err)
+	client := NewPoolClient(pool, DefaultPolicy(), logger.NewLogger())
+
+	account := solana.NewWallet().PublicKey()
+	sim.Airdrop(account, solana.LAMPORTS_PER_SOL)
+	balance, err := client.GetBalance(ctx, account.String())
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1).Equal(balance))
+
+	stats := pool.Stats()
+	assert.Equal(t, sim.URL, stats[0].URL, "the failed endpoint is ranked last")
+	assert.Equal(t, uint64(1), statsByURL(pool)[dead.URL].Failures)
+}
+
+func TestPoolHedgesSlowReads(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+		sim.Simulator.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	pool, err := NewPool([]Endpoint{{URL: slow.URL}, {URL: sim.URL}}, PoolOptions{HedgeDelay: 50 * time.Millisecond})
+	require.NoError(t, err)
+	client := NewPoolClient(pool, DefaultPolicy(), logger.NewLogger())
+
+	start := time.Now()
+	_, err = client.GetBalance(ctx, solana.NewWallet().PublicKey().String())
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestPoolBroadcastsSends(t *testing.T) {
+	ctx := context.Background()
+	a := simulator.NewServer()
+	defer a.Close()
+	b := simulator.NewServer()
+	defer b.Close()
+
+	pool, err := NewPool([]Endpoint{{URL: a.URL}, {URL: b.URL}}, PoolOptions{SendFanout: 2})
+	require.NoError(t, err)
+	client := NewPoolClient(pool, DefaultPolicy(), logger.NewLogger())
+
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	a.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+	b.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	_, err = client.Transfer(ctx, from.PrivateKey, to, decimal.NewFromFloat(0.5))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return a.Balance(to) == solana.LAMPORTS_PER_SOL/2 && b.Balance(to) == solana.LAMPORTS_PER_SOL/2
+	}, time.Second, 10*time.Millisecond)
+
+	// A failed preflight is the request's fault and is returned as is.
+	_, err = client.Transfer(ctx, from.PrivateKey, to, decimal.NewFromInt(5))
+	assert.Error(t, err)
+	for _, s := range pool.Stats() {
+		assert.Zero(t, s.Failures)
+	}
+}
+
+func TestPoolRespectsRateLimits(t *testing.T) {
+	ctx := context.Background()
+	limited := simulator.NewServer()
+	defer limited.Close()
+	open := simulator.NewServer()
+	defer open.Close()
+
+	pool, err := NewPool([]Endpoint{{URL: limited.URL, RateLimit: 0.001, Burst: 1}, {URL: open.URL}}, PoolOptions{})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		var slot uint64
+		require.NoError(t, pool.CallForInto(ctx, &slot, "getSlot", nil))
+	}
+	stats := statsByURL(pool)
+	assert.Equal(t, uint64(1), stats[limited.URL].Requests)
+	assert.Equal(t, uint64(2), stats[open.URL].Requests)
+}
@@ -0,0 +1,155 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	// ErrKeyNotFound is returned by a Signer that holds no key with the given ID.
+	ErrKeyNotFound = errors.New("signing key not found")
+	// ErrKeyExists is returned by a KeyGenerator asked to reuse a key ID.
+	ErrKeyExists = errors.New("signing key already exists")
+)
+
+// Signer signs messages with keys it holds, addressed by key ID. Private key
+// material stays with the signer; callers only see public keys and
+// signatures.
+type Signer interface {
+	// PublicKey returns the public key of a key.
+	PublicKey(ctx context.Context, keyID string) (solana.PublicKey, error)
+	// Sign signs message, a serialized transaction message, with a key.
+	Sign(ctx context.Context, keyID string, message []byte) (solana.Signature, error)
+}
+
+// KeyGenerator is implemented by signers that can create keys, so that new
+// wallets need not be created outside the signer and imported.
+type KeyGenerator interface {
+	// GenerateKey creates a new key with the given ID.
+	GenerateKey(ctx context.Context, keyID string) (solana.PublicKey, error)
+}
+
+// Key is a key held by a Signer. PublicKey is known up front so transactions
+// can be built without asking the signer.
+type Key struct {
+	ID        string
+	PublicKey solana.PublicKey
+	Signer    Signer
+}
+
+// LocalKey wraps a private key held in memory, such as one supplied with a
+// request.
+func LocalKey(key solana.PrivateKey) Key {
+	pub := key.PublicKey()
+	return Key{ID: pub.String(), PublicKey: pub, Signer: NewLocalSigner(key)}
+}
+
+// LocalSigner holds private keys in memory, addressed by their base58
+// public key.
+type LocalSigner struct {
+	mu   sync.RWMutex
+	keys map[string]solana.PrivateKey
+}
+
+func NewLocalSigner(keys ...solana.PrivateKey) *LocalSigner {
+	s := &LocalSigner{keys: make(map[string]solana.PrivateKey)}
+	for _, key := range keys {
+		s.Add(key.PublicKey().String(), key)
+	}
+	return s
+}
+
+// Add stores key under keyID.
+func (s *LocalSigner) Add(keyID string, key solana.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[keyID] = key
+}
+
+func (s *LocalSigner) GenerateKey(_ context.Context, keyID string) (solana.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[keyID]; ok {
+		return solana.PublicKey{}, fmt.Errorf("%w: %s", ErrKeyExists, keyID)
+	}
+	key := solana.NewWallet().PrivateKey
+	s.keys[keyID] = key
+	return key.PublicKey(), nil
+}
+
+// KeyIDs returns the IDs of the stored keys.
+func (s *LocalSigner) KeyIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (s *LocalSigner) key(keyID string) (solana.PrivateKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return key, nil
+}
+
+func (s *LocalSigner) PublicKey(_ context.Context, keyID string) (solana.PublicKey, error) {
+	key, err := s.key(keyID)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	return key.PublicKey(), nil
+}
+
+func (s *LocalSigner) Sign(_ context.Context, keyID string, message []byte) (solana.Signature, error) {
+	key, err := s.key(keyID)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	return key.Sign(message)
+}
+
+// SignTransaction signs tx with keys, which must cover every required signer
+// of tx. Each signature is verified, so a signer that answers with the wrong
+// key is caught before the transaction is sent.
+func SignTransaction(ctx context.Context, tx *solana.Transaction, keys ...Key) error {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	required := int(tx.Message.Header.NumRequiredSignatures)
+	if len(tx.Signatures) != required {
+		tx.Signatures = make([]solana.Signature, required)
+	}
+
+	for i, pub := range tx.Message.AccountKeys[:required] {
+		var key *Key
+		for j := range keys {
+			if keys[j].PublicKey.Equals(pub) {
+				key = &keys[j]
+				break
+			}
+		}
+		if key == nil {
+			return fmt.Errorf("no key for signer %s", pub)
+		}
+		sig, err := key.Signer.Sign(ctx, key.ID, message)
+		if err != nil {
+			return fmt.Errorf("failed to sign with %s: %w", key.ID, err)
+		}
+		if !sig.Verify(pub, message) {
+			return fmt.Errorf("signer returned an invalid signature for %s", key.ID)
+		}
+		tx.Signatures[i] = sig
+	}
+	return nil
+}
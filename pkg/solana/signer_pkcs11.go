@@ -0,0 +1,534 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// The PKCS#11-style signer talks to a network HSM that exposes the PKCS#11
+// call sequence as JSON-RPC 2.0 over HTTP. Calls and their params follow
+// PKCS#11 (C_OpenSession, C_Login, C_FindObjects*, C_GetAttributeValue,
+// C_GenerateKeyPair, C_SignInit, C_Sign) with handles as integers and byte strings as base64.
+// Failed calls return the CKR_* name as error message and its value as error
+// code. Ed25519 keys are found by CKA_LABEL, the key ID, and used with
+// CKM_EDDSA; the public key is the CKA_EC_POINT of the public key object with
+// the same label.
+
+// PKCS#11 return values used by the signer and the stand-in token.
+const (
+	ckrGeneralError            = 0x05
+	ckrArgumentsBad            = 0x07
+	ckrMechanismInvalid        = 0x70
+	ckrObjectHandleInvalid     = 0x82
+	ckrOperationActive         = 0x90
+	ckrOperationNotInitialized = 0x91
+	ckrPinIncorrect            = 0xA0
+	ckrSessionClosed           = 0xB0
+	ckrSessionHandleInvalid    = 0xB3
+	ckrSlotIDInvalid           = 0x03
+	ckrUserNotLoggedIn         = 0x101
+	ckrFunctionNotSupported    = 0x54
+)
+
+var ckrNames = map[int]string{
+	ckrGeneralError:            "CKR_GENERAL_ERROR",
+	ckrArgumentsBad:            "CKR_ARGUMENTS_BAD",
+	ckrMechanismInvalid:        "CKR_MECHANISM_INVALID",
+	ckrObjectHandleInvalid:     "CKR_OBJECT_HANDLE_INVALID",
+	ckrOperationActive:         "CKR_OPERATION_ACTIVE",
+	ckrOperationNotInitialized: "CKR_OPERATION_NOT_INITIALIZED",
+	ckrPinIncorrect:            "CKR_PIN_INCORRECT",
+	ckrSessionClosed:           "CKR_SESSION_CLOSED",
+	ckrSessionHandleInvalid:    "CKR_SESSION_HANDLE_INVALID",
+	ckrSlotIDInvalid:           "CKR_SLOT_ID_INVALID",
+	ckrUserNotLoggedIn:         "CKR_USER_NOT_LOGGED_IN",
+	ckrFunctionNotSupported:    "CKR_FUNCTION_NOT_SUPPORTED",
+}
+
+// PKCS11Error is a PKCS#11 error returned by the token.
+type PKCS11Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *PKCS11Error) Error() string {
+	return fmt.Sprintf("pkcs11: %s (0x%x)", e.Message, e.Code)
+}
+
+func ckr(code int) *PKCS11Error {
+	return &PKCS11Error{Code: code, Message: ckrNames[code]}
+}
+
+type pkcs11Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type pkcs11Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *PKCS11Error    `json:"error,omitempty"`
+}
+
+type pkcs11Params struct {
+	Slot      uint64            `json:"slot,omitempty"`
+	Session   uint64            `json:"session,omitempty"`
+	UserType  string            `json:"user_type,omitempty"`
+	Pin       string            `json:"pin,omitempty"`
+	Template  map[string]string `json:"template,omitempty"`
+	Max       int               `json:"max,omitempty"`
+	Object    uint64            `json:"object,omitempty"`
+	Attribute string            `json:"attribute,omitempty"`
+	Mechanism string            `json:"mechanism,omitempty"`
+	Key       uint64            `json:"key,omitempty"`
+	Data      string            `json:"data,omitempty"`
+}
+
+type pkcs11Result struct {
+	Session   uint64   `json:"session,omitempty"`
+	Objects   []uint64 `json:"objects,omitempty"`
+	Value     string   `json:"value,omitempty"`
+	Signature string   `json:"signature,omitempty"`
+}
+
+type pkcs11Key struct {
+	handle uint64
+	pub    solana.PublicKey
+}
+
+// PKCS11Signer is a Signer and KeyGenerator backed by a network HSM speaking
+// the PKCS#11-style protocol. It keeps one logged-in session, opening a new one when the token
+// drops it, and serializes calls on it as PKCS#11 requires.
+type PKCS11Signer struct {
+	url  string
+	slot uint64
+	pin  string
+	http *http.Client
+
+	mu      sync.Mutex
+	nextID  uint64
+	session uint64
+	// keys caches key handles by label; handles are only valid in session.
+	keys map[string]pkcs11Key
+}
+
+// NewPKCS11Signer returns a signer using the token in slot at url, logging
+// in as user with pin.
+func NewPKCS11Signer(url string, slot uint64, pin string) *PKCS11Signer {
+	return &PKCS11Signer{
+		url:  url,
+		slot: slot,
+		pin:  pin,
+		http: &http.Client{Timeout: 10 * time.Second},
+		keys: make(map[string]pkcs11Key),
+	}
+}
+
+func (s *PKCS11Signer) PublicKey(ctx context.Context, keyID string) (solana.PublicKey, error) {
+	var pub solana.PublicKey
+	err := s.withSession(ctx, func() error {
+		key, err := s.findKey(ctx, keyID)
+		pub = key.pub
+		return err
+	})
+	return pub, err
+}
+
+func (s *PKCS11Signer) Sign(ctx context.Context, keyID string, message []byte) (solana.Signature, error) {
+	var sig solana.Signature
+	err := s.withSession(ctx, func() error {
+		key, err := s.findKey(ctx, keyID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.call(ctx, "C_SignInit", pkcs11Params{Mechanism: "CKM_EDDSA", Key: key.handle}); err != nil {
+			return err
+		}
+		res, err := s.call(ctx, "C_Sign", pkcs11Params{Data: base64.StdEncoding.EncodeToString(message)})
+		if err != nil {
+			return err
+		}
+		raw, err := base64.StdEncoding.DecodeString(res.Signature)
+		if err != nil || len(raw) != len(sig) {
+			return errors.New("pkcs11: token returned an invalid signature")
+		}
+		copy(sig[:], raw)
+		return nil
+	})
+	return sig, err
+}
+
+// GenerateKey creates an Ed25519 key pair labelled keyID on the token.
+func (s *PKCS11Signer) GenerateKey(ctx context.Context, keyID string) (solana.PublicKey, error) {
+	var pub solana.PublicKey
+	err := s.withSession(ctx, func() error {
+		_, err := s.findObject(ctx, "CKO_PRIVATE_KEY", keyID)
+		if err == nil {
+			return fmt.Errorf("%w: %s", ErrKeyExists, keyID)
+		}
+		if !errors.Is(err, ErrKeyNotFound) {
+			return err
+		}
+		_, err = s.call(ctx, "C_GenerateKeyPair", pkcs11Params{
+			Mechanism: "CKM_EC_EDWARDS_KEY_PAIR_GEN",
+			Template:  map[string]string{"CKA_LABEL": keyID},
+		})
+		if err != nil {
+			return err
+		}
+		key, err := s.findKey(ctx, keyID)
+		pub = key.pub
+		return err
+	})
+	return pub, err
+}
+
+// withSession runs fn on a logged-in session, retrying once on a new session
+// when the token no longer knows the current one.
+func (s *PKCS11Signer) withSession(ctx context.Context, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if s.session == 0 {
+			if err := s.openSession(ctx); err != nil {
+				return err
+			}
+		}
+		err := fn()
+		var p11 *PKCS11Error
+		if attempt > 0 || !errors.As(err, &p11) {
+			return err
+		}
+		switch p11.Code {
+		case ckrSessionHandleInvalid, ckrSessionClosed, ckrUserNotLoggedIn:
+			s.session = 0
+		default:
+			return err
+		}
+	}
+}
+
+func (s *PKCS11Signer) openSession(ctx context.Context) error {
+	s.keys = make(map[string]pkcs11Key)
+	res, err := s.call(ctx, "C_OpenSession", pkcs11Params{Slot: s.slot})
+	if err != nil {
+		return err
+	}
+	s.session = res.Session
+	if _, err := s.call(ctx, "C_Login", pkcs11Params{UserType: "CKU_USER", Pin: s.pin}); err != nil {
+		s.session = 0
+		return err
+	}
+	return nil
+}
+
+func (s *PKCS11Signer) findKey(ctx context.Context, label string) (pkcs11Key, error) {
+	if key, ok := s.keys[label]; ok {
+		return key, nil
+	}
+	private, err := s.findObject(ctx, "CKO_PRIVATE_KEY", label)
+	if err != nil {
+		return pkcs11Key{}, err
+	}
+	public, err := s.findObject(ctx, "CKO_PUBLIC_KEY", label)
+	if err != nil {
+		return pkcs11Key{}, err
+	}
+	res, err := s.call(ctx, "C_GetAttributeValue", pkcs11Params{Object: public, Attribute: "CKA_EC_POINT"})
+	if err != nil {
+		return pkcs11Key{}, err
+	}
+	point, err := base64.StdEncoding.DecodeString(res.Value)
+	if err != nil {
+		return pkcs11Key{}, fmt.Errorf("pkcs11: invalid CKA_EC_POINT for %s", label)
+	}
+	// CKA_EC_POINT is a DER OCTET STRING; some tokens return the raw point.
+	if len(point) == solana.PublicKeyLength+2 && point[0] == 0x04 && point[1] == solana.PublicKeyLength {
+		point = point[2:]
+	}
+	if len(point) != solana.PublicKeyLength {
+		return pkcs11Key{}, fmt.Errorf("pkcs11: %s is not an Ed25519 key", label)
+	}
+	key := pkcs11Key{handle: private, pub: solana.PublicKeyFromBytes(point)}
+	s.keys[label] = key
+	return key, nil
+}
+
+func (s *PKCS11Signer) findObject(ctx context.Context, class, label string) (uint64, error) {
+	template := map[string]string{"CKA_CLASS": class, "CKA_LABEL": label}
+	if _, err := s.call(ctx, "C_FindObjectsInit", pkcs11Params{Template: template}); err != nil {
+		return 0, err
+	}
+	res, err := s.call(ctx, "C_FindObjects", pkcs11Params{Max: 2})
+	if _, finalErr := s.call(ctx, "C_FindObjectsFinal", pkcs11Params{}); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	switch len(res.Objects) {
+	case 0:
+		return 0, fmt.Errorf("%w: %s", ErrKeyNotFound, label)
+	case 1:
+		return res.Objects[0], nil
+	default:
+		return 0, fmt.Errorf("pkcs11: label %s matches several keys", label)
+	}
+}
+
+// call makes a call on the current session.
+func (s *PKCS11Signer) call(ctx context.Context, method string, params pkcs11Params) (*pkcs11Result, error) {
+	if method != "C_OpenSession" {
+		params.Session = s.session
+	}
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	s.nextID++
+	body, err := json.Marshal(pkcs11Request{JSONRPC: "2.0", ID: s.nextID, Method: method, Params: rawParams})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs11: %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	var out pkcs11Response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("pkcs11: %s: invalid response (status %d)", method, resp.StatusCode)
+	}
+	if out.Error != nil {
+		return nil, out.Error
+	}
+	var res pkcs11Result
+	if len(out.Result) > 0 {
+		if err := json.Unmarshal(out.Result, &res); err != nil {
+			return nil, fmt.Errorf("pkcs11: %s: invalid result: %w", method, err)
+		}
+	}
+	return &res, nil
+}
+
+// pkcs11Token is a software token serving the PKCS#11-style protocol with
+// the keys of a Signer, labelled by key ID.
+type pkcs11Token struct {
+	signer Signer
+	slot   uint64
+	pin    string
+
+	mu          sync.Mutex
+	nextHandle  uint64
+	sessions    map[uint64]*pkcs11Session
+	objects     map[uint64]pkcs11TokenObject
+	objectByKey map[string]uint64
+}
+
+type pkcs11TokenObject struct {
+	class string
+	label string
+}
+
+type pkcs11Session struct {
+	loggedIn bool
+	found    []uint64
+	finding  bool
+	signKey  string
+	signing  bool
+}
+
+// NewPKCS11Handler serves the PKCS#11-style protocol as a token in slot,
+// holding the keys of signer and requiring pin to log in. It is meant as a
+// local stand-in for a network HSM.
+func NewPKCS11Handler(signer Signer, slot uint64, pin string) http.Handler {
+	return &pkcs11Token{
+		signer:      signer,
+		slot:        slot,
+		pin:         pin,
+		sessions:    make(map[uint64]*pkcs11Session),
+		objects:     make(map[uint64]pkcs11TokenObject),
+		objectByKey: make(map[string]uint64),
+	}
+}
+
+func (t *pkcs11Token) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req pkcs11Request
+	if r.Method != http.MethodPost || json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&req) != nil {
+		writeJSON(w, http.StatusBadRequest, pkcs11Response{JSONRPC: "2.0", Error: ckr(ckrArgumentsBad)})
+		return
+	}
+	var params pkcs11Params
+	resp := pkcs11Response{JSONRPC: "2.0", ID: req.ID}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		resp.Error = ckr(ckrArgumentsBad)
+	} else if res, err := t.handle(r.Context(), req.Method, params); err != nil {
+		resp.Error = err
+	} else {
+		resp.Result, _ = json.Marshal(res)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (t *pkcs11Token) handle(ctx context.Context, method string, p pkcs11Params) (*pkcs11Result, *PKCS11Error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if method == "C_OpenSession" {
+		if p.Slot != t.slot {
+			return nil, ckr(ckrSlotIDInvalid)
+		}
+		t.nextHandle++
+		t.sessions[t.nextHandle] = &pkcs11Session{}
+		return &pkcs11Result{Session: t.nextHandle}, nil
+	}
+	session, ok := t.sessions[p.Session]
+	if !ok {
+		return nil, ckr(ckrSessionHandleInvalid)
+	}
+	if method == "C_Login" {
+		if p.UserType != "CKU_USER" || p.Pin != t.pin {
+			return nil, ckr(ckrPinIncorrect)
+		}
+		session.loggedIn = true
+		return &pkcs11Result{}, nil
+	}
+	if method == "C_CloseSession" {
+		delete(t.sessions, p.Session)
+		return &pkcs11Result{}, nil
+	}
+	if !session.loggedIn {
+		return nil, ckr(ckrUserNotLoggedIn)
+	}
+
+	switch method {
+	case "C_FindObjectsInit":
+		if session.finding {
+			return nil, ckr(ckrOperationActive)
+		}
+		session.finding, session.found = true, nil
+		class, label := p.Template["CKA_CLASS"], p.Template["CKA_LABEL"]
+		if (class != "CKO_PRIVATE_KEY" && class != "CKO_PUBLIC_KEY") || label == "" {
+			return &pkcs11Result{}, nil
+		}
+		if _, err := t.signer.PublicKey(ctx, label); err != nil {
+			if errors.Is(err, ErrKeyNotFound) {
+				return &pkcs11Result{}, nil
+			}
+			return nil, ckr(ckrGeneralError)
+		}
+		session.found = []uint64{t.object(class, label)}
+		return &pkcs11Result{}, nil
+	case "C_FindObjects":
+		if !session.finding {
+			return nil, ckr(ckrOperationNotInitialized)
+		}
+		n := len(session.found)
+		if p.Max > 0 && p.Max < n {
+			n = p.Max
+		}
+		found := session.found[:n]
+		session.found = session.found[n:]
+		return &pkcs11Result{Objects: found}, nil
+	case "C_FindObjectsFinal":
+		if !session.finding {
+			return nil, ckr(ckrOperationNotInitialized)
+		}
+		session.finding, session.found = false, nil
+		return &pkcs11Result{}, nil
+	case "C_GetAttributeValue":
+		obj, ok := t.objects[p.Object]
+		if !ok {
+			return nil, ckr(ckrObjectHandleInvalid)
+		}
+		if obj.class != "CKO_PUBLIC_KEY" || p.Attribute != "CKA_EC_POINT" {
+			return nil, ckr(ckrArgumentsBad)
+		}
+		pub, err := t.signer.PublicKey(ctx, obj.label)
+		if err != nil {
+			return nil, ckr(ckrObjectHandleInvalid)
+		}
+		point := append([]byte{0x04, solana.PublicKeyLength}, pub[:]...)
+		return &pkcs11Result{Value: base64.StdEncoding.EncodeToString(point)}, nil
+	case "C_GenerateKeyPair":
+		generator, ok := t.signer.(KeyGenerator)
+		if !ok {
+			return nil, ckr(ckrFunctionNotSupported)
+		}
+		if p.Mechanism != "CKM_EC_EDWARDS_KEY_PAIR_GEN" {
+			return nil, ckr(ckrMechanismInvalid)
+		}
+		label := p.Template["CKA_LABEL"]
+		if label == "" {
+			return nil, ckr(ckrArgumentsBad)
+		}
+		if _, err := generator.GenerateKey(ctx, label); err != nil {
+			if errors.Is(err, ErrKeyExists) {
+				return nil, ckr(ckrArgumentsBad)
+			}
+			return nil, ckr(ckrGeneralError)
+		}
+		return &pkcs11Result{Objects: []uint64{t.object("CKO_PUBLIC_KEY", label), t.object("CKO_PRIVATE_KEY", label)}}, nil
+	case "C_SignInit":
+		if session.signing {
+			return nil, ckr(ckrOperationActive)
+		}
+		if p.Mechanism != "CKM_EDDSA" {
+			return nil, ckr(ckrMechanismInvalid)
+		}
+		obj, ok := t.objects[p.Key]
+		if !ok || obj.class != "CKO_PRIVATE_KEY" {
+			return nil, ckr(ckrObjectHandleInvalid)
+		}
+		session.signing, session.signKey = true, obj.label
+		return &pkcs11Result{}, nil
+	case "C_Sign":
+		if !session.signing {
+			return nil, ckr(ckrOperationNotInitialized)
+		}
+		// C_Sign ends the operation whatever its outcome.
+		session.signing = false
+		data, err := base64.StdEncoding.DecodeString(p.Data)
+		if err != nil || len(data) == 0 {
+			return nil, ckr(ckrArgumentsBad)
+		}
+		sig, err := t.signer.Sign(ctx, session.signKey, data)
+		if err != nil {
+			return nil, ckr(ckrGeneralError)
+		}
+		return &pkcs11Result{Signature: base64.StdEncoding.EncodeToString(sig[:])}, nil
+	default:
+		return nil, ckr(ckrFunctionNotSupported)
+	}
+}
+
+// object returns the handle of an object, assigning one on first use.
+func (t *pkcs11Token) object(class, label string) uint64 {
+	key := class + "/" + label
+	if handle, ok := t.objectByKey[key]; ok {
+		return handle
+	}
+	t.nextHandle++
+	t.objects[t.nextHandle] = pkcs11TokenObject{class: class, label: label}
+	t.objectByKey[key] = t.nextHandle
+	return t.nextHandle
+}
@@ -0,0 +1,224 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// The remote signer protocol is plain JSON over HTTP, authenticated with a
+// bearer token:
+//
+//	GET  /v1/keys/{id}       -> {"id": "...", "public_key": "<base58>"}
+//	PUT  /v1/keys/{id}       -> {"id": "...", "public_key": "<base58>"}, creates the key
+//	POST /v1/keys/{id}/sign  {"message": "<base64>"} -> {"signature": "<base58>"}
+//
+// Errors are returned as {"error": "..."} with status 404 for unknown keys,
+// 409 when creating a key that exists, 401 for a bad token and 400 for
+// malformed requests. Key IDs are path escaped.
+
+const remoteSignerPrefix = "/v1/keys/"
+
+type remoteKeyResponse struct {
+	ID        string `json:"id"`
+	PublicKey string `json:"public_key"`
+}
+
+type remoteSignRequest struct {
+	Message string `json:"message"`
+}
+
+type remoteSignResponse struct {
+	Signature string `json:"signature"`
+}
+
+type remoteErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// RemoteSigner is a Signer that asks a signing service speaking the remote
+// signer protocol. Keys never leave that service.
+type RemoteSigner struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+// NewRemoteSigner returns a signer for the service at baseURL.
+func NewRemoteSigner(baseURL, token string) *RemoteSigner {
+	return &RemoteSigner{
+		url:   strings.TrimRight(baseURL, "/"),
+		token: token,
+		http:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *RemoteSigner) PublicKey(ctx context.Context, keyID string) (solana.PublicKey, error) {
+	return s.key(ctx, http.MethodGet, keyID)
+}
+
+func (s *RemoteSigner) GenerateKey(ctx context.Context, keyID string) (solana.PublicKey, error) {
+	return s.key(ctx, http.MethodPut, keyID)
+}
+
+func (s *RemoteSigner) key(ctx context.Context, method, keyID string) (solana.PublicKey, error) {
+	var out remoteKeyResponse
+	if err := s.do(ctx, method, keyID, "", nil, &out); err != nil {
+		return solana.PublicKey{}, err
+	}
+	pub, err := solana.PublicKeyFromBase58(out.PublicKey)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("remote signer returned an invalid public key: %w", err)
+	}
+	return pub, nil
+}
+
+func (s *RemoteSigner) Sign(ctx context.Context, keyID string, message []byte) (solana.Signature, error) {
+	var out remoteSignResponse
+	req := remoteSignRequest{Message: base64.StdEncoding.EncodeToString(message)}
+	if err := s.do(ctx, http.MethodPost, keyID, "/sign", req, &out); err != nil {
+		return solana.Signature{}, err
+	}
+	sig, err := solana.SignatureFromBase58(out.Signature)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("remote signer returned an invalid signature: %w", err)
+	}
+	return sig, nil
+}
+
+func (s *RemoteSigner) do(ctx context.Context, method, keyID, suffix string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.url+remoteSignerPrefix+url.PathEscape(keyID)+suffix, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote signer: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return fmt.Errorf("remote signer: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e remoteErrorResponse
+		_ = json.Unmarshal(data, &e)
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+		case http.StatusConflict:
+			return fmt.Errorf("%w: %s", ErrKeyExists, keyID)
+		}
+		return fmt.Errorf("remote signer: status %d: %s", resp.StatusCode, e.Error)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("remote signer: invalid response: %w", err)
+	}
+	return nil
+}
+
+// NewRemoteSignerHandler serves the remote signer protocol for signer,
+// requiring token as bearer token. It is meant as a local stand-in for a
+// signing service.
+func NewRemoteSignerHandler(signer Signer, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, remoteErrorResponse{Error: "invalid token"})
+			return
+		}
+		path := strings.TrimPrefix(r.URL.EscapedPath(), remoteSignerPrefix)
+		if path == r.URL.EscapedPath() {
+			writeJSON(w, http.StatusNotFound, remoteErrorResponse{Error: "not found"})
+			return
+		}
+		escaped, sign := strings.CutSuffix(path, "/sign")
+		keyID, err := url.PathUnescape(escaped)
+		if err != nil || keyID == "" || strings.Contains(escaped, "/") {
+			writeJSON(w, http.StatusNotFound, remoteErrorResponse{Error: "not found"})
+			return
+		}
+
+		switch {
+		case !sign && r.Method == http.MethodGet:
+			pub, err := signer.PublicKey(r.Context(), keyID)
+			if err != nil {
+				writeSignerError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, remoteKeyResponse{ID: keyID, PublicKey: pub.String()})
+		case !sign && r.Method == http.MethodPut:
+			generator, ok := signer.(KeyGenerator)
+			if !ok {
+				writeJSON(w, http.StatusNotImplemented, remoteErrorResponse{Error: "key generation is not supported"})
+				return
+			}
+			pub, err := generator.GenerateKey(r.Context(), keyID)
+			if err != nil {
+				writeSignerError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, remoteKeyResponse{ID: keyID, PublicKey: pub.String()})
+		case sign && r.Method == http.MethodPost:
+			var req remoteSignRequest
+			if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, remoteErrorResponse{Error: "invalid request"})
+				return
+			}
+			message, err := base64.StdEncoding.DecodeString(req.Message)
+			if err != nil || len(message) == 0 {
+				writeJSON(w, http.StatusBadRequest, remoteErrorResponse{Error: "message must be base64"})
+				return
+			}
+			sig, err := signer.Sign(r.Context(), keyID, message)
+			if err != nil {
+				writeSignerError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, remoteSignResponse{Signature: sig.String()})
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, remoteErrorResponse{Error: "method not allowed"})
+		}
+	})
+}
+
+func writeSignerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		writeJSON(w, http.StatusNotFound, remoteErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrKeyExists):
+		writeJSON(w, http.StatusConflict, remoteErrorResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, remoteErrorResponse{Error: err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,104 @@
+package solana
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"mywallet/pkg/logger"
+	"mywallet/pkg/solana/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigners(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	from := solana.NewWallet()
+	payer := solana.NewWallet()
+	local := NewLocalSigner()
+	local.Add("treasury/hot", from.PrivateKey)
+	local.Add("fee-payer", payer.PrivateKey)
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+	sim.Airdrop(payer.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	remote := httptest.NewServer(NewRemoteSignerHandler(local, "secret"))
+	defer remote.Close()
+	token := httptest.NewServer(NewPKCS11Handler(local, 1, "1234"))
+	defer token.Close()
+
+	signers := map[string]Signer{
+		"local":  local,
+		"remote": NewRemoteSigner(remote.URL, "secret"),
+		"pkcs11": NewPKCS11Signer(token.URL, 1, "1234"),
+	}
+	for name, signer := range signers {
+		t.Run(name, func(t *testing.T) {
+			pub, err := signer.PublicKey(ctx, "treasury/hot")
+			require.NoError(t, err)
+			assert.Equal(t, from.PublicKey(), pub)
+			_, err = signer.PublicKey(ctx, "missing")
+			assert.ErrorIs(t, err, ErrKeyNotFound)
+
+			to := solana.NewWallet().PublicKey()
+			fromKey := Key{ID: "treasury/hot", PublicKey: pub, Signer: signer}
+			payerKey := Key{ID: "fee-payer", PublicKey: payer.PublicKey(), Signer: signer}
+			_, err = client.TransferFrom(ctx, fromKey, &payerKey, to, decimal.NewFromFloat(0.1), "")
+			require.NoError(t, err)
+			assert.Equal(t, solana.LAMPORTS_PER_SOL/10, sim.Balance(to))
+
+			generated, err := signer.(KeyGenerator).GenerateKey(ctx, "generated-"+name)
+			require.NoError(t, err)
+			pub, err = signer.PublicKey(ctx, "generated-"+name)
+			require.NoError(t, err)
+			assert.Equal(t, generated, pub)
+			_, err = signer.(KeyGenerator).GenerateKey(ctx, "fee-payer")
+			assert.Error(t, err, "existing keys are not replaced")
+
+			// A key registered under the wrong public key is caught before sending.
+			wrong := Key{ID: "fee-payer", PublicKey: from.PublicKey(), Signer: signer}
+			_, err = client.TransferFrom(ctx, wrong, nil, to, decimal.NewFromFloat(0.1), "")
+			assert.ErrorContains(t, err, "invalid signature")
+		})
+	}
+
+	_, err := NewRemoteSigner(remote.URL, "wrong").PublicKey(ctx, "fee-payer")
+	assert.ErrorContains(t, err, "401")
+	_, err = NewPKCS11Signer(token.URL, 1, "0000").PublicKey(ctx, "fee-payer")
+	var p11 *PKCS11Error
+	require.ErrorAs(t, err, &p11)
+	assert.Equal(t, "CKR_PIN_INCORRECT", p11.Message)
+}
+
+func TestPKCS11SignerReopensSession(t *testing.T) {
+	ctx := context.Background()
+	key := solana.NewWallet().PrivateKey
+	local := NewLocalSigner(key)
+
+	// Swapping the token drops its sessions, as an HSM restart would.
+	var token atomic.Value
+	token.Store(NewPKCS11Handler(local, 0, "pin"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token.Load().(http.Handler).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	signer := NewPKCS11Signer(server.URL, 0, "pin")
+	message := []byte("message")
+	sig, err := signer.Sign(ctx, key.PublicKey().String(), message)
+	require.NoError(t, err)
+	assert.True(t, sig.Verify(key.PublicKey(), message))
+
+	token.Store(NewPKCS11Handler(local, 0, "pin"))
+	sig, err = signer.Sign(ctx, key.PublicKey().String(), message)
+	require.NoError(t, err)
+	assert.True(t, sig.Verify(key.PublicKey(), message))
+}
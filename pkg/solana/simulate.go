@@ -0,0 +1,111 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// TransferSimulation is the outcome of simulating a transfer.
+type TransferSimulation struct {
+	// Slot is the slot the node simulated the transaction at.
+	Slot uint64
+	// Fee is the fee in SOL the transaction would be charged.
+	Fee           decimal.Decimal
+	UnitsConsumed uint64
+	Logs          []string
+	// BalanceChanges lists the accounts the transfer writes to. It is empty
+	// when the simulation failed, as nodes return no account state then.
+	BalanceChanges []BalanceChange
+	// Err is the transaction error as reported by the node, such as
+	// {"InstructionError":[0,{"Custom":1}]}, or nil if it would succeed.
+	Err interface{}
+}
+
+// BalanceChange is the balance of an account before and after a simulated
+// transaction, in SOL.
+type BalanceChange struct {
+	Address string
+	Before  decimal.Decimal
+	After   decimal.Decimal
+}
+
+// Change returns the difference the transaction makes to the balance.
+func (b BalanceChange) Change() decimal.Decimal {
+	return b.After.Sub(b.Before)
+}
+
+// SimulateTransfer builds and signs the transaction Transfer would send and
+// simulates it instead. An error is returned only when the simulation could
+// not be run; a transfer that would fail is reported in TransferSimulation.Err.
+func (c *Client) SimulateTransfer(ctx context.Context, fromPrivateKey solana.PrivateKey, toPublicKey solana.PublicKey, amount decimal.Decimal) (*TransferSimulation, error) {
+	from := LocalKey(fromPrivateKey)
+	tx, err := c.buildTransfer(ctx, from, from, toPublicKey, amount, "")
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := c.client.GetFeeForMessage(ctx, tx.Message.ToBase64(), rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee: %w", err)
+	}
+	if fee.Value == nil {
+		return nil, fmt.Errorf("failed to get fee: blockhash %s expired", tx.Message.RecentBlockhash)
+	}
+
+	addresses := []solana.PublicKey{from.PublicKey}
+	if !toPublicKey.Equals(addresses[0]) {
+		addresses = append(addresses, toPublicKey)
+	}
+	before := make([]uint64, len(addresses))
+	for i, address := range addresses {
+		balance, err := c.client.GetBalance(ctx, address, rpc.CommitmentFinalized)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get balance: %w", err)
+		}
+		before[i] = balance.Value
+	}
+
+	out, err := c.client.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:  true,
+		Commitment: rpc.CommitmentFinalized,
+		Accounts: &rpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			Addresses: addresses,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+
+	sim := &TransferSimulation{
+		Slot: out.Context.Slot,
+		Fee:  lamportsToSOL(*fee.Value),
+		Logs: out.Value.Logs,
+		Err:  out.Value.Err,
+	}
+	if out.Value.UnitsConsumed != nil {
+		sim.UnitsConsumed = *out.Value.UnitsConsumed
+	}
+	if sim.Err == nil && len(out.Value.Accounts) == len(addresses) {
+		for i, address := range addresses {
+			var after uint64
+			if account := out.Value.Accounts[i]; account != nil {
+				after = account.Lamports
+			}
+			sim.BalanceChanges = append(sim.BalanceChanges, BalanceChange{
+				Address: address.String(),
+				Before:  lamportsToSOL(before[i]),
+				After:   lamportsToSOL(after),
+			})
+		}
+	}
+	return sim, nil
+}
+
+func lamportsToSOL(lamports uint64) decimal.Decimal {
+	return decimal.NewFromInt(int64(lamports)).Div(decimal.NewFromInt(1e9))
+}
@@ -0,0 +1,57 @@
+package solana
+
+import (
+	"context"
+	"testing"
+
+	"mywallet/pkg/logger"
+	"mywallet/pkg/solana/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateTransfer(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	result, err := client.SimulateTransfer(context.Background(), from.PrivateKey, to, decimal.NewFromFloat(0.25))
+	require.NoError(t, err)
+	assert.Nil(t, result.Err)
+	assert.True(t, decimal.NewFromFloat(0.000005).Equal(result.Fee))
+	assert.NotZero(t, result.UnitsConsumed)
+	assert.NotEmpty(t, result.Logs)
+
+	require.Len(t, result.BalanceChanges, 2)
+	assert.Equal(t, from.PublicKey().String(), result.BalanceChanges[0].Address)
+	assert.True(t, decimal.NewFromFloat(-0.250005).Equal(result.BalanceChanges[0].Change()))
+	assert.Equal(t, to.String(), result.BalanceChanges[1].Address)
+	assert.True(t, decimal.Zero.Equal(result.BalanceChanges[1].Before))
+	assert.True(t, decimal.NewFromFloat(0.25).Equal(result.BalanceChanges[1].After))
+
+	// Nothing is committed.
+	assert.Equal(t, solana.LAMPORTS_PER_SOL, sim.Balance(from.PublicKey()))
+	assert.Zero(t, sim.Balance(to))
+}
+
+func TestSimulateFailingTransfer(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	client := NewClient(sim.URL, logger.NewLogger())
+
+	from := solana.NewWallet()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	result, err := client.SimulateTransfer(context.Background(), from.PrivateKey, solana.NewWallet().PublicKey(), decimal.NewFromInt(2))
+	require.NoError(t, err)
+	assert.NotNil(t, result.Err)
+	assert.Contains(t, result.Logs[len(result.Logs)-1], "failed")
+	assert.Empty(t, result.BalanceChanges)
+}
@@ -0,0 +1,161 @@
+package simulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+)
+
+const (
+	// NonceAccountRent is the rent-exempt minimum of a nonce account.
+	NonceAccountRent = 1447680
+	// nonceAccountSize is the size of the nonce account state.
+	nonceAccountSize = 80
+)
+
+// nonceState is the state of an initialized durable nonce account.
+type nonceState struct {
+	authority solana.PublicKey
+	value     solana.Hash
+}
+
+// CreateNonceAccount creates an initialized, rent-exempt durable nonce
+// account controlled by authority and returns its nonce.
+func (s *Simulator) CreateNonceAccount(account, authority solana.PublicKey) solana.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value := nextNonce(solana.Hash(account), s.slot)
+	s.accounts[account] += NonceAccountRent
+	s.nonces[account] = &nonceState{authority: authority, value: value}
+	return value
+}
+
+// Nonce returns the current nonce of a nonce account, or the zero hash if
+// account is not one.
+func (s *Simulator) Nonce(account solana.PublicKey) solana.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n, ok := s.nonces[account]; ok {
+		return n.value
+	}
+	return solana.Hash{}
+}
+
+// AdvanceNonce advances a nonce account as a transaction signed by its
+// authority would, invalidating transactions that use the current nonce.
+func (s *Simulator) AdvanceNonce(account solana.PublicKey) solana.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.nonces[account]
+	if !ok {
+		return solana.Hash{}
+	}
+	s.slot++
+	s.rotateBlockhash()
+	n.value = nextNonce(n.value, s.slot)
+	return n.value
+}
+
+func nextNonce(prev solana.Hash, slot uint64) solana.Hash {
+	var buf [40]byte
+	copy(buf[:], prev[:])
+	binary.LittleEndian.PutUint64(buf[32:], slot)
+	return solana.Hash(sha256.Sum256(buf[:]))
+}
+
+// usesDurableNonce reports whether tx starts by advancing a nonce account
+// whose current nonce is its recent blockhash. Callers must hold mu.
+func (s *Simulator) usesDurableNonce(tx *solana.Transaction) bool {
+	if len(tx.Message.Instructions) == 0 {
+		return false
+	}
+	advance, ok := decodeAdvanceNonce(tx, tx.Message.Instructions[0])
+	if !ok {
+		return false
+	}
+	n, ok := s.nonces[advance.GetNonceAccount().PublicKey]
+	return ok && n.value == tx.Message.RecentBlockhash
+}
+
+func decodeAdvanceNonce(tx *solana.Transaction, inst solana.CompiledInstruction) (*system.AdvanceNonceAccount, bool) {
+	programID, err := tx.ResolveProgramIDIndex(inst.ProgramIDIndex)
+	if err != nil || !programID.Equals(solana.SystemProgramID) {
+		return nil, false
+	}
+	accounts, err := inst.ResolveInstructionAccounts(&tx.Message)
+	if err != nil {
+		return nil, false
+	}
+	decoded, err := system.DecodeInstruction(accounts, inst.Data)
+	if err != nil {
+		return nil, false
+	}
+	advance, ok := decoded.Impl.(*system.AdvanceNonceAccount)
+	return advance, ok
+}
+
+// executeAdvanceNonce advances a nonce account signed by its authority. The
+// new nonce is applied when the transaction is committed.
+func (s *Simulator) executeAdvanceNonce(index int, advance *system.AdvanceNonceAccount, programID solana.PublicKey, res *execResult) error {
+	account := advance.GetNonceAccount().PublicKey
+	n, ok := s.nonces[account]
+	if !ok {
+		res.logs = append(res.logs, fmt.Sprintf("Program %s failed: invalid account data for instruction", programID))
+		return &instructionError{Index: index, Msg: "invalid account data for instruction"}
+	}
+	authority := advance.GetNonceAuthorityAccount()
+	if !authority.IsSigner || !authority.PublicKey.Equals(n.authority) {
+		return &instructionError{Index: index, Msg: "missing required signature"}
+	}
+	if res.nonces == nil {
+		res.nonces = make(map[solana.PublicKey]solana.Hash)
+	}
+	res.nonces[account] = nextNonce(n.value, s.slot)
+	res.units += systemTransferUnits
+	res.logs = append(res.logs, fmt.Sprintf("Program %s success", programID))
+	return nil
+}
+
+// nonceData encodes the state of a nonce account as stored on chain.
+func nonceData(n *nonceState) []byte {
+	var buf bytes.Buffer
+	_ = system.NonceAccount{
+		Version:          1,
+		State:            1,
+		AuthorizedPubkey: n.authority,
+		Nonce:            solana.PublicKey(n.value),
+		FeeCalculator:    system.FeeCalculator{LamportsPerSignature: LamportsPerSignature},
+	}.MarshalWithEncoder(bin.NewBinEncoder(&buf))
+	return buf.Bytes()
+}
+
+func (s *Simulator) rpcGetAccountInfo(params []json.RawMessage) (interface{}, *rpcError) {
+	pk, rerr := pubkeyParam(params, 0)
+	if rerr != nil {
+		return nil, rerr
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := map[string]interface{}{
+		"context": rpcContext{Slot: s.slot},
+		"value":   nil,
+	}
+	lamports, ok := s.accounts[pk]
+	if !ok {
+		return result, nil
+	}
+	value := accountValue(lamports)
+	if n, ok := s.nonces[pk]; ok {
+		value["data"] = []string{base64.StdEncoding.EncodeToString(nonceData(n)), "base64"}
+		value["space"] = nonceAccountSize
+	}
+	result["value"] = value
+	return result, nil
+}
@@ -0,0 +1,243 @@
+package simulator
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gorilla/websocket"
+)
+
+// pubsub serves the websocket subscriptions of a simulator: account changes,
+// signature confirmations and transaction logs mentioning an address.
+type pubsub struct {
+	mu     sync.Mutex
+	nextID uint64
+	subs   map[uint64]*wsSubscription
+	conns  map[*wsConn]struct{}
+}
+
+type wsSubscription struct {
+	id        uint64
+	conn      *wsConn
+	kind      string
+	account   solana.PublicKey
+	signature solana.Signature
+}
+
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (c *wsConn) write(v interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.WriteJSON(v)
+}
+
+type wsNotification struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  wsNotifParams `json:"params"`
+}
+
+type wsNotifParams struct {
+	Result       interface{} `json:"result"`
+	Subscription uint64      `json:"subscription"`
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+// WSURL returns the websocket endpoint of the server.
+func (s *Server) WSURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+// DropConnections closes every websocket connection, as a restarting node
+// would. Their subscriptions are forgotten.
+func (s *Simulator) DropConnections() {
+	s.pubsub.mu.Lock()
+	conns := make([]*wsConn, 0, len(s.pubsub.conns))
+	for c := range s.pubsub.conns {
+		conns = append(conns, c)
+	}
+	s.pubsub.mu.Unlock()
+	for _, c := range conns {
+		_ = c.conn.Close()
+	}
+}
+
+// Subscriptions returns the number of active websocket subscriptions.
+func (s *Simulator) Subscriptions() int {
+	s.pubsub.mu.Lock()
+	defer s.pubsub.mu.Unlock()
+	return len(s.pubsub.subs)
+}
+
+func (s *Simulator) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &wsConn{conn: conn}
+	p := &s.pubsub
+	p.mu.Lock()
+	p.conns[c] = struct{}{}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.conns, c)
+		for id, sub := range p.subs {
+			if sub.conn == c {
+				delete(p.subs, id)
+			}
+		}
+		p.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	for {
+		var req rpcRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+		resp.Result, resp.Error = s.handleWS(c, req)
+		c.write(resp)
+	}
+}
+
+func (s *Simulator) handleWS(c *wsConn, req rpcRequest) (interface{}, *rpcError) {
+	sub := &wsSubscription{conn: c, kind: req.Method}
+	switch req.Method {
+	case "accountSubscribe":
+		account, err := pubkeyParam(req.Params, 0)
+		if err != nil {
+			return nil, err
+		}
+		sub.account = account
+	case "signatureSubscribe":
+		var str string
+		if err := param(req.Params, 0, &str); err != nil {
+			return nil, err
+		}
+		sig, err := solana.SignatureFromBase58(str)
+		if err != nil {
+			return nil, invalidParams("Invalid param: %v", err)
+		}
+		sub.signature = sig
+	case "logsSubscribe":
+		var filter struct {
+			Mentions []string `json:"mentions"`
+		}
+		if err := param(req.Params, 0, &filter); err != nil || len(filter.Mentions) != 1 {
+			return nil, invalidParams("Invalid param: a single mentions address is required")
+		}
+		account, err := solana.PublicKeyFromBase58(filter.Mentions[0])
+		if err != nil {
+			return nil, invalidParams("Invalid param: %v", err)
+		}
+		sub.account = account
+	case "accountUnsubscribe", "signatureUnsubscribe", "logsUnsubscribe":
+		var id uint64
+		if err := param(req.Params, 0, &id); err != nil {
+			return nil, err
+		}
+		s.pubsub.mu.Lock()
+		defer s.pubsub.mu.Unlock()
+		existing, ok := s.pubsub.subs[id]
+		if !ok || existing.conn != c {
+			return nil, invalidParams("Invalid subscription id.")
+		}
+		delete(s.pubsub.subs, id)
+		return true, nil
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "Method not found: " + req.Method}
+	}
+
+	s.pubsub.mu.Lock()
+	defer s.pubsub.mu.Unlock()
+	s.pubsub.nextID++
+	sub.id = s.pubsub.nextID
+	s.pubsub.subs[sub.id] = sub
+	return sub.id, nil
+}
+
+// publishAccounts notifies account subscribers of new balances.
+func (s *Simulator) publishAccounts(slot uint64, balances map[solana.PublicKey]uint64) {
+	s.publish(func(sub *wsSubscription) (string, interface{}, bool) {
+		lamports, ok := balances[sub.account]
+		if sub.kind != "accountSubscribe" || !ok {
+			return "", nil, false
+		}
+		return "accountNotification", map[string]interface{}{
+			"context": rpcContext{Slot: slot},
+			"value":   accountValue(lamports),
+		}, true
+	})
+}
+
+// publishTransaction notifies subscribers of a processed transaction.
+// Signature subscriptions end with their notification, as on a real node.
+func (s *Simulator) publishTransaction(rec *txRecord) {
+	changed := make(map[solana.PublicKey]uint64)
+	for i, k := range rec.accountKeys {
+		if rec.preBalances[i] != rec.postBalances[i] {
+			changed[k] = rec.postBalances[i]
+		}
+	}
+	s.publishAccounts(rec.slot, changed)
+
+	s.publish(func(sub *wsSubscription) (string, interface{}, bool) {
+		switch {
+		case sub.kind == "signatureSubscribe" && sub.signature == rec.signature:
+			return "signatureNotification", map[string]interface{}{
+				"context": rpcContext{Slot: rec.slot},
+				"value":   map[string]interface{}{"err": nil},
+			}, true
+		case sub.kind == "logsSubscribe" && rec.accountKeys.Contains(sub.account):
+			return "logsNotification", map[string]interface{}{
+				"context": rpcContext{Slot: rec.slot},
+				"value": map[string]interface{}{
+					"signature": rec.signature.String(),
+					"err":       nil,
+					"logs":      rec.logs,
+				},
+			}, true
+		}
+		return "", nil, false
+	})
+}
+
+// publish sends a notification to every subscription match selects.
+func (s *Simulator) publish(match func(sub *wsSubscription) (string, interface{}, bool)) {
+	type delivery struct {
+		conn *wsConn
+		msg  wsNotification
+	}
+	var out []delivery
+
+	s.pubsub.mu.Lock()
+	for id, sub := range s.pubsub.subs {
+		method, result, ok := match(sub)
+		if !ok {
+			continue
+		}
+		out = append(out, delivery{conn: sub.conn, msg: wsNotification{
+			JSONRPC: "2.0",
+			Method:  method,
+			Params:  wsNotifParams{Result: result, Subscription: id},
+		}})
+		if sub.kind == "signatureSubscribe" {
+			delete(s.pubsub.subs, id)
+		}
+	}
+	s.pubsub.mu.Unlock()
+
+	for _, d := range out {
+		d.conn.write(d.msg)
+	}
+}
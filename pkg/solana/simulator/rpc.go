@@ -0,0 +1,400 @@
+package simulator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gorilla/websocket"
+	"github.com/mr-tron/base58"
+)
+
+// JSON-RPC error codes used by Solana validators.
+const (
+	codeInvalidParams   = -32602
+	codeMethodNotFound  = -32601
+	codeParseError      = -32700
+	codePreflightFailed = -32002
+	codeSigVerifyFailed = -32003
+)
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcContext struct {
+	Slot uint64 `json:"slot"`
+}
+
+type handlerFunc func(s *Simulator, params []json.RawMessage) (interface{}, *rpcError)
+
+var handlers = map[string]handlerFunc{
+	"getBalance":              (*Simulator).rpcGetBalance,
+	"getLatestBlockhash":      (*Simulator).rpcGetLatestBlockhash,
+	"sendTransaction":         (*Simulator).rpcSendTransaction,
+	"simulateTransaction":     (*Simulator).rpcSimulateTransaction,
+	"getSignatureStatuses":    (*Simulator).rpcGetSignatureStatuses,
+	"getTransaction":          (*Simulator).rpcGetTransaction,
+	"getSignaturesForAddress": (*Simulator).rpcGetSignaturesForAddress,
+	"getSlot":                 (*Simulator).rpcGetSlot,
+	"getFeeForMessage":        (*Simulator).rpcGetFeeForMessage,
+	"getAccountInfo":          (*Simulator).rpcGetAccountInfo,
+}
+
+// ServeHTTP implements a single (non-batched) JSON-RPC 2.0 endpoint, and the
+// pubsub endpoint for websocket upgrade requests.
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWS(w, r)
+		return
+	}
+	var req rpcRequest
+	resp := rpcResponse{JSONRPC: "2.0"}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error = &rpcError{Code: codeParseError, Message: err.Error()}
+	} else {
+		resp.ID = req.ID
+		h, ok := handlers[req.Method]
+		if !ok {
+			resp.Error = &rpcError{Code: codeMethodNotFound, Message: "Method not found: " + req.Method}
+		} else {
+			resp.Result, resp.Error = h(s, req.Params)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func invalidParams(format string, args ...interface{}) *rpcError {
+	return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// param decodes params[i] into v, leaving v untouched if it is absent.
+func param(params []json.RawMessage, i int, v interface{}) *rpcError {
+	if i >= len(params) {
+		return nil
+	}
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return invalidParams("invalid param %d: %v", i, err)
+	}
+	return nil
+}
+
+func pubkeyParam(params []json.RawMessage, i int) (solana.PublicKey, *rpcError) {
+	var str string
+	if i >= len(params) {
+		return solana.PublicKey{}, invalidParams("missing param %d", i)
+	}
+	if err := param(params, i, &str); err != nil {
+		return solana.PublicKey{}, err
+	}
+	pk, err := solana.PublicKeyFromBase58(str)
+	if err != nil {
+		return solana.PublicKey{}, invalidParams("Invalid param: %v", err)
+	}
+	return pk, nil
+}
+
+// decodeTransaction parses a wire transaction encoded as base64 or base58.
+func decodeTransaction(params []json.RawMessage) (*solana.Transaction, []byte, *rpcError) {
+	var encoded string
+	if len(params) == 0 {
+		return nil, nil, invalidParams("missing transaction")
+	}
+	if err := param(params, 0, &encoded); err != nil {
+		return nil, nil, err
+	}
+	var opts struct {
+		Encoding string `json:"encoding"`
+	}
+	if err := param(params, 1, &opts); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		raw []byte
+		err error
+	)
+	if opts.Encoding == "base64" {
+		raw, err = base64.StdEncoding.DecodeString(encoded)
+	} else {
+		raw, err = base58.Decode(encoded)
+	}
+	if err != nil {
+		return nil, nil, invalidParams("failed to decode transaction: %v", err)
+	}
+	tx, err := solana.TransactionFromBytes(raw)
+	if err != nil {
+		return nil, nil, invalidParams("failed to deserialize transaction: %v", err)
+	}
+	return tx, raw, nil
+}
+
+// transactionErr renders an execution error the way validators report it
+// in meta.err and signature statuses.
+func transactionErr(err error) interface{} {
+	if err == nil {
+		return nil
+	}
+	var ie *instructionError
+	if errors.As(err, &ie) {
+		return map[string]interface{}{
+			"InstructionError": []interface{}{ie.Index, map[string]uint32{"Custom": ie.Code}},
+		}
+	}
+	return err.Error()
+}
+
+// accountValue renders a system-owned account holding lamports.
+func accountValue(lamports uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"lamports":   lamports,
+		"owner":      solana.SystemProgramID.String(),
+		"data":       []string{"", "base64"},
+		"executable": false,
+		"rentEpoch":  0,
+		"space":      0,
+	}
+}
+
+func (s *Simulator) rpcGetBalance(params []json.RawMessage) (interface{}, *rpcError) {
+	pk, rerr := pubkeyParam(params, 0)
+	if rerr != nil {
+		return nil, rerr
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]interface{}{
+		"context": rpcContext{Slot: s.slot},
+		"value":   s.accounts[pk],
+	}, nil
+}
+
+func (s *Simulator) rpcGetSlot(params []json.RawMessage) (interface{}, *rpcError) {
+	return s.Slot(), nil
+}
+
+func (s *Simulator) rpcGetLatestBlockhash(params []json.RawMessage) (interface{}, *rpcError) {
+	hash, lastValid, slot := s.latestBlockhash()
+	return map[string]interface{}{
+		"context": rpcContext{Slot: slot},
+		"value": map[string]interface{}{
+			"blockhash":            hash.String(),
+			"lastValidBlockHeight": lastValid,
+		},
+	}, nil
+}
+
+func (s *Simulator) rpcSendTransaction(params []json.RawMessage) (interface{}, *rpcError) {
+	tx, raw, rerr := decodeTransaction(params)
+	if rerr != nil {
+		return nil, rerr
+	}
+	sig, err := s.send(tx, raw)
+	if err != nil {
+		var ie *instructionError
+		if errors.As(err, &ie) || errors.Is(err, errInsufficientFee) || errors.Is(err, errBlockhashNotFound) || errors.Is(err, errAccountNotFound) {
+			return nil, &rpcError{
+				Code:    codePreflightFailed,
+				Message: "Transaction simulation failed: " + err.Error(),
+				Data:    map[string]interface{}{"err": transactionErr(err)},
+			}
+		}
+		return nil, &rpcError{Code: codeSigVerifyFailed, Message: err.Error()}
+	}
+	return sig.String(), nil
+}
+
+func (s *Simulator) rpcSimulateTransaction(params []json.RawMessage) (interface{}, *rpcError) {
+	tx, _, rerr := decodeTransaction(params)
+	if rerr != nil {
+		return nil, rerr
+	}
+	var opts struct {
+		SigVerify              bool `json:"sigVerify"`
+		ReplaceRecentBlockhash bool `json:"replaceRecentBlockhash"`
+		Accounts               struct {
+			Addresses []string `json:"addresses"`
+		} `json:"accounts"`
+	}
+	if err := param(params, 1, &opts); err != nil {
+		return nil, err
+	}
+	addresses := make([]solana.PublicKey, len(opts.Accounts.Addresses))
+	for i, str := range opts.Accounts.Addresses {
+		pk, err := solana.PublicKeyFromBase58(str)
+		if err != nil {
+			return nil, invalidParams("Invalid param: %v", err)
+		}
+		addresses[i] = pk
+	}
+
+	res, accounts, slot, err := s.simulate(tx, opts.SigVerify, opts.ReplaceRecentBlockhash, addresses)
+	value := map[string]interface{}{
+		"err":           nil,
+		"logs":          []string{},
+		"accounts":      nil,
+		"unitsConsumed": uint64(0),
+	}
+	if err != nil {
+		value["err"] = transactionErr(err)
+	} else {
+		value["err"] = transactionErr(res.err)
+		value["logs"] = res.logs
+		value["unitsConsumed"] = res.units
+		if len(addresses) > 0 {
+			value["accounts"] = accounts
+		}
+	}
+	return map[string]interface{}{
+		"context": rpcContext{Slot: slot},
+		"value":   value,
+	}, nil
+}
+
+func (s *Simulator) rpcGetFeeForMessage(params []json.RawMessage) (interface{}, *rpcError) {
+	var encoded string
+	if len(params) == 0 {
+		return nil, invalidParams("missing message")
+	}
+	if err := param(params, 0, &encoded); err != nil {
+		return nil, err
+	}
+	var msg solana.Message
+	if err := msg.UnmarshalBase64(encoded); err != nil {
+		return nil, invalidParams("failed to deserialize message: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value := interface{}(nil)
+	if _, ok := s.blockhash[msg.RecentBlockhash]; ok {
+		value = fee(&msg)
+	}
+	return map[string]interface{}{
+		"context": rpcContext{Slot: s.slot},
+		"value":   value,
+	}, nil
+}
+
+func (s *Simulator) rpcGetSignatureStatuses(params []json.RawMessage) (interface{}, *rpcError) {
+	var sigs []string
+	if err := param(params, 0, &sigs); err != nil {
+		return nil, err
+	}
+	statuses := make([]interface{}, len(sigs))
+	for i, str := range sigs {
+		sig, err := solana.SignatureFromBase58(str)
+		if err != nil {
+			return nil, invalidParams("Invalid param: %v", err)
+		}
+		rec := s.transaction(sig)
+		if rec == nil {
+			continue
+		}
+		statuses[i] = map[string]interface{}{
+			"slot":               rec.slot,
+			"confirmations":      nil,
+			"err":                nil,
+			"confirmationStatus": "finalized",
+		}
+	}
+	return map[string]interface{}{
+		"context": rpcContext{Slot: s.Slot()},
+		"value":   statuses,
+	}, nil
+}
+
+func (s *Simulator) rpcGetTransaction(params []json.RawMessage) (interface{}, *rpcError) {
+	var str string
+	if err := param(params, 0, &str); err != nil {
+		return nil, err
+	}
+	sig, err := solana.SignatureFromBase58(str)
+	if err != nil {
+		return nil, invalidParams("Invalid param: %v", err)
+	}
+	rec := s.transaction(sig)
+	if rec == nil {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"slot":      rec.slot,
+		"blockTime": rec.blockTime,
+		// Always answer in binary form; rpc.TransactionResultEnvelope
+		// decodes it regardless of the requested encoding.
+		"transaction": []string{base64.StdEncoding.EncodeToString(rec.raw), "base64"},
+		"meta": map[string]interface{}{
+			"err":                  nil,
+			"fee":                  rec.fee,
+			"preBalances":          rec.preBalances,
+			"postBalances":         rec.postBalances,
+			"innerInstructions":    []interface{}{},
+			"preTokenBalances":     []interface{}{},
+			"postTokenBalances":    []interface{}{},
+			"logMessages":          rec.logs,
+			"status":               map[string]interface{}{"Ok": nil},
+			"rewards":              []interface{}{},
+			"computeUnitsConsumed": rec.units,
+		},
+	}, nil
+}
+
+func (s *Simulator) rpcGetSignaturesForAddress(params []json.RawMessage) (interface{}, *rpcError) {
+	pk, rerr := pubkeyParam(params, 0)
+	if rerr != nil {
+		return nil, rerr
+	}
+	var opts struct {
+		Limit  int    `json:"limit"`
+		Before string `json:"before"`
+		Until  string `json:"until"`
+	}
+	if err := param(params, 1, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Limit <= 0 || opts.Limit > 1000 {
+		opts.Limit = 1000
+	}
+
+	out := make([]map[string]interface{}, 0)
+	started := opts.Before == ""
+	for _, rec := range s.signaturesFor(pk) {
+		sig := rec.signature.String()
+		if !started {
+			started = sig == opts.Before
+			continue
+		}
+		if sig == opts.Until || len(out) >= opts.Limit {
+			break
+		}
+		out = append(out, map[string]interface{}{
+			"signature":          sig,
+			"slot":               rec.slot,
+			"err":                nil,
+			"memo":               nil,
+			"blockTime":          rec.blockTime,
+			"confirmationStatus": "finalized",
+		})
+	}
+	return out, nil
+}
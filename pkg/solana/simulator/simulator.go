@@ -0,0 +1,410 @@
+// Package simulator implements a lightweight in-process Solana JSON-RPC
+// server backed by in-memory account state. It verifies transaction
+// signatures and applies system program transfers and durable nonce advances,
+// which is enough to drive pkg/solana.Client and the wallet API end to end
+// without network access.
+package simulator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+)
+
+const (
+	// LamportsPerSignature is the flat fee charged per transaction signature.
+	LamportsPerSignature = 5000
+	// blockhashValidity mirrors the ~150 slot lifetime of a real blockhash.
+	blockhashValidity = 150
+	// systemTransferUnits is the compute cost reported for a system transfer.
+	systemTransferUnits = 150
+	// memoUnits is the compute cost reported for a memo instruction.
+	memoUnits = 1000
+)
+
+var (
+	errBlockhashNotFound = errors.New("BlockhashNotFound")
+	errAccountNotFound   = errors.New("AccountNotFound")
+	errInsufficientFee   = errors.New("InsufficientFundsForFee")
+)
+
+// instructionError mirrors the {"InstructionError":[index,{"Custom":code}]}
+// shape returned by real validators.
+type instructionError struct {
+	Index int
+	Code  uint32
+	Msg   string
+}
+
+func (e *instructionError) Error() string {
+	return fmt.Sprintf("instruction %d failed: %s", e.Index, e.Msg)
+}
+
+// txRecord is a processed transaction as kept by the simulator.
+type txRecord struct {
+	signature    solana.Signature
+	raw          []byte
+	slot         uint64
+	blockTime    int64
+	fee          uint64
+	accountKeys  solana.PublicKeySlice
+	preBalances  []uint64
+	postBalances []uint64
+	logs         []string
+	units        uint64
+}
+
+// execResult is the outcome of executing a transaction against a state copy.
+type execResult struct {
+	balances map[solana.PublicKey]uint64
+	fee      uint64
+	pre      []uint64
+	post     []uint64
+	logs     []string
+	units    uint64
+	err      error
+	// nonces holds the nonce accounts the transaction advanced.
+	nonces map[solana.PublicKey]solana.Hash
+}
+
+// Simulator holds the in-memory chain state.
+type Simulator struct {
+	mu        sync.Mutex
+	slot      uint64
+	now       func() time.Time
+	accounts  map[solana.PublicKey]uint64
+	blockhash map[solana.Hash]uint64 // blockhash -> slot it was issued at
+	latest    solana.Hash
+	txs       map[solana.Signature]*txRecord
+	byAddress map[solana.PublicKey][]solana.Signature
+	nonces    map[solana.PublicKey]*nonceState
+	pubsub    pubsub
+}
+
+// New returns a simulator at slot 1 with no funded accounts.
+func New() *Simulator {
+	s := &Simulator{
+		slot:      1,
+		now:       time.Now,
+		accounts:  make(map[solana.PublicKey]uint64),
+		blockhash: make(map[solana.Hash]uint64),
+		txs:       make(map[solana.Signature]*txRecord),
+		byAddress: make(map[solana.PublicKey][]solana.Signature),
+		nonces:    make(map[solana.PublicKey]*nonceState),
+		pubsub: pubsub{
+			subs:  make(map[uint64]*wsSubscription),
+			conns: make(map[*wsConn]struct{}),
+		},
+	}
+	s.rotateBlockhash()
+	return s
+}
+
+// Server is a simulator listening on a local HTTP port.
+type Server struct {
+	*Simulator
+	*httptest.Server
+}
+
+// NewServer starts a simulator behind an httptest server. Callers point
+// pkg/solana.NewClient at Server.URL, subscriptions at Server.WSURL, and
+// must Close it when done.
+func NewServer() *Server {
+	sim := New()
+	return &Server{Simulator: sim, Server: httptest.NewServer(sim)}
+}
+
+// Airdrop credits lamports to an account.
+func (s *Simulator) Airdrop(account solana.PublicKey, lamports uint64) {
+	s.mu.Lock()
+	s.accounts[account] += lamports
+	balance, slot := s.accounts[account], s.slot
+	s.mu.Unlock()
+	s.publishAccounts(slot, map[solana.PublicKey]uint64{account: balance})
+}
+
+// Balance returns the lamports held by an account.
+func (s *Simulator) Balance(account solana.PublicKey) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accounts[account]
+}
+
+// Slot returns the current slot.
+func (s *Simulator) Slot() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.slot
+}
+
+// rotateBlockhash derives a fresh blockhash for the current slot and forgets
+// the ones that are no longer valid. Callers must hold mu.
+func (s *Simulator) rotateBlockhash() {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], s.slot)
+	s.latest = solana.Hash(sha256.Sum256(buf[:]))
+	s.blockhash[s.latest] = s.slot
+	for h, slot := range s.blockhash {
+		if s.slot-slot > blockhashValidity {
+			delete(s.blockhash, h)
+		}
+	}
+}
+
+// latestBlockhash returns the current blockhash and its last valid height.
+func (s *Simulator) latestBlockhash() (solana.Hash, uint64, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest, s.slot + blockhashValidity, s.slot
+}
+
+// execute runs tx against a copy of the current state without committing.
+// Callers must hold mu.
+func (s *Simulator) execute(tx *solana.Transaction, verify bool) (*execResult, error) {
+	if verify {
+		if err := tx.VerifySignatures(); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := s.blockhash[tx.Message.RecentBlockhash]; !ok && !s.usesDurableNonce(tx) {
+		return nil, errBlockhashNotFound
+	}
+
+	keys := tx.Message.AccountKeys
+	if len(keys) == 0 {
+		return nil, errAccountNotFound
+	}
+
+	res := &execResult{
+		balances: make(map[solana.PublicKey]uint64, len(keys)),
+		fee:      fee(&tx.Message),
+	}
+	for _, k := range keys {
+		res.balances[k] = s.accounts[k]
+		res.pre = append(res.pre, s.accounts[k])
+	}
+
+	payer := keys[0]
+	if _, ok := s.accounts[payer]; !ok {
+		return nil, errAccountNotFound
+	}
+	if res.balances[payer] < res.fee {
+		return nil, errInsufficientFee
+	}
+	res.balances[payer] -= res.fee
+
+	// The fee is charged even when an instruction fails, so snapshot the
+	// post-fee state for rollback.
+	afterFee := make(map[solana.PublicKey]uint64, len(res.balances))
+	for k, v := range res.balances {
+		afterFee[k] = v
+	}
+
+	for i, inst := range tx.Message.Instructions {
+		if err := s.executeInstruction(tx, i, inst, res); err != nil {
+			res.err = err
+			res.balances = afterFee
+			break
+		}
+	}
+
+	for _, k := range keys {
+		res.post = append(res.post, res.balances[k])
+	}
+	return res, nil
+}
+
+func (s *Simulator) executeInstruction(tx *solana.Transaction, index int, inst solana.CompiledInstruction, res *execResult) error {
+	programID, err := tx.ResolveProgramIDIndex(inst.ProgramIDIndex)
+	if err != nil {
+		return &instructionError{Index: index, Msg: err.Error()}
+	}
+	res.logs = append(res.logs, fmt.Sprintf("Program %s invoke [1]", programID))
+
+	if programID.Equals(solana.MemoProgramID) {
+		return s.executeMemo(tx, index, inst, programID, res)
+	}
+	if !programID.Equals(solana.SystemProgramID) {
+		res.logs = append(res.logs, fmt.Sprintf("Program %s failed: unsupported program", programID))
+		return &instructionError{Index: index, Msg: "unsupported program " + programID.String()}
+	}
+
+	accounts, err := inst.ResolveInstructionAccounts(&tx.Message)
+	if err != nil {
+		return &instructionError{Index: index, Msg: err.Error()}
+	}
+	decoded, err := system.DecodeInstruction(accounts, inst.Data)
+	if err != nil {
+		return &instructionError{Index: index, Msg: err.Error()}
+	}
+
+	if advance, ok := decoded.Impl.(*system.AdvanceNonceAccount); ok {
+		return s.executeAdvanceNonce(index, advance, programID, res)
+	}
+	transfer, ok := decoded.Impl.(*system.Transfer)
+	if !ok {
+		res.logs = append(res.logs, fmt.Sprintf("Program %s failed: unsupported instruction", programID))
+		return &instructionError{Index: index, Msg: "unsupported system instruction"}
+	}
+
+	from := transfer.GetFundingAccount().PublicKey
+	to := transfer.GetRecipientAccount().PublicKey
+	lamports := *transfer.Lamports
+	if !transfer.GetFundingAccount().IsSigner {
+		return &instructionError{Index: index, Msg: "missing required signature"}
+	}
+	if res.balances[from] < lamports {
+		res.logs = append(res.logs,
+			fmt.Sprintf("Transfer: insufficient lamports %d, need %d", res.balances[from], lamports),
+			fmt.Sprintf("Program %s failed: custom program error: 0x1", programID))
+		// SystemError::ResultWithNegativeLamports
+		return &instructionError{Index: index, Code: 1, Msg: "insufficient lamports"}
+	}
+	res.balances[from] -= lamports
+	res.balances[to] += lamports
+	res.units += systemTransferUnits
+	res.logs = append(res.logs, fmt.Sprintf("Program %s success", programID))
+	return nil
+}
+
+// executeMemo validates a memo instruction: the memo must be UTF-8 and every
+// referenced account must have signed the transaction.
+func (s *Simulator) executeMemo(tx *solana.Transaction, index int, inst solana.CompiledInstruction, programID solana.PublicKey, res *execResult) error {
+	accounts, err := inst.ResolveInstructionAccounts(&tx.Message)
+	if err != nil {
+		return &instructionError{Index: index, Msg: err.Error()}
+	}
+	for _, account := range accounts {
+		if !account.IsSigner {
+			return &instructionError{Index: index, Msg: "missing required signature"}
+		}
+	}
+	if !utf8.Valid(inst.Data) {
+		res.logs = append(res.logs, fmt.Sprintf("Program %s failed: invalid UTF-8", programID))
+		return &instructionError{Index: index, Msg: "invalid instruction data"}
+	}
+	res.units += memoUnits
+	res.logs = append(res.logs,
+		fmt.Sprintf("Program log: Memo (len %d): %q", len(inst.Data), string(inst.Data)),
+		fmt.Sprintf("Program %s success", programID))
+	return nil
+}
+
+// send executes tx and, on success, commits it in a new slot and notifies
+// subscribers.
+func (s *Simulator) send(tx *solana.Transaction, raw []byte) (solana.Signature, error) {
+	rec, err := s.commit(tx, raw)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	s.publishTransaction(rec)
+	return rec.signature, nil
+}
+
+func (s *Simulator) commit(tx *solana.Transaction, raw []byte) (*txRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(tx.Signatures) == 0 {
+		return nil, errors.New("transaction has no signatures")
+	}
+	sig := tx.Signatures[0]
+	if _, dup := s.txs[sig]; dup {
+		return nil, errors.New("AlreadyProcessed")
+	}
+
+	res, err := s.execute(tx, true)
+	if err != nil {
+		return nil, err
+	}
+	if res.err != nil {
+		// Preflight rejects failing transactions before they land.
+		return nil, res.err
+	}
+
+	for k, v := range res.balances {
+		s.accounts[k] = v
+	}
+	for k, v := range res.nonces {
+		s.nonces[k].value = v
+	}
+	s.slot++
+	s.rotateBlockhash()
+
+	rec := &txRecord{
+		signature:    sig,
+		raw:          raw,
+		slot:         s.slot,
+		blockTime:    s.now().Unix(),
+		fee:          res.fee,
+		accountKeys:  tx.Message.AccountKeys,
+		preBalances:  res.pre,
+		postBalances: res.post,
+		logs:         res.logs,
+		units:        res.units,
+	}
+	s.txs[sig] = rec
+	for _, k := range tx.Message.AccountKeys {
+		s.byAddress[k] = append(s.byAddress[k], sig)
+	}
+	return rec, nil
+}
+
+// simulate executes tx against the current state and discards the result.
+// It also returns the post-simulation state of addresses, with nil for
+// accounts that do not exist.
+func (s *Simulator) simulate(tx *solana.Transaction, verify, replaceBlockhash bool, addresses []solana.PublicKey) (*execResult, []interface{}, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if replaceBlockhash {
+		tx.Message.RecentBlockhash = s.latest
+	}
+	res, err := s.execute(tx, verify)
+	if err != nil || res.err != nil {
+		// Validators return no account state for failed simulations.
+		return res, nil, s.slot, err
+	}
+	accounts := make([]interface{}, len(addresses))
+	for i, k := range addresses {
+		lamports, ok := res.balances[k]
+		if !ok {
+			lamports, ok = s.accounts[k]
+		}
+		if ok {
+			accounts[i] = accountValue(lamports)
+		}
+	}
+	return res, accounts, s.slot, nil
+}
+
+// fee returns the fee a message would be charged.
+func fee(msg *solana.Message) uint64 {
+	return uint64(msg.Header.NumRequiredSignatures) * LamportsPerSignature
+}
+
+// transaction looks up a processed transaction by signature.
+func (s *Simulator) transaction(sig solana.Signature) *txRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txs[sig]
+}
+
+// signaturesFor returns the signatures touching account, newest first.
+func (s *Simulator) signaturesFor(account solana.PublicKey) []*txRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sigs := s.byAddress[account]
+	out := make([]*txRecord, 0, len(sigs))
+	for i := len(sigs) - 1; i >= 0; i-- {
+		out = append(out, s.txs[sigs[i]])
+	}
+	return out
+}
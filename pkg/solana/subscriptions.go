@@ -0,0 +1,590 @@
+package solana
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"mywallet/pkg/logger"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPingInterval      = 20 * time.Second
+	defaultReconnectDelay    = 500 * time.Millisecond
+	defaultMaxReconnectDelay = 30 * time.Second
+	defaultSubscriptionBuf   = 64
+
+	wsWriteTimeout = 10 * time.Second
+)
+
+// ErrSubscriptionClosed is reported by Subscription.Err once the manager
+// stopped.
+var ErrSubscriptionClosed = errors.New("subscription manager stopped")
+
+// SubscriptionOptions tunes a SubscriptionManager.
+type SubscriptionOptions struct {
+	// PingInterval is how often the connection is pinged. It is considered
+	// dead when nothing, not even a pong, arrived for twice as long.
+	PingInterval time.Duration
+	// ReconnectDelay is the delay before the first reconnect attempt. It
+	// doubles with every failed attempt up to MaxReconnectDelay, with jitter.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// Buffer is the capacity of each subscription's channel.
+	Buffer int
+}
+
+// SubscriptionStatus is a snapshot of a SubscriptionManager.
+type SubscriptionStatus struct {
+	Connected bool `json:"connected"`
+	// Topics counts the distinct node subscriptions, Subscribers the
+	// Subscriptions sharing them.
+	Topics      int    `json:"topics"`
+	Subscribers int    `json:"subscribers"`
+	Reconnects  uint64 `json:"reconnects"`
+	// Dropped counts notifications discarded because a channel was full.
+	Dropped uint64 `json:"dropped"`
+}
+
+// AccountUpdate is an accountNotification.
+type AccountUpdate struct {
+	Slot       uint64
+	Lamports   uint64
+	Owner      solana.PublicKey
+	Data       []byte
+	Executable bool
+}
+
+// SignatureUpdate is a signatureNotification. Err is the transaction error,
+// nil when it succeeded.
+type SignatureUpdate struct {
+	Slot uint64
+	Err  interface{}
+}
+
+// LogsUpdate is a logsNotification.
+type LogsUpdate struct {
+	Slot      uint64
+	Signature string
+	Err       interface{}
+	Logs      []string
+}
+
+// Subscription delivers the notifications of one subscribe call.
+type Subscription[T any] struct {
+	// C receives the notifications. It is closed after Unsubscribe, after
+	// the single notification of a signature subscription, when the node
+	// rejected the subscription and when the manager stopped; Err tells
+	// which.
+	C <-chan T
+	// Resync receives a value whenever notifications may have been missed:
+	// after a reconnect, or when C was full and a notification was dropped.
+	// Consumers should then re-read the state they track.
+	Resync <-chan struct{}
+
+	m   *SubscriptionManager
+	sub *subscriber
+}
+
+// Unsubscribe stops the subscription and closes C.
+func (s *Subscription[T]) Unsubscribe() {
+	s.m.unsubscribe(s.sub)
+}
+
+// Err returns why C was closed: nil after Unsubscribe or a completed
+// signature subscription, the node's error for a rejected subscription, or
+// ErrSubscriptionClosed.
+func (s *Subscription[T]) Err() error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	return s.sub.err
+}
+
+// SubscriptionManager multiplexes subscriptions over one Solana pubsub
+// websocket. Subscriptions with the same method and parameters share a single
+// node subscription. The connection is kept alive with pings and re-dialled
+// when it drops, after which every subscription is renewed.
+type SubscriptionManager struct {
+	url    string
+	opts   SubscriptionOptions
+	logger *logger.Logger
+
+	mu         sync.Mutex
+	conn       *wsConn
+	topics     map[string]*topic
+	byServerID map[uint64]*topic
+	requests   map[uint64]*topic
+	nextID     uint64
+	reconnects uint64
+	dropped    uint64
+	stopped    bool
+}
+
+// topic is one node subscription and the subscribers sharing it.
+type topic struct {
+	key         string
+	method      string
+	unsubscribe string
+	params      []interface{}
+	// once marks signature subscriptions, which the node ends after their
+	// first notification.
+	once        bool
+	serverID    uint64
+	subscribed  bool
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	topic *topic
+	// deliver decodes a notification and sends it without blocking,
+	// reporting false when the channel was full.
+	deliver func(result json.RawMessage) bool
+	close   func()
+	resync  chan struct{}
+	closed  bool
+	err     error
+}
+
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (c *wsConn) writeJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(v)
+}
+
+func (c *wsConn) ping() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+}
+
+type wsRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type wsMessage struct {
+	ID     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Method string `json:"method"`
+	Params struct {
+		Result       json.RawMessage `json:"result"`
+		Subscription uint64          `json:"subscription"`
+	} `json:"params"`
+}
+
+// NewSubscriptionManager returns a manager for the pubsub endpoint wsURL.
+// Subscriptions may be made right away; they are sent once Run connected.
+func NewSubscriptionManager(wsURL string, opts SubscriptionOptions, logger *logger.Logger) *SubscriptionManager {
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = defaultPingInterval
+	}
+	if opts.ReconnectDelay <= 0 {
+		opts.ReconnectDelay = defaultReconnectDelay
+	}
+	if opts.MaxReconnectDelay <= 0 {
+		opts.MaxReconnectDelay = defaultMaxReconnectDelay
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultSubscriptionBuf
+	}
+	return &SubscriptionManager{
+		url:        wsURL,
+		opts:       opts,
+		logger:     logger,
+		topics:     make(map[string]*topic),
+		byServerID: make(map[uint64]*topic),
+		requests:   make(map[uint64]*topic),
+	}
+}
+
+// SubscribeAccount notifies of every change to account.
+func (m *SubscriptionManager) SubscribeAccount(account solana.PublicKey, commitment rpc.CommitmentType) *Subscription[AccountUpdate] {
+	params := []interface{}{account.String(), map[string]string{"encoding": "base64", "commitment": string(commitment)}}
+	return subscribe(m, "accountSubscribe", "accountUnsubscribe", params, false, decodeAccountUpdate)
+}
+
+// SubscribeSignature notifies once, when the transaction reached commitment.
+func (m *SubscriptionManager) SubscribeSignature(signature solana.Signature, commitment rpc.CommitmentType) *Subscription[SignatureUpdate] {
+	params := []interface{}{signature.String(), map[string]string{"commitment": string(commitment)}}
+	return subscribe(m, "signatureSubscribe", "signatureUnsubscribe", params, true, decodeSignatureUpdate)
+}
+
+// SubscribeLogs notifies of the logs of every transaction mentioning account.
+func (m *SubscriptionManager) SubscribeLogs(mentions solana.PublicKey, commitment rpc.CommitmentType) *Subscription[LogsUpdate] {
+	params := []interface{}{map[string][]string{"mentions": {mentions.String()}}, map[string]string{"commitment": string(commitment)}}
+	return subscribe(m, "logsSubscribe", "logsUnsubscribe", params, false, decodeLogsUpdate)
+}
+
+func subscribe[T any](m *SubscriptionManager, method, unsubscribe string, params []interface{}, once bool, decode func(json.RawMessage) (T, error)) *Subscription[T] {
+	ch := make(chan T, m.opts.Buffer)
+	resync := make(chan struct{}, 1)
+	sub := &subscriber{
+		resync: resync,
+		close:  func() { close(ch) },
+	}
+	sub.deliver = func(result json.RawMessage) bool {
+		v, err := decode(result)
+		if err != nil {
+			m.logger.Logger.Warn("failed to decode notification", zap.String("method", method), zap.Error(err))
+			return true
+		}
+		select {
+		case ch <- v:
+			return true
+		default:
+			return false
+		}
+	}
+
+	encoded, _ := json.Marshal(params)
+	key := method + string(encoded)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		sub.closed, sub.err = true, ErrSubscriptionClosed
+		sub.close()
+		return &Subscription[T]{C: ch, Resync: resync, m: m, sub: sub}
+	}
+
+	t, ok := m.topics[key]
+	if !ok {
+		t = &topic{
+			key:         key,
+			method:      method,
+			unsubscribe: unsubscribe,
+			params:      params,
+			once:        once,
+			subscribers: make(map[*subscriber]struct{}),
+		}
+		m.topics[key] = t
+		if m.conn != nil {
+			m.sendSubscribe(m.conn, t)
+		}
+	}
+	sub.topic = t
+	t.subscribers[sub] = struct{}{}
+	return &Subscription[T]{C: ch, Resync: resync, m: m, sub: sub}
+}
+
+func (m *SubscriptionManager) unsubscribe(sub *subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	m.closeSubscriber(sub, nil)
+
+	t := sub.topic
+	delete(t.subscribers, sub)
+	if len(t.subscribers) > 0 || m.topics[t.key] != t {
+		return
+	}
+	delete(m.topics, t.key)
+	if t.subscribed {
+		delete(m.byServerID, t.serverID)
+		if m.conn != nil {
+			m.send(m.conn, t.unsubscribe, []interface{}{t.serverID}, nil)
+		}
+	}
+}
+
+// Status returns a snapshot of the manager.
+func (m *SubscriptionManager) Status() SubscriptionStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := SubscriptionStatus{
+		Connected:  m.conn != nil,
+		Topics:     len(m.topics),
+		Reconnects: m.reconnects,
+		Dropped:    m.dropped,
+	}
+	for _, t := range m.topics {
+		s.Subscribers += len(t.subscribers)
+	}
+	return s
+}
+
+// Run keeps the connection up until ctx is cancelled, then closes every
+// subscription with ErrSubscriptionClosed.
+func (m *SubscriptionManager) Run(ctx context.Context) {
+	defer m.stop()
+
+	delay := m.opts.ReconnectDelay
+	for connected := false; ; {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, m.url, nil)
+		if err == nil {
+			delay = m.opts.ReconnectDelay
+			m.serve(ctx, &wsConn{conn: conn}, connected)
+			connected = true
+		} else if ctx.Err() == nil {
+			m.logger.Logger.Warn("failed to connect to solana pubsub", zap.Error(err))
+		}
+
+		// Full jitter keeps many instances from reconnecting at once.
+		wait := time.Duration(rand.Int63n(int64(delay) + 1))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if delay *= 2; delay > m.opts.MaxReconnectDelay {
+			delay = m.opts.MaxReconnectDelay
+		}
+	}
+}
+
+// serve renews every subscription on conn and dispatches its messages until
+// it fails or ctx is cancelled.
+func (m *SubscriptionManager) serve(ctx context.Context, conn *wsConn, reconnect bool) {
+	deadline := 2 * m.opts.PingInterval
+	_ = conn.conn.SetReadDeadline(time.Now().Add(deadline))
+	conn.conn.SetPongHandler(func(string) error {
+		return conn.conn.SetReadDeadline(time.Now().Add(deadline))
+	})
+
+	m.mu.Lock()
+	m.conn = conn
+	if reconnect {
+		m.reconnects++
+	}
+	for _, t := range m.topics {
+		m.sendSubscribe(conn, t)
+		if reconnect {
+			for sub := range t.subscribers {
+				signal(sub.resync)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(m.opts.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				_ = conn.conn.Close()
+				return
+			case <-ticker.C:
+				if err := conn.ping(); err != nil {
+					_ = conn.conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var msg wsMessage
+		if err := conn.conn.ReadJSON(&msg); err != nil {
+			if ctx.Err() == nil {
+				m.logger.Logger.Warn("solana pubsub connection lost", zap.Error(err))
+			}
+			break
+		}
+		_ = conn.conn.SetReadDeadline(time.Now().Add(deadline))
+		m.dispatch(conn, &msg)
+	}
+
+	_ = conn.conn.Close()
+	m.mu.Lock()
+	m.conn = nil
+	m.byServerID = make(map[uint64]*topic)
+	m.requests = make(map[uint64]*topic)
+	for _, t := range m.topics {
+		t.subscribed = false
+	}
+	m.mu.Unlock()
+}
+
+func (m *SubscriptionManager) dispatch(conn *wsConn, msg *wsMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg.ID != nil {
+		t, ok := m.requests[*msg.ID]
+		delete(m.requests, *msg.ID)
+		if !ok || t == nil {
+			// Unsubscribe acknowledgements need no handling.
+			return
+		}
+		if msg.Error != nil {
+			err := fmt.Errorf("%s rejected: %s (%d)", t.method, msg.Error.Message, msg.Error.Code)
+			m.logger.Logger.Warn("solana pubsub subscription rejected", zap.Error(err))
+			m.endTopic(t, err)
+			return
+		}
+		var id uint64
+		if err := json.Unmarshal(msg.Result, &id); err != nil {
+			m.endTopic(t, fmt.Errorf("%s: invalid subscription id: %w", t.method, err))
+			return
+		}
+		if m.topics[t.key] != t {
+			// Everyone unsubscribed while the request was in flight.
+			m.send(conn, t.unsubscribe, []interface{}{id}, nil)
+			return
+		}
+		t.serverID, t.subscribed = id, true
+		m.byServerID[id] = t
+		return
+	}
+
+	t, ok := m.byServerID[msg.Params.Subscription]
+	if !ok {
+		return
+	}
+	for sub := range t.subscribers {
+		if !sub.deliver(msg.Params.Result) {
+			m.dropped++
+			signal(sub.resync)
+		}
+	}
+	if t.once {
+		delete(m.byServerID, t.serverID)
+		m.endTopic(t, nil)
+	}
+}
+
+// endTopic closes every subscriber of t and forgets it. Callers must hold mu.
+func (m *SubscriptionManager) endTopic(t *topic, err error) {
+	for sub := range t.subscribers {
+		m.closeSubscriber(sub, err)
+	}
+	if m.topics[t.key] == t {
+		delete(m.topics, t.key)
+	}
+}
+
+// closeSubscriber closes sub's channel once. Callers must hold mu.
+func (m *SubscriptionManager) closeSubscriber(sub *subscriber, err error) {
+	if sub.closed {
+		return
+	}
+	sub.closed, sub.err = true, err
+	sub.close()
+}
+
+// sendSubscribe asks the node for t. Callers must hold mu.
+func (m *SubscriptionManager) sendSubscribe(conn *wsConn, t *topic) {
+	m.send(conn, t.method, t.params, t)
+}
+
+// send writes a request; t is the topic a subscribe request is for. A failed
+// write is left to the read loop, which notices the broken connection and
+// reconnects. Callers must hold mu.
+func (m *SubscriptionManager) send(conn *wsConn, method string, params []interface{}, t *topic) {
+	m.nextID++
+	id := m.nextID
+	if t != nil {
+		m.requests[id] = t
+	}
+	if err := conn.writeJSON(wsRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}); err != nil {
+		m.logger.Logger.Warn("failed to write solana pubsub request", zap.String("method", method), zap.Error(err))
+		_ = conn.conn.Close()
+	}
+}
+
+func (m *SubscriptionManager) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	for _, t := range m.topics {
+		m.endTopic(t, ErrSubscriptionClosed)
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+type notificationEnvelope struct {
+	Context struct {
+		Slot uint64 `json:"slot"`
+	} `json:"context"`
+	Value json.RawMessage `json:"value"`
+}
+
+func decodeAccountUpdate(raw json.RawMessage) (AccountUpdate, error) {
+	var env notificationEnvelope
+	var value struct {
+		Lamports   uint64           `json:"lamports"`
+		Owner      solana.PublicKey `json:"owner"`
+		Data       []string         `json:"data"`
+		Executable bool             `json:"executable"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return AccountUpdate{}, err
+	}
+	if err := json.Unmarshal(env.Value, &value); err != nil {
+		return AccountUpdate{}, err
+	}
+	u := AccountUpdate{Slot: env.Context.Slot, Lamports: value.Lamports, Owner: value.Owner, Executable: value.Executable}
+	if len(value.Data) > 0 && value.Data[0] != "" {
+		data, err := base64.StdEncoding.DecodeString(value.Data[0])
+		if err != nil {
+			return AccountUpdate{}, fmt.Errorf("invalid account data: %w", err)
+		}
+		u.Data = data
+	}
+	return u, nil
+}
+
+func decodeSignatureUpdate(raw json.RawMessage) (SignatureUpdate, error) {
+	var env notificationEnvelope
+	var value struct {
+		Err interface{} `json:"err"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return SignatureUpdate{}, err
+	}
+	if err := json.Unmarshal(env.Value, &value); err != nil {
+		return SignatureUpdate{}, err
+	}
+	return SignatureUpdate{Slot: env.Context.Slot, Err: value.Err}, nil
+}
+
+func decodeLogsUpdate(raw json.RawMessage) (LogsUpdate, error) {
+	var env notificationEnvelope
+	var value struct {
+		Signature string      `json:"signature"`
+		Err       interface{} `json:"err"`
+		Logs      []string    `json:"logs"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return LogsUpdate{}, err
+	}
+	if err := json.Unmarshal(env.Value, &value); err != nil {
+		return LogsUpdate{}, err
+	}
+	return LogsUpdate{Slot: env.Context.Slot, Signature: value.Signature, Err: value.Err, Logs: value.Logs}, nil
+}
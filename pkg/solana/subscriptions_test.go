@@ -0,0 +1,163 @@
+package solana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mywallet/pkg/logger"
+	"mywallet/pkg/solana/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startSubscriptions(t *testing.T, sim *simulator.Server) (*SubscriptionManager, context.CancelFunc) {
+	m := NewSubscriptionManager(sim.WSURL(), SubscriptionOptions{ReconnectDelay: 10 * time.Millisecond}, logger.NewLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	go m.Run(ctx)
+	t.Cleanup(cancel)
+	return m, cancel
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification")
+	}
+	var zero T
+	return zero
+}
+
+func waitSubscriptions(t *testing.T, sim *simulator.Server, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return sim.Subscriptions() == n }, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestSubscribeAccountMultiplexes(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	m, _ := startSubscriptions(t, sim)
+
+	account := solana.NewWallet().PublicKey()
+	a := m.SubscribeAccount(account, rpc.CommitmentConfirmed)
+	b := m.SubscribeAccount(account, rpc.CommitmentConfirmed)
+	waitSubscriptions(t, sim, 1)
+	assert.Equal(t, 1, m.Status().Topics)
+	assert.Equal(t, 2, m.Status().Subscribers)
+
+	sim.Airdrop(account, solana.LAMPORTS_PER_SOL)
+	assert.Equal(t, solana.LAMPORTS_PER_SOL, receive(t, a.C).Lamports)
+	assert.Equal(t, solana.LAMPORTS_PER_SOL, receive(t, b.C).Lamports)
+
+	a.Unsubscribe()
+	_, open := <-a.C
+	assert.False(t, open)
+	assert.NoError(t, a.Err())
+	waitSubscriptions(t, sim, 1)
+
+	b.Unsubscribe()
+	waitSubscriptions(t, sim, 0)
+	assert.Zero(t, m.Status().Topics)
+}
+
+func TestSubscribeSignatureAndLogs(t *testing.T) {
+	ctx := context.Background()
+	sim := simulator.NewServer()
+	defer sim.Close()
+	m, _ := startSubscriptions(t, sim)
+
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+
+	client := rpc.New(sim.URL)
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	require.NoError(t, err)
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{system.NewTransferInstruction(1000, from.PublicKey(), to).Build()},
+		recent.Value.Blockhash,
+		solana.TransactionPayer(from.PublicKey()),
+	)
+	require.NoError(t, err)
+	_, err = tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &from.PrivateKey })
+	require.NoError(t, err)
+
+	sigSub := m.SubscribeSignature(tx.Signatures[0], rpc.CommitmentFinalized)
+	logsSub := m.SubscribeLogs(to, rpc.CommitmentConfirmed)
+	waitSubscriptions(t, sim, 2)
+
+	_, err = client.SendTransaction(ctx, tx)
+	require.NoError(t, err)
+
+	update := receive(t, sigSub.C)
+	assert.Nil(t, update.Err)
+	_, open := <-sigSub.C
+	assert.False(t, open, "a signature subscription ends after its notification")
+	assert.NoError(t, sigSub.Err())
+
+	logs := receive(t, logsSub.C)
+	assert.Equal(t, tx.Signatures[0].String(), logs.Signature)
+	assert.NotEmpty(t, logs.Logs)
+}
+
+func TestSubscriptionsSurviveReconnects(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	m, cancel := startSubscriptions(t, sim)
+
+	account := solana.NewWallet().PublicKey()
+	sub := m.SubscribeAccount(account, rpc.CommitmentConfirmed)
+	waitSubscriptions(t, sim, 1)
+
+	sim.DropConnections()
+	receive(t, sub.Resync)
+	waitSubscriptions(t, sim, 1)
+	assert.Equal(t, uint64(1), m.Status().Reconnects)
+
+	sim.Airdrop(account, 42)
+	assert.Equal(t, uint64(42), receive(t, sub.C).Lamports)
+
+	cancel()
+	require.Eventually(t, func() bool { return sub.Err() != nil }, 2*time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, sub.Err(), ErrSubscriptionClosed)
+	_, open := <-sub.C
+	assert.False(t, open)
+}
+
+func TestRejectedSubscriptionIsClosed(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	m, _ := startSubscriptions(t, sim)
+
+	sub := subscribe(m, "accountSubscribe", "accountUnsubscribe", []interface{}{"not-a-key"}, false, decodeAccountUpdate)
+	_, open := <-sub.C
+	assert.False(t, open)
+	assert.Error(t, sub.Err())
+	assert.Zero(t, m.Status().Topics)
+}
+
+func TestTransferNotifiesAccountSubscribers(t *testing.T) {
+	sim := simulator.NewServer()
+	defer sim.Close()
+	m, _ := startSubscriptions(t, sim)
+
+	from := solana.NewWallet()
+	to := solana.NewWallet().PublicKey()
+	sim.Airdrop(from.PublicKey(), solana.LAMPORTS_PER_SOL)
+	sub := m.SubscribeAccount(to, rpc.CommitmentConfirmed)
+	waitSubscriptions(t, sim, 1)
+
+	client := NewClient(sim.URL, logger.NewLogger())
+	_, err := client.Transfer(context.Background(), from.PrivateKey, to, decimal.NewFromFloat(0.25))
+	require.NoError(t, err)
+	assert.Equal(t, solana.LAMPORTS_PER_SOL/4, receive(t, sub.C).Lamports)
+}
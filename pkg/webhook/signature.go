@@ -0,0 +1,81 @@
+// Package webhook signs and verifies webhook deliveries.
+//
+// Each delivery carries a Unix timestamp header and a signature header of the
+// form "v1=<hex>", where <hex> is HMAC-SHA256(secret, timestamp + "." + body).
+// Receivers should recompute the signature over the raw request body and
+// reject timestamps outside a small tolerance to prevent replays.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderEvent carries the event type.
+	HeaderEvent = "X-MyWallet-Event"
+	// HeaderDelivery carries the delivery ID, stable across retries.
+	HeaderDelivery = "X-MyWallet-Delivery"
+	// HeaderTimestamp carries the Unix time the delivery was signed at.
+	HeaderTimestamp = "X-MyWallet-Timestamp"
+	// HeaderSignature carries the signature, "v1=<hex>".
+	HeaderSignature = "X-MyWallet-Signature"
+
+	signatureVersion = "v1"
+
+	// DefaultTolerance is the recommended maximum age of a delivery.
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrInvalidTimestamp = errors.New("webhook: invalid timestamp")
+	ErrExpired          = errors.New("webhook: timestamp outside tolerance")
+)
+
+// Sign returns the signature header value for body signed at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signatureVersion + "=" + hex.EncodeToString(mac(secret, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+// Verify checks the signature and timestamp headers of a delivery against the
+// raw body. A zero tolerance disables the timestamp age check.
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if tolerance > 0 {
+		age := time.Since(time.Unix(unix, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrExpired
+		}
+	}
+
+	expected := mac(secret, timestamp, body)
+	// Multiple signatures may be sent while secrets rotate.
+	for _, part := range strings.Split(signature, ",") {
+		version, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || version != signatureVersion {
+			continue
+		}
+		got, err := hex.DecodeString(value)
+		if err == nil && hmac.Equal(got, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"type":"deposit.completed"}`)
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := Sign("secret", now, body)
+
+	assert.NoError(t, Verify("secret", sig, ts, body, DefaultTolerance))
+	assert.NoError(t, Verify("secret", "v1=00,"+sig, ts, body, DefaultTolerance))
+
+	assert.ErrorIs(t, Verify("other", sig, ts, body, DefaultTolerance), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", sig, ts, []byte(`{}`), DefaultTolerance), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", sig, "abc", body, DefaultTolerance), ErrInvalidTimestamp)
+
+	old := now.Add(-time.Hour)
+	assert.ErrorIs(t, Verify("secret", Sign("secret", old, body), strconv.FormatInt(old.Unix(), 10), body, DefaultTolerance), ErrExpired)
+}